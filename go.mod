@@ -11,6 +11,7 @@ require (
 	github.com/google/certificate-transparency-go v1.1.7
 	github.com/google/gopacket v1.1.19
 	github.com/miekg/dns v1.1.58
+	github.com/quic-go/quic-go v0.41.0
 	github.com/refraction-networking/utls v1.6.1
 	golang.org/x/crypto v0.18.0
 	golang.org/x/net v0.20.0
@@ -21,9 +22,14 @@ require (
 	github.com/andybalholm/brotli v1.1.0 // indirect
 	github.com/cloudflare/circl v1.3.7 // indirect
 	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/go-task/slim-sprig v0.0.0-20230315185526-52ccab3ef572 // indirect
+	github.com/google/pprof v0.0.0-20210407192527-94a9f03dee38 // indirect
 	github.com/klauspost/compress v1.17.4 // indirect
-	github.com/quic-go/quic-go v0.41.0 // indirect
+	github.com/onsi/ginkgo/v2 v2.9.5 // indirect
+	go.uber.org/mock v0.3.0 // indirect
+	golang.org/x/exp v0.0.0-20221205204356-47842c84f3db // indirect
 	golang.org/x/mod v0.14.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 	golang.org/x/tools v0.17.0 // indirect
 	google.golang.org/protobuf v1.32.0 // indirect
 	k8s.io/klog/v2 v2.120.1 // indirect
@@ -112,7 +112,7 @@ func MaxBatchSize(url string) (int64, error) {
 // start is the start index and fetch as many log entries as possible with one query.
 // The returned int64 counts the number of parsed log entries.
 //
-// This function use IsValid() and append only the unique entries.
+// This function use IsDomain() and append only the unique entries normalized with dns.Clean() (eg.: lower cased, U-labels converted to A-labels).
 func GetDomains(url string, start int64) ([]string, int64, error) {
 
 	c, err := client.New(url, http.DefaultClient, jsonclient.Options{})
@@ -139,55 +139,32 @@ func GetDomains(url string, start int64) ([]string, int64, error) {
 		n++
 
 		if e.X509Cert != nil {
-
-			if dns.IsDomain(e.X509Cert.Subject.CommonName) {
-				r = slicer.AppendUnique(r, e.X509Cert.Subject.CommonName)
-			}
-
-			for ii := range e.X509Cert.DNSNames {
-				if dns.IsDomain(e.X509Cert.DNSNames[ii]) {
-					r = slicer.AppendUnique(r, e.X509Cert.DNSNames[ii])
-				}
-			}
-
-			for ii := range e.X509Cert.PermittedDNSDomains {
-				if dns.IsDomain(e.X509Cert.PermittedDNSDomains[ii]) {
-					r = slicer.AppendUnique(r, e.X509Cert.PermittedDNSDomains[ii])
-				}
-			}
-
-			for ii := range e.X509Cert.ExcludedDNSDomains {
-				if dns.IsDomain(e.X509Cert.ExcludedDNSDomains[ii]) {
-					r = slicer.AppendUnique(r, e.X509Cert.ExcludedDNSDomains[ii])
-				}
-			}
+			r = appendDomains(r, e.X509Cert.Subject.CommonName)
+			r = appendDomains(r, e.X509Cert.DNSNames...)
+			r = appendDomains(r, e.X509Cert.PermittedDNSDomains...)
+			r = appendDomains(r, e.X509Cert.ExcludedDNSDomains...)
 		}
 
 		if e.Precert != nil && e.Precert.TBSCertificate != nil {
-
-			if dns.IsDomain(e.Precert.TBSCertificate.Subject.CommonName) {
-				r = slicer.AppendUnique(r, e.Precert.TBSCertificate.Subject.CommonName)
-			}
-
-			for ii := range e.Precert.TBSCertificate.DNSNames {
-				if dns.IsDomain(e.Precert.TBSCertificate.DNSNames[ii]) {
-					r = slicer.AppendUnique(r, e.Precert.TBSCertificate.DNSNames[ii])
-				}
-			}
-
-			for ii := range e.Precert.TBSCertificate.PermittedDNSDomains {
-				if dns.IsDomain(e.Precert.TBSCertificate.PermittedDNSDomains[ii]) {
-					r = slicer.AppendUnique(r, e.Precert.TBSCertificate.PermittedDNSDomains[ii])
-				}
-			}
-
-			for ii := range e.Precert.TBSCertificate.ExcludedDNSDomains {
-				if dns.IsDomain(e.Precert.TBSCertificate.ExcludedDNSDomains[ii]) {
-					r = slicer.AppendUnique(r, e.Precert.TBSCertificate.ExcludedDNSDomains[ii])
-				}
-			}
+			r = appendDomains(r, e.Precert.TBSCertificate.Subject.CommonName)
+			r = appendDomains(r, e.Precert.TBSCertificate.DNSNames...)
+			r = appendDomains(r, e.Precert.TBSCertificate.PermittedDNSDomains...)
+			r = appendDomains(r, e.Precert.TBSCertificate.ExcludedDNSDomains...)
 		}
 	}
 
 	return r, n, nil
 }
+
+// appendDomains appends the valid domains from names to r if not already in it.
+// The domains are normalized with dns.Clean(), so the U-label and the A-label ("xn--") form of a name is appended once.
+func appendDomains(r []string, names ...string) []string {
+
+	for i := range names {
+		if dns.IsDomain(names[i]) {
+			r = slicer.AppendUnique(r, dns.Clean(names[i]))
+		}
+	}
+
+	return r
+}
@@ -77,3 +77,12 @@ func TestGetDomains(t *testing.T) {
 	t.Logf("index: %d, total: %d\n", index.Load(), total)
 
 }
+
+func TestAppendDomains(t *testing.T) {
+
+	r := appendDomains(nil, "Bücher.Example", "xn--bcher-kva.example", "bücher.example.", "WWW.example.com", "www.example.com", "*.example.com", "")
+
+	if strings.Join(r, ",") != "xn--bcher-kva.example,www.example.com" {
+		t.Fatalf("FAIL: Invalid domains: %v\n", r)
+	}
+}
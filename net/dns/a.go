@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"fmt"
 	"net"
 
@@ -9,16 +10,8 @@ import (
 
 var TypeA uint16 = 1
 
-// QueryA ask the server and returns a slice of net.IP.
-// The answer slice will be nil in case of error.
-//
-// The other record types are ignored.
-func (s *Server) QueryA(name string) ([]net.IP, error) {
-
-	rr, err := s.query(name, TypeA)
-	if err != nil {
-		return nil, err
-	}
+// parseA converts the answer rr to A.
+func parseA(rr []mdns.RR) ([]net.IP, error) {
 
 	r := make([]net.IP, 0, len(rr))
 
@@ -33,6 +26,9 @@ func (s *Server) QueryA(name string) ([]net.IP, error) {
 		case *mdns.DNAME:
 			// Ignore DNAME
 			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
 		default:
 			return nil, fmt.Errorf("unknown type: %T", v)
 		}
@@ -41,22 +37,56 @@ func (s *Server) QueryA(name string) ([]net.IP, error) {
 	return r, nil
 }
 
+// QueryA ask the server and returns a slice of net.IP.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QueryA(name string) ([]net.IP, error) {
+
+	return s.QueryAContext(context.Background(), name)
+}
+
+// QueryAContext is like QueryA, but the query is bound to ctx.
+func (s *Server) QueryAContext(ctx context.Context, name string) ([]net.IP, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeA)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseA(rr)
+}
+
 // QueryA ask a random server from servers and returns a slice of net.IP.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func (s *Servers) QueryA(name string) ([]net.IP, error) {
 
-	return s.Get(-1).QueryA(name)
+	return s.QueryAContext(context.Background(), name)
 }
 
-// QueryA ask a random server from DefaultServers and returns a slice of net.IP.
+// QueryAContext is like QueryA, but the query is bound to ctx.
+func (s *Servers) QueryAContext(ctx context.Context, name string) ([]net.IP, error) {
+
+	return s.Get(-1).QueryAContext(ctx, name)
+}
+
+// QueryA asks the DefaultResolver and returns a slice of net.IP.
+// Unlike Servers.QueryA, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QueryA is the same as TryQueryA.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func QueryA(name string) ([]net.IP, error) {
 
-	return DefaultServers.QueryA(name)
+	return QueryAContext(context.Background(), name)
+}
+
+// QueryAContext is like QueryA, but the query is bound to ctx.
+func QueryAContext(ctx context.Context, name string) ([]net.IP, error) {
+
+	return QueryAWith(ctx, DefaultResolver, name)
 }
 
 // TryQueryA asks the servers for type A. If any error occurred, retries with next server (except if error is NXDOMAIN).
@@ -66,50 +96,61 @@ func QueryA(name string) ([]net.IP, error) {
 // The first used server is random. The other record types are ignored.
 func (s *Servers) TryQueryA(name string) ([]net.IP, error) {
 
-	rr, err := s.TryQuery(name, TypeA)
-	if err != nil {
-		return nil, err
-	}
+	return s.TryQueryAContext(context.Background(), name)
+}
 
-	r := make([]net.IP, 0, len(rr))
+// TryQueryAContext is like TryQueryA, but the query is bound to ctx.
+func (s *Servers) TryQueryAContext(ctx context.Context, name string) ([]net.IP, error) {
 
-	for i := range rr {
-
-		switch v := rr[i].(type) {
-		case *mdns.A:
-			r = append(r, v.A)
-		case *mdns.CNAME:
-			// Ignore CNAME
-			continue
-		case *mdns.DNAME:
-			// Ignore DNAME
-			continue
-		default:
-			return nil, fmt.Errorf("unknown type: %T", v)
-		}
-	}
-
-	return r, nil
+	return QueryAWith(ctx, s, name)
 }
 
-// TryQueryA asks the DefaultServers for type A. If any error occurred, retries with next server (except if error is NXDOMAIN).
+// TryQueryA asks the DefaultResolver for type A. If any error occurred, retries with next server (except if error is NXDOMAIN).
 //
 // In case of error, the answer will be nil and return ErrX or any unknown error.
 //
 // The first used server is random. The other record types are ignored.
 func TryQueryA(name string) ([]net.IP, error) {
 
-	return DefaultServers.TryQueryA(name)
+	return TryQueryAContext(context.Background(), name)
+}
+
+// TryQueryAContext is like TryQueryA, but the query is bound to ctx.
+func TryQueryAContext(ctx context.Context, name string) ([]net.IP, error) {
+
+	return QueryAWith(ctx, DefaultResolver, name)
+}
+
+// QueryAWith asks r for type A and converts the answer like QueryA.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QueryAWith(ctx context.Context, r Resolver, name string) ([]net.IP, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeA)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseA(rr)
 }
 
 // IsSetA checks whether an A type record set for name.
 // NXDOMAIN is not an error here, because it means "not found".
 func (s *Servers) IsSetA(name string) (bool, error) {
-	return s.IsSet(name, TypeA)
+	return s.IsSetAContext(context.Background(), name)
 }
 
-// IsSetA checks whether an A type record set for name using the DefaultServers.
+// IsSetAContext is like IsSetA, but the query is bound to ctx.
+func (s *Servers) IsSetAContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeA)
+}
+
+// IsSetA checks whether an A type record set for name using the DefaultResolver.
 // NXDOMAIN is not an error here, because it means "not found".
 func IsSetA(name string) (bool, error) {
-	return DefaultServers.IsSetA(name)
+	return IsSetAContext(context.Background(), name)
+}
+
+// IsSetAContext is like IsSetA, but the query is bound to ctx.
+func IsSetAContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeA)
 }
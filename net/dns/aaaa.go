@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"fmt"
 	"net"
 
@@ -9,16 +10,8 @@ import (
 
 var TypeAAAA uint16 = 28
 
-// QueryAAAA ask the server and returns a slice of net.IP.
-// The answer slice will be nil in case of error.
-//
-// The other record types are ignored.
-func (s *Server) QueryAAAA(name string) ([]net.IP, error) {
-
-	rr, err := s.query(name, TypeAAAA)
-	if err != nil {
-		return nil, err
-	}
+// parseAAAA converts the answer rr to AAAA.
+func parseAAAA(rr []mdns.RR) ([]net.IP, error) {
 
 	r := make([]net.IP, 0, len(rr))
 
@@ -33,6 +26,9 @@ func (s *Server) QueryAAAA(name string) ([]net.IP, error) {
 		case *mdns.DNAME:
 			// Ignore DNAME
 			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
 		default:
 			return nil, fmt.Errorf("unknown type: %T", v)
 		}
@@ -41,22 +37,56 @@ func (s *Server) QueryAAAA(name string) ([]net.IP, error) {
 	return r, nil
 }
 
+// QueryAAAA ask the server and returns a slice of net.IP.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QueryAAAA(name string) ([]net.IP, error) {
+
+	return s.QueryAAAAContext(context.Background(), name)
+}
+
+// QueryAAAAContext is like QueryAAAA, but the query is bound to ctx.
+func (s *Server) QueryAAAAContext(ctx context.Context, name string) ([]net.IP, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeAAAA)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAAAA(rr)
+}
+
 // QueryAAAA ask a random server from servers and returns a slice of net.IP.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func (s *Servers) QueryAAAA(name string) ([]net.IP, error) {
 
-	return s.Get(-1).QueryAAAA(name)
+	return s.QueryAAAAContext(context.Background(), name)
 }
 
-// QueryAAAA ask a random server from DefaultServers and returns a slice of net.IP.
+// QueryAAAAContext is like QueryAAAA, but the query is bound to ctx.
+func (s *Servers) QueryAAAAContext(ctx context.Context, name string) ([]net.IP, error) {
+
+	return s.Get(-1).QueryAAAAContext(ctx, name)
+}
+
+// QueryAAAA asks the DefaultResolver and returns a slice of net.IP.
+// Unlike Servers.QueryAAAA, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QueryAAAA is the same as TryQueryAAAA.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func QueryAAAA(name string) ([]net.IP, error) {
 
-	return DefaultServers.QueryAAAA(name)
+	return QueryAAAAContext(context.Background(), name)
+}
+
+// QueryAAAAContext is like QueryAAAA, but the query is bound to ctx.
+func QueryAAAAContext(ctx context.Context, name string) ([]net.IP, error) {
+
+	return QueryAAAAWith(ctx, DefaultResolver, name)
 }
 
 // TryQueryAAAA asks the servers for type AAAA. If any error occurred, retries with next server (except if error is NXDOMAIN).
@@ -66,50 +96,61 @@ func QueryAAAA(name string) ([]net.IP, error) {
 // The first used server is random. The other record types are ignored.
 func (s *Servers) TryQueryAAAA(name string) ([]net.IP, error) {
 
-	rr, err := s.TryQuery(name, TypeAAAA)
-	if err != nil {
-		return nil, err
-	}
+	return s.TryQueryAAAAContext(context.Background(), name)
+}
 
-	r := make([]net.IP, 0, len(rr))
+// TryQueryAAAAContext is like TryQueryAAAA, but the query is bound to ctx.
+func (s *Servers) TryQueryAAAAContext(ctx context.Context, name string) ([]net.IP, error) {
 
-	for i := range rr {
-
-		switch v := rr[i].(type) {
-		case *mdns.AAAA:
-			r = append(r, v.AAAA)
-		case *mdns.CNAME:
-			// Ignore CNAME
-			continue
-		case *mdns.DNAME:
-			// Ignore DNAME
-			continue
-		default:
-			return nil, fmt.Errorf("unknown type: %T", v)
-		}
-	}
-
-	return r, nil
+	return QueryAAAAWith(ctx, s, name)
 }
 
-// TryQueryAAAA asks the DefaultServers for type A. If any error occurred, retries with next server (except if error is NXDOMAIN).
+// TryQueryAAAA asks the DefaultResolver for type A. If any error occurred, retries with next server (except if error is NXDOMAIN).
 //
 // In case of error, the answer will be nil and return ErrX or any unknown error.
 //
 // The first used server is random. The other record types are ignored.
 func TryQueryAAAA(name string) ([]net.IP, error) {
 
-	return DefaultServers.TryQueryAAAA(name)
+	return TryQueryAAAAContext(context.Background(), name)
+}
+
+// TryQueryAAAAContext is like TryQueryAAAA, but the query is bound to ctx.
+func TryQueryAAAAContext(ctx context.Context, name string) ([]net.IP, error) {
+
+	return QueryAAAAWith(ctx, DefaultResolver, name)
+}
+
+// QueryAAAAWith asks r for type AAAA and converts the answer like QueryAAAA.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QueryAAAAWith(ctx context.Context, r Resolver, name string) ([]net.IP, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeAAAA)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAAAA(rr)
 }
 
 // IsSetAAAA checks whether an AAAA type record set for name.
 // NXDOMAIN is not an error here, because it means "not found".
 func (s *Servers) IsSetAAAA(name string) (bool, error) {
-	return s.IsSet(name, TypeAAAA)
+	return s.IsSetAAAAContext(context.Background(), name)
 }
 
-// IsSetAAAA checks whether an AAAA type record set for name using the DefaultServers.
+// IsSetAAAAContext is like IsSetAAAA, but the query is bound to ctx.
+func (s *Servers) IsSetAAAAContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeAAAA)
+}
+
+// IsSetAAAA checks whether an AAAA type record set for name using the DefaultResolver.
 // NXDOMAIN is not an error here, because it means "not found".
 func IsSetAAAA(name string) (bool, error) {
-	return DefaultServers.IsSetAAAA(name)
+	return IsSetAAAAContext(context.Background(), name)
+}
+
+// IsSetAAAAContext is like IsSetAAAA, but the query is bound to ctx.
+func IsSetAAAAContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeAAAA)
 }
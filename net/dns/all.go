@@ -1,274 +1,95 @@
 package dns
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/elmasy-com/slices"
 	mdns "github.com/miekg/dns"
 )
 
+// queryAllTypes is the types queried in QueryAll in order.
+var queryAllTypes = []uint16{TypeA, TypeAAAA, TypeCAA, TypeCNAME, TypeDNAME, TypeMX, TypeNS, TypeSOA, TypeSRV, TypeTXT, TypeTLSA, TypeSSHFP, TypeDS, TypeDNSKEY, TypeHTTPS, TypeSVCB, TypeNAPTR}
+
+// queryAllOptional is the types of queryAllTypes that are treated as empty in QueryAll if the query fails
+// (eg.: NOTIMP, FORMERR or SERVFAIL from a server that does not support the type).
+var queryAllOptional = []uint16{TypeTLSA, TypeSSHFP, TypeDS, TypeDNSKEY, TypeHTTPS, TypeSVCB, TypeNAPTR}
+
 type Record struct {
 	Type  uint16
 	Value string
 }
 
 // QueryAll query every known type and returns the records.
-// This function checks whether name with the type is a wildcard, and if name is a wildcard, ommit from the retuned []Record.
+// This function probes the parent of name for wildcard with every type (see ProbeWildcard()),
+// and omits the answer of a type from the returned []Record if it matches the wildcard fingerprint.
+// The genuine records under a wildcard parent are returned.
+//
+// The failed queries of TLSA, SSHFP, DS, DNSKEY, HTTPS, SVCB and NAPTR are treated as empty answers,
+// because some servers do not support them.
 //
 // It is possible to return records when error returned.
 func (s *Servers) QueryAll(name string) ([]Record, error) {
 
-	var (
-		rr = make([]mdns.RR, 0)
-	)
-
-	/*
-	 * A
-	 */
-
-	r, err := s.TryQuery(name, TypeA)
-	if err != nil {
-
-		// NXDOMAIN means, that there is no record for name
-		// If server responds NOERROR with 0 answer, means that there is a record for name, but not with the given type
-		return nil, err
-
-	}
-
-	if len(r) > 0 {
-
-		// Checks whether name is a wildcard
-		wc, err := s.IsWildcard(name, TypeA)
-		if err != nil {
-
-			// Ignore error and assume that name is a wildcard
-			wc = true
-		}
-
-		// If not a wildcard domain, append the result
-		if !wc {
-			rr = append(rr, r...)
-		}
-	}
-
-	/*
-	 * AAAA
-	 */
-
-	r, err = s.TryQuery(name, TypeAAAA)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(r) > 0 {
-
-		// Checks whether name is a wildcard
-		wc, err := s.IsWildcard(name, TypeAAAA)
-		if err != nil {
-			// Ignore error and assume that name is a wildcard
-			wc = true
-		}
-
-		// If not a wildcard domain, append the result
-		if !wc {
-			rr = append(rr, r...)
-		}
-	}
-
-	/*
-	 * CAA
-	 */
-
-	r, err = s.TryQuery(name, TypeCAA)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(r) > 0 {
-
-		// Checks whether name is a wildcard
-		wc, err := s.IsWildcard(name, TypeCAA)
-		if err != nil {
-			// Ignore error and assume that name is a wildcard
-			wc = true
-		}
-
-		// If not a wildcard domain, append the result
-		if !wc {
-			rr = append(rr, r...)
-		}
-	}
-
-	/*
-	 * CNAME
-	 */
-
-	r, err = s.TryQuery(name, TypeCNAME)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(r) > 0 {
-
-		// Checks whether name is a wildcard
-		wc, err := s.IsWildcard(name, TypeCNAME)
-		if err != nil {
-			// Ignore error and assume that name is a wildcard
-			wc = true
-		}
+	return s.QueryAllContext(context.Background(), name)
+}
 
-		// If not a wildcard domain, append the result
-		if !wc {
-			rr = append(rr, r...)
-		}
-	}
+// QueryAllContext is like QueryAll, but the queries and the wildcard checks are bound to ctx.
+func (s *Servers) QueryAllContext(ctx context.Context, name string) ([]Record, error) {
 
-	/*
-	 * DNAME
-	 */
+	return QueryAllWith(ctx, s, name)
+}
 
-	r, err = s.TryQuery(name, TypeDNAME)
-	if err != nil {
-		return nil, err
-	}
+// QueryAllWith is like QueryAll, but uses r for the queries and the wildcard checks (see ProbeWildcardWith()).
+func QueryAllWith(ctx context.Context, r Resolver, name string) ([]Record, error) {
 
-	if len(r) > 0 {
+	var (
+		rs = make([]Record, 0)
+	)
 
-		// Checks whether name is a wildcard
-		wc, err := s.IsWildcard(name, TypeDNAME)
-		if err != nil {
-			// Ignore error and assume that name is a wildcard
-			wc = true
-		}
+	for _, t := range queryAllTypes {
 
-		// If not a wildcard domain, append the result
-		if !wc {
-			rr = append(rr, r...)
+		rr, err := r.TryQueryContext(ctx, name, t)
+		if err != nil && ctx.Err() == nil && !errors.Is(err, ErrName) && slices.Contains(queryAllOptional, t) {
+			continue
 		}
-	}
-
-	/*
-	 * MX
-	 */
-	r, err = s.TryQuery(name, TypeMX)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(r) > 0 {
 
-		// Checks whether name is a wildcard
-		wc, err := s.IsWildcard(name, TypeMX)
 		if err != nil {
-			// Ignore error and assume that name is a wildcard
-			wc = true
-		}
 
-		// If not a wildcard domain, append the result
-		if !wc {
-			rr = append(rr, r...)
+			// NXDOMAIN means, that there is no record for name
+			// If server responds NOERROR with 0 answer, means that there is a record for name, but not with the given type
+			return nil, err
 		}
-	}
-
-	/*
-	 * NS
-	 */
-
-	r, err = s.TryQuery(name, TypeNS)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(r) > 0 {
 
-		// Checks whether name is a wildcard
-		wc, err := s.IsWildcard(name, TypeNS)
-		if err != nil {
-			// Ignore error and assume that name is a wildcard
-			wc = true
-		}
-
-		// If not a wildcard domain, append the result
-		if !wc {
-			rr = append(rr, r...)
+		if len(rr) == 0 {
+			continue
 		}
-	}
-
-	/*
-	 * SOA
-	 */
 
-	r, err = s.TryQuery(name, TypeSOA)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(r) > 0 {
-
-		// Checks whether name is a wildcard
-		wc, err := s.IsWildcard(name, TypeSOA)
+		recs, err := toRecords(rr)
 		if err != nil {
-			// Ignore error and assume that name is a wildcard
-			wc = true
+			return rs, err
 		}
 
-		// If not a wildcard domain, append the result
-		if !wc {
-			rr = append(rr, r...)
-		}
-	}
-
-	/*
-	 * SRV
-	 */
-
-	r, err = s.TryQuery(name, TypeSRV)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(r) > 0 {
-
-		// Checks whether name is a wildcard
-		wc, err := s.IsWildcard(name, TypeSRV)
+		// Checks whether the answer is synthesized from a wildcard
+		wc, err := ProbeWildcardWith(ctx, r, name, t)
 		if err != nil {
-			// Ignore error and assume that name is a wildcard
-			wc = true
+			// Ignore error and assume that the answer is from a wildcard
+			continue
 		}
 
-		// If not a wildcard domain, append the result
-		if !wc {
-			rr = append(rr, r...)
+		for i := range wc.Filter(recs) {
+			rs = slices.AppendUnique(rs, recs[i])
 		}
 	}
 
-	/*
-	 * TXT
-	 */
-
-	r, err = s.TryQuery(name, TypeTXT)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(r) > 0 {
+	return rs, nil
+}
 
-		// Checks whether name is a wildcard
-		wc, err := s.IsWildcard(name, TypeTXT)
-		if err != nil {
-			// Ignore error and assume that name is a wildcard
-			wc = true
-		}
+// toRecords converts rr to a slice of unique Record.
+// Returns the already converted records and an error on unknown type.
+func toRecords(rr []mdns.RR) ([]Record, error) {
 
-		// If not a wildcard domain, append the result
-		if !wc {
-			rr = append(rr, r...)
-		}
-	}
-
-	/*
-	 * Read the answers
-	 */
 	rs := make([]Record, 0, len(rr))
 
 	for i := range rr {
@@ -288,6 +109,8 @@ func (s *Servers) QueryAll(name string) ([]Record, error) {
 			rs = slices.AppendUnique(rs, Record{Type: TypeMX, Value: fmt.Sprintf("%d %s", v.Preference, v.Mx)})
 		case *mdns.NS:
 			rs = slices.AppendUnique(rs, Record{Type: TypeNS, Value: v.Ns})
+		case *mdns.PTR:
+			rs = slices.AppendUnique(rs, Record{Type: TypePTR, Value: v.Ptr})
 		case *mdns.SOA:
 			rs = slices.AppendUnique(rs, Record{Type: TypeSOA, Value: fmt.Sprintf("%s %s %d %d %d %d %d", v.Ns, v.Mbox, v.Serial, v.Refresh, v.Retry, v.Expire, v.Minttl)})
 		case *mdns.SRV:
@@ -296,6 +119,23 @@ func (s *Servers) QueryAll(name string) ([]Record, error) {
 			for ii := range v.Txt {
 				rs = slices.AppendUnique(rs, Record{Type: TypeTXT, Value: v.Txt[ii]})
 			}
+		case *mdns.TLSA:
+			rs = slices.AppendUnique(rs, Record{Type: TypeTLSA, Value: TLSA{Usage: v.Usage, Selector: v.Selector, MatchingType: v.MatchingType, Certificate: v.Certificate}.String()})
+		case *mdns.SSHFP:
+			rs = slices.AppendUnique(rs, Record{Type: TypeSSHFP, Value: SSHFP{Algorithm: v.Algorithm, Type: v.Type, FingerPrint: v.FingerPrint}.String()})
+		case *mdns.DS:
+			rs = slices.AppendUnique(rs, Record{Type: TypeDS, Value: DS{KeyTag: v.KeyTag, Algorithm: v.Algorithm, DigestType: v.DigestType, Digest: v.Digest}.String()})
+		case *mdns.DNSKEY:
+			rs = slices.AppendUnique(rs, Record{Type: TypeDNSKEY, Value: DNSKEY{Flags: v.Flags, Protocol: v.Protocol, Algorithm: v.Algorithm, PublicKey: v.PublicKey}.String()})
+		case *mdns.HTTPS:
+			rs = slices.AppendUnique(rs, Record{Type: TypeHTTPS, Value: newSVCB(&v.SVCB).String()})
+		case *mdns.SVCB:
+			rs = slices.AppendUnique(rs, Record{Type: TypeSVCB, Value: newSVCB(v).String()})
+		case *mdns.NAPTR:
+			rs = slices.AppendUnique(rs, Record{Type: TypeNAPTR, Value: NAPTR{Order: v.Order, Preference: v.Preference, Flags: v.Flags, Service: v.Service, Regexp: v.Regexp, Replacement: v.Replacement}.String()})
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
 		default:
 			return rs, fmt.Errorf("unknown type: %T", v)
 		}
@@ -305,8 +145,14 @@ func (s *Servers) QueryAll(name string) ([]Record, error) {
 }
 
 // QueryAll query every known type and returns the records.
-// This function checks whether name with the type is a wildcard.
+// This function omits the answers that match the wildcard fingerprint of the parent of name.
 func QueryAll(name string) ([]Record, error) {
 
-	return DefaultServers.QueryAll(name)
+	return QueryAllWith(context.Background(), DefaultResolver, name)
+}
+
+// QueryAllContext is like QueryAll, but the queries and the wildcard checks are bound to ctx.
+func QueryAllContext(ctx context.Context, name string) ([]Record, error) {
+
+	return QueryAllWith(ctx, DefaultResolver, name)
 }
@@ -2,8 +2,12 @@ package dns
 
 import (
 	"errors"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
+	mdns "github.com/miekg/dns"
 )
 
 func TestQueryAll(t *testing.T) {
@@ -34,6 +38,41 @@ func TestQueryAllInvalid(t *testing.T) {
 	}
 }
 
+func TestServersQueryAllLocal(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, dnstest.MustZone("example.com",
+		"@ 300 IN A 192.0.2.1",
+		"@ 300 IN AAAA 2001:db8::1",
+		"@ 300 IN MX 10 mail",
+		"@ 300 IN TXT \"v=spf1 -all\"",
+	))
+
+	rr, err := srvs.QueryAll("example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	types := make(map[uint16]int)
+	for i := range rr {
+		types[rr[i].Type]++
+	}
+
+	for _, typ := range []uint16{TypeA, TypeAAAA, TypeMX, TypeTXT, TypeSOA} {
+		if types[typ] != 1 {
+			t.Fatalf("FAIL: expected one %s record, got %d\n", TypeToString(typ), types[typ])
+		}
+	}
+
+	rr, err = srvs.QueryAll("invalid.example.com")
+	if err != nil && !errors.Is(err, ErrName) {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(rr) > 0 {
+		t.Fatalf("FAIL: Invalid number of response: want: 0, got: %d\n", len(rr))
+	}
+}
+
 func BenchmarkQueryAll(b *testing.B) {
 
 	// Sleep 2 sec to not overflow the DNS server
@@ -67,3 +106,70 @@ func BenchmarkQueryAllInvalid(b *testing.B) {
 		srvs.QueryAll("invalid.example.com")
 	}
 }
+
+func TestServersQueryAllTyped(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, dnstest.MustZone(".",
+		"example.com. 300 IN A 192.0.2.1",
+		"example.com. 300 IN SSHFP 4 2 123456789abcdef67890123456789abcdef67890123456789abcdef123456789",
+		"example.com. 300 IN DS 60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118",
+		"example.com. 300 IN HTTPS 1 . alpn=h2,h3 ipv4hint=192.0.2.1",
+		"example.com. 300 IN NAPTR 100 10 \"S\" \"SIP+D2U\" \"\" _sip._udp.example.com.",
+	))
+
+	rr, err := srvs.QueryAll("example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	want := []string{
+		"A 192.0.2.1",
+		"SSHFP 4 2 123456789abcdef67890123456789abcdef67890123456789abcdef123456789",
+		"DS 60485 5 1 2bb183af5f22588179a53b0a98631fad1a292118",
+		"HTTPS 1 . alpn=h2,h3 ipv4hint=192.0.2.1",
+		"NAPTR 100 10 \"S\" \"SIP+D2U\" \"\" _sip._udp.example.com.",
+	}
+
+	if len(rr) != len(want) {
+		t.Fatalf("FAIL: Invalid number of records: %d, want: %d\n%v\n", len(rr), len(want), rr)
+	}
+
+	for i := range want {
+		if got := TypeToString(rr[i].Type) + " " + rr[i].Value; !strings.EqualFold(got, want[i]) {
+			t.Fatalf("FAIL: record wanted: %s, record got: %s\n", want[i], got)
+		}
+	}
+}
+
+func TestServersQueryAllUnsupportedType(t *testing.T) {
+
+	srvs, ts := newZoneTestServers(t, dnstest.MustZone("example.com",
+		"@ 300 IN A 192.0.2.1",
+		"@ 300 IN HTTPS 1 . alpn=h2",
+	))
+
+	// The server does not support HTTPS
+	ts.SetRule("", mdns.TypeHTTPS, dnstest.Rule{Rcode: mdns.RcodeNotImplemented})
+
+	rr, err := srvs.QueryAll("example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	for i := range rr {
+		if rr[i].Type == TypeHTTPS {
+			t.Fatalf("FAIL: Unexpected HTTPS record: %v\n", rr)
+		}
+	}
+
+	if len(rr) != 2 || rr[0] != (Record{Type: TypeA, Value: "192.0.2.1"}) {
+		t.Fatalf("FAIL: Invalid records: %v\n", rr)
+	}
+
+	// The failure of a baseline type is still an error
+	ts.SetRule("", mdns.TypeTXT, dnstest.Rule{Rcode: mdns.RcodeServerFailure})
+
+	if _, err := srvs.QueryAll("example.com"); !errors.Is(err, ErrServerFailure) {
+		t.Fatalf("FAIL: Expected ErrServerFailure, got: %v\n", err)
+	}
+}
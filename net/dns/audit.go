@@ -0,0 +1,580 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elmasy-com/slices"
+	mdns "github.com/miekg/dns"
+)
+
+// Severity is the severity of a Finding.
+type Severity int
+
+const (
+	SeverityInfo     Severity = iota // Informational, deviates from the recommendations
+	SeverityWarning                  // Degrades the resiliency or the security of the zone
+	SeverityCritical                 // The resolution is broken or unreliable
+)
+
+func (s Severity) String() string {
+
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// Finding is an issue found by Audit.
+type Finding struct {
+	Severity Severity
+	Check    string // "delegation", "ns", "glue", "lame", "transport", "recursion" or "soa"
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s: %s", f.Severity, f.Check, f.Message)
+}
+
+// AuditConfig configures Audit.
+type AuditConfig struct {
+	Port          string        // Port of the nameservers, defaults to "53"
+	Timeout       time.Duration // Timeout of a query, defaults to 2 seconds
+	RecursionName string        // Name outside of the zone asked recursively for NS to detect the open resolvers, defaults to "."
+}
+
+// NSAudit is the result of the checks of an address of a nameserver.
+type NSAudit struct {
+	NS            string   // Name of the nameserver
+	IP            string   // Empty if the address of NS cant be resolved
+	Glue          bool     // IP is in the glue of the parent
+	UDP           bool     // Reachable over UDP
+	TCP           bool     // Reachable over TCP
+	Rcode         string   // Rcode of the SOA query, empty if unreachable
+	Authoritative bool     // The SOA answer has the AA flag
+	SOA           *SOA     // Nil if not authoritative
+	Nameservers   []string // The sorted NS set of the zone served by the nameserver
+	Recursive     bool     // Answered the recursive query for AuditConfig.RecursionName
+	Unreachable   bool     // The local host has no route to IP (eg.: IPv6 on an IPv4 only host), IP is not checked
+	Err           error
+}
+
+// AuditReport is the nameserver configuration of a zone.
+type AuditReport struct {
+	Zone     string
+	Parent   string              // The parent zone, empty if not found
+	ParentNS []string            // The sorted NS set in the delegation of the parent
+	Glue     map[string][]string // The glue addresses in the delegation by nameserver
+	ChildNS  []string            // The sorted NS set served by the first authoritative nameserver
+	SOA      *SOA                // The SOA served by the first authoritative nameserver
+	Servers  []NSAudit           // A result for every address of every nameserver (in the parent or in the zone), sorted by NS and IP
+	Findings []Finding
+}
+
+// add appends a Finding to the AuditReport.
+func (r *AuditReport) add(sev Severity, check string, format string, a ...any) {
+
+	r.Findings = append(r.Findings, Finding{Severity: sev, Check: check, Message: fmt.Sprintf(format, a...)})
+}
+
+// Max returns the highest severity of the findings.
+// Returns SeverityInfo if there is no finding.
+func (r *AuditReport) Max() Severity {
+
+	max := SeverityInfo
+
+	for i := range r.Findings {
+		if r.Findings[i].Severity > max {
+			max = r.Findings[i].Severity
+		}
+	}
+
+	return max
+}
+
+// directQuery sends a query for name with type t to s without the EDNS options of s and retries over TCP if the response is truncated.
+// If rd is false, the RD flag is cleared.
+func directQuery(ctx context.Context, s *Server, name string, t uint16, rd bool) (*mdns.Msg, error) {
+
+	msg := new(mdns.Msg)
+	msg.SetQuestion(name, t)
+	msg.RecursionDesired = rd
+
+	in, err := s.exchangeContext(ctx, msg)
+	if err == nil && in.Truncated && s.Protocol == "udp" {
+		in, err = s.ToTCP().exchangeContext(ctx, msg)
+	}
+
+	return in, err
+}
+
+// Audit checks the nameserver configuration of zone: the delegation in the parent zone (NS set and glue),
+// the reachability over UDP and TCP, the authority (lame delegation), the recursion (open resolver) and the SOA of every address of every nameserver.
+// The parent zone and the addresses of the nameservers are resolved with s, the nameservers are queried directly.
+//
+// The lookup errors are reported in the findings, error is returned only if ctx is done.
+func (s *Servers) Audit(zone string, conf AuditConfig) (*AuditReport, error) {
+
+	return s.AuditContext(context.Background(), zone, conf)
+}
+
+// AuditContext is like Audit, but the queries are bound to ctx.
+func (s *Servers) AuditContext(ctx context.Context, zone string, conf AuditConfig) (*AuditReport, error) {
+
+	if conf.Port == "" {
+		conf.Port = "53"
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = 2 * time.Second
+	}
+
+	if conf.RecursionName == "" {
+		conf.RecursionName = "."
+	}
+
+	r := &AuditReport{Zone: strings.ToLower(mdns.Fqdn(zone)), Glue: make(map[string][]string)}
+
+	r.auditDelegation(ctx, s, conf)
+
+	nss, err := s.TryQueryNSContext(ctx, r.Zone)
+	if err != nil && ctx.Err() == nil {
+		r.add(SeverityWarning, "ns", "failed to query NS: %s", err)
+	}
+
+	names := append([]string(nil), r.ParentNS...)
+	for i := range nss {
+		names = slices.AppendUnique(names, strings.ToLower(nss[i]))
+	}
+
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		r.add(SeverityCritical, "ns", "%s has no nameserver", r.Zone)
+	}
+
+	addrs := r.auditAddresses(ctx, s, names)
+
+	var wg sync.WaitGroup
+
+	for i := range r.Servers {
+
+		if r.Servers[i].IP == "" {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(a *NSAudit) {
+			defer wg.Done()
+			a.check(ctx, r.Zone, conf)
+		}(&r.Servers[i])
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range r.Servers {
+		if r.Servers[i].Authoritative && r.Servers[i].SOA != nil {
+			r.ChildNS = r.Servers[i].Nameservers
+			r.SOA = r.Servers[i].SOA
+			break
+		}
+	}
+
+	r.analyzeNS(names)
+	r.analyzeGlue(addrs)
+	r.analyzeServers()
+	r.analyzeSOA()
+
+	return r, nil
+}
+
+// auditDelegation finds the parent zone with s and fills the NS set and the glue of the delegation from the first usable parent nameserver.
+func (r *AuditReport) auditDelegation(ctx context.Context, s *Servers, conf AuditConfig) {
+
+	if r.Zone == "." {
+		return
+	}
+
+	var parentNS []string
+
+	for off, end := mdns.NextLabel(r.Zone, 0); ; off, end = mdns.NextLabel(r.Zone, off) {
+
+		r.Parent = r.Zone[off:]
+		if end {
+			r.Parent = "."
+		}
+
+		parentNS, _ = s.TryQueryNSContext(ctx, r.Parent)
+		if len(parentNS) > 0 || end || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if len(parentNS) == 0 {
+		r.Parent = ""
+		r.add(SeverityCritical, "delegation", "failed to find the parent zone of %s", r.Zone)
+		return
+	}
+
+	var lastErr error
+
+	for _, ns := range parentNS {
+
+		for _, ip := range resolveNS(ctx, s, ns) {
+
+			if !routable(ip) {
+				continue
+			}
+
+			srv, err := NewServer("udp", ip, conf.Port, conf.Timeout)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+
+			in, err := directQuery(ctx, &srv, r.Zone, TypeNS, false)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+
+			if in.Rcode == mdns.RcodeNameError {
+				r.add(SeverityCritical, "delegation", "%s is not delegated from %s (NXDOMAIN)", r.Zone, r.Parent)
+				return
+			}
+
+			var nss []string
+
+			// The parent and the child may share the nameservers
+			if in.Authoritative && len(in.Answer) > 0 {
+				nss, _ = parseNS(in.Answer)
+			} else if child, v := referral(in, strings.ToLower(r.Parent), r.Zone); child == r.Zone {
+				nss = v
+			}
+
+			if len(nss) == 0 {
+				lastErr = fmt.Errorf("no delegation from %s (%s)", ns, ip)
+				continue
+			}
+
+			for i := range nss {
+				r.ParentNS = slices.AppendUnique(r.ParentNS, strings.ToLower(nss[i]))
+			}
+
+			sort.Strings(r.ParentNS)
+
+			for i := range r.ParentNS {
+				if ips := glue(in, r.ParentNS[i:i+1]); len(ips) > 0 {
+					r.Glue[r.ParentNS[i]] = ips
+				}
+			}
+
+			return
+		}
+	}
+
+	if ctx.Err() == nil {
+		r.add(SeverityCritical, "delegation", "failed to get the delegation of %s from %s: %v", r.Zone, r.Parent, lastErr)
+	}
+}
+
+// resolveNS returns the IPv4 and IPv6 addresses of ns resolved with s.
+func resolveNS(ctx context.Context, s *Servers, ns string) []string {
+
+	var r []string
+
+	v4, _ := s.TryQueryAContext(ctx, ns)
+	for i := range v4 {
+		r = append(r, v4[i].String())
+	}
+
+	v6, _ := s.TryQueryAAAAContext(ctx, ns)
+	for i := range v6 {
+		r = append(r, v6[i].String())
+	}
+
+	return r
+}
+
+// auditAddresses fills the servers of r with the glue and the resolved addresses of names.
+// Returns the resolved addresses by nameserver.
+func (r *AuditReport) auditAddresses(ctx context.Context, s *Servers, names []string) map[string][]string {
+
+	resolved := make(map[string][]string, len(names))
+
+	for _, ns := range names {
+
+		resolved[ns] = resolveNS(ctx, s, ns)
+
+		ips := append([]string(nil), r.Glue[ns]...)
+		for i := range resolved[ns] {
+			ips = slices.AppendUnique(ips, resolved[ns][i])
+		}
+
+		sort.Strings(ips)
+
+		if len(ips) == 0 {
+			r.Servers = append(r.Servers, NSAudit{NS: ns, Err: ErrNoAddress})
+			continue
+		}
+
+		for i := range ips {
+			r.Servers = append(r.Servers, NSAudit{NS: ns, IP: ips[i], Glue: slices.Contains(r.Glue[ns], ips[i])})
+		}
+	}
+
+	return resolved
+}
+
+// check probes a.IP over UDP and TCP, and asks it for the SOA and the NS of zone and for the recursive query.
+func (a *NSAudit) check(ctx context.Context, zone string, conf AuditConfig) {
+
+	if !routable(a.IP) {
+		a.Unreachable = true
+		a.Err = ErrNoRoute
+		return
+	}
+
+	a.UDP, _ = ProbeContext(ctx, "udp", a.IP, conf.Port, conf.Timeout)
+	a.TCP, _ = ProbeContext(ctx, "tcp", a.IP, conf.Port, conf.Timeout)
+
+	network := "udp"
+
+	switch {
+	case !a.UDP && !a.TCP:
+		return
+	case !a.UDP:
+		network = "tcp"
+	}
+
+	srv, err := NewServer(network, a.IP, conf.Port, conf.Timeout)
+	if err != nil {
+		a.Err = err
+		return
+	}
+
+	in, err := directQuery(ctx, &srv, zone, TypeSOA, false)
+	if err != nil {
+		a.Err = fmt.Errorf("failed to query SOA: %w", err)
+		return
+	}
+
+	a.Rcode = mdns.RcodeToString[in.Rcode]
+	a.Authoritative = in.Authoritative && in.Rcode == mdns.RcodeSuccess
+
+	if a.Authoritative {
+
+		a.SOA, err = parseSOA(in.Answer)
+		if err != nil {
+			a.Err = fmt.Errorf("failed to parse SOA: %w", err)
+			return
+		}
+
+		if a.SOA == nil {
+			a.Authoritative = false
+		}
+	}
+
+	if a.Authoritative {
+
+		in, err = directQuery(ctx, &srv, zone, TypeNS, false)
+		if err != nil {
+			a.Err = fmt.Errorf("failed to query NS: %w", err)
+			return
+		}
+
+		nss, _ := parseNS(in.Answer)
+		for i := range nss {
+			a.Nameservers = append(a.Nameservers, strings.ToLower(nss[i]))
+		}
+
+		sort.Strings(a.Nameservers)
+	}
+
+	in, err = directQuery(ctx, &srv, mdns.Fqdn(conf.RecursionName), TypeNS, true)
+	if err == nil {
+		a.Recursive = in.RecursionAvailable && !in.Authoritative && in.Rcode == mdns.RcodeSuccess && len(in.Answer) > 0
+	}
+}
+
+// analyzeNS compares the NS set of the parent and the zone.
+func (r *AuditReport) analyzeNS(names []string) {
+
+	if len(names) == 1 {
+		r.add(SeverityWarning, "ns", "%s has only one nameserver, at least two is required (RFC 1034 4.1)", r.Zone)
+	}
+
+	if r.ParentNS == nil || r.ChildNS == nil {
+		return
+	}
+
+	common := 0
+
+	for _, ns := range r.ParentNS {
+		if slices.Contains(r.ChildNS, ns) {
+			common++
+		} else {
+			r.add(SeverityWarning, "ns", "%s is in the delegation of %s, but not in the NS records of the zone", ns, r.Parent)
+		}
+	}
+
+	for _, ns := range r.ChildNS {
+		if !slices.Contains(r.ParentNS, ns) {
+			r.add(SeverityWarning, "ns", "%s is in the NS records of the zone, but not in the delegation of %s", ns, r.Parent)
+		}
+	}
+
+	if common == 0 {
+		r.add(SeverityCritical, "ns", "the NS set of the parent (%s) and the zone (%s) are disjoint", strings.Join(r.ParentNS, " "), strings.Join(r.ChildNS, " "))
+	}
+}
+
+// analyzeGlue checks the glue of the in-bailiwick nameservers and compares it to the resolved addresses.
+func (r *AuditReport) analyzeGlue(resolved map[string][]string) {
+
+	for _, ns := range r.ParentNS {
+
+		g := r.Glue[ns]
+
+		if len(g) == 0 {
+			if mdns.IsSubDomain(r.Zone, ns) {
+				r.add(SeverityCritical, "glue", "missing glue for the in-bailiwick nameserver %s", ns)
+			}
+			continue
+		}
+
+		a := append([]string(nil), resolved[ns]...)
+		sort.Strings(a)
+
+		sorted := append([]string(nil), g...)
+		sort.Strings(sorted)
+
+		if strings.Join(sorted, " ") != strings.Join(a, " ") {
+			r.add(SeverityWarning, "glue", "the glue of %s (%s) differs from its address records (%s)", ns, strings.Join(sorted, " "), strings.Join(a, " "))
+		}
+	}
+}
+
+// analyzeServers reports the unreachable, lame, single transport and recursive addresses and the inconsistent serials and NS sets.
+// The addresses without local route are reported once per address family.
+func (r *AuditReport) analyzeServers() {
+
+	var (
+		serials []int
+		nsSets  []string
+		noRoute = make(map[string][]string)
+	)
+
+	for _, a := range r.Servers {
+
+		switch {
+		case a.Unreachable:
+			family := "IPv6"
+			if ip := net.ParseIP(a.IP); ip != nil && ip.To4() != nil {
+				family = "IPv4"
+			}
+			noRoute[family] = append(noRoute[family], fmt.Sprintf("%s (%s)", a.NS, a.IP))
+			continue
+		case a.IP == "":
+			r.add(SeverityCritical, "ns", "failed to resolve the address of %s", a.NS)
+			continue
+		case !a.UDP && !a.TCP:
+			r.add(SeverityCritical, "transport", "%s (%s) is unreachable", a.NS, a.IP)
+			continue
+		case !a.TCP:
+			r.add(SeverityWarning, "transport", "%s (%s) is reachable only over UDP, TCP is required (RFC 7766)", a.NS, a.IP)
+		case !a.UDP:
+			r.add(SeverityWarning, "transport", "%s (%s) is reachable only over TCP", a.NS, a.IP)
+		}
+
+		if a.Recursive {
+			r.add(SeverityWarning, "recursion", "%s (%s) is an open resolver", a.NS, a.IP)
+		}
+
+		if a.Err != nil {
+			r.add(SeverityWarning, "lame", "%s (%s): %s", a.NS, a.IP, a.Err)
+			continue
+		}
+
+		if !a.Authoritative {
+			r.add(SeverityCritical, "lame", "lame delegation: %s (%s) is not authoritative for %s (%s)", a.NS, a.IP, r.Zone, a.Rcode)
+			continue
+		}
+
+		serials = slices.AppendUnique(serials, a.SOA.Serial)
+		nsSets = slices.AppendUnique(nsSets, strings.Join(a.Nameservers, " "))
+	}
+
+	if len(serials) > 1 {
+		sort.Ints(serials)
+		r.add(SeverityWarning, "soa", "the nameservers serve different serials: %v", serials)
+	}
+
+	if len(nsSets) > 1 {
+		r.add(SeverityWarning, "ns", "the nameservers serve different NS sets: %q", nsSets)
+	}
+
+	for _, family := range []string{"IPv4", "IPv6"} {
+		if v := noRoute[family]; len(v) > 0 {
+			r.add(SeverityInfo, "transport", "the %s addresses are not checked, the local host has no %s route: %s", family, family, strings.Join(v, ", "))
+		}
+	}
+}
+
+// analyzeSOA checks the SOA timers by the recommendations of RFC 1912 2.2 and RFC 2308 5.
+func (r *AuditReport) analyzeSOA() {
+
+	soa := r.SOA
+	if soa == nil {
+		return
+	}
+
+	if soa.Retry >= soa.Refresh {
+		r.add(SeverityWarning, "soa", "retry (%d) should be less than refresh (%d)", soa.Retry, soa.Refresh)
+	}
+
+	if soa.Expire <= soa.Refresh+soa.Retry {
+		r.add(SeverityWarning, "soa", "expire (%d) should be greater than refresh + retry (%d)", soa.Expire, soa.Refresh+soa.Retry)
+	}
+
+	if soa.Refresh < 1200 || soa.Refresh > 43200 {
+		r.add(SeverityInfo, "soa", "refresh (%d) is outside of the recommended 1200-43200", soa.Refresh)
+	}
+
+	if soa.Expire < 1209600 || soa.Expire > 2419200 {
+		r.add(SeverityInfo, "soa", "expire (%d) is outside of the recommended 1209600-2419200", soa.Expire)
+	}
+
+	if soa.MinTTL < 300 || soa.MinTTL > 86400 {
+		r.add(SeverityInfo, "soa", "negative caching TTL (%d) is outside of the recommended 300-86400", soa.MinTTL)
+	}
+}
+
+// Audit checks the nameserver configuration of zone using the DefaultServers to resolve the parent zone and the nameservers.
+// See Servers.Audit().
+func Audit(zone string, conf AuditConfig) (*AuditReport, error) {
+
+	return DefaultServers.AuditContext(context.Background(), zone, conf)
+}
+
+// AuditContext is like Audit, but the queries are bound to ctx.
+func AuditContext(ctx context.Context, zone string, conf AuditConfig) (*AuditReport, error) {
+
+	return DefaultServers.AuditContext(ctx, zone, conf)
+}
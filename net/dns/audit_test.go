@@ -0,0 +1,155 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
+	mdns "github.com/miekg/dns"
+)
+
+// newAuditTestServers returns Servers with an iterative resolver using the local stand-ins and the port of the stand-ins:
+//
+//   - 127.0.0.1: root, delegates "com." to 127.0.0.2
+//   - 127.0.0.2: "com.", delegates "example.com." to ns0 (glue 127.0.0.5), ns1 (glue 127.0.0.3) and ns2 (no glue)
+//   - 127.0.0.3: "example.com." with ns1, ns2 and ns3, ns0 is 127.0.0.6 (nothing listens)
+//   - 127.0.0.4: "example.com." only over UDP and answers the recursive queries
+//   - 127.0.0.5: lame, refuses everything
+func newAuditTestServers(t *testing.T) (Servers, string) {
+
+	root := dnstest.MustZone(".",
+		"@ 300 IN SOA a.root. admin.root. 1 3600 600 86400 30",
+		"@ 300 IN NS a.root.",
+		"com. 300 IN NS ns.com.",
+		"ns.com. 300 IN A 127.0.0.2",
+	)
+
+	com := dnstest.MustZone("com",
+		"@ 300 IN SOA ns admin 1 3600 600 86400 30",
+		"@ 300 IN NS ns",
+		"ns 300 IN A 127.0.0.2",
+		"example 300 IN NS ns0.example",
+		"example 300 IN NS ns1.example",
+		"example 300 IN NS ns2.example",
+		"ns0.example 300 IN A 127.0.0.5",
+		"ns1.example 300 IN A 127.0.0.3",
+	)
+
+	example := dnstest.MustZone("example.com",
+		"@ 300 IN SOA ns1 admin 1 3600 7200 1209600 300",
+		"@ 300 IN NS ns1",
+		"@ 300 IN NS ns2",
+		"@ 300 IN NS ns3",
+		"ns0 300 IN A 127.0.0.6",
+		"ns1 300 IN A 127.0.0.3",
+		"ns2 300 IN A 127.0.0.4",
+		"ns3 300 IN A 127.0.0.3",
+	)
+
+	_, ns, port := newZoneTestServersOnIPs(t, []*dnstest.Zone{root}, []*dnstest.Zone{com}, []*dnstest.Zone{example}, []*dnstest.Zone{example, root}, []*dnstest.Zone{example})
+
+	ns[3].SetRecursion(true)
+	ns[3].SetRule("", 0, dnstest.Rule{Network: "tcp", Drop: true})
+	ns[4].SetRule("", 0, dnstest.Rule{Rcode: mdns.RcodeRefused})
+
+	s, err := NewServerIterative([]string{"127.0.0.1"}, time.Second)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create server: %s\n", err)
+	}
+
+	s.iter.port = port
+
+	return NewServersSlice(1, s), port
+}
+
+func hasAuditFinding(r *AuditReport, sev Severity, check string, substr string) bool {
+
+	for _, f := range r.Findings {
+		if f.Severity == sev && f.Check == check && strings.Contains(f.Message, substr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestAudit(t *testing.T) {
+
+	srvs, port := newAuditTestServers(t)
+
+	r, err := srvs.Audit("Example.com", AuditConfig{Port: port, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if r.Parent != "com." || strings.Join(r.ParentNS, " ") != "ns0.example.com. ns1.example.com. ns2.example.com." {
+		t.Fatalf("FAIL: Invalid delegation: %s %v\n", r.Parent, r.ParentNS)
+	}
+
+	if strings.Join(r.ChildNS, " ") != "ns1.example.com. ns2.example.com. ns3.example.com." || r.SOA == nil || r.SOA.Serial != 1 {
+		t.Fatalf("FAIL: Invalid zone: %v %v\n", r.ChildNS, r.SOA)
+	}
+
+	if len(r.Servers) != 5 {
+		t.Fatalf("FAIL: Invalid servers: %#v\n", r.Servers)
+	}
+
+	cases := []struct {
+		Severity Severity
+		Check    string
+		Substr   string
+	}{
+		{SeverityWarning, "ns", "ns0.example.com. is in the delegation"},
+		{SeverityWarning, "ns", "ns3.example.com. is in the NS records"},
+		{SeverityCritical, "glue", "ns2.example.com."},
+		{SeverityWarning, "glue", "ns0.example.com. (127.0.0.5) differs"},
+		{SeverityCritical, "lame", "127.0.0.5"},
+		{SeverityCritical, "transport", "127.0.0.6"},
+		{SeverityWarning, "transport", "ns2.example.com. (127.0.0.4) is reachable only over UDP"},
+		{SeverityWarning, "recursion", "127.0.0.4"},
+		{SeverityWarning, "soa", "retry (7200)"},
+	}
+
+	for _, c := range cases {
+		if !hasAuditFinding(r, c.Severity, c.Check, c.Substr) {
+			t.Fatalf("FAIL: Missing finding: [%s] %s: %s\nFindings: %v\n", c.Severity, c.Check, c.Substr, r.Findings)
+		}
+	}
+
+	if len(r.Findings) != len(cases) {
+		t.Fatalf("FAIL: Unexpected findings: %v\n", r.Findings)
+	}
+
+	if r.Max() != SeverityCritical {
+		t.Fatalf("FAIL: Invalid max severity: %s\n", r.Max())
+	}
+}
+
+func TestAuditNotDelegated(t *testing.T) {
+
+	srvs, port := newAuditTestServers(t)
+
+	r, err := srvs.Audit("invalid.com", AuditConfig{Port: port, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if !hasAuditFinding(r, SeverityCritical, "delegation", "not delegated") || !hasAuditFinding(r, SeverityCritical, "ns", "no nameserver") {
+		t.Fatalf("FAIL: Invalid findings: %v\n", r.Findings)
+	}
+}
+
+func TestAuditUnreachableFamily(t *testing.T) {
+
+	r := &AuditReport{Zone: "example.com.", Servers: []NSAudit{
+		{NS: "ns1.example.com.", IP: "2001:db8::1", Unreachable: true, Err: ErrNoRoute},
+		{NS: "ns2.example.com.", IP: "2001:db8::2", Unreachable: true, Err: ErrNoRoute},
+	}}
+
+	r.analyzeServers()
+
+	if len(r.Findings) != 1 || !hasAuditFinding(r, SeverityInfo, "transport", "IPv6 addresses are not checked, the local host has no IPv6 route: ns1.example.com. (2001:db8::1), ns2.example.com. (2001:db8::2)") {
+		t.Fatalf("FAIL: Invalid findings: %v\n", r.Findings)
+	}
+}
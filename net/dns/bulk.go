@@ -0,0 +1,277 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	mdns "github.com/miekg/dns"
+)
+
+// BulkJob is a single query in a bulk resolution.
+type BulkJob struct {
+	Name string // Name to resolve
+	Type uint16 // Type of the record (eg.: TypeA)
+}
+
+// BulkResult is the result of a BulkJob.
+type BulkResult struct {
+	Job      BulkJob
+	Records  []Record  // Parsed records of the answer
+	Answer   []mdns.RR // Raw answer section
+	Server   string    // The last server asked (Server.String()), empty if answered from the cache
+	Attempts int       // Number of queries sent
+	Err      error     // ErrX, context error or any unknown error
+}
+
+// BulkConfig configures the bulk resolution.
+type BulkConfig struct {
+	Workers    int     // Number of concurrent workers. If 0, defaults to 10 per server.
+	PerServer  int     // Maximum number of concurrent queries per server. If 0, unlimited.
+	QPS        float64 // Maximum number of queries per second per server. If 0, unlimited.
+	MaxRetries int     // Maximum number of attempts per job. If 0, defaults to the MaxRetries of the Servers.
+}
+
+// limiter spaces the events evenly to keep the rate under qps.
+type limiter struct {
+	m        sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newLimiter(qps float64) *limiter {
+
+	if qps <= 0 {
+		return nil
+	}
+
+	return &limiter{interval: time.Duration(float64(time.Second) / qps)}
+}
+
+// wait blocks until the next slot is available or ctx is done.
+func (l *limiter) wait(ctx context.Context) error {
+
+	if l == nil {
+		return nil
+	}
+
+	l.m.Lock()
+
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+
+	slot := l.next
+	l.next = l.next.Add(l.interval)
+
+	l.m.Unlock()
+
+	d := time.Until(slot)
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// bulkServer is a server with its limits.
+type bulkServer struct {
+	srv *Server
+	idx int // Index in Servers
+	sem chan struct{}
+	lim *limiter
+}
+
+// acquire blocks until a query can be sent to the server.
+func (b *bulkServer) acquire(ctx context.Context) error {
+
+	if b.sem != nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case b.sem <- struct{}{}:
+		}
+	}
+
+	if err := b.lim.wait(ctx); err != nil {
+		b.release()
+		return err
+	}
+
+	return nil
+}
+
+func (b *bulkServer) release() {
+
+	if b.sem != nil {
+		<-b.sem
+	}
+}
+
+// Bulk resolves the jobs concurrently and streams the results through the returned channel.
+// The server of every attempt is selected with the strategy of s (see SetStrategy()), the quarantined servers are skipped.
+// A failed query (except NXDOMAIN) is retried with an other server.
+//
+// The returned channel is closed when jobs is closed and every job is done, or when ctx is done.
+// The jobs not started before ctx is done are dropped.
+//
+// Panics if no server is configured.
+func (s *Servers) Bulk(ctx context.Context, jobs <-chan BulkJob, conf BulkConfig) <-chan BulkResult {
+
+	results := make(chan BulkResult)
+
+	go func() {
+		s.BulkFunc(ctx, jobs, conf, func(r BulkResult) {
+			select {
+			case results <- r:
+			case <-ctx.Done():
+			}
+		})
+		close(results)
+	}()
+
+	return results
+}
+
+// BulkFunc is like Bulk, but calls fn with every result instead of streaming them through a channel.
+// fn is called concurrently from the workers.
+//
+// Returns when jobs is closed and every job is done (nil), or when ctx is done (ctx.Err()).
+func (s *Servers) BulkFunc(ctx context.Context, jobs <-chan BulkJob, conf BulkConfig, fn func(BulkResult)) error {
+
+	s.m.Lock()
+	srvs := make([]bulkServer, len(s.srvs))
+	for i := range s.srvs {
+		srv := s.srvs[i]
+		srvs[i].srv = &srv
+		srvs[i].idx = i
+		if conf.PerServer > 0 {
+			srvs[i].sem = make(chan struct{}, conf.PerServer)
+		}
+		srvs[i].lim = newLimiter(conf.QPS)
+	}
+	s.m.Unlock()
+
+	if len(srvs) == 0 {
+		panic("no servers configured")
+	}
+
+	if conf.Workers <= 0 {
+		conf.Workers = 10 * len(srvs)
+	}
+
+	if conf.MaxRetries <= 0 {
+		conf.MaxRetries = s.GetMaxRetries()
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < conf.Workers; i++ {
+
+		wg.Add(1)
+
+		go func() {
+
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job, ok := <-jobs:
+					if !ok {
+						return
+					}
+
+					fn(s.bulkResolve(ctx, srvs, conf.MaxRetries, job))
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// bulkResolve resolves job with up to retries attempts, the servers are selected like in TryQuery (see Servers.next()).
+func (s *Servers) bulkResolve(ctx context.Context, srvs []bulkServer, retries int, job BulkJob) BulkResult {
+
+	res := BulkResult{Job: job, Err: ErrInvalidMaxRetries}
+
+	cache := s.Cache()
+	if cache != nil && isCacheDisabled(ctx) {
+		cache = nil
+	}
+
+	if cache != nil {
+		if rr, ok, err := cache.get(job.Name, job.Type); ok {
+			res.Answer, res.Err = rr, err
+			if err == nil {
+				res.Records, res.Err = toRecords(rr)
+			}
+			return res
+		}
+	}
+
+	var (
+		in    *mdns.Msg
+		tried []int
+	)
+
+	for i := 0; i < retries; i++ {
+
+		idx := s.next(len(srvs), tried)
+		tried = append(tried, idx)
+
+		b := &srvs[idx]
+
+		if res.Err = b.acquire(ctx); res.Err != nil {
+			if s.health != nil {
+				// Clear the probing state
+				s.health.record(b.idx, 0, res.Err)
+			}
+			return res
+		}
+
+		res.Attempts++
+		res.Server = b.srv.String()
+
+		qstart := time.Now()
+
+		in, res.Err = b.srv.queryMsgContext(ctx, job.Name, job.Type)
+
+		b.release()
+
+		if s.health != nil {
+			s.health.record(b.idx, time.Since(qstart), res.Err)
+		}
+
+		if res.Err == nil || errors.Is(res.Err, ErrName) || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if cache != nil {
+		cache.put(job.Name, job.Type, in, res.Err)
+	}
+
+	if res.Err != nil {
+		return res
+	}
+
+	res.Answer = in.Answer
+	res.Records, res.Err = toRecords(in.Answer)
+
+	return res
+}
@@ -0,0 +1,180 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
+	mdns "github.com/miekg/dns"
+)
+
+// bulkTestZone returns the zone of the bulk tests.
+//
+// Zone:
+//   - hostN.example.com. A 192.0.2.N (N is 0-49)
+//   - flaky.example.com. A 192.0.2.255 (see setBulkTestRules())
+//   - everything else is NXDOMAIN
+func bulkTestZone() *dnstest.Zone {
+
+	records := []string{"flaky 60 IN A 192.0.2.255"}
+
+	for i := 0; i < 50; i++ {
+		records = append(records, fmt.Sprintf("host%d 60 IN A 192.0.2.%d", i, i))
+	}
+
+	return dnstest.MustZone("example.com", records...)
+}
+
+// setBulkTestRules delays every query by delay and answers SERVFAIL for the first query of flaky.example.com on each server.
+func setBulkTestRules(ts []*dnstest.Server, delay time.Duration) {
+
+	for i := range ts {
+		ts[i].SetRule("", 0, dnstest.Rule{Delay: delay})
+		ts[i].SetRule("flaky.example.com", TypeA, dnstest.Rule{Rcode: mdns.RcodeServerFailure, Delay: delay, Count: 1})
+	}
+}
+
+func TestBulk(t *testing.T) {
+
+	srvs, ts := newZoneTestServersN(t, 2, bulkTestZone())
+	setBulkTestRules(ts, 10*time.Millisecond)
+
+	jobs := make(chan BulkJob)
+
+	go func() {
+		for i := 0; i < 50; i++ {
+			jobs <- BulkJob{Name: fmt.Sprintf("host%d.example.com", i), Type: TypeA}
+		}
+		jobs <- BulkJob{Name: "flaky.example.com", Type: TypeA}
+		jobs <- BulkJob{Name: "missing.example.com", Type: TypeA}
+		close(jobs)
+	}()
+
+	n := 0
+
+	for r := range srvs.Bulk(context.Background(), jobs, BulkConfig{Workers: 20, PerServer: 3, MaxRetries: 3}) {
+
+		n++
+
+		switch r.Job.Name {
+		case "missing.example.com":
+			if !errors.Is(r.Err, ErrName) {
+				t.Fatalf("FAIL: %s: error wanted: %s, error got: %v\n", r.Job.Name, ErrName, r.Err)
+			}
+		case "flaky.example.com":
+			// Retried on the other server first
+			if r.Err != nil || r.Attempts != 3 || len(r.Records) != 1 || r.Records[0].Value != "192.0.2.255" {
+				t.Fatalf("FAIL: %s: Invalid result: %+v\n", r.Job.Name, r)
+			}
+		default:
+			var i int
+			fmt.Sscanf(r.Job.Name, "host%d.example.com", &i)
+
+			if r.Err != nil || len(r.Records) != 1 || r.Records[0].Value != fmt.Sprintf("192.0.2.%d", i) {
+				t.Fatalf("FAIL: %s: Invalid result: %+v\n", r.Job.Name, r)
+			}
+		}
+	}
+
+	if n != 52 {
+		t.Fatalf("FAIL: Invalid number of results: %d, want: 52\n", n)
+	}
+
+	for i := range ts {
+		if p := ts[i].MaxInflight(); p > 3 {
+			t.Fatalf("FAIL: Per server limit exceeded on server %d: %d\n", i, p)
+		}
+	}
+}
+
+func TestBulkQPS(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, bulkTestZone())
+
+	jobs := make(chan BulkJob, 10)
+	for i := 0; i < 10; i++ {
+		jobs <- BulkJob{Name: fmt.Sprintf("host%d.example.com", i), Type: TypeA}
+	}
+	close(jobs)
+
+	var (
+		m       sync.Mutex
+		results int
+	)
+
+	start := time.Now()
+
+	err := srvs.BulkFunc(context.Background(), jobs, BulkConfig{Workers: 10, QPS: 50}, func(r BulkResult) {
+		m.Lock()
+		results++
+		m.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	// 10 queries with 50 QPS takes at least 9*20ms
+	if d := time.Since(start); d < 180*time.Millisecond {
+		t.Fatalf("FAIL: QPS limit is not honored: 10 queries in %s\n", d)
+	}
+
+	if results != 10 {
+		t.Fatalf("FAIL: Invalid number of results: %d, want: 10\n", results)
+	}
+}
+
+func TestBulkCanceled(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, bulkTestZone())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	jobs := make(chan BulkJob)
+
+	if err := srvs.BulkFunc(ctx, jobs, BulkConfig{}, func(BulkResult) {}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", context.Canceled, err)
+	}
+}
+
+func TestServersBulkQuarantine(t *testing.T) {
+
+	var good, bad atomic.Bool
+
+	bad.Store(true)
+
+	srvs, err := NewServersStr(3, time.Second, newHealthTestServer(t, &good), newHealthTestServer(t, &bad))
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create servers: %s\n", err)
+	}
+
+	srvs.SetQuarantine(1, time.Minute)
+	srvs.health.record(1, 0, ErrRefused)
+
+	jobs := make(chan BulkJob)
+
+	go func() {
+		for i := 0; i < 20; i++ {
+			jobs <- BulkJob{Name: "example.com", Type: TypeA}
+		}
+		close(jobs)
+	}()
+
+	err = srvs.BulkFunc(context.Background(), jobs, BulkConfig{Workers: 4}, func(r BulkResult) {
+		if r.Err != nil || r.Attempts != 1 {
+			t.Errorf("FAIL: Invalid result: %+v\n", r)
+		}
+	})
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if h := srvs.Health(); h[1].Queries != 1 || h[0].Queries != 20 {
+		t.Fatalf("FAIL: Quarantined server is used by Bulk: %+v\n", h)
+	}
+}
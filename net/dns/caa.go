@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"fmt"
 
 	mdns "github.com/miekg/dns"
@@ -18,16 +19,8 @@ func (c CAA) String() string {
 	return fmt.Sprintf("%d %s %s", c.Flag, c.Tag, c.Value)
 }
 
-// QueryCAA ask the server and returns a slice of CAA.
-// The answer slice will be nil in case of error.
-//
-// The other record types are ignored.
-func (s *Server) QueryCAA(name string) ([]CAA, error) {
-
-	rr, err := s.query(name, TypeCAA)
-	if err != nil {
-		return nil, err
-	}
+// parseCAA converts the answer rr to CAA.
+func parseCAA(rr []mdns.RR) ([]CAA, error) {
 
 	r := make([]CAA, 0, len(rr))
 
@@ -42,6 +35,9 @@ func (s *Server) QueryCAA(name string) ([]CAA, error) {
 		case *mdns.DNAME:
 			// Ignore DNAME
 			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
 		default:
 			return nil, fmt.Errorf("unknown type: %T", v)
 		}
@@ -50,22 +46,56 @@ func (s *Server) QueryCAA(name string) ([]CAA, error) {
 	return r, nil
 }
 
+// QueryCAA ask the server and returns a slice of CAA.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QueryCAA(name string) ([]CAA, error) {
+
+	return s.QueryCAAContext(context.Background(), name)
+}
+
+// QueryCAAContext is like QueryCAA, but the query is bound to ctx.
+func (s *Server) QueryCAAContext(ctx context.Context, name string) ([]CAA, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeCAA)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCAA(rr)
+}
+
 // QueryCAA ask a random server from servers and returns a slice of CAA.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func (s *Servers) QueryCAA(name string) ([]CAA, error) {
 
-	return s.Get(-1).QueryCAA(name)
+	return s.QueryCAAContext(context.Background(), name)
 }
 
-// QueryCAA ask a random server from DefaultServers and returns a slice of CAA.
+// QueryCAAContext is like QueryCAA, but the query is bound to ctx.
+func (s *Servers) QueryCAAContext(ctx context.Context, name string) ([]CAA, error) {
+
+	return s.Get(-1).QueryCAAContext(ctx, name)
+}
+
+// QueryCAA asks the DefaultResolver and returns a slice of CAA.
+// Unlike Servers.QueryCAA, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QueryCAA is the same as TryQueryCAA.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func QueryCAA(name string) ([]CAA, error) {
 
-	return DefaultServers.QueryCAA(name)
+	return QueryCAAContext(context.Background(), name)
+}
+
+// QueryCAAContext is like QueryCAA, but the query is bound to ctx.
+func QueryCAAContext(ctx context.Context, name string) ([]CAA, error) {
+
+	return QueryCAAWith(ctx, DefaultResolver, name)
 }
 
 // TryQueryCAA asks the servers for type CAA. If any error occurred, retries with next server (except if error is NXDOMAIN).
@@ -75,50 +105,61 @@ func QueryCAA(name string) ([]CAA, error) {
 // The first used server is random. The other record types are ignored.
 func (s *Servers) TryQueryCAA(name string) ([]CAA, error) {
 
-	rr, err := s.TryQuery(name, TypeCAA)
-	if err != nil {
-		return nil, err
-	}
+	return s.TryQueryCAAContext(context.Background(), name)
+}
 
-	r := make([]CAA, 0, len(rr))
+// TryQueryCAAContext is like TryQueryCAA, but the query is bound to ctx.
+func (s *Servers) TryQueryCAAContext(ctx context.Context, name string) ([]CAA, error) {
 
-	for i := range rr {
-
-		switch v := rr[i].(type) {
-		case *mdns.CAA:
-			r = append(r, CAA{Flag: v.Flag, Tag: v.Tag, Value: v.Value})
-		case *mdns.CNAME:
-			// Ignore CNAME
-			continue
-		case *mdns.DNAME:
-			// Ignore DNAME
-			continue
-		default:
-			return nil, fmt.Errorf("unknown type: %T", v)
-		}
-	}
-
-	return r, nil
+	return QueryCAAWith(ctx, s, name)
 }
 
-// TryQueryCAA asks the DefaultServers for type CAA. If any error occurred, retries with next server (except if error is NXDOMAIN).
+// TryQueryCAA asks the DefaultResolver for type CAA. If any error occurred, retries with next server (except if error is NXDOMAIN).
 //
 // In case of error, the answer will be nil and return ErrX or any unknown error.
 //
 // The first used server is random. The other record types are ignored.
 func TryQueryCAA(name string) ([]CAA, error) {
 
-	return DefaultServers.TryQueryCAA(name)
+	return TryQueryCAAContext(context.Background(), name)
+}
+
+// TryQueryCAAContext is like TryQueryCAA, but the query is bound to ctx.
+func TryQueryCAAContext(ctx context.Context, name string) ([]CAA, error) {
+
+	return QueryCAAWith(ctx, DefaultResolver, name)
+}
+
+// QueryCAAWith asks r for type CAA and converts the answer like QueryCAA.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QueryCAAWith(ctx context.Context, r Resolver, name string) ([]CAA, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeCAA)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCAA(rr)
 }
 
 // IsSetCAA checks whether a CAA type record set for name.
 // NXDOMAIN is not an error here, because it means "not found".
 func (s *Servers) IsSetCAA(name string) (bool, error) {
-	return s.IsSet(name, TypeCAA)
+	return s.IsSetCAAContext(context.Background(), name)
 }
 
-// IsSetCAA checks whether a CAA type record set for name using the DefaultServers.
+// IsSetCAAContext is like IsSetCAA, but the query is bound to ctx.
+func (s *Servers) IsSetCAAContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeCAA)
+}
+
+// IsSetCAA checks whether a CAA type record set for name using the DefaultResolver.
 // NXDOMAIN is not an error here, because it means "not found".
 func IsSetCAA(name string) (bool, error) {
-	return DefaultServers.IsSetCAA(name)
+	return IsSetCAAContext(context.Background(), name)
+}
+
+// IsSetCAAContext is like IsSetCAA, but the query is bound to ctx.
+func IsSetCAAContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeCAA)
 }
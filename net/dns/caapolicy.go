@@ -0,0 +1,297 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	mdns "github.com/miekg/dns"
+)
+
+var (
+	ErrCAALoop         = errors.New("CNAME chain is too long")
+	ErrInvalidCAAValue = errors.New("invalid CAA property value")
+)
+
+// maxCAAChain is the maximum number of CNAMEs followed at one name.
+const maxCAAChain = 8
+
+// CAAFlagCritical is the Issuer Critical Flag.
+// A CA must not issue if the relevant RRset contains an unknown property with this flag set.
+const CAAFlagCritical uint8 = 128
+
+// CAAIssuer is a parsed "issue" or "issuewild" property value.
+// See more: https://www.rfc-editor.org/rfc/rfc8659.html#section-4.2
+type CAAIssuer struct {
+	Domain string            // Issuer domain name of the CA (eg.: "letsencrypt.org"), empty means no CA is authorized (eg.: "issue ;")
+	Params map[string]string // Parameters (eg.: "accounturi", "validationmethods")
+	Err    error             // Parse error, an invalid value does not match any CA
+}
+
+// AccountURI returns the "accounturi" parameter.
+// See more: https://www.rfc-editor.org/rfc/rfc8657.html#section-3
+func (c CAAIssuer) AccountURI() string {
+	return c.Params["accounturi"]
+}
+
+// ValidationMethods returns the "validationmethods" parameter.
+// See more: https://www.rfc-editor.org/rfc/rfc8657.html#section-4
+func (c CAAIssuer) ValidationMethods() []string {
+
+	v := c.Params["validationmethods"]
+	if v == "" {
+		return nil
+	}
+
+	return strings.Split(v, ",")
+}
+
+func (c CAAIssuer) String() string {
+
+	if len(c.Params) == 0 {
+		return c.Domain
+	}
+
+	params := make([]string, 0, len(c.Params))
+
+	for k, v := range c.Params {
+		params = append(params, k+"="+v)
+	}
+
+	sort.Strings(params)
+
+	return c.Domain + "; " + strings.Join(params, "; ")
+}
+
+// ParseCAAIssuer parses the value of an "issue" or "issuewild" property (eg.: "ca.example.net; accounturi=https://ca.example.net/acct/1").
+//
+// Returns ErrInvalidCAAValue (wrapped) if the value is malformed.
+func ParseCAAIssuer(value string) (CAAIssuer, error) {
+
+	domain, params, hasParams := strings.Cut(value, ";")
+
+	r := CAAIssuer{Domain: strings.ToLower(strings.TrimSpace(domain))}
+
+	if r.Domain != "" && !IsDomain(r.Domain) {
+		return CAAIssuer{}, fmt.Errorf("%w: invalid issuer domain name: %s", ErrInvalidCAAValue, r.Domain)
+	}
+
+	if !hasParams {
+		return r, nil
+	}
+
+	for _, p := range strings.Split(params, ";") {
+
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(p, "=")
+		if !ok || k == "" || strings.ContainsAny(k, " \t") || strings.ContainsAny(v, " \t") {
+			return CAAIssuer{}, fmt.Errorf("%w: invalid parameter: %s", ErrInvalidCAAValue, p)
+		}
+
+		if r.Params == nil {
+			r.Params = make(map[string]string)
+		}
+
+		r.Params[strings.ToLower(k)] = v
+	}
+
+	return r, nil
+}
+
+// CAAPolicy is the relevant CAA RRset of a name.
+// See more: https://www.rfc-editor.org/rfc/rfc8659.html#section-3
+type CAAPolicy struct {
+	Name      string      // The name where the relevant RRset found, empty if no CAA found up to the root
+	Aliases   []string    // The CNAME targets followed at Name
+	Records   []CAA       // The relevant RRset
+	Issue     []CAAIssuer // "issue" properties
+	IssueWild []CAAIssuer // "issuewild" properties
+	IODEF     []string    // "iodef" reporting URLs (mailto: or http(s):)
+	Critical  []CAA       // Unknown properties with the critical flag
+}
+
+// newCAAPolicy parses the relevant RRset rr.
+func newCAAPolicy(name string, aliases []string, rr []CAA) *CAAPolicy {
+
+	p := &CAAPolicy{Name: name, Aliases: aliases, Records: rr}
+
+	for i := range rr {
+
+		switch strings.ToLower(rr[i].Tag) {
+		case "issue", "issuewild":
+
+			c, err := ParseCAAIssuer(rr[i].Value)
+			if err != nil {
+				c.Err = err
+			}
+
+			if strings.EqualFold(rr[i].Tag, "issue") {
+				p.Issue = append(p.Issue, c)
+			} else {
+				p.IssueWild = append(p.IssueWild, c)
+			}
+
+		case "iodef":
+			p.IODEF = append(p.IODEF, rr[i].Value)
+		default:
+			if rr[i].Flag&CAAFlagCritical != 0 {
+				p.Critical = append(p.Critical, rr[i])
+			}
+		}
+	}
+
+	return p
+}
+
+// Found returns whether a relevant CAA RRset found.
+// If not, any CA is authorized.
+func (p *CAAPolicy) Found() bool {
+	return len(p.Records) > 0
+}
+
+// Issuers returns the property set applied to the certificate type: "issuewild" if wildcard is true and any "issuewild" property exists, "issue" otherwise.
+// Nil means that no restriction applies.
+func (p *CAAPolicy) Issuers(wildcard bool) []CAAIssuer {
+
+	if wildcard && len(p.IssueWild) > 0 {
+		return p.IssueWild
+	}
+
+	return p.Issue
+}
+
+// CanIssue returns whether the CA identified by ca (issuer domain name, eg.: "letsencrypt.org") is authorized to issue a certificate.
+// If wildcard is true, the authorization for a wildcard certificate is evaluated.
+func (p *CAAPolicy) CanIssue(ca string, wildcard bool) bool {
+
+	return p.CanIssueWith(ca, wildcard, "", "")
+}
+
+// CanIssueWith is like CanIssue, but the "accounturi" and the "validationmethods" parameters are evaluated too.
+// If accountURI or method is empty, the parameter is not checked.
+// See more: https://www.rfc-editor.org/rfc/rfc8657.html
+func (p *CAAPolicy) CanIssueWith(ca string, wildcard bool, accountURI string, method string) bool {
+
+	if len(p.Critical) > 0 {
+		return false
+	}
+
+	issuers := p.Issuers(wildcard)
+	if len(issuers) == 0 {
+		return true
+	}
+
+	ca = strings.ToLower(Clean(ca))
+
+	for i := range issuers {
+
+		if issuers[i].Err != nil || issuers[i].Domain == "" || issuers[i].Domain != ca {
+			continue
+		}
+
+		if accountURI != "" && issuers[i].AccountURI() != "" && issuers[i].AccountURI() != accountURI {
+			continue
+		}
+
+		if methods := issuers[i].ValidationMethods(); method != "" && methods != nil && !slices.Contains(methods, method) {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// caaAt returns the CAA RRset of name and the CNAME targets followed.
+func (s *Servers) caaAt(ctx context.Context, name string) ([]CAA, []string, error) {
+
+	var aliases []string
+
+	for {
+
+		rr, err := s.TryQueryContext(ctx, name, TypeCAA)
+		if err != nil {
+			return nil, aliases, err
+		}
+
+		var (
+			r      []CAA
+			target string
+		)
+
+		for i := range rr {
+			switch v := rr[i].(type) {
+			case *mdns.CAA:
+				r = append(r, CAA{Flag: v.Flag, Tag: v.Tag, Value: v.Value})
+			case *mdns.CNAME:
+				target = Clean(v.Target)
+				aliases = append(aliases, target)
+			}
+		}
+
+		// A recursive resolver includes the RRset of the target, an authoritative server may not
+		if len(r) > 0 || target == "" {
+			return r, aliases, nil
+		}
+
+		if len(aliases) > maxCAAChain {
+			return nil, aliases, ErrCAALoop
+		}
+
+		name = target
+	}
+}
+
+// RelevantCAA finds the relevant CAA RRset of name by climbing the DNS tree up to the TLD (RFC 8659 3.).
+// The CNAMEs are followed, but the search is not continued at the parent of the target.
+// The leading "*." of a wildcard name is removed.
+//
+// If no CAA RRset found, the returned policy has an empty Name and any CA is authorized.
+// If any error other than NXDOMAIN occurred, returns the error, because the issuance must not be authorized.
+func (s *Servers) RelevantCAA(name string) (*CAAPolicy, error) {
+
+	return s.RelevantCAAContext(context.Background(), name)
+}
+
+// RelevantCAAContext is like RelevantCAA, but the query is bound to ctx.
+func (s *Servers) RelevantCAAContext(ctx context.Context, name string) (*CAAPolicy, error) {
+
+	name = strings.TrimPrefix(Clean(name), "*.")
+
+	for name != "" {
+
+		rr, aliases, err := s.caaAt(ctx, name)
+		if err != nil && !errors.Is(err, ErrName) {
+			return nil, fmt.Errorf("failed to query %s: %w", name, err)
+		}
+
+		if len(rr) > 0 {
+			return newCAAPolicy(name, aliases, rr), nil
+		}
+
+		_, name, _ = strings.Cut(name, ".")
+	}
+
+	return &CAAPolicy{}, nil
+}
+
+// RelevantCAA finds the relevant CAA RRset of name using the DefaultServers.
+// See Servers.RelevantCAA().
+func RelevantCAA(name string) (*CAAPolicy, error) {
+
+	return RelevantCAAContext(context.Background(), name)
+}
+
+// RelevantCAAContext is like RelevantCAA, but the query is bound to ctx.
+func RelevantCAAContext(ctx context.Context, name string) (*CAAPolicy, error) {
+
+	return DefaultServers.RelevantCAAContext(ctx, name)
+}
@@ -0,0 +1,98 @@
+package dns
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
+)
+
+func TestParseCAAIssuer(t *testing.T) {
+
+	c, err := ParseCAAIssuer("CA.example.net; accounturi=https://ca.example.net/acct/1 ; validationmethods=dns-01,http-01")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if c.Domain != "ca.example.net" || c.AccountURI() != "https://ca.example.net/acct/1" || len(c.ValidationMethods()) != 2 {
+		t.Fatalf("FAIL: invalid issuer: %#v\n", c)
+	}
+
+	if c, err = ParseCAAIssuer(";"); err != nil || c.Domain != "" {
+		t.Fatalf("FAIL: invalid empty issuer: %#v, %v\n", c, err)
+	}
+
+	for _, v := range []string{"ca..example.net", "ca.example.net; foo", "ca.example.net; a=b c"} {
+		if _, err := ParseCAAIssuer(v); !errors.Is(err, ErrInvalidCAAValue) {
+			t.Errorf("FAIL: %q: expected ErrInvalidCAAValue, got %v\n", v, err)
+		}
+	}
+}
+
+func TestServersRelevantCAA(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, dnstest.MustZone(".",
+		`example.com. 300 IN CAA 0 issue "ca.example.net; accounturi=https://ca.example.net/acct/1"`,
+		`example.com. 300 IN CAA 0 issuewild ";"`,
+		`example.com. 300 IN CAA 0 iodef "mailto:security@example.com"`,
+		`www.example.com. 300 IN A 192.0.2.1`,
+		`alias.example.com. 300 IN CNAME target.example.org.`,
+		`target.example.org. 300 IN CAA 0 issue "other.example"`,
+		`critical.example.com. 300 IN CAA 128 tbs "foo"`,
+		`critical.example.com. 300 IN CAA 0 issue "ca.example.net"`,
+		`example.org. 300 IN A 192.0.2.2`,
+	))
+
+	tests := []struct {
+		Name       string
+		CA         string
+		Wildcard   bool
+		AccountURI string
+		Found      string
+		Result     bool
+	}{
+		{"www.example.com", "ca.example.net", false, "", "example.com", true},
+		{"www.example.com", "CA.example.net.", false, "https://ca.example.net/acct/1", "example.com", true},
+		{"www.example.com", "ca.example.net", false, "https://ca.example.net/acct/2", "example.com", false},
+		{"*.example.com", "ca.example.net", true, "", "example.com", false},
+		{"nx.sub.example.com", "other.example", false, "", "example.com", false},
+		{"alias.example.com", "other.example", false, "", "alias.example.com", true},
+		{"alias.example.com", "ca.example.net", false, "", "alias.example.com", false},
+		{"critical.example.com", "ca.example.net", false, "", "critical.example.com", false},
+		{"www.example.org", "anyone.example", true, "", "", true},
+	}
+
+	for i := range tests {
+
+		p, err := srvs.RelevantCAA(tests[i].Name)
+		if err != nil {
+			t.Fatalf("FAIL: %s: %s\n", tests[i].Name, err)
+		}
+
+		if p.Name != tests[i].Found {
+			t.Errorf("FAIL: %s: expected relevant RRset at %q, got %q\n", tests[i].Name, tests[i].Found, p.Name)
+		}
+
+		if r := p.CanIssueWith(tests[i].CA, tests[i].Wildcard, tests[i].AccountURI, ""); r != tests[i].Result {
+			t.Errorf("FAIL: %s: %s (wildcard: %v): expected %v, got %v\n", tests[i].Name, tests[i].CA, tests[i].Wildcard, tests[i].Result, r)
+		}
+	}
+
+	p, err := srvs.RelevantCAA("example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(p.IODEF) != 1 || p.IODEF[0] != "mailto:security@example.com" {
+		t.Fatalf("FAIL: invalid iodef: %v\n", p.IODEF)
+	}
+
+	p, err = srvs.RelevantCAA("alias.example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(p.Aliases) != 1 || p.Aliases[0] != "target.example.org" {
+		t.Fatalf("FAIL: invalid aliases: %v\n", p.Aliases)
+	}
+}
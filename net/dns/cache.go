@@ -0,0 +1,268 @@
+package dns
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	mdns "github.com/miekg/dns"
+)
+
+// MaxNegativeTTL is the upper limit of the negative cache TTL.
+// See: https://www.rfc-editor.org/rfc/rfc2308.html#section-5
+var MaxNegativeTTL = 3 * time.Hour
+
+type cacheKey struct {
+	name string
+	t    uint16
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	answer  []mdns.RR // nil for negative entries
+	err     error     // ErrName for NXDOMAIN, nil for NOERROR/NODATA
+	expires time.Time
+}
+
+// CacheStats holds the statistics of a Cache.
+type CacheStats struct {
+	Hits      uint64 // Number of queries answered from the cache
+	Misses    uint64 // Number of queries not found in the cache
+	Evictions uint64 // Number of entries removed to make room for new ones
+	Entries   int    // Number of entries currently in the cache
+}
+
+// Cache is a bounded, concurrency-safe response cache for Servers.
+//
+// Positive answers are cached for the lowest TTL in the answer.
+// NXDOMAIN and NODATA answers are cached for the lowest of the SOA TTL and SOA MINIMUM field (RFC 2308),
+// if the response contains an SOA record in the authority section.
+//
+// When the cache is full, the least recently used entry is evicted.
+type Cache struct {
+	m       sync.Mutex
+	size    int
+	maxTTL  time.Duration
+	entries map[cacheKey]*list.Element
+	lru     *list.List
+	stats   CacheStats
+	now     func() time.Time
+}
+
+type noCacheKey struct{}
+
+// NewCache creates a new Cache that holds up to size entries.
+// If maxTTL is greater than 0, the TTL of the entries is capped to maxTTL.
+//
+// Panics if size is less than 1.
+func NewCache(size int, maxTTL time.Duration) *Cache {
+
+	if size < 1 {
+		panic("invalid cache size")
+	}
+
+	return &Cache{
+		size:    size,
+		maxTTL:  maxTTL,
+		entries: make(map[cacheKey]*list.Element, size),
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+// WithoutCache returns a copy of ctx that disables the cache for the queries made with it.
+// The response is not stored in the cache either.
+func WithoutCache(ctx context.Context) context.Context {
+
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// isCacheDisabled returns whether the cache is disabled by ctx.
+func isCacheDisabled(ctx context.Context) bool {
+
+	v, _ := ctx.Value(noCacheKey{}).(bool)
+	return v
+}
+
+func newCacheKey(name string, t uint16) cacheKey {
+
+	return cacheKey{name: strings.ToLower(mdns.Fqdn(name)), t: t}
+}
+
+// get returns the cached answer for name with type t.
+// The TTLs in the returned copy are set to the remaining time.
+// The returned bool reports whether the entry is found, the error is ErrName for cached NXDOMAIN.
+func (c *Cache) get(name string, t uint16) ([]mdns.RR, bool, error) {
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	e, ok := c.entries[newCacheKey(name, t)]
+	if !ok {
+		c.stats.Misses++
+		return nil, false, nil
+	}
+
+	entry := e.Value.(*cacheEntry)
+
+	remaining := entry.expires.Sub(c.now())
+	if remaining <= 0 {
+		c.remove(e)
+		c.stats.Misses++
+		return nil, false, nil
+	}
+
+	c.lru.MoveToFront(e)
+	c.stats.Hits++
+
+	if entry.err != nil {
+		return nil, true, entry.err
+	}
+
+	rr := make([]mdns.RR, 0, len(entry.answer))
+
+	for i := range entry.answer {
+		r := mdns.Copy(entry.answer[i])
+		r.Header().Ttl = uint32(remaining.Seconds())
+		rr = append(rr, r)
+	}
+
+	return rr, true, nil
+}
+
+// put stores the response in for name with type t.
+// err must be the error returned with in (nil or an rcode error).
+// Only NOERROR and NXDOMAIN responses are cached.
+func (c *Cache) put(name string, t uint16, in *mdns.Msg, err error) {
+
+	if in == nil || (err != nil && !errors.Is(err, ErrName)) {
+		return
+	}
+
+	var ttl time.Duration
+
+	if err == nil && len(in.Answer) > 0 {
+		ttl = answerTTL(in.Answer)
+	} else {
+		ttl = negativeTTL(in.Ns)
+	}
+
+	if c.maxTTL > 0 && ttl > c.maxTTL {
+		ttl = c.maxTTL
+	}
+
+	if ttl <= 0 {
+		return
+	}
+
+	entry := &cacheEntry{key: newCacheKey(name, t), err: err, expires: c.now().Add(ttl)}
+
+	if err == nil {
+		entry.answer = make([]mdns.RR, 0, len(in.Answer))
+		for i := range in.Answer {
+			entry.answer = append(entry.answer, mdns.Copy(in.Answer[i]))
+		}
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if e, ok := c.entries[entry.key]; ok {
+		e.Value = entry
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	for c.lru.Len() >= c.size {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+
+	c.entries[entry.key] = c.lru.PushFront(entry)
+}
+
+// remove removes e from the cache. The caller must hold the lock.
+func (c *Cache) remove(e *list.Element) {
+
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*cacheEntry).key)
+}
+
+// answerTTL returns the lowest TTL in rr.
+func answerTTL(rr []mdns.RR) time.Duration {
+
+	var ttl uint32
+
+	for i := range rr {
+		if i == 0 || rr[i].Header().Ttl < ttl {
+			ttl = rr[i].Header().Ttl
+		}
+	}
+
+	return time.Duration(ttl) * time.Second
+}
+
+// negativeTTL returns the negative cache TTL based on the SOA record in the authority section ns.
+// Returns 0 if no SOA record found.
+// See: https://www.rfc-editor.org/rfc/rfc2308.html#section-5
+func negativeTTL(ns []mdns.RR) time.Duration {
+
+	for i := range ns {
+
+		soa, ok := ns[i].(*mdns.SOA)
+		if !ok {
+			continue
+		}
+
+		ttl := soa.Hdr.Ttl
+		if soa.Minttl < ttl {
+			ttl = soa.Minttl
+		}
+
+		d := time.Duration(ttl) * time.Second
+		if d > MaxNegativeTTL {
+			d = MaxNegativeTTL
+		}
+
+		return d
+	}
+
+	return 0
+}
+
+// Delete removes the cached answer for name with type t.
+func (c *Cache) Delete(name string, t uint16) {
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if e, ok := c.entries[newCacheKey(name, t)]; ok {
+		c.remove(e)
+	}
+}
+
+// Flush removes every entry from the cache.
+// The statistics are not reset.
+func (c *Cache) Flush() {
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.entries = make(map[cacheKey]*list.Element, c.size)
+	c.lru.Init()
+}
+
+// Stats returns the statistics of the cache.
+func (c *Cache) Stats() CacheStats {
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	st := c.stats
+	st.Entries = c.lru.Len()
+
+	return st
+}
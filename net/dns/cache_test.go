@@ -0,0 +1,243 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
+	mdns "github.com/miekg/dns"
+)
+
+// cacheTestZones returns the zones of the cache tests.
+//
+// Zone:
+//   - example.com. A 192.0.2.1 with TTL 60
+//   - empty.example.com. NODATA with SOA (TTL 300, MINIMUM 30)
+//   - nosoa.example.com. NODATA without SOA
+//   - everything else is NXDOMAIN with SOA (TTL 300, MINIMUM 30)
+func cacheTestZones() []*dnstest.Zone {
+
+	nosoa := dnstest.MustZone("nosoa.example.com", `@ 60 IN TXT "nodata"`)
+
+	// Remove the default SOA
+	nosoa.Records = nosoa.Records[1:]
+
+	return []*dnstest.Zone{
+		dnstest.MustZone("example.com",
+			"@ 300 IN SOA ns admin 1 3600 600 86400 30",
+			"@ 60 IN A 192.0.2.1",
+			`empty 60 IN TXT "nodata"`,
+		),
+		nosoa,
+	}
+}
+
+func TestCachePositive(t *testing.T) {
+
+	srvs, ts := newZoneTestServers(t, cacheTestZones()...)
+
+	c := NewCache(10, 0)
+	srvs.SetCache(c)
+
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+
+		r, err := srvs.TryQueryA("example.com")
+		if err != nil {
+			t.Fatalf("FAIL: Failed to query example.com: %s\n", err)
+		}
+
+		if len(r) != 1 || !r[0].Equal(net.ParseIP("192.0.2.1")) {
+			t.Fatalf("FAIL: Invalid answer: %v\n", r)
+		}
+	}
+
+	if v := len(ts.Queries()); v != 1 {
+		t.Fatalf("FAIL: Invalid number of queries: %d, want: 1\n", v)
+	}
+
+	// The TTL is decreased
+	now = now.Add(20 * time.Second)
+
+	rr, err := srvs.TryQuery("example.com", TypeA)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to query example.com: %s\n", err)
+	}
+
+	if ttl := rr[0].Header().Ttl; ttl != 40 {
+		t.Fatalf("FAIL: Invalid TTL: %d, want: 40\n", ttl)
+	}
+
+	// Expired
+	now = now.Add(41 * time.Second)
+
+	if _, err = srvs.TryQuery("example.com", TypeA); err != nil {
+		t.Fatalf("FAIL: Failed to query example.com: %s\n", err)
+	}
+
+	if v := len(ts.Queries()); v != 2 {
+		t.Fatalf("FAIL: Invalid number of queries: %d, want: 2\n", v)
+	}
+
+	st := c.Stats()
+	if st.Hits != 3 || st.Misses != 2 || st.Entries != 1 {
+		t.Fatalf("FAIL: Invalid stats: %+v\n", st)
+	}
+}
+
+func TestCacheNegative(t *testing.T) {
+
+	srvs, ts := newZoneTestServers(t, cacheTestZones()...)
+
+	c := NewCache(10, 0)
+	srvs.SetCache(c)
+
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := srvs.TryQuery("invalid.example.com", TypeA); !errors.Is(err, ErrName) {
+			t.Fatalf("FAIL: error wanted: %s, error got: %v\n", ErrName, err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if set, err := srvs.IsSetA("empty.example.com"); err != nil || set {
+			t.Fatalf("FAIL: empty.example.com: set: %v, error: %v\n", set, err)
+		}
+	}
+
+	if v := len(ts.Queries()); v != 2 {
+		t.Fatalf("FAIL: Invalid number of queries: %d, want: 2\n", v)
+	}
+
+	// Negative TTL is min(SOA TTL, SOA MINIMUM) = 30
+	now = now.Add(31 * time.Second)
+
+	if _, err := srvs.TryQuery("invalid.example.com", TypeA); !errors.Is(err, ErrName) {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", ErrName, err)
+	}
+
+	if v := len(ts.Queries()); v != 3 {
+		t.Fatalf("FAIL: Invalid number of queries: %d, want: 3\n", v)
+	}
+
+	// NODATA without SOA is not cached
+	for i := 0; i < 2; i++ {
+		if _, err := srvs.TryQuery("nosoa.example.com", TypeA); err != nil {
+			t.Fatalf("FAIL: Failed to query nosoa.example.com: %s\n", err)
+		}
+	}
+
+	if v := len(ts.Queries()); v != 5 {
+		t.Fatalf("FAIL: Invalid number of queries: %d, want: 5\n", v)
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+
+	srvs, ts := newZoneTestServers(t, cacheTestZones()...)
+
+	c := NewCache(10, 0)
+	srvs.SetCache(c)
+
+	ctx := WithoutCache(context.Background())
+
+	for i := 0; i < 2; i++ {
+		if _, err := srvs.TryQueryAContext(ctx, "example.com"); err != nil {
+			t.Fatalf("FAIL: Failed to query example.com: %s\n", err)
+		}
+	}
+
+	if v := len(ts.Queries()); v != 2 {
+		t.Fatalf("FAIL: Invalid number of queries: %d, want: 2\n", v)
+	}
+
+	if st := c.Stats(); st.Entries != 0 {
+		t.Fatalf("FAIL: The response is cached with disabled cache: %+v\n", st)
+	}
+
+	// Flush
+	if _, err := srvs.TryQueryA("example.com"); err != nil {
+		t.Fatalf("FAIL: Failed to query example.com: %s\n", err)
+	}
+
+	c.Flush()
+
+	if _, err := srvs.TryQueryA("example.com"); err != nil {
+		t.Fatalf("FAIL: Failed to query example.com: %s\n", err)
+	}
+
+	if v := len(ts.Queries()); v != 4 {
+		t.Fatalf("FAIL: Invalid number of queries: %d, want: 4\n", v)
+	}
+
+	srvs.SetCache(nil)
+
+	if _, err := srvs.TryQueryA("example.com"); err != nil {
+		t.Fatalf("FAIL: Failed to query example.com: %s\n", err)
+	}
+
+	if v := len(ts.Queries()); v != 5 {
+		t.Fatalf("FAIL: Invalid number of queries: %d, want: 5\n", v)
+	}
+}
+
+func TestCacheConcurrentSet(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, cacheTestZones()...)
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			srvs.SetCache(NewCache(10, 0))
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		if _, err := srvs.TryQueryA("example.com"); err != nil {
+			t.Fatalf("FAIL: Failed to query example.com: %s\n", err)
+		}
+	}
+
+	<-done
+}
+
+func TestCacheEviction(t *testing.T) {
+
+	c := NewCache(2, time.Minute)
+
+	for _, name := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+
+		m := new(mdns.Msg)
+		m.SetQuestion(mdns.Fqdn(name), mdns.TypeA)
+		m.Answer = append(m.Answer, &mdns.A{Hdr: mdns.RR_Header{Name: mdns.Fqdn(name), Rrtype: mdns.TypeA, Class: mdns.ClassINET, Ttl: 3600}, A: net.ParseIP("192.0.2.1")})
+
+		c.put(name, TypeA, m, nil)
+	}
+
+	if _, ok, _ := c.get("a.example.com", TypeA); ok {
+		t.Fatalf("FAIL: The least recently used entry is not evicted\n")
+	}
+
+	rr, ok, _ := c.get("C.EXAMPLE.COM.", TypeA)
+	if !ok {
+		t.Fatalf("FAIL: c.example.com is not found\n")
+	}
+
+	// Capped to maxTTL
+	if ttl := rr[0].Header().Ttl; ttl > 60 {
+		t.Fatalf("FAIL: The TTL is not capped: %d\n", ttl)
+	}
+
+	if st := c.Stats(); st.Evictions != 1 || st.Entries != 2 {
+		t.Fatalf("FAIL: Invalid stats: %+v\n", st)
+	}
+}
@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"fmt"
 
 	mdns "github.com/miekg/dns"
@@ -8,16 +9,8 @@ import (
 
 var TypeCNAME uint16 = 5
 
-// QueryCNAME ask the server and returns a slice of string.
-// The answer slice will be nil in case of error.
-//
-// The other record types are ignored.
-func (s *Server) QueryCNAME(name string) ([]string, error) {
-
-	rr, err := s.query(name, TypeCNAME)
-	if err != nil {
-		return nil, err
-	}
+// parseCNAME converts the answer rr to CNAME.
+func parseCNAME(rr []mdns.RR) ([]string, error) {
 
 	r := make([]string, 0, len(rr))
 
@@ -29,6 +22,9 @@ func (s *Server) QueryCNAME(name string) ([]string, error) {
 		case *mdns.DNAME:
 			// Ignore DNAME
 			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
 		default:
 			return nil, fmt.Errorf("unknown type: %T", v)
 		}
@@ -37,22 +33,56 @@ func (s *Server) QueryCNAME(name string) ([]string, error) {
 	return r, nil
 }
 
+// QueryCNAME ask the server and returns a slice of string.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QueryCNAME(name string) ([]string, error) {
+
+	return s.QueryCNAMEContext(context.Background(), name)
+}
+
+// QueryCNAMEContext is like QueryCNAME, but the query is bound to ctx.
+func (s *Server) QueryCNAMEContext(ctx context.Context, name string) ([]string, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeCNAME)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCNAME(rr)
+}
+
 // QueryCNAME ask a random server from servers and returns a slice of string.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func (s *Servers) QueryCNAME(name string) ([]string, error) {
 
-	return s.Get(-1).QueryCNAME(name)
+	return s.QueryCNAMEContext(context.Background(), name)
+}
+
+// QueryCNAMEContext is like QueryCNAME, but the query is bound to ctx.
+func (s *Servers) QueryCNAMEContext(ctx context.Context, name string) ([]string, error) {
+
+	return s.Get(-1).QueryCNAMEContext(ctx, name)
 }
 
-// QueryCNAME ask a random server from DefaultServers and returns a slice of string.
+// QueryCNAME asks the DefaultResolver and returns a slice of string.
+// Unlike Servers.QueryCNAME, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QueryCNAME is the same as TryQueryCNAME.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func QueryCNAME(name string) ([]string, error) {
 
-	return DefaultServers.QueryCNAME(name)
+	return QueryCNAMEContext(context.Background(), name)
+}
+
+// QueryCNAMEContext is like QueryCNAME, but the query is bound to ctx.
+func QueryCNAMEContext(ctx context.Context, name string) ([]string, error) {
+
+	return QueryCNAMEWith(ctx, DefaultResolver, name)
 }
 
 // TryQueryCNAME asks the servers for type CNAME. If any error occurred, retries with next server (except if error is NXDOMAIN).
@@ -62,47 +92,61 @@ func QueryCNAME(name string) ([]string, error) {
 // The first used server is random. The other record types are ignored.
 func (s *Servers) TryQueryCNAME(name string) ([]string, error) {
 
-	rr, err := s.TryQuery(name, TypeCNAME)
-	if err != nil {
-		return nil, err
-	}
-
-	r := make([]string, 0, len(rr))
-
-	for i := range rr {
+	return s.TryQueryCNAMEContext(context.Background(), name)
+}
 
-		switch v := rr[i].(type) {
-		case *mdns.CNAME:
-			r = append(r, v.Target)
-		case *mdns.DNAME:
-			// Ignore DNAME
-			continue
-		default:
-			return nil, fmt.Errorf("unknown type: %T", v)
-		}
-	}
+// TryQueryCNAMEContext is like TryQueryCNAME, but the query is bound to ctx.
+func (s *Servers) TryQueryCNAMEContext(ctx context.Context, name string) ([]string, error) {
 
-	return r, nil
+	return QueryCNAMEWith(ctx, s, name)
 }
 
-// TryQueryCNAME asks the DefaultServers for type CNAME. If any error occurred, retries with next server (except if error is NXDOMAIN).
+// TryQueryCNAME asks the DefaultResolver for type CNAME. If any error occurred, retries with next server (except if error is NXDOMAIN).
 //
 // In case of error, the answer will be nil and return ErrX or any unknown error.
 //
 // The first used server is random. The other record types are ignored.
 func TryQueryCNAME(name string) ([]string, error) {
 
-	return DefaultServers.TryQueryCNAME(name)
+	return TryQueryCNAMEContext(context.Background(), name)
+}
+
+// TryQueryCNAMEContext is like TryQueryCNAME, but the query is bound to ctx.
+func TryQueryCNAMEContext(ctx context.Context, name string) ([]string, error) {
+
+	return QueryCNAMEWith(ctx, DefaultResolver, name)
+}
+
+// QueryCNAMEWith asks r for type CNAME and converts the answer like QueryCNAME.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QueryCNAMEWith(ctx context.Context, r Resolver, name string) ([]string, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeCNAME)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCNAME(rr)
 }
 
 // IsSetCNAME checks whether an CNAME type record set for name.
 // NXDOMAIN is not an error here, because it means "not found".
 func (s *Servers) IsSetCNAME(name string) (bool, error) {
-	return s.IsSet(name, TypeA)
+	return s.IsSetCNAMEContext(context.Background(), name)
 }
 
-// IsSetCNAME checks whether an CNAME type record set for name using the DefaultServers.
+// IsSetCNAMEContext is like IsSetCNAME, but the query is bound to ctx.
+func (s *Servers) IsSetCNAMEContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeCNAME)
+}
+
+// IsSetCNAME checks whether an CNAME type record set for name using the DefaultResolver.
 // NXDOMAIN is not an error here, because it means "not found".
 func IsSetCNAME(name string) (bool, error) {
-	return DefaultServers.IsSetCNAME(name)
+	return IsSetCNAMEContext(context.Background(), name)
+}
+
+// IsSetCNAMEContext is like IsSetCNAME, but the query is bound to ctx.
+func IsSetCNAMEContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeCNAME)
 }
@@ -1,6 +1,10 @@
 package dns
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
+)
 
 func TestQueryCNAME(t *testing.T) {
 
@@ -41,3 +45,22 @@ func TestIsSetCNAME(t *testing.T) {
 		t.Fatalf("FAIL: CNAME is not set for autodiscover.elmasy.com\n")
 	}
 }
+
+func TestServersIsSetCNAME(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, dnstest.MustZone("example.com",
+		"www 300 IN A 192.0.2.1",
+		"alias 300 IN CNAME www",
+	))
+
+	r, err := srvs.IsSetCNAME("alias.example.com")
+	if err != nil || !r {
+		t.Fatalf("FAIL: CNAME is not set for alias.example.com: %v\n", err)
+	}
+
+	// A record without CNAME
+	r, err = srvs.IsSetCNAME("www.example.com")
+	if err != nil || r {
+		t.Fatalf("FAIL: CNAME is set for www.example.com: %v\n", err)
+	}
+}
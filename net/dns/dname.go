@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"fmt"
 
 	mdns "github.com/miekg/dns"
@@ -8,16 +9,8 @@ import (
 
 var TypeDNAME uint16 = 39
 
-// QueryDNAME ask the server and returns the target string.
-// The answer slice will be nil in case of error.
-//
-// The other record types are ignored.
-func (s *Server) QueryDNAME(name string) (string, error) {
-
-	rr, err := s.query(name, TypeDNAME)
-	if err != nil {
-		return "", err
-	}
+// parseDNAME converts the answer rr to DNAME.
+func parseDNAME(rr []mdns.RR) (string, error) {
 
 	for i := range rr {
 
@@ -27,6 +20,9 @@ func (s *Server) QueryDNAME(name string) (string, error) {
 		case *mdns.CNAME:
 			// Ignore CNAME
 			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
 		default:
 			return "", fmt.Errorf("unknown type: %T", v)
 		}
@@ -35,22 +31,56 @@ func (s *Server) QueryDNAME(name string) (string, error) {
 	return "", nil
 }
 
+// QueryDNAME ask the server and returns the target string.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QueryDNAME(name string) (string, error) {
+
+	return s.QueryDNAMEContext(context.Background(), name)
+}
+
+// QueryDNAMEContext is like QueryDNAME, but the query is bound to ctx.
+func (s *Server) QueryDNAMEContext(ctx context.Context, name string) (string, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeDNAME)
+	if err != nil {
+		return "", err
+	}
+
+	return parseDNAME(rr)
+}
+
 // QueryDNAME ask a random server from servers and returns the target string.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func (s *Servers) QueryDNAME(name string) (string, error) {
 
-	return s.Get(-1).QueryDNAME(name)
+	return s.QueryDNAMEContext(context.Background(), name)
+}
+
+// QueryDNAMEContext is like QueryDNAME, but the query is bound to ctx.
+func (s *Servers) QueryDNAMEContext(ctx context.Context, name string) (string, error) {
+
+	return s.Get(-1).QueryDNAMEContext(ctx, name)
 }
 
-// QueryDNAME ask a random server from DefaultServers and returns the target of string.
+// QueryDNAME asks the DefaultResolver and returns the target of string.
+// Unlike Servers.QueryDNAME, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QueryDNAME is the same as TryQueryDNAME.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func QueryDNAME(name string) (string, error) {
 
-	return DefaultServers.QueryDNAME(name)
+	return QueryDNAMEContext(context.Background(), name)
+}
+
+// QueryDNAMEContext is like QueryDNAME, but the query is bound to ctx.
+func QueryDNAMEContext(ctx context.Context, name string) (string, error) {
+
+	return QueryDNAMEWith(ctx, DefaultResolver, name)
 }
 
 // TryQueryDNAME asks the servers for type DNAME. If any error occurred, retries with next server (except if error is NXDOMAIN).
@@ -60,45 +90,61 @@ func QueryDNAME(name string) (string, error) {
 // The first used server is random. The other record types are ignored.
 func (s *Servers) TryQueryDNAME(name string) (string, error) {
 
-	rr, err := s.TryQuery(name, TypeDNAME)
-	if err != nil {
-		return "", err
-	}
-
-	for i := range rr {
+	return s.TryQueryDNAMEContext(context.Background(), name)
+}
 
-		switch v := rr[i].(type) {
-		case *mdns.DNAME:
-			return v.Target, nil
-		case *mdns.CNAME:
-			// Ignore DNAME
-			continue
-		default:
-			return "", fmt.Errorf("unknown type: %T", v)
-		}
-	}
+// TryQueryDNAMEContext is like TryQueryDNAME, but the query is bound to ctx.
+func (s *Servers) TryQueryDNAMEContext(ctx context.Context, name string) (string, error) {
 
-	return "", nil
+	return QueryDNAMEWith(ctx, s, name)
 }
 
-// TryQueryDNAME asks the DefaultServers for type DNAME. If any error occurred, retries with next server (except if error is NXDOMAIN).
+// TryQueryDNAME asks the DefaultResolver for type DNAME. If any error occurred, retries with next server (except if error is NXDOMAIN).
 //
 // In case of error, the answer will be nil and return ErrX or any unknown error.
 //
 // The first used server is random. The other record types are ignored.
 func TryQueryDNAME(name string) (string, error) {
 
-	return DefaultServers.TryQueryDNAME(name)
+	return TryQueryDNAMEContext(context.Background(), name)
+}
+
+// TryQueryDNAMEContext is like TryQueryDNAME, but the query is bound to ctx.
+func TryQueryDNAMEContext(ctx context.Context, name string) (string, error) {
+
+	return QueryDNAMEWith(ctx, DefaultResolver, name)
+}
+
+// QueryDNAMEWith asks r for type DNAME and converts the answer like QueryDNAME.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QueryDNAMEWith(ctx context.Context, r Resolver, name string) (string, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeDNAME)
+	if err != nil {
+		return "", err
+	}
+
+	return parseDNAME(rr)
 }
 
 // IsSetDNAME checks whether an DNAME type record set for name.
 // NXDOMAIN is not an error here, because it means "not found".
 func (s *Servers) IsSetDNAME(name string) (bool, error) {
-	return s.IsSet(name, TypeDNAME)
+	return s.IsSetDNAMEContext(context.Background(), name)
 }
 
-// IsSetCNAME checks whether an DNAME type record set for name using the DefaultServers.
+// IsSetDNAMEContext is like IsSetDNAME, but the query is bound to ctx.
+func (s *Servers) IsSetDNAMEContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeDNAME)
+}
+
+// IsSetDNAME checks whether an DNAME type record set for name using the DefaultResolver.
 // NXDOMAIN is not an error here, because it means "not found".
 func IsSetDNAME(name string) (bool, error) {
-	return DefaultServers.IsSetDNAME(name)
+	return IsSetDNAMEContext(context.Background(), name)
+}
+
+// IsSetDNAMEContext is like IsSetDNAME, but the query is bound to ctx.
+func IsSetDNAMEContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeDNAME)
 }
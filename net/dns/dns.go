@@ -4,6 +4,7 @@
 package dns
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
@@ -34,82 +35,19 @@ func init() {
 
 }
 
-// IsExists checks whether a record with type A, AAAA, TXT, CNAME, MX, NS, CAA or SRV is set for name.
+// IsExists checks whether a record with type A, AAAA, TXT, CNAME, MX, NS, CAA or SRV is set for name using the DefaultResolver.
 // NXDOMAIN is not an error here, because it means "not found".
 //
 // If found a setted record, this function returns without trying for the other types.
 func IsExists(name string) (bool, error) {
 
-	// A
-	setA, err := IsSetA(name)
-	if err != nil {
-		return false, fmt.Errorf("check A failed: %w", err)
-	}
-	if setA {
-		return true, nil
-	}
-
-	// AAAA
-	setAAAA, err := IsSetAAAA(name)
-	if err != nil {
-		return false, fmt.Errorf("check AAAA failed: %w", err)
-	}
-	if setAAAA {
-		return true, nil
-	}
-
-	// TXT
-	setTXT, err := IsSetTXT(name)
-	if err != nil {
-		return false, fmt.Errorf("check TXT failed: %w", err)
-	}
-	if setTXT {
-		return true, nil
-	}
-
-	// CNAME
-	setCNAME, err := IsSetCNAME(name)
-	if err != nil {
-		return false, fmt.Errorf("check CNAME failed: %w", err)
-	}
-	if setCNAME {
-		return true, nil
-	}
-
-	// MX
-	setMX, err := IsSetMX(name)
-	if err != nil {
-		return false, fmt.Errorf("check MX failed: %w", err)
-	}
-	if setMX {
-		return true, nil
-	}
-
-	// NS
-	setNS, err := IsSetNS(name)
-	if err != nil {
-		return false, fmt.Errorf("check NS failed: %w", err)
-	}
-	if setNS {
-		return true, nil
-	}
-
-	// CAA
-	setCAA, err := IsSetCAA(name)
-	if err != nil {
-		return false, fmt.Errorf("chack CAA failed: %w", err)
-	}
-	if setCAA {
-		return true, nil
-	}
+	return IsExistsContext(context.Background(), name)
+}
 
-	// SRV
-	setSRV, err := IsSetSRV(name)
-	if err != nil {
-		return false, fmt.Errorf("check SRV failed: %w", err)
-	}
+// IsExistsContext is like IsExists, but the queries are bound to ctx.
+func IsExistsContext(ctx context.Context, name string) (bool, error) {
 
-	return setSRV, nil
+	return IsExistsWith(ctx, DefaultResolver, name)
 }
 
 func TypeToString(t uint16) string {
@@ -135,6 +73,28 @@ func TypeToString(t uint16) string {
 		return "SRV"
 	case TypeTXT:
 		return "TXT"
+	case TypePTR:
+		return "PTR"
+	case TypeTLSA:
+		return "TLSA"
+	case TypeSSHFP:
+		return "SSHFP"
+	case TypeDS:
+		return "DS"
+	case TypeDNSKEY:
+		return "DNSKEY"
+	case TypeHTTPS:
+		return "HTTPS"
+	case TypeSVCB:
+		return "SVCB"
+	case TypeNAPTR:
+		return "NAPTR"
+	case TypeNSEC:
+		return "NSEC"
+	case TypeNSEC3:
+		return "NSEC3"
+	case TypeNSEC3PARAM:
+		return "NSEC3PARAM"
 	default:
 		return "unknown"
 	}
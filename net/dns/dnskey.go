@@ -0,0 +1,176 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+
+	mdns "github.com/miekg/dns"
+)
+
+var TypeDNSKEY uint16 = 48
+
+// See more: https://www.rfc-editor.org/rfc/rfc4034.html#section-2.1
+type DNSKEY struct {
+	Flags     uint16 // 256 is ZSK, 257 is KSK (Secure Entry Point)
+	Protocol  uint8  // Always 3
+	Algorithm uint8  // Algorithm of the key (eg.: 13 is ECDSAP256SHA256)
+	PublicKey string // Public key in base64
+}
+
+func (d DNSKEY) String() string {
+	return fmt.Sprintf("%d %d %d %s", d.Flags, d.Protocol, d.Algorithm, d.PublicKey)
+}
+
+// KeyTag returns the key tag of the key.
+// See more: https://www.rfc-editor.org/rfc/rfc4034.html#appendix-B
+func (d DNSKEY) KeyTag() uint16 {
+
+	k := mdns.DNSKEY{Hdr: mdns.RR_Header{Rrtype: mdns.TypeDNSKEY, Class: mdns.ClassINET}, Flags: d.Flags, Protocol: d.Protocol, Algorithm: d.Algorithm, PublicKey: d.PublicKey}
+
+	return k.KeyTag()
+}
+
+// parseDNSKEY converts the answer rr to DNSKEY.
+func parseDNSKEY(rr []mdns.RR) ([]DNSKEY, error) {
+
+	r := make([]DNSKEY, 0, len(rr))
+
+	for i := range rr {
+
+		switch v := rr[i].(type) {
+		case *mdns.DNSKEY:
+			r = append(r, DNSKEY{Flags: v.Flags, Protocol: v.Protocol, Algorithm: v.Algorithm, PublicKey: v.PublicKey})
+		case *mdns.CNAME:
+			// Ignore CNAME
+			continue
+		case *mdns.DNAME:
+			// Ignore DNAME
+			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
+		default:
+			return nil, fmt.Errorf("unknown type: %T", v)
+		}
+	}
+
+	return r, nil
+}
+
+// QueryDNSKEY ask the server and returns a slice of DNSKEY.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QueryDNSKEY(name string) ([]DNSKEY, error) {
+
+	return s.QueryDNSKEYContext(context.Background(), name)
+}
+
+// QueryDNSKEYContext is like QueryDNSKEY, but the query is bound to ctx.
+func (s *Server) QueryDNSKEYContext(ctx context.Context, name string) ([]DNSKEY, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeDNSKEY)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDNSKEY(rr)
+}
+
+// QueryDNSKEY ask a random server from servers and returns a slice of DNSKEY.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Servers) QueryDNSKEY(name string) ([]DNSKEY, error) {
+
+	return s.QueryDNSKEYContext(context.Background(), name)
+}
+
+// QueryDNSKEYContext is like QueryDNSKEY, but the query is bound to ctx.
+func (s *Servers) QueryDNSKEYContext(ctx context.Context, name string) ([]DNSKEY, error) {
+
+	return s.Get(-1).QueryDNSKEYContext(ctx, name)
+}
+
+// QueryDNSKEY asks the DefaultResolver and returns a slice of DNSKEY.
+// Unlike Servers.QueryDNSKEY, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QueryDNSKEY is the same as TryQueryDNSKEY.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func QueryDNSKEY(name string) ([]DNSKEY, error) {
+
+	return QueryDNSKEYContext(context.Background(), name)
+}
+
+// QueryDNSKEYContext is like QueryDNSKEY, but the query is bound to ctx.
+func QueryDNSKEYContext(ctx context.Context, name string) ([]DNSKEY, error) {
+
+	return QueryDNSKEYWith(ctx, DefaultResolver, name)
+}
+
+// TryQueryDNSKEY asks the servers for type DNSKEY. If any error occurred, retries with next server (except if error is NXDOMAIN).
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func (s *Servers) TryQueryDNSKEY(name string) ([]DNSKEY, error) {
+
+	return s.TryQueryDNSKEYContext(context.Background(), name)
+}
+
+// TryQueryDNSKEYContext is like TryQueryDNSKEY, but the query is bound to ctx.
+func (s *Servers) TryQueryDNSKEYContext(ctx context.Context, name string) ([]DNSKEY, error) {
+
+	return QueryDNSKEYWith(ctx, s, name)
+}
+
+// TryQueryDNSKEY asks the DefaultResolver for type DNSKEY. If any error occurred, retries with next server (except if error is NXDOMAIN).
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func TryQueryDNSKEY(name string) ([]DNSKEY, error) {
+
+	return TryQueryDNSKEYContext(context.Background(), name)
+}
+
+// TryQueryDNSKEYContext is like TryQueryDNSKEY, but the query is bound to ctx.
+func TryQueryDNSKEYContext(ctx context.Context, name string) ([]DNSKEY, error) {
+
+	return QueryDNSKEYWith(ctx, DefaultResolver, name)
+}
+
+// QueryDNSKEYWith asks r for type DNSKEY and converts the answer like QueryDNSKEY.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QueryDNSKEYWith(ctx context.Context, r Resolver, name string) ([]DNSKEY, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeDNSKEY)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDNSKEY(rr)
+}
+
+// IsSetDNSKEY checks whether an DNSKEY type record set for name.
+// NXDOMAIN is not an error here, because it means "not found".
+func (s *Servers) IsSetDNSKEY(name string) (bool, error) {
+	return s.IsSetDNSKEYContext(context.Background(), name)
+}
+
+// IsSetDNSKEYContext is like IsSetDNSKEY, but the query is bound to ctx.
+func (s *Servers) IsSetDNSKEYContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeDNSKEY)
+}
+
+// IsSetDNSKEY checks whether an DNSKEY type record set for name using the DefaultResolver.
+// NXDOMAIN is not an error here, because it means "not found".
+func IsSetDNSKEY(name string) (bool, error) {
+	return IsSetDNSKEYContext(context.Background(), name)
+}
+
+// IsSetDNSKEYContext is like IsSetDNSKEY, but the query is bound to ctx.
+func IsSetDNSKEYContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeDNSKEY)
+}
@@ -0,0 +1,33 @@
+package dns
+
+import (
+	"testing"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
+)
+
+func TestQueryDNSKEY(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, dnstest.MustZone(".",
+		"example.com. 300 IN DNSKEY 257 3 13 mdsswUyr3DPW132mOi8V9xESWE8jTo0dxCjjnopKl+GqJxpVXckHAeF+KkxLbxILfDLUT0rAK9iUzy1L53eKGQ==",
+		"example.com. 300 IN DS 2371 13 2 1F987CC6583E92DF0890718C42B9D9A17A2E3C2F5AAD2A5A70F4DB2A2C8B5D8A",
+	))
+
+	k, err := srvs.TryQueryDNSKEY("example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(k) != 1 || k[0].Flags != 257 || k[0].Algorithm != 13 {
+		t.Fatalf("FAIL: Invalid DNSKEY: %+v\n", k)
+	}
+
+	ds, err := srvs.TryQueryDS("example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(ds) != 1 || ds[0].KeyTag != k[0].KeyTag() {
+		t.Fatalf("FAIL: Key tag of the DNSKEY (%d) does not match the DS: %+v\n", k[0].KeyTag(), ds)
+	}
+}
@@ -0,0 +1,427 @@
+package dnstest
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	mdns "github.com/miekg/dns"
+)
+
+// maxChain is the maximum number of CNAMEs followed in the answer.
+const maxChain = 8
+
+// Rule overrides the response of the matching queries.
+type Rule struct {
+	Rcode    int           // If not 0, the response has this rcode without any record
+	Delay    time.Duration // Delay before the response
+	Truncate bool          // Truncate the UDP response (TC bit set, no records), the TCP response is not affected
+	Drop     bool          // Do not respond at all
+	Network  string        // The rule matches only the queries over this network ("udp" or "tcp"), empty means both
+	Count    int           // If not 0, the rule is removed after matching Count queries (eg.: 1 to fail only the first query)
+}
+
+type ruleKey struct {
+	name  string
+	qtype uint16
+}
+
+// Server is an in-memory authoritative DNS server listening on the same local port over UDP and TCP.
+type Server struct {
+	Addr string // Address of the server (eg.: "127.0.0.1:5353")
+
+	m         sync.RWMutex
+	zones     []*Zone
+	rules     map[ruleKey]Rule
+	udpSize   int
+	recursion bool
+	queries   []mdns.Question
+	inflight  int // Number of the queries being served
+	peak      int // Maximum of inflight
+
+	udp *mdns.Server
+	tcp *mdns.Server
+}
+
+// NewServer starts a Server on a random port of 127.0.0.1 serving zones.
+// The Server must be closed with Close().
+func NewServer(zones ...*Zone) (*Server, error) {
+
+	return NewServerAddr("127.0.0.1:0", zones...)
+}
+
+// NewServerAddr starts a Server on addr serving zones.
+// If the port of addr is 0, a random port is used that is free on both UDP and TCP.
+func NewServerAddr(addr string, zones ...*Zone) (*Server, error) {
+
+	s := &Server{zones: zones, rules: make(map[ruleKey]Rule)}
+
+	var (
+		pc  net.PacketConn
+		l   net.Listener
+		err error
+	)
+
+	// The random UDP port may be used on TCP, so retry
+	for i := 0; i < 10; i++ {
+
+		pc, err = net.ListenPacket("udp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on UDP: %w", err)
+		}
+
+		l, err = net.Listen("tcp", pc.LocalAddr().String())
+		if err == nil {
+			break
+		}
+
+		pc.Close()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on TCP: %w", err)
+	}
+
+	s.Addr = pc.LocalAddr().String()
+
+	udpStarted := make(chan struct{})
+	tcpStarted := make(chan struct{})
+
+	s.udp = &mdns.Server{PacketConn: pc, Handler: mdns.HandlerFunc(s.serve), NotifyStartedFunc: func() { close(udpStarted) }}
+	s.tcp = &mdns.Server{Listener: l, Handler: mdns.HandlerFunc(s.serve), NotifyStartedFunc: func() { close(tcpStarted) }}
+
+	go s.udp.ActivateAndServe()
+	go s.tcp.ActivateAndServe()
+
+	<-udpStarted
+	<-tcpStarted
+
+	return s, nil
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+
+	errUDP := s.udp.Shutdown()
+	errTCP := s.tcp.Shutdown()
+
+	if errUDP != nil {
+		return errUDP
+	}
+
+	return errTCP
+}
+
+// AddZone adds zones to the served zones.
+// If a zone with the same origin is served, it is replaced.
+func (s *Server) AddZone(zones ...*Zone) {
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for _, z := range zones {
+
+		replaced := false
+
+		for i := range s.zones {
+			if equal(s.zones[i].Origin, z.Origin) {
+				s.zones[i] = z
+				replaced = true
+			}
+		}
+
+		if !replaced {
+			s.zones = append(s.zones, z)
+		}
+	}
+}
+
+// SetRule sets the Rule of the queries for name with type qtype.
+// If name is empty, the rule matches any name. If qtype is 0, the rule matches any type.
+// The most specific rule is used.
+func (s *Server) SetRule(name string, qtype uint16, r Rule) {
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if name != "" {
+		name = strings.ToLower(mdns.CanonicalName(name))
+	}
+
+	s.rules[ruleKey{name: name, qtype: qtype}] = r
+}
+
+// ResetRules removes every Rule.
+func (s *Server) ResetRules() {
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.rules = make(map[ruleKey]Rule)
+}
+
+// SetUDPSize sets the maximum size of the UDP responses.
+// The larger responses are truncated.
+// If n is 0 (default), the size is the EDNS0 buffer size of the query or 512.
+func (s *Server) SetUDPSize(n int) {
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.udpSize = n
+}
+
+// SetRecursion sets whether the server acts like a recursive resolver with the zones in its cache:
+// the queries with the RD bit are answered with the RA bit and without the AA bit.
+func (s *Server) SetRecursion(enabled bool) {
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.recursion = enabled
+}
+
+// Queries returns the questions received by the server.
+func (s *Server) Queries() []mdns.Question {
+
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	r := make([]mdns.Question, len(s.queries))
+	copy(r, s.queries)
+
+	return r
+}
+
+// MaxInflight returns the maximum number of queries that were served concurrently.
+func (s *Server) MaxInflight() int {
+
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return s.peak
+}
+
+// rule returns the most specific Rule for q received over network.
+// The Rule is removed if its Count is reached, s.m must be locked.
+func (s *Server) rule(q mdns.Question, network string) (Rule, bool) {
+
+	name := strings.ToLower(q.Name)
+
+	for _, k := range []ruleKey{{name, q.Qtype}, {name, 0}, {"", q.Qtype}, {"", 0}} {
+
+		r, ok := s.rules[k]
+		if !ok || (r.Network != "" && r.Network != network) {
+			continue
+		}
+
+		switch {
+		case r.Count == 1:
+			delete(s.rules, k)
+		case r.Count > 1:
+			s.rules[k] = Rule{Rcode: r.Rcode, Delay: r.Delay, Truncate: r.Truncate, Drop: r.Drop, Network: r.Network, Count: r.Count - 1}
+		}
+
+		return r, true
+	}
+
+	return Rule{}, false
+}
+
+// zone returns the closest zone of name.
+func (s *Server) zone(name string) *Zone {
+
+	var r *Zone
+
+	for i := range s.zones {
+		if s.zones[i].Contains(name) && (r == nil || mdns.CountLabel(s.zones[i].Origin) > mdns.CountLabel(r.Origin)) {
+			r = s.zones[i]
+		}
+	}
+
+	return r
+}
+
+func (s *Server) serve(w mdns.ResponseWriter, req *mdns.Msg) {
+
+	if len(req.Question) != 1 {
+		m := new(mdns.Msg)
+		m.SetRcode(req, mdns.RcodeFormatError)
+		w.WriteMsg(m)
+		return
+	}
+
+	q := req.Question[0]
+	_, isUDP := w.RemoteAddr().(*net.UDPAddr)
+
+	network := "tcp"
+	if isUDP {
+		network = "udp"
+	}
+
+	s.m.Lock()
+	s.queries = append(s.queries, q)
+	rule, hasRule := s.rule(q, network)
+	udpSize := s.udpSize
+	recursion := s.recursion
+	s.inflight++
+	if s.inflight > s.peak {
+		s.peak = s.inflight
+	}
+	s.m.Unlock()
+
+	defer func() {
+		s.m.Lock()
+		s.inflight--
+		s.m.Unlock()
+	}()
+
+	if hasRule && rule.Delay > 0 {
+		time.Sleep(rule.Delay)
+	}
+
+	if hasRule && rule.Drop {
+		return
+	}
+
+	m := new(mdns.Msg)
+	m.SetReply(req)
+
+	switch {
+	case hasRule && rule.Rcode != 0:
+		m.Rcode = rule.Rcode
+	case hasRule && rule.Truncate && isUDP:
+		m.Truncated = true
+	default:
+		opt := req.IsEdns0()
+		s.m.RLock()
+		s.answer(m, q, opt != nil && opt.Do())
+		s.m.RUnlock()
+
+		if recursion && req.RecursionDesired && m.Rcode != mdns.RcodeRefused {
+			m.RecursionAvailable = true
+			m.Authoritative = false
+		}
+	}
+
+	if isUDP {
+
+		size := udpSize
+
+		if size == 0 {
+			size = mdns.MinMsgSize
+			if opt := req.IsEdns0(); opt != nil {
+				size = int(opt.UDPSize())
+			}
+		}
+
+		// Sets the TC bit if any record is dropped
+		m.Truncate(size)
+	}
+
+	w.WriteMsg(m)
+}
+
+// answer fills m with the authoritative answer for q.
+// If dnssec is true (the DO bit is set), the answers contain the RRSIG records of the zone that cover the answered type
+// and the negative answers contain the NSEC or NSEC3 records of the zone (see Zone.denial()).
+func (s *Server) answer(m *mdns.Msg, q mdns.Question, dnssec bool) {
+
+	z := s.zone(q.Name)
+	if z == nil {
+		m.Rcode = mdns.RcodeRefused
+		return
+	}
+
+	name := strings.ToLower(q.Name)
+
+	for i := 0; i <= maxChain; i++ {
+
+		// Referral
+		if ns := z.cut(name); ns != nil && !(q.Qtype == mdns.TypeDS && equal(ns[0].Header().Name, name)) {
+
+			if len(m.Answer) == 0 {
+				m.Authoritative = false
+				m.Ns = ns
+				m.Extra = z.glue(ns)
+			}
+
+			return
+		}
+
+		m.Authoritative = true
+
+		rrs := z.lookup(name)
+
+		if len(rrs) == 0 {
+
+			if z.exists(name) {
+				// Empty non-terminal
+				m.Ns = z.negative(name, true, dnssec)
+				return
+			}
+
+			rrs = synthesize(z.wildcard(name), name)
+
+			if len(rrs) == 0 {
+				if len(m.Answer) == 0 {
+					m.Rcode = mdns.RcodeNameError
+				}
+				m.Ns = z.negative(name, false, dnssec)
+				return
+			}
+		}
+
+		var answer []mdns.RR
+
+		if q.Qtype == mdns.TypeANY {
+			answer = rrs
+		} else {
+			answer = filter(rrs, q.Qtype)
+		}
+
+		if len(answer) > 0 {
+			m.Answer = append(m.Answer, answer...)
+			if dnssec && q.Qtype != mdns.TypeANY && q.Qtype != mdns.TypeRRSIG {
+				m.Answer = append(m.Answer, signatures(rrs, q.Qtype)...)
+			}
+			return
+		}
+
+		cname := filter(rrs, mdns.TypeCNAME)
+
+		if len(cname) == 0 {
+			// NODATA
+			m.Ns = z.negative(name, true, dnssec)
+			return
+		}
+
+		m.Answer = append(m.Answer, cname[0])
+		if dnssec {
+			m.Answer = append(m.Answer, signatures(rrs, mdns.TypeCNAME)...)
+		}
+
+		// Follow the CNAME if the target is served
+		name = strings.ToLower(mdns.CanonicalName(cname[0].(*mdns.CNAME).Target))
+
+		if z = s.zone(name); z == nil {
+			return
+		}
+	}
+}
+
+// synthesize copies the wildcard records rrs with owner name.
+func synthesize(rrs []mdns.RR, name string) []mdns.RR {
+
+	r := make([]mdns.RR, 0, len(rrs))
+
+	for i := range rrs {
+		rr := mdns.Copy(rrs[i])
+		rr.Header().Name = name
+		r = append(r, rr)
+	}
+
+	return r
+}
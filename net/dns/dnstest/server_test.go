@@ -0,0 +1,410 @@
+package dnstest
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	mdns "github.com/miekg/dns"
+)
+
+func newTestServer(t *testing.T, zones ...*Zone) *Server {
+
+	s, err := NewServer(zones...)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to start server: %s\n", err)
+	}
+
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func exchange(t *testing.T, s *Server, network string, name string, qtype uint16) *mdns.Msg {
+
+	m := new(mdns.Msg)
+	m.SetQuestion(mdns.Fqdn(name), qtype)
+
+	c := &mdns.Client{Net: network, Timeout: time.Second}
+
+	in, _, err := c.Exchange(m, s.Addr)
+	if err != nil {
+		t.Fatalf("FAIL: %s %s: %s\n", name, mdns.TypeToString[qtype], err)
+	}
+
+	return in
+}
+
+func TestServerAnswer(t *testing.T) {
+
+	s := newTestServer(t, MustZone("example.com",
+		"@ 300 IN A 192.0.2.1",
+		"www 300 IN CNAME @",
+		"*.wild 300 IN A 192.0.2.2",
+		"a.b.ent 300 IN TXT \"ent\"",
+		"sub 300 IN NS ns.sub",
+		"ns.sub 300 IN A 192.0.2.53",
+	))
+
+	tests := []struct {
+		Name    string
+		Type    uint16
+		Rcode   int
+		AA      bool
+		Answers int
+		Ns      int
+	}{
+		{"example.com", mdns.TypeA, mdns.RcodeSuccess, true, 1, 0},
+		{"WWW.example.com", mdns.TypeA, mdns.RcodeSuccess, true, 2, 0},
+		{"www.example.com", mdns.TypeCNAME, mdns.RcodeSuccess, true, 1, 0},
+		{"foo.wild.example.com", mdns.TypeA, mdns.RcodeSuccess, true, 1, 0},
+		{"foo.wild.example.com", mdns.TypeAAAA, mdns.RcodeSuccess, true, 0, 1},
+		{"b.ent.example.com", mdns.TypeA, mdns.RcodeSuccess, true, 0, 1},
+		{"nx.example.com", mdns.TypeA, mdns.RcodeNameError, true, 0, 1},
+		{"host.sub.example.com", mdns.TypeA, mdns.RcodeSuccess, false, 0, 1},
+		{"example.org", mdns.TypeA, mdns.RcodeRefused, false, 0, 0},
+	}
+
+	for i := range tests {
+
+		in := exchange(t, s, "udp", tests[i].Name, tests[i].Type)
+
+		if in.Rcode != tests[i].Rcode || in.Authoritative != tests[i].AA || len(in.Answer) != tests[i].Answers || len(in.Ns) != tests[i].Ns {
+			t.Errorf("FAIL: %s %s: unexpected response:\n%s\n", tests[i].Name, mdns.TypeToString[tests[i].Type], in)
+		}
+	}
+
+	in := exchange(t, s, "udp", "foo.wild.example.com", mdns.TypeA)
+	if in.Answer[0].Header().Name != "foo.wild.example.com." {
+		t.Fatalf("FAIL: wildcard answer is not synthesized: %s\n", in.Answer[0])
+	}
+
+	in = exchange(t, s, "udp", "host.sub.example.com", mdns.TypeA)
+	if len(in.Extra) != 1 || in.Extra[0].(*mdns.A).A.String() != "192.0.2.53" {
+		t.Fatalf("FAIL: missing glue: %v\n", in.Extra)
+	}
+}
+
+// exchangeDO is like exchange over UDP, but the query has the DO bit.
+func exchangeDO(t *testing.T, s *Server, name string, qtype uint16) *mdns.Msg {
+
+	m := new(mdns.Msg)
+	m.SetQuestion(mdns.Fqdn(name), qtype)
+	m.SetEdns0(4096, true)
+
+	c := &mdns.Client{Timeout: time.Second}
+
+	in, _, err := c.Exchange(m, s.Addr)
+	if err != nil {
+		t.Fatalf("FAIL: %s %s: %s\n", name, mdns.TypeToString[qtype], err)
+	}
+
+	return in
+}
+
+func TestServerDenialNSEC(t *testing.T) {
+
+	s := newTestServer(t, MustZone("example.com",
+		"@ 300 IN A 192.0.2.1",
+		"@ 300 IN NSEC api A NSEC",
+		"api 300 IN A 192.0.2.2",
+		"api 300 IN NSEC www A NSEC",
+		"www 300 IN A 192.0.2.3",
+		"www 300 IN NSEC @ A NSEC",
+	))
+
+	tests := []struct {
+		Name  string
+		Type  uint16
+		Rcode int
+		NSEC  string // Owners of the NSEC records in the authority section
+	}{
+		{"b.example.com", mdns.TypeA, mdns.RcodeNameError, "api.example.com. example.com."},
+		{"zzz.example.com", mdns.TypeA, mdns.RcodeNameError, "www.example.com. example.com."},
+		{"api.example.com", mdns.TypeAAAA, mdns.RcodeSuccess, "api.example.com."},
+	}
+
+	for i := range tests {
+
+		in := exchangeDO(t, s, tests[i].Name, tests[i].Type)
+
+		var owners []string
+		for _, rr := range in.Ns {
+			if v, ok := rr.(*mdns.NSEC); ok {
+				owners = append(owners, v.Hdr.Name)
+			}
+		}
+
+		if in.Rcode != tests[i].Rcode || strings.Join(owners, " ") != tests[i].NSEC {
+			t.Errorf("FAIL: %s %s: unexpected response:\n%s\n", tests[i].Name, mdns.TypeToString[tests[i].Type], in)
+		}
+	}
+
+	// Without the DO bit
+	if in := exchange(t, s, "udp", "b.example.com", mdns.TypeA); len(in.Ns) != 1 {
+		t.Fatalf("FAIL: NSEC without DO bit:\n%s\n", in)
+	}
+}
+
+func TestServerDenialNSEC3(t *testing.T) {
+
+	var hashes []string
+	for _, n := range []string{"example.org.", "www.example.org."} {
+		hashes = append(hashes, mdns.HashName(n, mdns.SHA1, 1, "AABBCCDD"))
+	}
+
+	sort.Strings(hashes)
+
+	s := newTestServer(t, MustZone("example.org",
+		"@ 300 IN NSEC3PARAM 1 0 1 AABBCCDD",
+		"www 300 IN A 192.0.2.1",
+		hashes[0]+" 300 IN NSEC3 1 0 1 AABBCCDD "+hashes[1]+" A",
+		hashes[1]+" 300 IN NSEC3 1 0 1 AABBCCDD "+hashes[0]+" A",
+	))
+
+	in := exchangeDO(t, s, "nx.example.org", mdns.TypeA)
+
+	var matched, covered bool
+
+	for _, rr := range in.Ns {
+		if v, ok := rr.(*mdns.NSEC3); ok {
+			matched = matched || v.Match("example.org.")
+			covered = covered || v.Cover("nx.example.org.")
+		}
+	}
+
+	if in.Rcode != mdns.RcodeNameError || !matched || !covered {
+		t.Fatalf("FAIL: invalid closest encloser proof:\n%s\n", in)
+	}
+
+	in = exchangeDO(t, s, "www.example.org", mdns.TypeAAAA)
+	if len(in.Ns) != 2 || !in.Ns[1].(*mdns.NSEC3).Match("www.example.org.") {
+		t.Fatalf("FAIL: invalid NODATA proof:\n%s\n", in)
+	}
+}
+
+func TestServerRules(t *testing.T) {
+
+	s := newTestServer(t, MustZone("example.com", "@ 300 IN A 192.0.2.1"))
+
+	s.SetRule("example.com", mdns.TypeA, Rule{Rcode: mdns.RcodeServerFailure})
+	s.SetRule("", mdns.TypeTXT, Rule{Truncate: true})
+
+	if in := exchange(t, s, "udp", "example.com", mdns.TypeA); in.Rcode != mdns.RcodeServerFailure {
+		t.Fatalf("FAIL: expected SERVFAIL, got %s\n", mdns.RcodeToString[in.Rcode])
+	}
+
+	if in := exchange(t, s, "udp", "example.com", mdns.TypeTXT); !in.Truncated {
+		t.Fatalf("FAIL: expected truncated UDP response\n")
+	}
+
+	if in := exchange(t, s, "tcp", "example.com", mdns.TypeTXT); in.Truncated || in.Rcode != mdns.RcodeSuccess {
+		t.Fatalf("FAIL: unexpected TCP response:\n%s\n", in)
+	}
+
+	s.ResetRules()
+	s.SetRule("example.com", 0, Rule{Delay: 100 * time.Millisecond})
+
+	start := time.Now()
+
+	if in := exchange(t, s, "udp", "example.com", mdns.TypeA); len(in.Answer) != 1 {
+		t.Fatalf("FAIL: unexpected response:\n%s\n", in)
+	}
+
+	if time.Since(start) < 100*time.Millisecond {
+		t.Fatalf("FAIL: response is not delayed\n")
+	}
+
+	if n := len(s.Queries()); n != 4 {
+		t.Fatalf("FAIL: expected 4 queries, got %d\n", n)
+	}
+}
+
+func TestServerRuleCount(t *testing.T) {
+
+	s := newTestServer(t, MustZone("example.com", "@ 300 IN A 192.0.2.1"))
+
+	s.SetRule("", 0, Rule{Delay: 50 * time.Millisecond})
+	s.SetRule("example.com", mdns.TypeA, Rule{Rcode: mdns.RcodeServerFailure, Count: 2})
+
+	for i := 0; i < 2; i++ {
+		if in := exchange(t, s, "udp", "example.com", mdns.TypeA); in.Rcode != mdns.RcodeServerFailure {
+			t.Fatalf("FAIL: expected SERVFAIL, got %s\n", mdns.RcodeToString[in.Rcode])
+		}
+	}
+
+	// The less specific rule is used after Count queries
+	start := time.Now()
+
+	if in := exchange(t, s, "udp", "example.com", mdns.TypeA); in.Rcode != mdns.RcodeSuccess || len(in.Answer) != 1 {
+		t.Fatalf("FAIL: unexpected response:\n%s\n", in)
+	}
+
+	if time.Since(start) < 50*time.Millisecond {
+		t.Fatalf("FAIL: response is not delayed\n")
+	}
+
+	if n := s.MaxInflight(); n != 1 {
+		t.Fatalf("FAIL: expected 1 concurrent query, got %d\n", n)
+	}
+
+	errs := make(chan error)
+
+	for i := 0; i < 3; i++ {
+		go func() {
+			m := new(mdns.Msg)
+			m.SetQuestion("example.com.", mdns.TypeA)
+			_, _, err := (&mdns.Client{Timeout: time.Second}).Exchange(m, s.Addr)
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("FAIL: %s\n", err)
+		}
+	}
+
+	if n := s.MaxInflight(); n < 2 || n > 3 {
+		t.Fatalf("FAIL: expected 2-3 concurrent queries, got %d\n", n)
+	}
+}
+
+func TestServerTruncate(t *testing.T) {
+
+	records := make([]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		records = append(records, "@ 300 IN TXT \"long long long long long long record\"")
+	}
+
+	s := newTestServer(t, MustZone("example.com", records...))
+
+	if in := exchange(t, s, "udp", "example.com", mdns.TypeTXT); !in.Truncated {
+		t.Fatalf("FAIL: expected truncated UDP response\n")
+	}
+
+	if in := exchange(t, s, "tcp", "example.com", mdns.TypeTXT); in.Truncated || len(in.Answer) != 50 {
+		t.Fatalf("FAIL: unexpected TCP response: truncated: %v, answers: %d\n", in.Truncated, len(in.Answer))
+	}
+}
+
+func TestServerRootZone(t *testing.T) {
+
+	z, err := NewZone(".", "www.example.com. 300 IN A 192.0.2.1")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if soa := z.SOA(); soa == nil || soa.Ns != "ns." || soa.Mbox != "hostmaster." {
+		t.Fatalf("FAIL: invalid default SOA: %v\n", soa)
+	}
+
+	s := newTestServer(t, z)
+
+	if in := exchange(t, s, "udp", "www.example.com", mdns.TypeA); len(in.Answer) != 1 {
+		t.Fatalf("FAIL: unexpected response:\n%s\n", in)
+	}
+
+	if in := exchange(t, s, "udp", "example.org", mdns.TypeA); in.Rcode != mdns.RcodeNameError || len(in.Ns) != 1 {
+		t.Fatalf("FAIL: expected NXDOMAIN with SOA, got:\n%s\n", in)
+	}
+}
+
+func TestLoadZone(t *testing.T) {
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "hosts.inc"), []byte("www IN A 192.0.2.1\n"), 0o644); err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	zone := "$ORIGIN example.com.\n$TTL 600\n@ IN SOA ns hostmaster 2024010101 7200 3600 1209600 300\n$INCLUDE " + filepath.Join(dir, "hosts.inc") + "\n"
+
+	if err := os.WriteFile(filepath.Join(dir, "example.com.zone"), []byte(zone), 0o644); err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	z, err := LoadZone("example.com", filepath.Join(dir, "example.com.zone"))
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(z.Records) != 2 || z.SOA().Serial != 2024010101 {
+		t.Fatalf("FAIL: unexpected records: %v\n", z.Records)
+	}
+
+	if z.Records[1].Header().Name != "www.example.com." || z.Records[1].Header().Ttl != 600 {
+		t.Fatalf("FAIL: unexpected record: %s\n", z.Records[1])
+	}
+}
+
+func TestServerRuleNetwork(t *testing.T) {
+
+	s := newTestServer(t, MustZone("example.com", "@ 300 IN A 192.0.2.1"))
+
+	s.SetRule("", 0, Rule{Rcode: mdns.RcodeRefused})
+	s.SetRule("example.com", 0, Rule{Network: "tcp", Rcode: mdns.RcodeServerFailure})
+
+	if in := exchange(t, s, "tcp", "example.com", mdns.TypeA); in.Rcode != mdns.RcodeServerFailure {
+		t.Fatalf("FAIL: expected SERVFAIL over TCP, got %s\n", mdns.RcodeToString[in.Rcode])
+	}
+
+	// The TCP only rule is skipped, the less specific rule matches
+	if in := exchange(t, s, "udp", "example.com", mdns.TypeA); in.Rcode != mdns.RcodeRefused {
+		t.Fatalf("FAIL: expected REFUSED over UDP, got %s\n", mdns.RcodeToString[in.Rcode])
+	}
+}
+
+func TestServerRecursion(t *testing.T) {
+
+	s := newTestServer(t, MustZone("example.com", "@ 300 IN A 192.0.2.1"))
+
+	if in := exchange(t, s, "udp", "example.com", mdns.TypeA); !in.Authoritative || in.RecursionAvailable {
+		t.Fatalf("FAIL: unexpected authoritative response:\n%s\n", in)
+	}
+
+	s.SetRecursion(true)
+
+	if in := exchange(t, s, "udp", "example.com", mdns.TypeA); in.Authoritative || !in.RecursionAvailable || len(in.Answer) != 1 {
+		t.Fatalf("FAIL: unexpected recursive response:\n%s\n", in)
+	}
+
+	m := new(mdns.Msg)
+	m.SetQuestion("example.com.", mdns.TypeA)
+	m.RecursionDesired = false
+
+	in, _, err := (&mdns.Client{Timeout: time.Second}).Exchange(m, s.Addr)
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if !in.Authoritative || in.RecursionAvailable {
+		t.Fatalf("FAIL: unexpected response without RD:\n%s\n", in)
+	}
+}
+
+func TestServerSignatures(t *testing.T) {
+
+	s := newTestServer(t, MustZone("example.com",
+		"@ 300 IN A 192.0.2.1",
+		"@ 300 IN RRSIG A 8 2 300 20300101000000 20200101000000 12345 example.com. AAAA",
+		"@ 300 IN RRSIG MX 8 2 300 20300101000000 20200101000000 12345 example.com. AAAA",
+	))
+
+	if in := exchange(t, s, "udp", "example.com", mdns.TypeA); len(in.Answer) != 1 {
+		t.Fatalf("FAIL: unexpected answer without DO:\n%s\n", in)
+	}
+
+	in := exchangeDO(t, s, "example.com", mdns.TypeA)
+
+	if len(in.Answer) != 2 {
+		t.Fatalf("FAIL: unexpected answer with DO:\n%s\n", in)
+	}
+
+	if v, ok := in.Answer[1].(*mdns.RRSIG); !ok || v.TypeCovered != mdns.TypeA {
+		t.Fatalf("FAIL: missing RRSIG of A:\n%s\n", in)
+	}
+}
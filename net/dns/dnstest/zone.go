@@ -0,0 +1,391 @@
+// In-memory authoritative DNS server for hermetic tests and local overrides.
+//
+// This package must not import the dns package, so the dns tests can use it.
+package dnstest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	mdns "github.com/miekg/dns"
+)
+
+// Zone is an in-memory DNS zone.
+type Zone struct {
+	Origin  string // FQDN of the zone apex (eg.: "example.com.")
+	Records []mdns.RR
+}
+
+// NewZone creates a Zone from records in zone file format (eg.: "www 300 IN A 192.0.2.1").
+// Relative names are relative to origin.
+//
+// If records has no SOA, a default SOA is added to the apex.
+func NewZone(origin string, records ...string) (*Zone, error) {
+
+	return ParseZone(origin, strings.NewReader(strings.Join(records, "\n")))
+}
+
+// ParseZone parses the zone file from r.
+// $ORIGIN, $TTL and $INCLUDE directives are supported.
+//
+// If the zone has no SOA, a default SOA is added to the apex.
+func ParseZone(origin string, r io.Reader) (*Zone, error) {
+
+	return parseZone(origin, r, "")
+}
+
+// LoadZone parses the zone file at path.
+// The $INCLUDE paths are relative to path.
+//
+// If the zone has no SOA, a default SOA is added to the apex.
+func LoadZone(origin string, path string) (*Zone, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zone file: %w", err)
+	}
+	defer f.Close()
+
+	return parseZone(origin, f, path)
+}
+
+func parseZone(origin string, r io.Reader, file string) (*Zone, error) {
+
+	z := &Zone{Origin: mdns.CanonicalName(origin)}
+
+	zp := mdns.NewZoneParser(r, z.Origin, file)
+	zp.SetIncludeAllowed(file != "")
+
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		z.Records = append(z.Records, rr)
+	}
+
+	if err := zp.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse zone %s: %w", z.Origin, err)
+	}
+
+	if z.SOA() == nil {
+
+		// The names of the root zone are "ns." and "hostmaster."
+		suffix := z.Origin
+		if suffix == "." {
+			suffix = ""
+		}
+
+		soa, err := mdns.NewRR(fmt.Sprintf("%s 3600 IN SOA ns.%s hostmaster.%s 1 7200 3600 1209600 300", z.Origin, suffix, suffix))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SOA: %w", err)
+		}
+
+		z.Records = append([]mdns.RR{soa}, z.Records...)
+	}
+
+	return z, nil
+}
+
+// MustZone is like NewZone, but panics on error.
+func MustZone(origin string, records ...string) *Zone {
+
+	z, err := NewZone(origin, records...)
+	if err != nil {
+		panic(err)
+	}
+
+	return z
+}
+
+// SOA returns the SOA record of the zone apex.
+// Returns nil if not found.
+func (z *Zone) SOA() *mdns.SOA {
+
+	for i := range z.Records {
+		if v, ok := z.Records[i].(*mdns.SOA); ok && equal(v.Hdr.Name, z.Origin) {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// authority returns the SOA of the negative answers.
+func (z *Zone) authority() []mdns.RR {
+
+	if soa := z.SOA(); soa != nil {
+		return []mdns.RR{soa}
+	}
+
+	return nil
+}
+
+// negative returns the authority section of the negative answer for name.
+// The NSEC or NSEC3 records are added if dnssec is true.
+func (z *Zone) negative(name string, nodata bool, dnssec bool) []mdns.RR {
+
+	r := z.authority()
+
+	if dnssec {
+		r = append(r, z.denial(name, nodata)...)
+	}
+
+	return r
+}
+
+// Contains returns whether name is in the zone (at or below the apex).
+func (z *Zone) Contains(name string) bool {
+
+	return mdns.IsSubDomain(z.Origin, mdns.CanonicalName(name))
+}
+
+// lookup returns the records of name.
+func (z *Zone) lookup(name string) []mdns.RR {
+
+	var r []mdns.RR
+
+	for i := range z.Records {
+		if equal(z.Records[i].Header().Name, name) {
+			r = append(r, z.Records[i])
+		}
+	}
+
+	return r
+}
+
+// exists returns whether name exists in the zone, including empty non-terminals.
+func (z *Zone) exists(name string) bool {
+
+	for i := range z.Records {
+		if mdns.IsSubDomain(name, mdns.CanonicalName(z.Records[i].Header().Name)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cut returns the NS records of the closest delegation point of name below the apex.
+// Returns nil if name is not delegated.
+func (z *Zone) cut(name string) []mdns.RR {
+
+	labels := mdns.SplitDomainName(name)
+	apex := mdns.CountLabel(z.Origin)
+
+	// Search from the top
+	for i := len(labels) - apex - 1; i >= 0; i-- {
+
+		ns := filter(z.lookup(strings.Join(labels[i:], ".")+"."), mdns.TypeNS)
+		if len(ns) > 0 {
+			return ns
+		}
+	}
+
+	return nil
+}
+
+// glue returns the address records of the nameservers in ns that are in the zone.
+func (z *Zone) glue(ns []mdns.RR) []mdns.RR {
+
+	var r []mdns.RR
+
+	for i := range ns {
+
+		v, ok := ns[i].(*mdns.NS)
+		if !ok {
+			continue
+		}
+
+		for _, rr := range z.lookup(v.Ns) {
+			if t := rr.Header().Rrtype; t == mdns.TypeA || t == mdns.TypeAAAA {
+				r = append(r, rr)
+			}
+		}
+	}
+
+	return r
+}
+
+// wildcard returns the wildcard records that can be used to synthesize the answer for the nonexistent name.
+// Returns nil if there is no matching wildcard.
+func (z *Zone) wildcard(name string) []mdns.RR {
+
+	// Find the closest encloser
+	for _, i := range mdns.Split(name)[1:] {
+
+		encloser := name[i:]
+
+		if !z.Contains(encloser) {
+			return nil
+		}
+
+		if !z.exists(encloser) {
+			continue
+		}
+
+		return z.lookup("*." + encloser)
+	}
+
+	return nil
+}
+
+// denial returns the NSEC or NSEC3 records that prove the nonexistence of name (or of the type at name if nodata is true).
+// For NSEC3, the closest encloser proof is returned (RFC 5155 7.2.2).
+// Returns nil if the zone has no NSEC and NSEC3 records.
+func (z *Zone) denial(name string, nodata bool) []mdns.RR {
+
+	var (
+		nsec  []*mdns.NSEC
+		nsec3 []*mdns.NSEC3
+	)
+
+	for i := range z.Records {
+		switch v := z.Records[i].(type) {
+		case *mdns.NSEC:
+			nsec = append(nsec, v)
+		case *mdns.NSEC3:
+			nsec3 = append(nsec3, v)
+		}
+	}
+
+	var r []mdns.RR
+
+	add := func(rr mdns.RR) {
+
+		if rr == nil {
+			return
+		}
+
+		for i := range r {
+			if r[i] == rr {
+				return
+			}
+		}
+
+		r = append(r, rr)
+	}
+
+	switch {
+	case len(nsec) > 0:
+
+		matchOrCover := func(n string) mdns.RR {
+			for _, v := range nsec {
+				if equal(v.Hdr.Name, n) || covers(v, n) {
+					return v
+				}
+			}
+			return nil
+		}
+
+		add(matchOrCover(name))
+
+		if !nodata {
+			add(matchOrCover("*." + z.encloser(name)))
+		}
+
+	case len(nsec3) > 0:
+
+		match := func(n string) mdns.RR {
+			for _, v := range nsec3 {
+				if v.Match(n) {
+					return v
+				}
+			}
+			return nil
+		}
+
+		cover := func(n string) mdns.RR {
+			for _, v := range nsec3 {
+				if v.Cover(n) {
+					return v
+				}
+			}
+			return nil
+		}
+
+		if nodata {
+			add(match(name))
+			break
+		}
+
+		ce := z.encloser(name)
+		labels := mdns.SplitDomainName(name)
+
+		add(match(ce))
+		add(cover(strings.Join(labels[len(labels)-mdns.CountLabel(ce)-1:], ".") + "."))
+		add(cover("*." + ce))
+	}
+
+	return r
+}
+
+// encloser returns the closest existing encloser of the nonexistent name.
+func (z *Zone) encloser(name string) string {
+
+	for _, i := range mdns.Split(name)[1:] {
+		if z.exists(name[i:]) {
+			return name[i:]
+		}
+	}
+
+	return z.Origin
+}
+
+// covers returns whether name is between the owner and the next name of rr in the canonical order (RFC 4034 6.1).
+func covers(rr *mdns.NSEC, name string) bool {
+
+	if canonicalLess(rr.Hdr.Name, rr.NextDomain) {
+		return canonicalLess(rr.Hdr.Name, name) && canonicalLess(name, rr.NextDomain)
+	}
+
+	// The last NSEC of the chain
+	return canonicalLess(rr.Hdr.Name, name) || canonicalLess(name, rr.NextDomain)
+}
+
+// canonicalLess returns whether a is before b in the canonical order of RFC 4034 6.1.
+func canonicalLess(a, b string) bool {
+
+	la := mdns.SplitDomainName(strings.ToLower(a))
+	lb := mdns.SplitDomainName(strings.ToLower(b))
+
+	for i := 1; i <= len(la) && i <= len(lb); i++ {
+		if x, y := la[len(la)-i], lb[len(lb)-i]; x != y {
+			return x < y
+		}
+	}
+
+	return len(la) < len(lb)
+}
+
+// equal compares the domain names a and b case insensitively.
+func equal(a, b string) bool {
+
+	return strings.EqualFold(mdns.CanonicalName(a), mdns.CanonicalName(b))
+}
+
+// filter returns the records of type t from rr.
+func filter(rr []mdns.RR, t uint16) []mdns.RR {
+
+	var r []mdns.RR
+
+	for i := range rr {
+		if rr[i].Header().Rrtype == t {
+			r = append(r, rr[i])
+		}
+	}
+
+	return r
+}
+
+// signatures returns the RRSIG records from rr that cover type t.
+func signatures(rr []mdns.RR, t uint16) []mdns.RR {
+
+	var r []mdns.RR
+
+	for i := range rr {
+		if v, ok := rr[i].(*mdns.RRSIG); ok && v.TypeCovered == t {
+			r = append(r, v)
+		}
+	}
+
+	return r
+}
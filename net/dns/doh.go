@@ -0,0 +1,166 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/elmasy-com/elnet/validator"
+	mdns "github.com/miekg/dns"
+)
+
+// dohContentType is the media type of the DNS wire format messages.
+// See: https://www.rfc-editor.org/rfc/rfc8484.html#section-6
+const dohContentType = "application/dns-message"
+
+// dohMaxMsgSize is the maximum size of a DNS message accepted in a DoH response.
+const dohMaxMsgSize = 65535
+
+// NewServerHTTPS creates a new DNS-over-HTTPS (RFC 8484) Server.
+//
+// uri is the URI template of the resolver without variables (eg.: "https://dns.google/dns-query").
+// If the path is empty, defaults to "/dns-query". If the port is empty, defaults to "443".
+//
+// method must be "GET" or "POST". If method is empty, defaults to "POST".
+//
+// client is used to send the HTTP requests. If client is nil, a new client is created with DefaultQueryTimeoutSec timeout.
+func NewServerHTTPS(uri string, method string, client *http.Client) (Server, error) {
+
+	if client == nil {
+		client = &http.Client{Timeout: time.Duration(DefaultQueryTimeoutSec) * time.Second}
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return Server{}, err
+	}
+
+	return newServerHTTPS(u, method, client)
+}
+
+// newServerHTTPS creates a DoH Server from the parsed URL u.
+func newServerHTTPS(u *url.URL, method string, client *http.Client) (Server, error) {
+
+	if u.Scheme != "https" {
+		return Server{}, fmt.Errorf("invalid protocol: %s", u.Scheme)
+	}
+
+	switch method {
+	case "":
+		method = http.MethodPost
+	case http.MethodGet, http.MethodPost:
+		// fine
+	default:
+		return Server{}, fmt.Errorf("invalid method: %s", method)
+	}
+
+	srv := Server{Protocol: "https", IP: u.Hostname(), Port: u.Port(), method: method, httpClient: client, edns: new(ednsState)}
+
+	switch {
+	case srv.IP == "":
+		return Server{}, fmt.Errorf("host is empty")
+	case validator.IPv4(srv.IP):
+		srv.family = 4
+	case validator.IPv6(srv.IP):
+		srv.family = 6
+	case IsDomain(srv.IP):
+		// Hostname, the family is decided by the HTTP client
+	default:
+		return Server{}, fmt.Errorf("invalid host: %s", srv.IP)
+	}
+
+	if srv.Port == "" {
+		srv.Port = "443"
+	}
+
+	if !validator.Port(srv.Port) {
+		return Server{}, fmt.Errorf("invalid port: %s", srv.Port)
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/dns-query"
+	}
+
+	srv.url = "https://" + net.JoinHostPort(srv.IP, srv.Port) + path
+
+	return srv, nil
+}
+
+// exchangeHTTPS sends msg to the DoH server s and returns the response.
+func (s *Server) exchangeHTTPS(ctx context.Context, msg *mdns.Msg) (*mdns.Msg, error) {
+
+	// Use ID 0 to make the requests cache friendly.
+	// See: https://www.rfc-editor.org/rfc/rfc8484.html#section-4.1
+	id := msg.Id
+	msg.Id = 0
+	defer func() { msg.Id = id }()
+
+	buf, err := msg.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack message: %w", err)
+	}
+
+	var req *http.Request
+
+	switch s.method {
+	case http.MethodGet:
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, s.url+"?dns="+base64.RawURLEncoding.EncodeToString(buf), nil)
+	default:
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(buf))
+		if err == nil {
+			req.Header.Set("Content-Type", dohContentType)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", dohContentType)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid status: %s", resp.Status)
+	}
+
+	if mt, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err != nil || mt != dohContentType {
+		return nil, fmt.Errorf("invalid content type: %s", resp.Header.Get("Content-Type"))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dohMaxMsgSize))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	in := new(mdns.Msg)
+
+	if err = in.Unpack(body); err != nil {
+		return nil, fmt.Errorf("failed to unpack message: %w", err)
+	}
+
+	if in.Id != 0 {
+		return nil, mdns.ErrId
+	}
+
+	in.Id = id
+
+	return in, nil
+}
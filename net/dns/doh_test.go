@@ -0,0 +1,190 @@
+package dns
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mdns "github.com/miekg/dns"
+)
+
+// newDoHServer starts a local DoH stand-in.
+// Returns an A record 192.0.2.1 for "example.com." and NXDOMAIN for everything else.
+func newDoHServer(t *testing.T) *httptest.Server {
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var (
+			buf []byte
+			err error
+		)
+
+		switch r.Method {
+		case http.MethodGet:
+			buf, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		case http.MethodPost:
+			if r.Header.Get("Content-Type") != dohContentType {
+				http.Error(w, "invalid content type", http.StatusUnsupportedMediaType)
+				return
+			}
+			buf, err = io.ReadAll(r.Body)
+		default:
+			http.Error(w, "invalid method", http.StatusMethodNotAllowed)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req := new(mdns.Msg)
+		if err := req.Unpack(buf); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		resp := new(mdns.Msg)
+		resp.SetReply(req)
+
+		if req.Question[0].Name == "example.com." && req.Question[0].Qtype == mdns.TypeA {
+			resp.Answer = append(resp.Answer, &mdns.A{
+				Hdr: mdns.RR_Header{Name: "example.com.", Rrtype: mdns.TypeA, Class: mdns.ClassINET, Ttl: 60},
+				A:   net.ParseIP("192.0.2.1"),
+			})
+		} else if req.Question[0].Name != "example.com." {
+			resp.Rcode = mdns.RcodeNameError
+		}
+
+		out, err := resp.Pack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", dohContentType)
+		w.Write(out)
+	}))
+
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func TestNewServerHTTPS(t *testing.T) {
+
+	cases := []struct {
+		V      string
+		Method string
+		S      string
+		Err    string
+	}{
+		{V: "https://dns.google/dns-query", Method: "", S: "https://dns.google:443/dns-query", Err: ""},
+		{V: "https://dns.google", Method: "GET", S: "https://dns.google:443/dns-query", Err: ""},
+		{V: "https://1.1.1.1:8443/resolve", Method: "POST", S: "https://1.1.1.1:8443/resolve", Err: ""},
+		{V: "https://[::1]/dns-query", Method: "", S: "https://[::1]:443/dns-query", Err: ""},
+
+		{V: "http://dns.google/dns-query", Method: "", S: "", Err: "invalid protocol"},
+		{V: "https://dns.google/dns-query", Method: "PUT", S: "", Err: "invalid method"},
+		{V: "https://dns..google/dns-query", Method: "", S: "", Err: "invalid host"},
+		{V: "https://dns.google:-1/dns-query", Method: "", S: "", Err: "invalid port"},
+	}
+
+	for i := range cases {
+
+		s, err := NewServerHTTPS(cases[i].V, cases[i].Method, nil)
+
+		if cases[i].Err != "" {
+			if err == nil || !strings.Contains(err.Error(), cases[i].Err) {
+				t.Fatalf("FAIL: %s error wanted: %s, error got: %v\n", cases[i].V, cases[i].Err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("FAIL: %s: %s\n", cases[i].V, err)
+		}
+
+		if s.String() != cases[i].S {
+			t.Fatalf("FAIL: %s string wanted: %s, string got: %s\n", cases[i].V, cases[i].S, s.String())
+		}
+	}
+}
+
+func TestNewServerStrHTTPS(t *testing.T) {
+
+	s, err := NewServerStr("https://dns.google/dns-query", 2*time.Second)
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if s.Protocol != "https" || s.IP != "dns.google" || s.Port != "443" {
+		t.Fatalf("FAIL: Invalid server: %#v\n", s)
+	}
+
+	if s.method != http.MethodPost {
+		t.Fatalf("FAIL: method wanted: %s, method got: %s\n", http.MethodPost, s.method)
+	}
+}
+
+func TestServerQueryAHTTPS(t *testing.T) {
+
+	ts := newDoHServer(t)
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+
+		s, err := NewServerHTTPS(ts.URL+"/dns-query", method, ts.Client())
+		if err != nil {
+			t.Fatalf("FAIL: Failed to create server: %s\n", err)
+		}
+
+		r, err := s.QueryA("example.com")
+		if err != nil {
+			t.Fatalf("FAIL: %s: Failed to query example.com: %s\n", method, err)
+		}
+
+		if len(r) != 1 || !r[0].Equal(net.ParseIP("192.0.2.1")) {
+			t.Fatalf("FAIL: %s: Invalid answer: %v\n", method, r)
+		}
+
+		_, err = s.QueryA("invalid.example.com")
+		if !errors.Is(err, ErrName) {
+			t.Fatalf("FAIL: %s: error wanted: %s, error got: %v\n", method, ErrName, err)
+		}
+	}
+}
+
+func TestServersTryQueryHTTPS(t *testing.T) {
+
+	ts := newDoHServer(t)
+
+	s, err := NewServerHTTPS(ts.URL, "", ts.Client())
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create server: %s\n", err)
+	}
+
+	srvs := NewServersSlice(3, s)
+
+	set, err := srvs.IsSetA("example.com")
+	if err != nil {
+		t.Fatalf("FAIL: Failed to query example.com: %s\n", err)
+	}
+
+	if !set {
+		t.Fatalf("FAIL: A record for example.com is not set!\n")
+	}
+
+	rs, err := srvs.QueryAll("example.com")
+	if err != nil {
+		t.Fatalf("FAIL: Failed to query all for example.com: %s\n", err)
+	}
+
+	if len(rs) != 1 || rs[0].Type != TypeA || rs[0].Value != "192.0.2.1" {
+		t.Fatalf("FAIL: Invalid records: %v\n", rs)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"strings"
 
 	"github.com/elmasy-com/elnet/validator"
-	"golang.org/x/net/publicsuffix"
 )
 
 type Parts struct {
@@ -14,10 +13,19 @@ type Parts struct {
 }
 
 // IsDomain returns whether d is valid domain.
+// Internationalized domain names are accepted, the U-labels and A-labels are validated with ToASCII().
 //
 // This function returns false for "." (root domain).
 func IsDomain(d string) bool {
 
+	if IsIDN(d) {
+		a, err := ToASCII(d)
+		if err != nil {
+			return false
+		}
+		d = a
+	}
+
 	// Domain checks if a ByteSeq is a presentation-format domain name
 	// (currently restricted to hostname-compatible "preferred name" LDH labels and
 	// SRV-like "underscore labels".
@@ -97,7 +105,8 @@ func IsDomain(d string) bool {
 // IsDomainPart returns whether d is valid domain part (eg.: subdomain part or Second Level Domain).
 //
 // Domain d can be up to 63 character long, can include a-z, A-Z, 0-9 and "-".
-// The string must not starts and ends with a hyphen ("-") and two consecutive hyphen is not allowed.
+// The string must not starts and ends with a hyphen ("-") and two consecutive hyphen is not allowed,
+// except in the A-labels that are valid punycode (eg.: "xn--bcher-kva", see ToASCII()).
 func IsDomainPart(d string) bool {
 
 	// Source: https://www.saveonhosting.com/scripts/index.php?rp=/knowledgebase/52/What-are-the-valid-characters-for-a-domain-name-and-how-long-can-a-domain-name-be.html
@@ -124,6 +133,14 @@ func IsDomainPart(d string) bool {
 		return false
 	}
 
+	// The A-labels contain consecutive hyphens (eg.: "xn--bcher-kva"), validate the punycode instead
+	aLabel := isALabel(d)
+	if aLabel {
+		if _, err := ToASCII(d); err != nil {
+			return false
+		}
+	}
+
 	// Indicates, that the last character was a hyphen
 	lastHypen := false
 
@@ -139,7 +156,7 @@ func IsDomainPart(d string) bool {
 			continue
 		case c == '-':
 			// Two consecutive hyphen is not allowed (eg.: "a--a")
-			if lastHypen {
+			if lastHypen && !aLabel {
 				return false
 			}
 
@@ -160,8 +177,15 @@ func IsValidSLD(d string) bool {
 }
 
 // Clean removes the trailing dot and returns a lower cased version of d.
+// The U-labels are converted to A-labels (see ToASCII()), if the conversion fails, d is only lower cased.
 func Clean(d string) string {
 
+	if !isASCII(d) {
+		if a, err := ToASCII(d); err == nil {
+			d = a
+		}
+	}
+
 	// Remove the trailing dot.
 	if d[len(d)-1] == '.' {
 		d = d[:len(d)-1]
@@ -176,7 +200,9 @@ func Clean(d string) string {
 //
 // If d does not contains subdomain, than Sub will be empty (eg.: "example.com" -> &Result{Sub: "", Domain: "example", TLD: "com"}).
 //
-// Returns nil if d is empty, a dot (".") or starts with a dot (eg.: ".example.com").
+// The U-labels are converted to A-labels (see ToASCII()), the parts are returned in A-label form.
+//
+// Returns nil if d is empty, a dot ("."), starts with a dot (eg.: ".example.com") or an invalid internationalized domain name.
 //
 // NOTE: This function does not validate and Clean() the given domain d. It is recommended to use IsValid() and Clean() before this function.
 func GetParts(d string) *Parts {
@@ -185,6 +211,14 @@ func GetParts(d string) *Parts {
 		return nil
 	}
 
+	if !isASCII(d) {
+		a, err := ToASCII(d)
+		if err != nil {
+			return nil
+		}
+		d = a
+	}
+
 	if d[len(d)-1] == '.' {
 		d = d[:len(d)-1]
 	}
@@ -211,25 +245,38 @@ func GetParts(d string) *Parts {
 }
 
 // GetTLD returns the Top Level Domain of d (eg.: sub.exmaple.com -> com).
+// The public suffix is looked up in the list set by SetSuffixList(), or in the compiled-in table.
+// The private suffixes are skipped, unless enabled by SetPrivateSuffixes().
 //
-// Returns an empty string ("") if d is empty, a dot (".") or starts with a dot (eg.: ".example.com").
+// The U-labels are converted to A-labels (see ToASCII()), the TLD is returned in A-label form.
+//
+// Returns an empty string ("") if d is empty, a dot ("."), starts with a dot (eg.: ".example.com") or an invalid internationalized domain name.
 func GetTLD(d string) string {
 
 	if d == "" || d == "." || d[0] == '.' {
 		return ""
 	}
 
+	if !isASCII(d) {
+		a, err := ToASCII(d)
+		if err != nil {
+			return ""
+		}
+		d = a
+	}
+
 	if d[len(d)-1] == '.' {
 		d = d[:len(d)-1]
 	}
 
 	tld := d
 	icann := false
+	private := privateSuffixes.Load()
 
 	for {
-		tld, icann = publicsuffix.PublicSuffix(tld)
-		// Break if ICANN managed
-		if icann {
+		tld, icann = publicSuffix(tld)
+		// Break if ICANN managed or the private suffixes are boundaries
+		if icann || private {
 			break
 		}
 
@@ -248,10 +295,11 @@ func GetTLD(d string) string {
 }
 
 // GetTLDIndex returns the index of the Top Level Domain in d (eg.: sub.example.com -> 12).
+// The index is in d, also if d contains U-labels.
 //
 // Returns 0 if d is a TLD.
 //
-// Returns -1 if d is empty, a dot (".") or starts with a dot (eg.: ".example.com").
+// Returns -1 if d is empty, a dot ("."), starts with a dot (eg.: ".example.com") or an invalid internationalized domain name.
 func GetTLDIndex(d string) int {
 
 	if d == "" || d == "." || d[0] == '.' {
@@ -262,19 +310,52 @@ func GetTLDIndex(d string) int {
 		d = d[:len(d)-1]
 	}
 
-	//return strings.LastIndex(d, GetTLD(d))
-	return len(d) - len(GetTLD(d))
+	tld := GetTLD(d)
+	if tld == "" {
+		return -1
+	}
+
+	// The TLD is in A-label form, so count the labels
+	return labelsIndex(d, strings.Count(tld, ".")+1)
+}
+
+// labelsIndex returns the index of the last n labels in d (eg.: "sub.example.com", 2 -> 4).
+// Returns 0 if d has n or less labels.
+func labelsIndex(d string, n int) int {
+
+	i := len(d)
+
+	for ; n > 0; n-- {
+
+		i = strings.LastIndexByte(d[:i], '.')
+		if i == -1 {
+			return 0
+		}
+	}
+
+	return i + 1
 }
 
 // GetDomain returns the domain of d (eg.: sub.example.com -> example.com).
 //
-// Returns an empty string ("") if d is empty, a dot ("."), starts with a dot (eg.: ".example.com") or d is just a TLD.
+// The U-labels are converted to A-labels (see ToASCII()), the domain is returned in A-label form.
+//
+// Returns an empty string ("") if d is empty, a dot ("."), starts with a dot (eg.: ".example.com"), d is just a TLD
+// or an invalid internationalized domain name.
 func GetDomain(d string) string {
 
 	if d == "" || d == "." || d[0] == '.' {
 		return ""
 	}
 
+	if !isASCII(d) {
+		a, err := ToASCII(d)
+		if err != nil {
+			return ""
+		}
+		d = a
+	}
+
 	if d[len(d)-1] == '.' {
 		d = d[:len(d)-1]
 	}
@@ -309,13 +390,23 @@ func GetDomainIndex(d string) int {
 		return -1
 	}
 
-	return len(d) - len(dom)
+	// The domain is in A-label form, so count the labels
+	return labelsIndex(d, strings.Count(dom, ".")+1)
 }
 
 // GetSub returns the Subdomain of the given domain d (eg.: eg.: sub.example.com -> example.com).
+// The U-labels are converted to A-labels (see ToASCII()), the subdomain is returned in A-label form.
 // If d is invalid or cant get the subdomain returns an empty string ("").
 func GetSub(d string) string {
 
+	if d != "" && !isASCII(d) {
+		a, err := ToASCII(d)
+		if err != nil {
+			return ""
+		}
+		d = a
+	}
+
 	s := GetDomain(d)
 	if s == "" {
 		return ""
@@ -73,6 +73,9 @@ func TestIsDomainPart(t *testing.T) {
 		{Domain: "a a", Result: false},
 		{Domain: "a=a", Result: false},
 		{Domain: "a-a", Result: true},
+		{Domain: "xn--bcher-kva", Result: true},
+		{Domain: "xn--a", Result: false},
+		{Domain: "a--a", Result: false},
 		{Domain: "-aa", Result: false},
 		{Domain: "aa-", Result: false},
 		{Domain: "a--a", Result: false},
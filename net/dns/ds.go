@@ -0,0 +1,167 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+
+	mdns "github.com/miekg/dns"
+)
+
+var TypeDS uint16 = 43
+
+// See more: https://www.rfc-editor.org/rfc/rfc4034.html#section-5.1
+type DS struct {
+	KeyTag     uint16 // Key tag of the referred DNSKEY
+	Algorithm  uint8  // Algorithm of the referred DNSKEY
+	DigestType uint8  // 1 is SHA-1, 2 is SHA-256, 4 is SHA-384
+	Digest     string // Digest of the referred DNSKEY in hex
+}
+
+func (d DS) String() string {
+	return fmt.Sprintf("%d %d %d %s", d.KeyTag, d.Algorithm, d.DigestType, d.Digest)
+}
+
+// parseDS converts the answer rr to DS.
+func parseDS(rr []mdns.RR) ([]DS, error) {
+
+	r := make([]DS, 0, len(rr))
+
+	for i := range rr {
+
+		switch v := rr[i].(type) {
+		case *mdns.DS:
+			r = append(r, DS{KeyTag: v.KeyTag, Algorithm: v.Algorithm, DigestType: v.DigestType, Digest: v.Digest})
+		case *mdns.CNAME:
+			// Ignore CNAME
+			continue
+		case *mdns.DNAME:
+			// Ignore DNAME
+			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
+		default:
+			return nil, fmt.Errorf("unknown type: %T", v)
+		}
+	}
+
+	return r, nil
+}
+
+// QueryDS ask the server and returns a slice of DS.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QueryDS(name string) ([]DS, error) {
+
+	return s.QueryDSContext(context.Background(), name)
+}
+
+// QueryDSContext is like QueryDS, but the query is bound to ctx.
+func (s *Server) QueryDSContext(ctx context.Context, name string) ([]DS, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeDS)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDS(rr)
+}
+
+// QueryDS ask a random server from servers and returns a slice of DS.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Servers) QueryDS(name string) ([]DS, error) {
+
+	return s.QueryDSContext(context.Background(), name)
+}
+
+// QueryDSContext is like QueryDS, but the query is bound to ctx.
+func (s *Servers) QueryDSContext(ctx context.Context, name string) ([]DS, error) {
+
+	return s.Get(-1).QueryDSContext(ctx, name)
+}
+
+// QueryDS asks the DefaultResolver and returns a slice of DS.
+// Unlike Servers.QueryDS, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QueryDS is the same as TryQueryDS.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func QueryDS(name string) ([]DS, error) {
+
+	return QueryDSContext(context.Background(), name)
+}
+
+// QueryDSContext is like QueryDS, but the query is bound to ctx.
+func QueryDSContext(ctx context.Context, name string) ([]DS, error) {
+
+	return QueryDSWith(ctx, DefaultResolver, name)
+}
+
+// TryQueryDS asks the servers for type DS. If any error occurred, retries with next server (except if error is NXDOMAIN).
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func (s *Servers) TryQueryDS(name string) ([]DS, error) {
+
+	return s.TryQueryDSContext(context.Background(), name)
+}
+
+// TryQueryDSContext is like TryQueryDS, but the query is bound to ctx.
+func (s *Servers) TryQueryDSContext(ctx context.Context, name string) ([]DS, error) {
+
+	return QueryDSWith(ctx, s, name)
+}
+
+// TryQueryDS asks the DefaultResolver for type DS. If any error occurred, retries with next server (except if error is NXDOMAIN).
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func TryQueryDS(name string) ([]DS, error) {
+
+	return TryQueryDSContext(context.Background(), name)
+}
+
+// TryQueryDSContext is like TryQueryDS, but the query is bound to ctx.
+func TryQueryDSContext(ctx context.Context, name string) ([]DS, error) {
+
+	return QueryDSWith(ctx, DefaultResolver, name)
+}
+
+// QueryDSWith asks r for type DS and converts the answer like QueryDS.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QueryDSWith(ctx context.Context, r Resolver, name string) ([]DS, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeDS)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDS(rr)
+}
+
+// IsSetDS checks whether an DS type record set for name.
+// NXDOMAIN is not an error here, because it means "not found".
+func (s *Servers) IsSetDS(name string) (bool, error) {
+	return s.IsSetDSContext(context.Background(), name)
+}
+
+// IsSetDSContext is like IsSetDS, but the query is bound to ctx.
+func (s *Servers) IsSetDSContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeDS)
+}
+
+// IsSetDS checks whether an DS type record set for name using the DefaultResolver.
+// NXDOMAIN is not an error here, because it means "not found".
+func IsSetDS(name string) (bool, error) {
+	return IsSetDSContext(context.Background(), name)
+}
+
+// IsSetDSContext is like IsSetDS, but the query is bound to ctx.
+func IsSetDSContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeDS)
+}
@@ -0,0 +1,261 @@
+package dns
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	mdns "github.com/miekg/dns"
+)
+
+// DefaultEDNSUDPSize is the advertised UDP payload size if EDNSOptions.UDPSize is 0.
+// See more: https://www.dnsflagday.net/2020/
+const DefaultEDNSUDPSize uint16 = 1232
+
+// EDNSOptions configures the OPT record of the queries (RFC 6891).
+type EDNSOptions struct {
+	UDPSize      uint16     // Advertised UDP payload size, defaults to DefaultEDNSUDPSize
+	DNSSECOK     bool       // Set the DO bit
+	ClientSubnet *net.IPNet // Send EDNS Client Subnet (RFC 7871), nil means not sent
+	Cookie       bool       // Send DNS Cookie (RFC 7873), the server cookie is remembered per Server
+	NSID         bool       // Request the Name Server Identifier (RFC 5001)
+}
+
+// EDNS is the OPT record of a response.
+type EDNS struct {
+	UDPSize       uint16
+	Version       uint8
+	DNSSECOK      bool
+	ExtendedRcode int          // The full rcode (eg.: 23 for BADCOOKIE)
+	NSID          string       // Name Server Identifier, empty if not returned
+	ClientSubnet  *net.IPNet   // The subnet with the scope prefix length of the answer, nil if not returned
+	ClientCookie  string       // Hex encoded client cookie, empty if not returned
+	ServerCookie  string       // Hex encoded server cookie, empty if not returned
+	Options       []mdns.EDNS0 // Every option of the OPT record
+}
+
+// Response is the answer of a query with the returned EDNS options.
+type Response struct {
+	Answer []mdns.RR
+	EDNS   *EDNS // Nil if the response has no OPT record
+}
+
+// ednsState is the EDNS options and the DNS Cookies of a Server.
+// Server is copied by value (eg.: NewServer(), Servers.Append()), so the state is referenced by pointer and shared by the copies.
+type ednsState struct {
+	opts    atomic.Pointer[EDNSOptions]
+	cookies cookieJar
+}
+
+// cookieJar stores the DNS Cookies of a Server.
+type cookieJar struct {
+	m      sync.Mutex
+	client string
+	server string
+}
+
+// newClientCookie returns a random hex encoded 8 bytes long client cookie.
+func newClientCookie() string {
+
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// cookie returns the cookie to send.
+// If j is nil, a new client cookie is returned.
+func (j *cookieJar) cookie() string {
+
+	if j == nil {
+		return newClientCookie()
+	}
+
+	j.m.Lock()
+	defer j.m.Unlock()
+
+	if j.client == "" {
+		j.client = newClientCookie()
+	}
+
+	return j.client + j.server
+}
+
+// update stores the server cookie from e if the client cookie matches.
+func (j *cookieJar) update(e *EDNS) {
+
+	if j == nil || e == nil || e.ServerCookie == "" {
+		return
+	}
+
+	j.m.Lock()
+	defer j.m.Unlock()
+
+	if strings.EqualFold(e.ClientCookie, j.client) {
+		j.server = e.ServerCookie
+	}
+}
+
+// apply adds the OPT record configured by o to m.
+func (o *EDNSOptions) apply(m *mdns.Msg, jar *cookieJar) {
+
+	size := o.UDPSize
+	if size == 0 {
+		size = DefaultEDNSUDPSize
+	}
+
+	m.SetEdns0(size, o.DNSSECOK)
+	opt := m.IsEdns0()
+
+	if o.NSID {
+		opt.Option = append(opt.Option, &mdns.EDNS0_NSID{Code: mdns.EDNS0NSID})
+	}
+
+	if o.ClientSubnet != nil {
+
+		ones, _ := o.ClientSubnet.Mask.Size()
+
+		e := &mdns.EDNS0_SUBNET{Code: mdns.EDNS0SUBNET, SourceNetmask: uint8(ones)}
+
+		if ip := o.ClientSubnet.IP.To4(); ip != nil {
+			e.Family = 1
+			e.Address = ip.Mask(o.ClientSubnet.Mask)
+		} else {
+			e.Family = 2
+			e.Address = o.ClientSubnet.IP.Mask(o.ClientSubnet.Mask)
+		}
+
+		opt.Option = append(opt.Option, e)
+	}
+
+	if o.Cookie {
+		opt.Option = append(opt.Option, &mdns.EDNS0_COOKIE{Code: mdns.EDNS0COOKIE, Cookie: jar.cookie()})
+	}
+}
+
+// newEDNS parses the OPT record of m.
+// Returns nil if m has no OPT record.
+func newEDNS(m *mdns.Msg) *EDNS {
+
+	opt := m.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+
+	e := &EDNS{
+		UDPSize:       opt.UDPSize(),
+		Version:       opt.Version(),
+		DNSSECOK:      opt.Do(),
+		ExtendedRcode: m.Rcode,
+		Options:       opt.Option,
+	}
+
+	for _, o := range opt.Option {
+
+		switch v := o.(type) {
+		case *mdns.EDNS0_NSID:
+			if b, err := hex.DecodeString(v.Nsid); err == nil {
+				e.NSID = string(b)
+			} else {
+				e.NSID = v.Nsid
+			}
+		case *mdns.EDNS0_SUBNET:
+			bits := 32
+			if v.Family == 2 {
+				bits = 128
+			}
+			e.ClientSubnet = &net.IPNet{IP: v.Address, Mask: net.CIDRMask(int(v.SourceScope), bits)}
+		case *mdns.EDNS0_COOKIE:
+			if len(v.Cookie) >= 16 {
+				e.ClientCookie = v.Cookie[:16]
+				e.ServerCookie = v.Cookie[16:]
+			}
+		}
+	}
+
+	return e
+}
+
+// newResponse creates a Response from m.
+// Returns nil if m is nil.
+func newResponse(m *mdns.Msg) *Response {
+
+	if m == nil {
+		return nil
+	}
+
+	return &Response{Answer: m.Answer, EDNS: newEDNS(m)}
+}
+
+// SetEDNS sets the EDNS options of the queries sent to s.
+// If o is nil, the queries are sent without OPT record (default).
+// It is safe to call SetEDNS concurrently with the queries.
+func (s *Server) SetEDNS(o *EDNSOptions) {
+
+	if s.edns == nil {
+		// Server is not created with a constructor
+		s.edns = new(ednsState)
+	}
+
+	s.edns.opts.Store(o)
+}
+
+// ednsOptions returns the EDNS options and the cookie jar of s.
+func (s *Server) ednsOptions() (*EDNSOptions, *cookieJar) {
+
+	if s.edns == nil {
+		return nil, nil
+	}
+
+	return s.edns.opts.Load(), &s.edns.cookies
+}
+
+// QueryEDNS asks the server for type t with the EDNS options o and returns the answer with the returned EDNS options.
+// If o is nil, the options of the Server are used (see SetEDNS()).
+//
+// If the server responded with a non-zero rcode, returns the Response along with the rcode error.
+func (s *Server) QueryEDNS(name string, t uint16, o *EDNSOptions) (*Response, error) {
+
+	return s.QueryEDNSContext(context.Background(), name, t, o)
+}
+
+// QueryEDNSContext is like QueryEDNS, but the query is bound to ctx.
+func (s *Server) QueryEDNSContext(ctx context.Context, name string, t uint16, o *EDNSOptions) (*Response, error) {
+
+	in, err := s.queryMsgEDNSContext(ctx, name, t, o)
+
+	return newResponse(in), err
+}
+
+// SetEDNS sets the EDNS options of every server in s (see Server.SetEDNS()).
+func (s *Servers) SetEDNS(o *EDNSOptions) {
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for i := range s.srvs {
+		s.srvs[i].SetEDNS(o)
+	}
+}
+
+// TryQueryEDNS is like TryQuery, but the queries are sent with the EDNS options o and returns the returned EDNS options too.
+// If o is nil, the options of the servers are used (see SetEDNS()).
+// The cache is not used.
+//
+// If the last server responded with a non-zero rcode, returns the Response along with the rcode error.
+func (s *Servers) TryQueryEDNS(name string, t uint16, o *EDNSOptions) (*Response, error) {
+
+	return s.TryQueryEDNSContext(context.Background(), name, t, o)
+}
+
+// TryQueryEDNSContext is like TryQueryEDNS, but the queries are bound to ctx.
+func (s *Servers) TryQueryEDNSContext(ctx context.Context, name string, t uint16, o *EDNSOptions) (*Response, error) {
+
+	in, err := s.tryQueryMsgContext(ctx, name, t, o)
+
+	return newResponse(in), err
+}
@@ -0,0 +1,153 @@
+package dns
+
+import (
+	"encoding/hex"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
+	mdns "github.com/miekg/dns"
+)
+
+func TestServersEDNS(t *testing.T) {
+
+	var (
+		m       sync.Mutex
+		cookies []string
+	)
+
+	addr := localDNS(t, func(w mdns.ResponseWriter, r *mdns.Msg) {
+
+		resp := new(mdns.Msg)
+		resp.SetReply(r)
+		resp.Answer = append(resp.Answer, &mdns.A{Hdr: mdns.RR_Header{Name: r.Question[0].Name, Rrtype: mdns.TypeA, Class: mdns.ClassINET, Ttl: 60}, A: net.IPv4(192, 0, 2, 1)})
+
+		opt := r.IsEdns0()
+		if opt == nil {
+			w.WriteMsg(resp)
+			return
+		}
+
+		resp.SetEdns0(4096, opt.Do())
+		ropt := resp.IsEdns0()
+
+		for _, o := range opt.Option {
+			switch v := o.(type) {
+			case *mdns.EDNS0_NSID:
+				ropt.Option = append(ropt.Option, &mdns.EDNS0_NSID{Code: mdns.EDNS0NSID, Nsid: hex.EncodeToString([]byte("anycast-1"))})
+			case *mdns.EDNS0_SUBNET:
+				v.SourceScope = 16
+				ropt.Option = append(ropt.Option, v)
+			case *mdns.EDNS0_COOKIE:
+				m.Lock()
+				cookies = append(cookies, v.Cookie)
+				m.Unlock()
+				ropt.Option = append(ropt.Option, &mdns.EDNS0_COOKIE{Code: mdns.EDNS0COOKIE, Cookie: v.Cookie[:16] + "0102030405060708"})
+			}
+		}
+
+		w.WriteMsg(resp)
+	})
+
+	srvs, err := NewServersStr(1, time.Second, "udp://"+addr)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create servers: %s\n", err)
+	}
+
+	// No OPT by default
+	r, err := srvs.TryQueryEDNS("example.com", TypeA, nil)
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if r.EDNS != nil || len(r.Answer) != 1 {
+		t.Fatalf("FAIL: unexpected response: %#v\n", r)
+	}
+
+	_, subnet, _ := net.ParseCIDR("198.51.100.0/24")
+
+	r, err = srvs.TryQueryEDNS("example.com", TypeA, &EDNSOptions{NSID: true, ClientSubnet: subnet, DNSSECOK: true})
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if r.EDNS == nil || r.EDNS.NSID != "anycast-1" || !r.EDNS.DNSSECOK || r.EDNS.UDPSize != 4096 {
+		t.Fatalf("FAIL: unexpected EDNS: %#v\n", r.EDNS)
+	}
+
+	if r.EDNS.ClientSubnet == nil || r.EDNS.ClientSubnet.String() != "198.51.100.0/16" {
+		t.Fatalf("FAIL: unexpected client subnet: %v\n", r.EDNS.ClientSubnet)
+	}
+
+	srvs.SetEDNS(&EDNSOptions{Cookie: true})
+
+	for i := 0; i < 2; i++ {
+		if _, err := srvs.TryQuery("example.com", TypeA); err != nil {
+			t.Fatalf("FAIL: %s\n", err)
+		}
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if len(cookies) != 2 || len(cookies[0]) != 16 || cookies[1] != cookies[0]+"0102030405060708" {
+		t.Fatalf("FAIL: the server cookie is not remembered: %v\n", cookies)
+	}
+}
+
+func TestServersEDNSConcurrentSet(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, dnstest.MustZone("example.com", "www 300 IN A 192.0.2.1"))
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			srvs.SetEDNS(&EDNSOptions{Cookie: true, NSID: true})
+			srvs.Get(0).SetEDNS(nil)
+		}()
+
+		go func() {
+			defer wg.Done()
+			if _, err := srvs.TryQueryA("www.example.com"); err != nil {
+				t.Errorf("FAIL: %s\n", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestServersEDNSDNSSECOK(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, dnstest.MustZone("example.com",
+		"@ 300 IN A 192.0.2.1",
+		"@ 300 IN RRSIG A 8 2 300 20300101000000 20200101000000 12345 example.com. AAAA",
+		"@ 300 IN TXT \"v=spf1 -all\"",
+		"@ 300 IN RRSIG TXT 8 2 300 20300101000000 20200101000000 12345 example.com. AAAA",
+	))
+
+	srvs.SetEDNS(&EDNSOptions{DNSSECOK: true})
+
+	ips, err := srvs.TryQueryA("example.com")
+	if err != nil || len(ips) != 1 || !ips[0].Equal(net.ParseIP("192.0.2.1")) {
+		t.Fatalf("FAIL: Invalid A: %v, %v\n", ips, err)
+	}
+
+	txt, err := srvs.TryQueryTXT("example.com")
+	if err != nil || len(txt) != 1 || txt[0] != "v=spf1 -all" {
+		t.Fatalf("FAIL: Invalid TXT: %v, %v\n", txt, err)
+	}
+
+	// A, SOA and TXT
+	rs, err := srvs.QueryAll("example.com")
+	if err != nil || len(rs) != 3 {
+		t.Fatalf("FAIL: Invalid records: %v, %v\n", rs, err)
+	}
+}
@@ -0,0 +1,277 @@
+package dns
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/elmasy-com/slices"
+)
+
+// EnumConfig configures the subdomain enumeration.
+type EnumConfig struct {
+	Words         []string   // Labels to try under every parent (eg.: "www", "mail")
+	NumericSuffix int        // If greater than 0, tries every word with the numeric suffixes 1-NumericSuffix (eg.: "dev1", "dev-1")
+	DashJoin      bool       // Tries every pair of words joined with a dash (eg.: "dev-api")
+	Types         []uint16   // Types to query, defaults to TypeA, TypeAAAA and TypeCNAME
+	Depth         int        // Maximum number of levels under the base domain, the found subdomains are enumerated recursively. If 0, defaults to 1.
+	Bulk          BulkConfig // Concurrency and rate limits of the queries (see Bulk())
+}
+
+// EnumResult is a found subdomain.
+type EnumResult struct {
+	Name    string   // The found subdomain
+	Depth   int      // Level under the base domain (1 is the direct subdomains)
+	Records []Record // Unique records of the found subdomain, excluding the wildcard types
+	Err     error    // Set if the existence of the subdomain cannot be determined (the query failed after every retry)
+}
+
+// ReadWordlist reads a wordlist from r.
+// Every line is a word, the empty lines and the lines starting with "#" are skipped.
+// The words are lower cased, the duplicates are removed.
+func ReadWordlist(r io.Reader) ([]string, error) {
+
+	var words []string
+
+	s := bufio.NewScanner(r)
+
+	for s.Scan() {
+
+		w := strings.ToLower(strings.TrimSpace(s.Text()))
+
+		if w == "" || w[0] == '#' {
+			continue
+		}
+
+		words = slices.AppendUnique(words, w)
+	}
+
+	return words, s.Err()
+}
+
+// candidates returns the labels generated from the words with the mutation rules.
+// Invalid labels are dropped.
+func (c *EnumConfig) candidates() []string {
+
+	labels := make([]string, 0, len(c.Words))
+	seen := make(map[string]bool, len(c.Words))
+
+	add := func(l string) {
+		l = strings.ToLower(l)
+		if !seen[l] && IsDomainPart(l) {
+			seen[l] = true
+			labels = append(labels, l)
+		}
+	}
+
+	for _, w := range c.Words {
+
+		add(w)
+
+		for i := 1; i <= c.NumericSuffix; i++ {
+			n := strconv.Itoa(i)
+			add(w + n)
+			add(w + "-" + n)
+		}
+	}
+
+	if c.DashJoin {
+		for _, a := range c.Words {
+			for _, b := range c.Words {
+				if a != b {
+					add(a + "-" + b)
+				}
+			}
+		}
+	}
+
+	return labels
+}
+
+// enumName is the state of a candidate name in the current level.
+type enumName struct {
+	pending int
+	records []Record
+	err     error
+}
+
+// Enumerate brute-forces the subdomains of domain with the words in conf and streams the found subdomains through the returned channel.
+//
+// For every level, the parents are probed for wildcard (see ProbeWildcard()) with every type, the answers that match the wildcard fingerprint are ignored.
+// The found subdomains are enumerated recursively up to conf.Depth levels.
+//
+// The returned channel is closed when the enumeration is done, a wildcard check fails or ctx is done.
+//
+// NOTE: Use IsValid() and Clean() before this function!
+func (s *Servers) Enumerate(ctx context.Context, domain string, conf EnumConfig) <-chan EnumResult {
+
+	results := make(chan EnumResult)
+
+	go func() {
+		s.EnumerateFunc(ctx, domain, conf, func(r EnumResult) {
+			select {
+			case results <- r:
+			case <-ctx.Done():
+			}
+		})
+		close(results)
+	}()
+
+	return results
+}
+
+// EnumerateFunc is like Enumerate, but calls fn with every result instead of streaming them through a channel.
+// fn is not called concurrently.
+//
+// Returns nil when the enumeration is done, an error if a wildcard check fails or ctx.Err() if ctx is done.
+func (s *Servers) EnumerateFunc(ctx context.Context, domain string, conf EnumConfig, fn func(EnumResult)) error {
+
+	if len(conf.Types) == 0 {
+		conf.Types = []uint16{TypeA, TypeAAAA, TypeCNAME}
+	}
+
+	if conf.Depth <= 0 {
+		conf.Depth = 1
+	}
+
+	labels := conf.candidates()
+	parents := []string{Clean(domain)}
+
+	for depth := 1; depth <= conf.Depth && len(parents) > 0; depth++ {
+
+		found, err := s.enumLevel(ctx, parents, labels, depth, conf, fn)
+		if err != nil {
+			return err
+		}
+
+		parents = found
+	}
+
+	return ctx.Err()
+}
+
+// enumLevel tries every label under every parent and returns the found subdomains.
+func (s *Servers) enumLevel(ctx context.Context, parents []string, labels []string, depth int, conf EnumConfig, fn func(EnumResult)) ([]string, error) {
+
+	// Wildcard fingerprints of the parents
+	wildcards := make(map[string]map[uint16]*Wildcard, len(parents))
+
+	for _, p := range parents {
+
+		wildcards[p] = make(map[uint16]*Wildcard, len(conf.Types))
+
+		for _, t := range conf.Types {
+
+			wc, err := s.ProbeWildcardContext(ctx, "enum."+p, t)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check wildcard for %s: %w", p, err)
+			}
+
+			wildcards[p][t] = wc
+		}
+	}
+
+	jobs := make(chan BulkJob)
+
+	go func() {
+
+		defer close(jobs)
+
+		for _, p := range parents {
+			for _, l := range labels {
+
+				name := l + "." + p
+				if len(name) > 253 {
+					continue
+				}
+
+				for _, t := range conf.Types {
+					select {
+					case jobs <- BulkJob{Name: name, Type: t}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	var (
+		m     sync.Mutex
+		names = make(map[string]*enumName)
+		found []string
+		out   sync.Mutex // Serializes the calls of fn
+	)
+
+	err := s.BulkFunc(ctx, jobs, conf.Bulk, func(r BulkResult) {
+
+		m.Lock()
+
+		st, ok := names[r.Job.Name]
+		if !ok {
+			st = &enumName{pending: len(conf.Types)}
+			names[r.Job.Name] = st
+		}
+
+		st.pending--
+
+		parent := r.Job.Name[strings.IndexByte(r.Job.Name, '.')+1:]
+
+		switch {
+		case r.Err != nil && !errors.Is(r.Err, ErrName):
+			st.err = r.Err
+		case wildcards[parent][r.Job.Type].Matches(r.Records):
+			// Ignore the answer synthesized from the wildcard
+		default:
+			for i := range r.Records {
+				st.records = slices.AppendUnique(st.records, r.Records[i])
+			}
+		}
+
+		if st.pending > 0 {
+			m.Unlock()
+			return
+		}
+
+		delete(names, r.Job.Name)
+
+		res := EnumResult{Name: r.Job.Name, Depth: depth, Records: st.records}
+
+		switch {
+		case len(st.records) > 0:
+			found = append(found, r.Job.Name)
+		case st.err != nil:
+			res.Err = st.err
+		default:
+			m.Unlock()
+			return
+		}
+
+		m.Unlock()
+
+		out.Lock()
+		fn(res)
+		out.Unlock()
+	})
+
+	return found, err
+}
+
+// Enumerate brute-forces the subdomains of domain using the DefaultServers.
+// See Servers.Enumerate().
+func Enumerate(ctx context.Context, domain string, conf EnumConfig) <-chan EnumResult {
+
+	return DefaultServers.Enumerate(ctx, domain, conf)
+}
+
+// EnumerateFunc is like Enumerate, but calls fn with every result instead of streaming them through a channel.
+// See Servers.EnumerateFunc().
+func EnumerateFunc(ctx context.Context, domain string, conf EnumConfig, fn func(EnumResult)) error {
+
+	return DefaultServers.EnumerateFunc(ctx, domain, conf, fn)
+}
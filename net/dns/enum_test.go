@@ -0,0 +1,106 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
+	mdns "github.com/miekg/dns"
+)
+
+func TestReadWordlist(t *testing.T) {
+
+	w, err := ReadWordlist(strings.NewReader("www\n# comment\n\n  Mail \nwww\napi"))
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if strings.Join(w, ",") != "www,mail,api" {
+		t.Fatalf("FAIL: Invalid words: %v\n", w)
+	}
+}
+
+func TestEnumConfigCandidates(t *testing.T) {
+
+	c := EnumConfig{Words: []string{"dev", "API", "-invalid"}, NumericSuffix: 2, DashJoin: true}
+
+	want := "dev,dev1,dev-1,dev2,dev-2,api,api1,api-1,api2,api-2,dev-api,api-dev"
+
+	if got := strings.Join(c.candidates(), ","); got != want {
+		t.Fatalf("FAIL: candidates wanted: %s, got: %s\n", want, got)
+	}
+}
+
+// enumTestZone returns the "example.com." zone of the enumeration tests:
+// www, dev1, dev-api and api.www have A records and wild has a wildcard A record below it.
+func enumTestZone() *dnstest.Zone {
+
+	return dnstest.MustZone("example.com",
+		"www 60 IN A 192.0.2.1",
+		"dev1 60 IN A 192.0.2.2",
+		"dev-api 60 IN A 192.0.2.3",
+		"api.www 60 IN A 192.0.2.4",
+		"wild 60 IN A 192.0.2.5",
+		"*.wild 60 IN A 192.0.2.99",
+	)
+}
+
+func TestServersEnumerate(t *testing.T) {
+
+	srvs, ts := newZoneTestServers(t, enumTestZone())
+	ts.SetRule("broken.example.com", 0, dnstest.Rule{Rcode: mdns.RcodeServerFailure})
+
+	conf := EnumConfig{
+		Words:         []string{"www", "dev", "api", "wild", "broken", "mail"},
+		NumericSuffix: 1,
+		DashJoin:      true,
+		Depth:         2,
+	}
+
+	var (
+		found  []string
+		failed []string
+	)
+
+	for r := range srvs.Enumerate(context.Background(), "Example.com.", conf) {
+
+		if r.Err != nil {
+			failed = append(failed, r.Name)
+			continue
+		}
+
+		found = append(found, r.Name)
+
+		if r.Name == "api.www.example.com" && (r.Depth != 2 || len(r.Records) != 1 || r.Records[0].Value != "192.0.2.4") {
+			t.Fatalf("FAIL: Invalid result: %+v\n", r)
+		}
+	}
+
+	sort.Strings(found)
+
+	want := "api.www.example.com,dev-api.example.com,dev1.example.com,wild.example.com,www.example.com"
+
+	if got := strings.Join(found, ","); got != want {
+		t.Fatalf("FAIL: found wanted: %s, got: %s\n", want, got)
+	}
+
+	if len(failed) != 1 || failed[0] != "broken.example.com" {
+		t.Fatalf("FAIL: Invalid failed names: %v\n", failed)
+	}
+}
+
+func TestServersEnumerateCanceled(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, enumTestZone())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := srvs.EnumerateFunc(ctx, "example.com", EnumConfig{Words: []string{"www"}}, func(EnumResult) {})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", context.Canceled, err)
+	}
+}
@@ -3,6 +3,9 @@ package dns
 import (
 	"errors"
 	"fmt"
+	"strings"
+
+	mdns "github.com/miekg/dns"
 )
 
 var (
@@ -40,3 +43,120 @@ func RcodeToError(rcode int) error {
 		return fmt.Errorf("%d", rcode)
 	}
 }
+
+// ExtendedError is an Extended DNS Error (RFC 8914) returned in the OPT record of a response.
+type ExtendedError struct {
+	Code uint16 // Info-Code (eg.: 15 for "Blocked")
+	Text string // Extra-Text, can be empty
+}
+
+func (e ExtendedError) String() string {
+
+	s := fmt.Sprintf("EDE %d", e.Code)
+
+	if name, ok := mdns.ExtendedErrorCodeToString[e.Code]; ok {
+		s += " (" + name + ")"
+	}
+
+	if e.Text != "" {
+		s += ": " + e.Text
+	}
+
+	return s
+}
+
+// extendedErrors returns the Extended DNS Errors of m.
+func extendedErrors(m *mdns.Msg) []ExtendedError {
+
+	opt := m.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+
+	var r []ExtendedError
+
+	for _, o := range opt.Option {
+		if v, ok := o.(*mdns.EDNS0_EDE); ok {
+			r = append(r, ExtendedError{Code: v.InfoCode, Text: v.ExtraText})
+		}
+	}
+
+	return r
+}
+
+// QueryError is the error of a query sent to a Server.
+// The Err is the sentinel error of the rcode (eg.: ErrName), or the error of the exchange if no response received,
+// so errors.Is() works with the sentinel errors.
+type QueryError struct {
+	Server   string          // The server (see Server.String())
+	Name     string          // Question name
+	Type     uint16          // Question type
+	Rcode    int             // -1 if no response received
+	Extended []ExtendedError // Extended DNS Errors of the response
+	Attempt  int             // The number of the attempt in TryQuery (starts with 1)
+	Err      error
+}
+
+// newQueryError creates a QueryError for the query of name with type t sent to s.
+// If in is nil, the query failed with err, otherwise the error is created from the rcode of in.
+func newQueryError(s *Server, name string, t uint16, in *mdns.Msg, err error) *QueryError {
+
+	e := &QueryError{Server: s.String(), Name: mdns.Fqdn(name), Type: t, Rcode: -1, Attempt: 1, Err: err}
+
+	if in != nil {
+		e.Rcode = in.Rcode
+		e.Extended = extendedErrors(in)
+		e.Err = RcodeToError(in.Rcode)
+	}
+
+	return e
+}
+
+func (e *QueryError) Error() string {
+
+	s := fmt.Sprintf("%s %s @%s: %s", e.Name, TypeToString(e.Type), e.Server, e.Err)
+
+	for i := range e.Extended {
+		s += ", " + e.Extended[i].String()
+	}
+
+	return s
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// QueryErrors is the aggregated error of TryQuery, contains the error of every attempt in order.
+// errors.Is() and errors.As() match the error of any attempt.
+type QueryErrors []error
+
+func (e QueryErrors) Error() string {
+
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	s := make([]string, 0, len(e))
+
+	for i := range e {
+		s = append(s, fmt.Sprintf("attempt %d: %s", i+1, e[i]))
+	}
+
+	return fmt.Sprintf("%d attempts failed: %s", len(e), strings.Join(s, "; "))
+}
+
+func (e QueryErrors) Unwrap() []error {
+	return e
+}
+
+// Last returns the error of the last attempt.
+// Returns nil if e is empty.
+func (e QueryErrors) Last() error {
+
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e[len(e)-1]
+}
@@ -0,0 +1,72 @@
+package dns
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	mdns "github.com/miekg/dns"
+)
+
+func TestServersQueryError(t *testing.T) {
+
+	addr := localDNS(t, func(w mdns.ResponseWriter, r *mdns.Msg) {
+
+		m := new(mdns.Msg)
+		m.SetReply(r)
+
+		if strings.HasPrefix(r.Question[0].Name, "nx.") {
+			m.Rcode = mdns.RcodeNameError
+		} else {
+			m.Rcode = mdns.RcodeServerFailure
+			m.SetEdns0(1232, false)
+			opt := m.IsEdns0()
+			opt.Option = append(opt.Option, &mdns.EDNS0_EDE{InfoCode: mdns.ExtendedErrorCodeDNSKEYMissing, ExtraText: "no key"})
+		}
+
+		w.WriteMsg(m)
+	})
+
+	srvs, err := NewServersStr(2, time.Second, "udp://"+addr, "udp://"+addr)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create servers: %s\n", err)
+	}
+
+	_, err = srvs.TryQuery("example.com", TypeA)
+	if !errors.Is(err, ErrServerFailure) {
+		t.Fatalf("FAIL: ErrServerFailure wanted, got %v\n", err)
+	}
+
+	var errs QueryErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("FAIL: QueryErrors with 2 attempts wanted, got %#v\n", err)
+	}
+
+	var qe *QueryError
+	if !errors.As(errs.Last(), &qe) {
+		t.Fatalf("FAIL: QueryError wanted, got %#v\n", errs.Last())
+	}
+
+	if qe.Attempt != 2 || qe.Rcode != mdns.RcodeServerFailure || qe.Server != "udp://"+addr || qe.Name != "example.com." || qe.Type != TypeA {
+		t.Fatalf("FAIL: unexpected QueryError: %#v\n", qe)
+	}
+
+	if len(qe.Extended) != 1 || qe.Extended[0].Code != mdns.ExtendedErrorCodeDNSKEYMissing || qe.Extended[0].Text != "no key" {
+		t.Fatalf("FAIL: unexpected extended errors: %v\n", qe.Extended)
+	}
+
+	if !strings.Contains(err.Error(), "attempt 2") || !strings.Contains(err.Error(), "DNSKEY Missing") {
+		t.Fatalf("FAIL: unexpected message: %s\n", err)
+	}
+
+	// NXDOMAIN is not retried
+	_, err = srvs.TryQuery("nx.example.com", TypeA)
+	if !errors.Is(err, ErrName) || errors.Is(err, ErrServerFailure) {
+		t.Fatalf("FAIL: ErrName wanted, got %v\n", err)
+	}
+
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("FAIL: one attempt wanted, got %#v\n", err)
+	}
+}
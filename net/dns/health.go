@@ -0,0 +1,415 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Strategy is the server selection strategy of Servers.
+type Strategy int
+
+const (
+	StrategyRandom     Strategy = iota // Uniformly random server (default)
+	StrategyRoundRobin                 // Servers in order
+	StrategyFastest                    // Server with the lowest average latency
+	StrategyWeighted                   // Random server, weighted by the success rate and the inverse of the average latency
+)
+
+var (
+	// DefaultQuarantineThreshold is the default number of consecutive failures after a server is quarantined.
+	DefaultQuarantineThreshold = 3
+
+	// DefaultQuarantineTime is the default duration of the first quarantine.
+	// The duration is doubled after every failed probe, up to MaxQuarantineTime.
+	DefaultQuarantineTime = 30 * time.Second
+
+	// MaxQuarantineTime is the maximum duration of a quarantine.
+	MaxQuarantineTime = 10 * time.Minute
+)
+
+// latencyAlpha is the smoothing factor of the latency EWMA.
+const latencyAlpha = 0.3
+
+// ServerHealth is the health statistics of a server.
+type ServerHealth struct {
+	Server              string        // Server.String()
+	Latency             time.Duration // Exponentially weighted moving average of the successful query latencies
+	Queries             uint64        // Number of queries
+	Failures            uint64        // Number of failed queries (including REFUSED and SERVFAIL)
+	Refused             uint64        // Number of REFUSED responses
+	ServFail            uint64        // Number of SERVFAIL responses
+	ConsecutiveFailures int           // Number of failures since the last success
+	Quarantined         bool          // The server is skipped until QuarantinedUntil
+	QuarantinedUntil    time.Time     // End of the quarantine, after that the next query is a probe
+}
+
+// RefusedRate returns the rate of REFUSED responses.
+func (h ServerHealth) RefusedRate() float64 {
+
+	if h.Queries == 0 {
+		return 0
+	}
+
+	return float64(h.Refused) / float64(h.Queries)
+}
+
+// ServFailRate returns the rate of SERVFAIL responses.
+func (h ServerHealth) ServFailRate() float64 {
+
+	if h.Queries == 0 {
+		return 0
+	}
+
+	return float64(h.ServFail) / float64(h.Queries)
+}
+
+type serverState struct {
+	ServerHealth
+	quarantine time.Duration // Duration of the current/last quarantine
+	probing    bool          // A probe query is in progress
+}
+
+// health tracks the health of the servers and selects the server to use.
+type health struct {
+	m         sync.Mutex
+	states    []serverState
+	strategy  Strategy
+	threshold int
+	duration  time.Duration
+	next      int // Round-robin index
+	now       func() time.Time
+}
+
+func newHealth() *health {
+
+	return &health{threshold: DefaultQuarantineThreshold, duration: DefaultQuarantineTime, now: time.Now}
+}
+
+// grow extends the states to n servers. The caller must hold the lock.
+func (h *health) grow(n int) {
+
+	for len(h.states) < n {
+		h.states = append(h.states, serverState{})
+	}
+}
+
+// available returns whether the server i can be used. The caller must hold the lock.
+// A server with an expired quarantine is available only for a single probe query,
+// because only the probes record the result that ends the quarantine.
+func (h *health) available(i int, now time.Time, probe bool) bool {
+
+	st := &h.states[i]
+
+	if !st.Quarantined {
+		return true
+	}
+
+	return probe && !st.probing && !now.Before(st.QuarantinedUntil)
+}
+
+// choose returns the index of the server to use from n servers.
+// If index is in range and the server is not quarantined, returns index, else select one with the strategy.
+// The servers in tried (eg.: the previous attempts of a query) are selected only if every other available server is tried.
+// If every server is quarantined, returns the one with the earliest quarantine end.
+//
+// If probe is true, the caller records the result of the query (see record()), so a server with an expired quarantine can be selected and marked as probing.
+func (h *health) choose(index int, n int, probe bool, tried ...int) int {
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.grow(n)
+
+	now := h.now()
+
+	if index >= 0 && index < n && h.available(index, now, probe) {
+		return h.use(index)
+	}
+
+	skip := make([]bool, n)
+	for _, i := range tried {
+		if i >= 0 && i < n {
+			skip[i] = true
+		}
+	}
+
+	avail := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		if h.available(i, now, probe) && !skip[i] {
+			avail = append(avail, i)
+		}
+	}
+
+	// Every available server is tried, start again
+	if len(avail) == 0 && len(tried) > 0 {
+
+		for i := range skip {
+			skip[i] = false
+		}
+
+		for i := 0; i < n; i++ {
+			if h.available(i, now, probe) {
+				avail = append(avail, i)
+			}
+		}
+	}
+
+	if len(avail) == 0 {
+
+		earliest := 0
+		for i := 1; i < n; i++ {
+			if h.states[i].QuarantinedUntil.Before(h.states[earliest].QuarantinedUntil) {
+				earliest = i
+			}
+		}
+
+		return earliest
+	}
+
+	switch h.strategy {
+	case StrategyRoundRobin:
+
+		for i := 0; i < n; i++ {
+			idx := (h.next + i) % n
+			if h.available(idx, now, probe) && !skip[idx] {
+				h.next = idx + 1
+				return h.use(idx)
+			}
+		}
+
+	case StrategyFastest:
+
+		best := avail[0]
+		for _, i := range avail[1:] {
+			// Unmeasured servers (0 latency) are preferred to measure them.
+			if h.states[i].Latency < h.states[best].Latency {
+				best = i
+			}
+		}
+
+		return h.use(best)
+
+	case StrategyWeighted:
+
+		weights := make([]float64, len(avail))
+		total := 0.0
+
+		for ii, i := range avail {
+
+			st := &h.states[i]
+
+			lat := st.Latency
+			if lat <= 0 {
+				lat = time.Millisecond
+			}
+
+			success := 1.0
+			if st.Queries > 0 {
+				success = float64(st.Queries-st.Failures+1) / float64(st.Queries+1)
+			}
+
+			weights[ii] = success / lat.Seconds()
+			total += weights[ii]
+		}
+
+		r := rand.Float64() * total
+		for ii := range weights {
+			r -= weights[ii]
+			if r <= 0 {
+				return h.use(avail[ii])
+			}
+		}
+
+		return h.use(avail[len(avail)-1])
+	}
+
+	return h.use(avail[rand.Intn(len(avail))])
+}
+
+// use marks the quarantined server i as probing. The caller must hold the lock.
+func (h *health) use(i int) int {
+
+	if h.states[i].Quarantined {
+		h.states[i].probing = true
+	}
+
+	return i
+}
+
+// record updates the statistics of server i with the result of a query.
+// Context errors are not counted.
+func (h *health) record(i int, rtt time.Duration, err error) {
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+
+		h.m.Lock()
+		if i < len(h.states) {
+			h.states[i].probing = false
+		}
+		h.m.Unlock()
+
+		return
+	}
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.grow(i + 1)
+
+	st := &h.states[i]
+
+	st.Queries++
+	st.probing = false
+
+	// NXDOMAIN is a valid answer
+	if err == nil || errors.Is(err, ErrName) {
+
+		if st.Latency == 0 {
+			st.Latency = rtt
+		} else {
+			st.Latency = time.Duration(latencyAlpha*float64(rtt) + (1-latencyAlpha)*float64(st.Latency))
+		}
+
+		st.ConsecutiveFailures = 0
+		st.Quarantined = false
+		st.QuarantinedUntil = time.Time{}
+		st.quarantine = 0
+
+		return
+	}
+
+	st.Failures++
+	st.ConsecutiveFailures++
+
+	switch {
+	case errors.Is(err, ErrRefused):
+		st.Refused++
+	case errors.Is(err, ErrServerFailure):
+		st.ServFail++
+	}
+
+	if h.threshold <= 0 {
+		return
+	}
+
+	switch {
+	case st.Quarantined:
+		// Failed probe, extend the quarantine
+		st.quarantine *= 2
+	case st.ConsecutiveFailures >= h.threshold:
+		st.quarantine = h.duration
+	default:
+		return
+	}
+
+	if st.quarantine > MaxQuarantineTime {
+		st.quarantine = MaxQuarantineTime
+	}
+
+	st.Quarantined = true
+	st.QuarantinedUntil = h.now().Add(st.quarantine)
+}
+
+// SetStrategy sets the server selection strategy used by Get(-1), TryQuery and the functions built on them.
+// Does nothing if s is not created by NewServersSlice() or NewServersStr().
+func (s *Servers) SetStrategy(st Strategy) {
+
+	if s.health == nil {
+		return
+	}
+
+	s.health.m.Lock()
+	s.health.strategy = st
+	s.health.m.Unlock()
+}
+
+// SetQuarantine configures the quarantine of the failing servers.
+// A server is quarantined after threshold consecutive failures for d.
+// After the quarantine, the next query is a probe: on success the server is recovered, on failure the quarantine is doubled (up to MaxQuarantineTime).
+//
+// Set threshold to 0 to disable the quarantine.
+// Does nothing if s is not created by NewServersSlice() or NewServersStr().
+func (s *Servers) SetQuarantine(threshold int, d time.Duration) {
+
+	if s.health == nil {
+		return
+	}
+
+	s.health.m.Lock()
+	s.health.threshold = threshold
+	s.health.duration = d
+	s.health.m.Unlock()
+}
+
+// Health returns the health statistics of the servers in order.
+func (s *Servers) Health() []ServerHealth {
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	r := make([]ServerHealth, 0, len(s.srvs))
+
+	if s.health == nil {
+		for i := range s.srvs {
+			r = append(r, ServerHealth{Server: s.srvs[i].String()})
+		}
+		return r
+	}
+
+	s.health.m.Lock()
+	defer s.health.m.Unlock()
+
+	s.health.grow(len(s.srvs))
+
+	for i := range s.srvs {
+		h := s.health.states[i].ServerHealth
+		h.Server = s.srvs[i].String()
+		r = append(r, h)
+	}
+
+	return r
+}
+
+// ProbeQuarantined sends a probe query ("." NS) to every quarantined server, regardless of the end of the quarantine.
+// The successfully answering servers are recovered.
+//
+// Returns the number of recovered servers.
+func (s *Servers) ProbeQuarantined(ctx context.Context) int {
+
+	if s.health == nil {
+		return 0
+	}
+
+	s.m.Lock()
+	srvs := append([]Server(nil), s.srvs...)
+	s.m.Unlock()
+
+	s.health.m.Lock()
+	s.health.grow(len(srvs))
+	idxs := make([]int, 0)
+	for i := range srvs {
+		if s.health.states[i].Quarantined && !s.health.states[i].probing {
+			s.health.states[i].probing = true
+			idxs = append(idxs, i)
+		}
+	}
+	s.health.m.Unlock()
+
+	n := 0
+
+	for _, i := range idxs {
+
+		start := time.Now()
+		_, err := srvs[i].queryContext(ctx, ".", TypeNS)
+		s.health.record(i, time.Since(start), err)
+
+		if err == nil {
+			n++
+		}
+	}
+
+	return n
+}
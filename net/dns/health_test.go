@@ -0,0 +1,244 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mdns "github.com/miekg/dns"
+)
+
+// newHealthTestServer starts a local DNS server that answers A 192.0.2.1 for every name, or REFUSED if refuse is true.
+func newHealthTestServer(t *testing.T, refuse *atomic.Bool) string {
+
+	return "udp://" + localDNS(t, func(w mdns.ResponseWriter, r *mdns.Msg) {
+
+		m := new(mdns.Msg)
+		m.SetReply(r)
+
+		if refuse.Load() {
+			m.Rcode = mdns.RcodeRefused
+		} else {
+			m.Answer = append(m.Answer, &mdns.A{Hdr: mdns.RR_Header{Name: r.Question[0].Name, Rrtype: mdns.TypeA, Class: mdns.ClassINET, Ttl: 60}, A: net.ParseIP("192.0.2.1")})
+		}
+
+		w.WriteMsg(m)
+	})
+}
+
+func TestServersQuarantine(t *testing.T) {
+
+	var good, bad atomic.Bool
+
+	bad.Store(true)
+
+	srvs, err := NewServersStr(3, time.Second, newHealthTestServer(t, &good), newHealthTestServer(t, &bad))
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create servers: %s\n", err)
+	}
+
+	srvs.SetQuarantine(2, time.Minute)
+
+	now := time.Now()
+	srvs.health.now = func() time.Time { return now }
+
+	for i := 0; i < 30; i++ {
+		if _, err := srvs.TryQueryA("example.com"); err != nil {
+			t.Fatalf("FAIL: Failed to query: %s\n", err)
+		}
+	}
+
+	h := srvs.Health()
+
+	if !h[1].Quarantined {
+		t.Fatalf("FAIL: The failing server is not quarantined: %+v\n", h[1])
+	}
+
+	if h[1].Queries != 2 || h[1].Refused != 2 || h[1].RefusedRate() != 1 {
+		t.Fatalf("FAIL: Invalid stats of the failing server: %+v\n", h[1])
+	}
+
+	if h[0].Queries < 28 || h[0].Failures != 0 || h[0].Latency == 0 {
+		t.Fatalf("FAIL: Invalid stats of the good server: %+v\n", h[0])
+	}
+
+	// Quarantined server is skipped even if selected by index
+	if srvs.Get(1) != &srvs.srvs[0] {
+		t.Fatalf("FAIL: Quarantined server is returned by Get\n")
+	}
+
+	// Failed probe doubles the quarantine
+	now = now.Add(time.Minute)
+
+	// Get does not record the result, so it must not probe
+	for i := 0; i < 10; i++ {
+		if srvs.Get(1) != &srvs.srvs[0] {
+			t.Fatalf("FAIL: Server is probed by Get\n")
+		}
+	}
+
+	if srvs.index(1, true) != 1 {
+		t.Fatalf("FAIL: Server is not probed after the quarantine\n")
+	}
+
+	// Only one probe at a time
+	if srvs.index(1, true) != 0 {
+		t.Fatalf("FAIL: Server is probed concurrently\n")
+	}
+
+	srvs.health.record(1, 0, ErrRefused)
+
+	if h = srvs.Health(); !h[1].QuarantinedUntil.Equal(now.Add(2 * time.Minute)) {
+		t.Fatalf("FAIL: Quarantine is not doubled: %s\n", h[1].QuarantinedUntil.Sub(now))
+	}
+
+	// Recovery with probe
+	bad.Store(false)
+
+	if n := srvs.ProbeQuarantined(context.Background()); n != 1 {
+		t.Fatalf("FAIL: Invalid number of recovered servers: %d, want: 1\n", n)
+	}
+
+	if h = srvs.Health(); h[1].Quarantined || h[1].ConsecutiveFailures != 0 {
+		t.Fatalf("FAIL: Server is not recovered: %+v\n", h[1])
+	}
+}
+
+func TestServersQuarantineRecovery(t *testing.T) {
+
+	var good, bad atomic.Bool
+
+	bad.Store(true)
+
+	srvs, err := NewServersStr(3, time.Second, newHealthTestServer(t, &good), newHealthTestServer(t, &bad))
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create servers: %s\n", err)
+	}
+
+	srvs.SetQuarantine(1, time.Minute)
+
+	now := time.Now()
+	srvs.health.now = func() time.Time { return now }
+
+	srvs.health.record(1, 0, ErrRefused)
+
+	now = now.Add(time.Minute)
+	bad.Store(false)
+
+	// The queries without recording must not block the recovery
+	for i := 0; i < 50; i++ {
+		if _, err := srvs.QueryA("example.com"); err != nil {
+			t.Fatalf("FAIL: Failed to query: %s\n", err)
+		}
+	}
+
+	for i := 0; i < 50 && srvs.Health()[1].Quarantined; i++ {
+		if _, err := srvs.TryQueryA("example.com"); err != nil {
+			t.Fatalf("FAIL: Failed to query: %s\n", err)
+		}
+	}
+
+	if h := srvs.Health(); h[1].Quarantined {
+		t.Fatalf("FAIL: Server is not recovered by TryQuery: %+v\n", h[1])
+	}
+}
+
+func TestServersNilHealth(t *testing.T) {
+
+	var srvs Servers
+
+	srvs.SetStrategy(StrategyFastest)
+	srvs.SetQuarantine(1, time.Minute)
+
+	if n := srvs.ProbeQuarantined(context.Background()); n != 0 {
+		t.Fatalf("FAIL: Invalid number of recovered servers: %d\n", n)
+	}
+}
+
+func TestServersStrategy(t *testing.T) {
+
+	srvs, err := NewServersStr(3, time.Second, "127.0.0.1", "127.0.0.2", "127.0.0.3")
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create servers: %s\n", err)
+	}
+
+	// Round-robin
+	srvs.SetStrategy(StrategyRoundRobin)
+
+	for i := 0; i < 6; i++ {
+		if s := srvs.Get(-1); s != &srvs.srvs[i%3] {
+			t.Fatalf("FAIL: Round-robin: server wanted: %s, server got: %s\n", srvs.srvs[i%3].String(), s.String())
+		}
+	}
+
+	// Fastest
+	srvs.SetStrategy(StrategyFastest)
+
+	srvs.health.record(0, 30*time.Millisecond, nil)
+	srvs.health.record(1, 10*time.Millisecond, nil)
+	srvs.health.record(2, 20*time.Millisecond, nil)
+
+	for i := 0; i < 5; i++ {
+		if s := srvs.Get(-1); s != &srvs.srvs[1] {
+			t.Fatalf("FAIL: Fastest: server wanted: %s, server got: %s\n", srvs.srvs[1].String(), s.String())
+		}
+	}
+
+	// Weighted: the fastest must be selected the most times
+	srvs.SetStrategy(StrategyWeighted)
+
+	n := make([]int, 3)
+
+	for i := 0; i < 3000; i++ {
+		for ii := range srvs.srvs {
+			if srvs.Get(-1) == &srvs.srvs[ii] {
+				n[ii]++
+			}
+		}
+	}
+
+	if n[1] <= n[2] || n[2] <= n[0] {
+		t.Fatalf("FAIL: Weighted: invalid distribution: %v\n", n)
+	}
+}
+
+func TestServersNext(t *testing.T) {
+
+	srvs, err := NewServersStr(3, time.Second, "127.0.0.1", "127.0.0.2", "127.0.0.3")
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create servers: %s\n", err)
+	}
+
+	srvs.SetStrategy(StrategyFastest)
+	srvs.SetQuarantine(1, time.Minute)
+
+	srvs.health.record(0, 30*time.Millisecond, nil)
+	srvs.health.record(1, 10*time.Millisecond, nil)
+	srvs.health.record(2, 20*time.Millisecond, nil)
+
+	// The retries are selected with the strategy from the not tried servers
+	cases := []struct {
+		Tried []int
+		Want  int
+	}{
+		{nil, 1},
+		{[]int{1}, 2},
+		{[]int{1, 2}, 0},
+		{[]int{1, 2, 0}, 1},
+	}
+
+	for i := range cases {
+		if idx := srvs.next(3, cases[i].Tried); idx != cases[i].Want {
+			t.Fatalf("FAIL: tried: %v, server wanted: %d, server got: %d\n", cases[i].Tried, cases[i].Want, idx)
+		}
+	}
+
+	// The quarantined server is skipped in the retries
+	srvs.health.record(2, 0, ErrRefused)
+
+	if idx := srvs.next(3, []int{1}); idx != 0 {
+		t.Fatalf("FAIL: Quarantined server is selected for retry: %d\n", idx)
+	}
+}
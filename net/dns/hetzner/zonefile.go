@@ -0,0 +1,114 @@
+package hetzner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/g0rbe/gmod/net/dns"
+	mdns "github.com/miekg/dns"
+)
+
+// ZoneRecord converts r to dns.ZoneRecord.
+// origin is the name of the zone, ttl is used if r has no TTL (the default TTL of the zone).
+func (r Record) ZoneRecord(origin string, ttl uint32) (dns.ZoneRecord, error) {
+
+	name := r.Name
+	if name == "" {
+		name = "@"
+	}
+
+	value := r.Value
+
+	// The API accepts unquoted TXT values
+	if strings.EqualFold(r.Type, "TXT") && !strings.HasPrefix(value, "\"") {
+		value = strconv.Quote(value)
+	}
+
+	s := fmt.Sprintf("%s IN %s %s", name, strings.ToUpper(r.Type), value)
+	if r.TTL > 0 {
+		s = fmt.Sprintf("%s %d IN %s %s", name, r.TTL, strings.ToUpper(r.Type), value)
+	}
+
+	zr, err := dns.ParseZoneRecord(s, origin, ttl)
+	if err != nil {
+		return dns.ZoneRecord{}, fmt.Errorf("failed to convert record %s: %w", r.ID, err)
+	}
+
+	return zr, nil
+}
+
+// NewRecord converts zr to Record in zone with zoneID.
+// origin is the name of the zone, the TTL is omitted if equals to ttl (the default TTL of the zone).
+func NewRecord(zoneID string, origin string, ttl uint32, zr dns.ZoneRecord) (Record, error) {
+
+	value, err := zr.RDATA()
+	if err != nil {
+		return Record{}, err
+	}
+
+	r := Record{
+		Type:   mdns.TypeToString[zr.Type],
+		ZoneID: zoneID,
+		Value:  value,
+	}
+
+	if zr.TTL != ttl {
+		r.TTL = int(zr.TTL)
+	}
+
+	origin = mdns.Fqdn(origin)
+	name := mdns.Fqdn(zr.Name)
+
+	switch {
+	case strings.EqualFold(name, origin):
+		r.Name = "@"
+	case mdns.IsSubDomain(origin, name):
+		r.Name = name[:len(name)-len(origin)-1]
+	default:
+		return Record{}, fmt.Errorf("%s is not in zone %s", zr.Name, origin)
+	}
+
+	return r, nil
+}
+
+// ToZone converts records of zone z to dns.Zone.
+func ToZone(z Zone, records []Record) (*dns.Zone, error) {
+
+	r := &dns.Zone{Origin: mdns.Fqdn(strings.ToLower(z.Name)), TTL: uint32(z.TTL)}
+
+	for i := range records {
+
+		zr, err := records[i].ZoneRecord(r.Origin, r.TTL)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Records = append(r.Records, zr)
+	}
+
+	return r, nil
+}
+
+// FromZone converts the records of dz to Records in zone z.
+// The SOA records are skipped, because the API manages them.
+func FromZone(z Zone, dz *dns.Zone) ([]Record, error) {
+
+	r := make([]Record, 0, len(dz.Records))
+
+	for i := range dz.Records {
+
+		if dz.Records[i].Type == dns.TypeSOA {
+			continue
+		}
+
+		rec, err := NewRecord(z.ID, dz.Origin, uint32(z.TTL), dz.Records[i])
+		if err != nil {
+			return nil, err
+		}
+
+		r = append(r, rec)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,43 @@
+package hetzner
+
+import (
+	"testing"
+
+	"github.com/g0rbe/gmod/net/dns"
+)
+
+func TestToZone(t *testing.T) {
+
+	z := Zone{ID: "zone1", Name: "example.com", TTL: 86400}
+
+	records := []Record{
+		{ID: "1", Type: "A", Name: "@", Value: "192.0.2.1", ZoneID: "zone1"},
+		{ID: "2", Type: "TXT", Name: "@", Value: "v=spf1 -all", ZoneID: "zone1", TTL: 300},
+		{ID: "3", Type: "MX", Name: "@", Value: "10 mail", ZoneID: "zone1"},
+		{ID: "4", Type: "CNAME", Name: "www", Value: "example.com.", ZoneID: "zone1"},
+	}
+
+	dz, err := ToZone(z, records)
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(dz.Records) != 4 || dz.Records[1].Value != "v=spf1 -all" || dz.Records[1].TTL != 300 || dz.Records[2].Value != "10 mail.example.com." {
+		t.Fatalf("FAIL: unexpected zone: %v\n", dz.Records)
+	}
+
+	dz.Add("@", 3600, dns.Record{Type: dns.TypeSOA, Value: "ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 300"})
+
+	rs, err := FromZone(z, dz)
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(rs) != 4 {
+		t.Fatalf("FAIL: expected 4 records, got %d\n", len(rs))
+	}
+
+	if rs[1].Value != `"v=spf1 -all"` || rs[1].TTL != 300 || rs[0].TTL != 0 || rs[3].Name != "www" || rs[3].ZoneID != "zone1" {
+		t.Fatalf("FAIL: unexpected records: %#v\n", rs)
+	}
+}
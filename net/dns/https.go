@@ -0,0 +1,155 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+
+	mdns "github.com/miekg/dns"
+)
+
+var TypeHTTPS uint16 = 65
+
+// parseHTTPS converts the answer rr to HTTPS.
+func parseHTTPS(rr []mdns.RR) ([]HTTPS, error) {
+
+	r := make([]HTTPS, 0, len(rr))
+
+	for i := range rr {
+
+		switch v := rr[i].(type) {
+		case *mdns.HTTPS:
+			r = append(r, newSVCB(&v.SVCB))
+		case *mdns.CNAME:
+			// Ignore CNAME
+			continue
+		case *mdns.DNAME:
+			// Ignore DNAME
+			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
+		default:
+			return nil, fmt.Errorf("unknown type: %T", v)
+		}
+	}
+
+	return r, nil
+}
+
+// QueryHTTPS ask the server and returns a slice of HTTPS.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QueryHTTPS(name string) ([]HTTPS, error) {
+
+	return s.QueryHTTPSContext(context.Background(), name)
+}
+
+// QueryHTTPSContext is like QueryHTTPS, but the query is bound to ctx.
+func (s *Server) QueryHTTPSContext(ctx context.Context, name string) ([]HTTPS, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeHTTPS)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseHTTPS(rr)
+}
+
+// QueryHTTPS ask a random server from servers and returns a slice of HTTPS.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Servers) QueryHTTPS(name string) ([]HTTPS, error) {
+
+	return s.QueryHTTPSContext(context.Background(), name)
+}
+
+// QueryHTTPSContext is like QueryHTTPS, but the query is bound to ctx.
+func (s *Servers) QueryHTTPSContext(ctx context.Context, name string) ([]HTTPS, error) {
+
+	return s.Get(-1).QueryHTTPSContext(ctx, name)
+}
+
+// QueryHTTPS asks the DefaultResolver and returns a slice of HTTPS.
+// Unlike Servers.QueryHTTPS, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QueryHTTPS is the same as TryQueryHTTPS.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func QueryHTTPS(name string) ([]HTTPS, error) {
+
+	return QueryHTTPSContext(context.Background(), name)
+}
+
+// QueryHTTPSContext is like QueryHTTPS, but the query is bound to ctx.
+func QueryHTTPSContext(ctx context.Context, name string) ([]HTTPS, error) {
+
+	return QueryHTTPSWith(ctx, DefaultResolver, name)
+}
+
+// TryQueryHTTPS asks the servers for type HTTPS. If any error occurred, retries with next server (except if error is NXDOMAIN).
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func (s *Servers) TryQueryHTTPS(name string) ([]HTTPS, error) {
+
+	return s.TryQueryHTTPSContext(context.Background(), name)
+}
+
+// TryQueryHTTPSContext is like TryQueryHTTPS, but the query is bound to ctx.
+func (s *Servers) TryQueryHTTPSContext(ctx context.Context, name string) ([]HTTPS, error) {
+
+	return QueryHTTPSWith(ctx, s, name)
+}
+
+// TryQueryHTTPS asks the DefaultResolver for type HTTPS. If any error occurred, retries with next server (except if error is NXDOMAIN).
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func TryQueryHTTPS(name string) ([]HTTPS, error) {
+
+	return TryQueryHTTPSContext(context.Background(), name)
+}
+
+// TryQueryHTTPSContext is like TryQueryHTTPS, but the query is bound to ctx.
+func TryQueryHTTPSContext(ctx context.Context, name string) ([]HTTPS, error) {
+
+	return QueryHTTPSWith(ctx, DefaultResolver, name)
+}
+
+// QueryHTTPSWith asks r for type HTTPS and converts the answer like QueryHTTPS.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QueryHTTPSWith(ctx context.Context, r Resolver, name string) ([]HTTPS, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeHTTPS)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseHTTPS(rr)
+}
+
+// IsSetHTTPS checks whether an HTTPS type record set for name.
+// NXDOMAIN is not an error here, because it means "not found".
+func (s *Servers) IsSetHTTPS(name string) (bool, error) {
+	return s.IsSetHTTPSContext(context.Background(), name)
+}
+
+// IsSetHTTPSContext is like IsSetHTTPS, but the query is bound to ctx.
+func (s *Servers) IsSetHTTPSContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeHTTPS)
+}
+
+// IsSetHTTPS checks whether an HTTPS type record set for name using the DefaultResolver.
+// NXDOMAIN is not an error here, because it means "not found".
+func IsSetHTTPS(name string) (bool, error) {
+	return IsSetHTTPSContext(context.Background(), name)
+}
+
+// IsSetHTTPSContext is like IsSetHTTPS, but the query is bound to ctx.
+func IsSetHTTPSContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeHTTPS)
+}
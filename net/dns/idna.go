@@ -0,0 +1,106 @@
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var ErrInvalidIDN = errors.New("invalid internationalized domain name")
+
+// idnaProfile is the UTS 46 lookup profile (nontransitional, IDNA 2008), but allows the underscore labels (eg.: "_dmarc").
+var idnaProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.Transitional(false),
+	idna.StrictDomainName(false),
+)
+
+// isASCII returns whether s contains only ASCII characters.
+func isASCII(s string) bool {
+
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsIDN returns whether d is an internationalized domain name: it contains U-labels (non-ASCII) or A-labels ("xn--").
+func IsIDN(d string) bool {
+
+	if !isASCII(d) {
+		return true
+	}
+
+	for _, l := range strings.Split(d, ".") {
+		if isALabel(l) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isALabel returns whether label l has the ACE prefix ("xn--").
+func isALabel(l string) bool {
+
+	return len(l) >= 4 && strings.EqualFold(l[:4], "xn--")
+}
+
+// ToASCII converts the U-labels in d to A-labels (eg.: "bücher.example" -> "xn--bcher-kva.example") using UTS 46 mapping (IDNA 2008, nontransitional).
+// The result is lower cased, the trailing dot is kept.
+//
+// Returns ErrInvalidIDN if a label is not valid, including the A-labels that are not valid punycode or not in the normalized form.
+func ToASCII(d string) (string, error) {
+
+	// Validate the A-labels: decoding and encoding again must give the same label
+	for _, l := range strings.Split(d, ".") {
+
+		if !isALabel(l) {
+			continue
+		}
+
+		l = strings.ToLower(l)
+
+		u, err := idnaProfile.ToUnicode(l)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrInvalidIDN, err)
+		}
+
+		if back, err := idnaProfile.ToASCII(u); err != nil || back != l {
+			return "", fmt.Errorf("%w: %s is not a valid A-label", ErrInvalidIDN, l)
+		}
+	}
+
+	a, err := idnaProfile.ToASCII(d)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidIDN, err)
+	}
+
+	return a, nil
+}
+
+// ToUnicode converts the A-labels in d to U-labels (eg.: "xn--bcher-kva.example" -> "bücher.example").
+// The result is lower cased, the trailing dot is kept.
+//
+// Returns ErrInvalidIDN if a label is not valid (see ToASCII()).
+func ToUnicode(d string) (string, error) {
+
+	// Validate with ToASCII, because ToUnicode returns the invalid labels unchanged
+	a, err := ToASCII(d)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := idnaProfile.ToUnicode(a)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidIDN, err)
+	}
+
+	return u, nil
+}
@@ -0,0 +1,105 @@
+package dns
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestToASCII(t *testing.T) {
+
+	cases := []struct {
+		D     string
+		ASCII string // Empty if invalid
+	}{
+		{D: "bücher.example", ASCII: "xn--bcher-kva.example"},
+		{D: "Bücher.Example.", ASCII: "xn--bcher-kva.example."},
+		{D: "xn--bcher-kva.example", ASCII: "xn--bcher-kva.example"},
+		{D: "faß.de", ASCII: "xn--fa-hia.de"}, // Nontransitional
+		{D: "例え.テスト", ASCII: "xn--r8jz45g.xn--zckzah"},
+		{D: "_dmarc.bücher.example", ASCII: "_dmarc.xn--bcher-kva.example"},
+		{D: "example.com", ASCII: "example.com"},
+		{D: "xn--a.example", ASCII: ""},          // Invalid punycode
+		{D: "xn--bcher-kva-.example", ASCII: ""}, // Not normalized
+		{D: "a‍b.example", ASCII: ""},            // Zero width joiner without context
+	}
+
+	for i := range cases {
+
+		a, err := ToASCII(cases[i].D)
+
+		if cases[i].ASCII == "" {
+			if !errors.Is(err, ErrInvalidIDN) {
+				t.Fatalf("FAIL: %s: expected ErrInvalidIDN, got %q, %v\n", cases[i].D, a, err)
+			}
+			continue
+		}
+
+		if err != nil || a != cases[i].ASCII {
+			t.Fatalf("FAIL: %s: got %q, %v, want: %q\n", cases[i].D, a, err, cases[i].ASCII)
+		}
+	}
+}
+
+func TestToUnicode(t *testing.T) {
+
+	u, err := ToUnicode("xn--bcher-kva.Example")
+	if err != nil || u != "bücher.example" {
+		t.Fatalf("FAIL: got %q, %v\n", u, err)
+	}
+
+	if _, err := ToUnicode("xn--a.example"); !errors.Is(err, ErrInvalidIDN) {
+		t.Fatalf("FAIL: expected ErrInvalidIDN, got %v\n", err)
+	}
+}
+
+func TestIDNDomain(t *testing.T) {
+
+	for _, d := range []string{"bücher.example", "xn--bcher-kva.example", "例え.テスト.", "münchen.de"} {
+		if !IsDomain(d) {
+			t.Fatalf("FAIL: %s is not a domain\n", d)
+		}
+	}
+
+	for _, d := range []string{"xn--a.example", "bü cher.example", "bücher"} {
+		if IsDomain(d) {
+			t.Fatalf("FAIL: %s is a domain\n", d)
+		}
+	}
+
+	if c := Clean("WWW.Bücher.Example."); c != "www.xn--bcher-kva.example" {
+		t.Fatalf("FAIL: Invalid clean: %s\n", c)
+	}
+
+	p := GetParts("www.bücher.co.uk")
+	if p == nil || p.Sub != "www" || p.Domain != "xn--bcher-kva" || p.TLD != "co.uk" {
+		t.Fatalf("FAIL: Invalid parts: %#v\n", p)
+	}
+
+	if p := GetParts("xn--a.bücher.com"); p != nil {
+		t.Fatalf("FAIL: Parts of invalid IDN: %#v\n", p)
+	}
+
+	if tld := GetTLD("bücher.公司.cn"); tld != "xn--55qx5d.cn" {
+		t.Fatalf("FAIL: Invalid TLD: %s\n", tld)
+	}
+
+	if d := GetDomain("www.bücher.co.uk"); d != "xn--bcher-kva.co.uk" {
+		t.Fatalf("FAIL: Invalid domain: %s\n", d)
+	}
+
+	if s := GetSub("www.bücher.co.uk"); s != "www" {
+		t.Fatalf("FAIL: Invalid sub: %s\n", s)
+	}
+
+	if i := GetDomainIndex("www.bücher.co.uk"); i != 4 {
+		t.Fatalf("FAIL: Invalid domain index: %d\n", i)
+	}
+
+	if i := GetTLDIndex("www.bücher.co.uk"); i != len("www.bücher.") {
+		t.Fatalf("FAIL: Invalid TLD index: %d\n", i)
+	}
+
+	if tld := GetTLD("xn--a.bücher.com"); tld != "" {
+		t.Fatalf("FAIL: TLD of invalid IDN: %s\n", tld)
+	}
+}
@@ -0,0 +1,348 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elmasy-com/elnet/validator"
+	mdns "github.com/miekg/dns"
+)
+
+// RootHints is the IPv4 addresses of the root servers (a.root-servers.net - m.root-servers.net).
+// See: https://www.iana.org/domains/root/servers
+var RootHints = []string{
+	"198.41.0.4",
+	"170.247.170.2",
+	"192.33.4.12",
+	"199.7.91.13",
+	"192.203.230.10",
+	"192.5.5.241",
+	"192.112.36.4",
+	"198.97.190.53",
+	"192.36.148.17",
+	"192.58.128.30",
+	"193.0.14.129",
+	"199.7.83.42",
+	"202.12.27.33",
+}
+
+var (
+	// MaxIterativeDepth is the maximum number of nested resolutions (CNAME targets and glueless NS names) in an iterative query.
+	MaxIterativeDepth = 8
+
+	// MaxIterativeReferrals is the maximum number of referrals followed while resolving a name.
+	MaxIterativeReferrals = 16
+)
+
+var (
+	ErrLameServer    = errors.New("lame server")
+	ErrMaxDepth      = errors.New("maximum depth exceeded")
+	ErrMaxReferrals  = errors.New("maximum referrals exceeded")
+	ErrNoNameservers = errors.New("no usable nameserver")
+)
+
+// iterative is a non-recursive resolver that walks the delegations from the root hints.
+type iterative struct {
+	hints   []string
+	port    string
+	timeout time.Duration
+}
+
+// NewServerIterative creates a Server that resolves the names iteratively, starting from hints
+// and following the referrals, instead of asking a recursive resolver.
+//
+// If hints is empty, RootHints is used. Timeout is the timeout of a single query sent to an authoritative server.
+//
+// The returned Server can be used as any other Server (eg.: in NewServersSlice()), the Protocol is "iterative".
+func NewServerIterative(hints []string, timeout time.Duration) (Server, error) {
+
+	if len(hints) == 0 {
+		hints = RootHints
+	}
+
+	for i := range hints {
+		if !validator.IPv4(hints[i]) && !validator.IPv6(hints[i]) {
+			return Server{}, fmt.Errorf("invalid hint: %s", hints[i])
+		}
+	}
+
+	srv := Server{Protocol: "iterative", IP: hints[0], Port: "53", edns: new(ednsState)}
+
+	if validator.IPv4(srv.IP) {
+		srv.family = 4
+	} else {
+		srv.family = 6
+	}
+
+	srv.iter = &iterative{hints: append([]string(nil), hints...), port: "53", timeout: timeout}
+
+	return srv, nil
+}
+
+// exchangeIterative resolves the question in msg iteratively and returns the response.
+func (s *Server) exchangeIterative(ctx context.Context, msg *mdns.Msg) (*mdns.Msg, error) {
+
+	if len(msg.Question) == 0 {
+		return nil, fmt.Errorf("question is empty")
+	}
+
+	res, err := s.iter.resolve(ctx, msg.Question[0].Name, msg.Question[0].Qtype, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	out := new(mdns.Msg)
+	out.SetReply(msg)
+	out.RecursionAvailable = true
+	out.Rcode = res.Rcode
+	out.Answer = res.Answer
+	out.Ns = res.Ns
+
+	return out, nil
+}
+
+// resolve resolves name with type t starting from the hints.
+// The returned message contains the final rcode, the answer (with the CNAME chain) and the authority section.
+func (it *iterative) resolve(ctx context.Context, name string, t uint16, depth int) (*mdns.Msg, error) {
+
+	if depth > MaxIterativeDepth {
+		return nil, ErrMaxDepth
+	}
+
+	name = strings.ToLower(mdns.Fqdn(name))
+
+	zone := "."
+	servers := it.hints
+
+	for i := 0; i < MaxIterativeReferrals; i++ {
+
+		in, err := it.ask(ctx, servers, zone, name, t)
+		if err != nil {
+			return nil, err
+		}
+
+		if in.Rcode == mdns.RcodeNameError {
+			return in, nil
+		}
+
+		if len(in.Answer) > 0 {
+			return it.follow(ctx, in, name, t, depth)
+		}
+
+		child, nss := referral(in, zone, name)
+		if child == "" {
+			// Authoritative NODATA
+			return in, nil
+		}
+
+		addrs := glue(in, nss)
+
+		// Glueless delegation, resolve the nameservers' addresses.
+		for ii := 0; len(addrs) == 0 && ii < len(nss); ii++ {
+
+			r, err := it.resolve(ctx, nss[ii], TypeA, depth+1)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				continue
+			}
+
+			for _, rr := range r.Answer {
+				if a, ok := rr.(*mdns.A); ok {
+					addrs = append(addrs, a.A.String())
+				}
+			}
+		}
+
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("%w for %s", ErrNoNameservers, child)
+		}
+
+		zone = child
+		servers = addrs
+	}
+
+	return nil, ErrMaxReferrals
+}
+
+// follow walks the CNAME chain of name in the answer of in.
+// If the chain leaves the answer section, the target is resolved from the hints.
+func (it *iterative) follow(ctx context.Context, in *mdns.Msg, name string, t uint16, depth int) (*mdns.Msg, error) {
+
+	cur := name
+
+	for i := 0; i < MaxIterativeDepth; i++ {
+
+		final := false
+		target := ""
+		next := false
+
+		for _, rr := range in.Answer {
+
+			if !strings.EqualFold(rr.Header().Name, cur) {
+				continue
+			}
+
+			if rr.Header().Rrtype == t {
+				final = true
+			} else if cname, ok := rr.(*mdns.CNAME); ok {
+				target = strings.ToLower(cname.Target)
+			}
+		}
+
+		if final || target == "" {
+			return in, nil
+		}
+
+		cur = target
+
+		// Check whether the chain continues in the answer
+		for _, rr := range in.Answer {
+			if strings.EqualFold(rr.Header().Name, cur) {
+				next = true
+				break
+			}
+		}
+
+		if next {
+			continue
+		}
+
+		r, err := it.resolve(ctx, cur, t, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		res := new(mdns.Msg)
+		res.Rcode = r.Rcode
+		res.Answer = append(append(res.Answer, in.Answer...), r.Answer...)
+		res.Ns = r.Ns
+
+		return res, nil
+	}
+
+	return nil, fmt.Errorf("CNAME chain is too long at %s: %w", cur, ErrMaxDepth)
+}
+
+// ask sends the non-recursive query to the servers in order and returns the first usable response.
+// A response is usable if it contains an answer, an NXDOMAIN, an authoritative NODATA or a referral under zone.
+// The other servers are lame.
+func (it *iterative) ask(ctx context.Context, servers []string, zone string, name string, t uint16) (*mdns.Msg, error) {
+
+	var lastErr error = ErrLameServer
+
+	for i := range servers {
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		srv, err := NewServer("udp", servers[i], it.port, it.timeout)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		msg := new(mdns.Msg)
+		msg.SetQuestion(name, t)
+		msg.RecursionDesired = false
+
+		in, err := srv.exchangeContext(ctx, msg)
+		if err == nil && in.Truncated {
+			in, err = srv.ToTCP().exchangeContext(ctx, msg)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+
+		switch {
+		case in.Rcode == mdns.RcodeNameError:
+			return in, nil
+		case in.Rcode != mdns.RcodeSuccess:
+			lastErr = RcodeToError(in.Rcode)
+		case len(in.Answer) > 0 || in.Authoritative:
+			return in, nil
+		default:
+			if child, _ := referral(in, zone, name); child != "" {
+				return in, nil
+			}
+			lastErr = ErrLameServer
+		}
+	}
+
+	return nil, fmt.Errorf("%w for %s: %w", ErrNoNameservers, zone, lastErr)
+}
+
+// referral returns the delegated zone and the names of its nameservers from the authority section of in.
+// The delegated zone must be under zone and must be a parent of name (or name itself).
+// Returns an empty string if in is not a referral.
+func referral(in *mdns.Msg, zone string, name string) (string, []string) {
+
+	var (
+		child string
+		nss   []string
+	)
+
+	for _, rr := range in.Ns {
+
+		ns, ok := rr.(*mdns.NS)
+		if !ok {
+			continue
+		}
+
+		owner := strings.ToLower(mdns.Fqdn(ns.Hdr.Name))
+
+		if owner == zone || !mdns.IsSubDomain(zone, owner) || !mdns.IsSubDomain(owner, name) {
+			continue
+		}
+
+		if child == "" {
+			child = owner
+		}
+
+		if owner == child {
+			nss = append(nss, strings.ToLower(ns.Ns))
+		}
+	}
+
+	return child, nss
+}
+
+// glue returns the addresses of nss from the additional section of in.
+// IPv4 addresses comes first.
+func glue(in *mdns.Msg, nss []string) []string {
+
+	var v4, v6 []string
+
+	for _, rr := range in.Extra {
+
+		isNS := false
+		for i := range nss {
+			if strings.EqualFold(rr.Header().Name, nss[i]) {
+				isNS = true
+				break
+			}
+		}
+
+		if !isNS {
+			continue
+		}
+
+		switch v := rr.(type) {
+		case *mdns.A:
+			v4 = append(v4, v.A.String())
+		case *mdns.AAAA:
+			v6 = append(v6, v.AAAA.String())
+		}
+	}
+
+	return append(v4, v6...)
+}
@@ -0,0 +1,305 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	mdns "github.com/miekg/dns"
+)
+
+// standInZone is a minimal authoritative server for the zone origin with records rrs.
+// Answers referrals for the delegated subzones (NS records not at the origin) with glue,
+// NODATA and NXDOMAIN with the SOA of the zone.
+func standInZone(t *testing.T, origin string, rrs ...string) mdns.HandlerFunc {
+
+	zone := make([]mdns.RR, 0, len(rrs))
+
+	for i := range rrs {
+		rr, err := mdns.NewRR(rrs[i])
+		if err != nil {
+			t.Fatalf("FAIL: Failed to parse %s: %s\n", rrs[i], err)
+		}
+		zone = append(zone, rr)
+	}
+
+	return func(w mdns.ResponseWriter, r *mdns.Msg) {
+
+		m := new(mdns.Msg)
+		m.SetReply(r)
+
+		q := r.Question[0]
+		qname := strings.ToLower(q.Name)
+
+		if !mdns.IsSubDomain(origin, qname) {
+			m.Rcode = mdns.RcodeRefused
+			w.WriteMsg(m)
+			return
+		}
+
+		// Delegation
+		for _, rr := range zone {
+			if ns, ok := rr.(*mdns.NS); ok && ns.Hdr.Name != origin && mdns.IsSubDomain(ns.Hdr.Name, qname) {
+
+				for _, v := range zone {
+					if v.Header().Name == ns.Hdr.Name && v.Header().Rrtype == mdns.TypeNS {
+						m.Ns = append(m.Ns, v)
+					}
+				}
+
+				for _, v := range zone {
+					for _, n := range m.Ns {
+						if v.Header().Name == n.(*mdns.NS).Ns && (v.Header().Rrtype == mdns.TypeA || v.Header().Rrtype == mdns.TypeAAAA) {
+							m.Extra = append(m.Extra, v)
+						}
+					}
+				}
+
+				w.WriteMsg(m)
+				return
+			}
+		}
+
+		m.Authoritative = true
+		exists := false
+
+		for _, rr := range zone {
+
+			if rr.Header().Name != qname {
+				continue
+			}
+
+			exists = true
+
+			if rr.Header().Rrtype == q.Qtype || rr.Header().Rrtype == mdns.TypeCNAME {
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+
+		if len(m.Answer) == 0 {
+
+			if !exists {
+				m.Rcode = mdns.RcodeNameError
+			}
+
+			for _, rr := range zone {
+				if rr.Header().Rrtype == mdns.TypeSOA {
+					m.Ns = append(m.Ns, rr)
+				}
+			}
+		}
+
+		w.WriteMsg(m)
+	}
+}
+
+// localDNSOnIPs starts a local UDP and TCP DNS server for every handler on 127.0.0.x addresses with the same port.
+// Returns the port.
+func localDNSOnIPs(t *testing.T, hs map[string]mdns.Handler) string {
+
+	for try := 0; try < 10; try++ {
+
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("FAIL: Failed to listen: %s\n", err)
+		}
+		_, port, _ := net.SplitHostPort(pc.LocalAddr().String())
+		pc.Close()
+
+		var (
+			srvs []*mdns.Server
+			ok   = true
+		)
+
+		for ip, h := range hs {
+
+			pc, err := net.ListenPacket("udp", net.JoinHostPort(ip, port))
+			if err != nil {
+				ok = false
+				break
+			}
+
+			l, err := net.Listen("tcp", net.JoinHostPort(ip, port))
+			if err != nil {
+				pc.Close()
+				ok = false
+				break
+			}
+
+			srvs = append(srvs, &mdns.Server{PacketConn: pc, Handler: h}, &mdns.Server{Listener: l, Handler: h})
+		}
+
+		if !ok {
+			for i := range srvs {
+				if srvs[i].PacketConn != nil {
+					srvs[i].PacketConn.Close()
+				} else {
+					srvs[i].Listener.Close()
+				}
+			}
+			continue
+		}
+
+		for _, srv := range srvs {
+			srv := srv
+			started := make(chan struct{})
+			srv.NotifyStartedFunc = func() { close(started) }
+			go srv.ActivateAndServe()
+			<-started
+			t.Cleanup(func() { srv.Shutdown() })
+		}
+
+		return port
+	}
+
+	t.Fatalf("FAIL: Failed to find a free port\n")
+
+	return ""
+}
+
+// newIterativeTestServer returns an iterative Server with the local stand-ins:
+//
+//   - 127.0.0.1: root, delegates "com." to 127.0.0.2
+//   - 127.0.0.2: "com.", delegates "example.com." to a lame server (127.0.0.5) and 127.0.0.3, "other.com." gluelessly to ns1.example.com.
+//   - 127.0.0.3: "example.com." and "other.com."
+//   - 127.0.0.5: lame, refuses everything
+func newIterativeTestServer(t *testing.T) Server {
+
+	soa := func(z string) string {
+		return z + " 300 IN SOA ns1.example.com. admin.example.com. 1 3600 600 86400 30"
+	}
+
+	example := standInZone(t, "example.com.",
+		soa("example.com."),
+		"example.com. 300 IN NS ns1.example.com.",
+		"ns1.example.com. 300 IN A 127.0.0.3",
+		"www.example.com. 300 IN A 192.0.2.10",
+		"alias.example.com. 300 IN CNAME www.example.com.",
+		"ext.example.com. 300 IN CNAME www.other.com.",
+	)
+
+	other := standInZone(t, "other.com.",
+		soa("other.com."),
+		"other.com. 300 IN NS ns1.example.com.",
+		"www.other.com. 300 IN A 192.0.2.20",
+	)
+
+	port := localDNSOnIPs(t, map[string]mdns.Handler{
+		"127.0.0.1": standInZone(t, ".",
+			soa("."),
+			". 300 IN NS a.root.",
+			"com. 300 IN NS ns.com.",
+			"ns.com. 300 IN A 127.0.0.2",
+		),
+		"127.0.0.2": standInZone(t, "com.",
+			soa("com."),
+			"com. 300 IN NS ns.com.",
+			"example.com. 300 IN NS ns0.example.com.",
+			"example.com. 300 IN NS ns1.example.com.",
+			"ns0.example.com. 300 IN A 127.0.0.5",
+			"ns1.example.com. 300 IN A 127.0.0.3",
+			"other.com. 300 IN NS ns1.example.com.",
+		),
+		"127.0.0.3": mdns.HandlerFunc(func(w mdns.ResponseWriter, r *mdns.Msg) {
+			if mdns.IsSubDomain("other.com.", strings.ToLower(r.Question[0].Name)) {
+				other(w, r)
+			} else {
+				example(w, r)
+			}
+		}),
+		"127.0.0.5": mdns.HandlerFunc(func(w mdns.ResponseWriter, r *mdns.Msg) {
+			m := new(mdns.Msg)
+			m.SetRcode(r, mdns.RcodeRefused)
+			w.WriteMsg(m)
+		}),
+	})
+
+	s, err := NewServerIterative([]string{"127.0.0.1"}, time.Second)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create server: %s\n", err)
+	}
+
+	s.iter.port = port
+
+	return s
+}
+
+func TestNewServerIterative(t *testing.T) {
+
+	s, err := NewServerIterative(nil, time.Second)
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if s.String() != "iterative://198.41.0.4:53" {
+		t.Fatalf("FAIL: Invalid string: %s\n", s.String())
+	}
+
+	if _, err = NewServerIterative([]string{"invalid"}, time.Second); err == nil {
+		t.Fatalf("FAIL: error wanted for invalid hint, got nil\n")
+	}
+}
+
+func TestIterativeQuery(t *testing.T) {
+
+	s := newIterativeTestServer(t)
+
+	srvs := NewServersSlice(1, s)
+
+	cases := []struct {
+		Name string
+		IP   string
+	}{
+		{Name: "www.example.com", IP: "192.0.2.10"},
+		{Name: "alias.example.com", IP: "192.0.2.10"}, // CNAME in the same zone
+		{Name: "ext.example.com", IP: "192.0.2.20"},   // CNAME into a glueless delegation
+		{Name: "WWW.Other.COM", IP: "192.0.2.20"},
+	}
+
+	for i := range cases {
+
+		r, err := srvs.TryQueryA(cases[i].Name)
+		if err != nil {
+			t.Fatalf("FAIL: Failed to query %s: %s\n", cases[i].Name, err)
+		}
+
+		if len(r) != 1 || r[0].String() != cases[i].IP {
+			t.Fatalf("FAIL: %s: answer wanted: %s, answer got: %v\n", cases[i].Name, cases[i].IP, r)
+		}
+	}
+
+	ns, err := srvs.TryQueryNS("example.com")
+	if err != nil {
+		t.Fatalf("FAIL: Failed to query NS: %s\n", err)
+	}
+
+	if len(ns) != 1 || ns[0] != "ns1.example.com." {
+		t.Fatalf("FAIL: Invalid NS: %v\n", ns)
+	}
+
+	// NXDOMAIN
+	if _, err = srvs.TryQueryA("invalid.example.com"); !errors.Is(err, ErrName) {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", ErrName, err)
+	}
+
+	// NODATA
+	r, err := s.QueryAAAA("www.example.com")
+	if err != nil || len(r) != 0 {
+		t.Fatalf("FAIL: NODATA wanted, answer: %v, error: %v\n", r, err)
+	}
+}
+
+func TestIterativeQueryCanceled(t *testing.T) {
+
+	s := newIterativeTestServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := s.QueryAContext(ctx, "www.example.com"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", context.Canceled, err)
+	}
+}
@@ -0,0 +1,154 @@
+package mailauth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/g0rbe/gmod/net/dns"
+)
+
+var (
+	ErrNoDKIM      = errors.New("no DKIM record")
+	ErrInvalidDKIM = errors.New("invalid DKIM record")
+)
+
+// DefaultDKIMSelectors is a list of commonly used DKIM selectors.
+var DefaultDKIMSelectors = []string{"default", "dkim", "google", "selector1", "selector2", "k1", "k2", "s1", "s2", "mail", "smtp", "mxvault"}
+
+// DKIM is a parsed DKIM key record.
+// See more: https://www.rfc-editor.org/rfc/rfc6376.html#section-3.6.1
+type DKIM struct {
+	Version      string   // v: "DKIM1" or empty
+	KeyType      string   // k: "rsa" (default) or "ed25519"
+	HashAlgs     []string // h: acceptable hash algorithms, empty means all
+	ServiceTypes []string // s: defaults to "*"
+	Flags        []string // t: "y" (testing) and/or "s" (strict)
+	Notes        string   // n
+	PublicKey    string   // p: base64 encoded public key, empty if the key is revoked
+	KeyBits      int      // Size of the public key in bits, 0 if revoked or unknown
+}
+
+// Revoked returns whether the key is revoked (empty "p" tag).
+func (d DKIM) Revoked() bool {
+	return d.PublicKey == ""
+}
+
+// Testing returns whether the domain is testing DKIM ("t=y").
+func (d DKIM) Testing() bool {
+
+	for i := range d.Flags {
+		if d.Flags[i] == "y" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseDKIM parses the DKIM key record s.
+// Returns ErrInvalidDKIM (wrapped) on syntax error or if the public key cannot be parsed.
+func ParseDKIM(s string) (*DKIM, error) {
+
+	tags, err := parseTags(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDKIM, err)
+	}
+
+	r := &DKIM{KeyType: "rsa", ServiceTypes: []string{"*"}}
+	hasKey := false
+
+	for i, t := range tags {
+
+		switch t[0] {
+		case "v":
+			if i != 0 || t[1] != "DKIM1" {
+				return nil, fmt.Errorf("%w: invalid version: %s", ErrInvalidDKIM, t[1])
+			}
+			r.Version = t[1]
+		case "k":
+			r.KeyType = strings.ToLower(t[1])
+		case "h":
+			r.HashAlgs = strings.Split(t[1], ":")
+		case "s":
+			r.ServiceTypes = strings.Split(t[1], ":")
+		case "t":
+			r.Flags = strings.Split(t[1], ":")
+		case "n":
+			r.Notes = t[1]
+		case "p":
+			hasKey = true
+			r.PublicKey = strings.Join(strings.Fields(t[1]), "")
+		}
+	}
+
+	if !hasKey {
+		return nil, fmt.Errorf("%w: missing p", ErrInvalidDKIM)
+	}
+
+	if r.PublicKey == "" {
+		return r, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(r.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid public key: %w", ErrInvalidDKIM, err)
+	}
+
+	switch r.KeyType {
+	case "rsa":
+
+		pub, err := x509.ParsePKIXPublicKey(key)
+		if err != nil {
+			// Some signers publish the PKCS #1 RSAPublicKey
+			pk, err1 := x509.ParsePKCS1PublicKey(key)
+			if err1 != nil {
+				return nil, fmt.Errorf("%w: invalid public key: %w", ErrInvalidDKIM, err)
+			}
+			pub = pk
+		}
+
+		rk, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: public key is not RSA: %T", ErrInvalidDKIM, pub)
+		}
+
+		r.KeyBits = rk.N.BitLen()
+
+	case "ed25519":
+
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid Ed25519 public key size: %d", ErrInvalidDKIM, len(key))
+		}
+
+		r.KeyBits = 256
+	}
+
+	return r, nil
+}
+
+// LookupDKIM returns the DKIM key record of selector in domain from selector._domainkey.domain.
+// Returns ErrNoDKIM if no record found.
+func LookupDKIM(ctx context.Context, srvs dns.Resolver, selector string, domain string) (string, error) {
+
+	txts, err := txtRecords(ctx, srvs, selector+"._domainkey."+dns.Clean(domain))
+	if err != nil {
+		if errors.Is(err, dns.ErrName) {
+			return "", ErrNoDKIM
+		}
+		return "", err
+	}
+
+	for i := range txts {
+		if strings.Contains(txts[i], "p=") {
+			return txts[i], nil
+		}
+	}
+
+	return "", ErrNoDKIM
+}
@@ -0,0 +1,216 @@
+package mailauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/g0rbe/gmod/net/dns"
+)
+
+var (
+	ErrNoDMARC       = errors.New("no DMARC record")
+	ErrMultipleDMARC = errors.New("multiple DMARC records")
+	ErrInvalidDMARC  = errors.New("invalid DMARC record")
+)
+
+// DMARC is a parsed DMARC record.
+// See more: https://www.rfc-editor.org/rfc/rfc7489.html#section-6.3
+type DMARC struct {
+	Policy          string   // p: "none", "quarantine" or "reject"
+	SubdomainPolicy string   // sp: defaults to Policy
+	Percent         int      // pct: defaults to 100
+	RUA             []string // rua: aggregate report URIs
+	RUF             []string // ruf: failure report URIs
+	ADKIM           string   // adkim: DKIM alignment, "r" (default) or "s"
+	ASPF            string   // aspf: SPF alignment, "r" (default) or "s"
+	FO              string   // fo: failure reporting options, defaults to "0"
+	RI              int      // ri: reporting interval in seconds, defaults to 86400
+}
+
+// IsDMARC returns whether the TXT record s is a DMARC record.
+func IsDMARC(s string) bool {
+
+	v, _, _ := strings.Cut(s, ";")
+
+	return strings.EqualFold(strings.ReplaceAll(v, " ", ""), "v=DMARC1")
+}
+
+// parseTags parses the tag-value list s (eg.: "v=DMARC1; p=none").
+// The tag names are lower cased.
+func parseTags(s string) ([][2]string, error) {
+
+	var tags [][2]string
+
+	for _, t := range strings.Split(s, ";") {
+
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(t, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid tag: %s", t)
+		}
+
+		tags = append(tags, [2]string{strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)})
+	}
+
+	return tags, nil
+}
+
+// splitList splits the comma separated list s.
+func splitList(s string) []string {
+
+	var r []string
+
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			r = append(r, v)
+		}
+	}
+
+	return r
+}
+
+// ParseDMARC parses the DMARC record s.
+// Returns ErrInvalidDMARC (wrapped) on syntax error.
+func ParseDMARC(s string) (*DMARC, error) {
+
+	if !IsDMARC(s) {
+		return nil, fmt.Errorf("%w: missing version", ErrInvalidDMARC)
+	}
+
+	tags, err := parseTags(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDMARC, err)
+	}
+
+	r := &DMARC{Percent: 100, ADKIM: "r", ASPF: "r", FO: "0", RI: 86400}
+
+	for _, t := range tags[1:] {
+
+		switch t[0] {
+		case "p", "sp":
+
+			v := strings.ToLower(t[1])
+			if v != "none" && v != "quarantine" && v != "reject" {
+				return nil, fmt.Errorf("%w: invalid %s: %s", ErrInvalidDMARC, t[0], t[1])
+			}
+
+			if t[0] == "p" {
+				r.Policy = v
+			} else {
+				r.SubdomainPolicy = v
+			}
+
+		case "pct":
+
+			n, err := strconv.Atoi(t[1])
+			if err != nil || n < 0 || n > 100 {
+				return nil, fmt.Errorf("%w: invalid pct: %s", ErrInvalidDMARC, t[1])
+			}
+
+			r.Percent = n
+
+		case "rua":
+			r.RUA = splitList(t[1])
+		case "ruf":
+			r.RUF = splitList(t[1])
+
+		case "adkim", "aspf":
+
+			v := strings.ToLower(t[1])
+			if v != "r" && v != "s" {
+				return nil, fmt.Errorf("%w: invalid %s: %s", ErrInvalidDMARC, t[0], t[1])
+			}
+
+			if t[0] == "adkim" {
+				r.ADKIM = v
+			} else {
+				r.ASPF = v
+			}
+
+		case "fo":
+			r.FO = t[1]
+
+		case "ri":
+
+			n, err := strconv.Atoi(t[1])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%w: invalid ri: %s", ErrInvalidDMARC, t[1])
+			}
+
+			r.RI = n
+		}
+	}
+
+	if r.Policy == "" {
+		// A record without p is treated as "none" if it has rua (RFC 7489 6.6.3)
+		if len(r.RUA) == 0 {
+			return nil, fmt.Errorf("%w: missing p", ErrInvalidDMARC)
+		}
+		r.Policy = "none"
+	}
+
+	if r.SubdomainPolicy == "" {
+		r.SubdomainPolicy = r.Policy
+	}
+
+	return r, nil
+}
+
+// LookupDMARC returns the DMARC record of domain from _dmarc.domain.
+// If domain has no DMARC record, the record of the organizational domain is returned (see dns.GetDomain()).
+//
+// The returned string is the name where the record is found.
+// Returns ErrNoDMARC if no record found and ErrMultipleDMARC if more than one DMARC records found.
+func LookupDMARC(ctx context.Context, srvs dns.Resolver, domain string) (string, string, error) {
+
+	domain = dns.Clean(domain)
+
+	rec, err := lookupDMARC(ctx, srvs, "_dmarc."+domain)
+	if !errors.Is(err, ErrNoDMARC) {
+		return "_dmarc." + domain, rec, err
+	}
+
+	org := dns.GetDomain(domain)
+	if org == "" || org == domain {
+		return "", "", err
+	}
+
+	rec, err = lookupDMARC(ctx, srvs, "_dmarc."+org)
+
+	return "_dmarc." + org, rec, err
+}
+
+func lookupDMARC(ctx context.Context, srvs dns.Resolver, name string) (string, error) {
+
+	txts, err := txtRecords(ctx, srvs, name)
+	if err != nil {
+		if errors.Is(err, dns.ErrName) {
+			return "", ErrNoDMARC
+		}
+		return "", err
+	}
+
+	var r []string
+
+	for i := range txts {
+		if IsDMARC(txts[i]) {
+			r = append(r, txts[i])
+		}
+	}
+
+	switch len(r) {
+	case 0:
+		return "", ErrNoDMARC
+	case 1:
+		return r[0], nil
+	default:
+		return "", ErrMultipleDMARC
+	}
+}
@@ -0,0 +1,51 @@
+package mailauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParseDMARC(t *testing.T) {
+
+	d, err := ParseDMARC("v=DMARC1; p=reject; rua=mailto:a@example.com, mailto:b@example.com; adkim=s")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if d.Policy != "reject" || d.SubdomainPolicy != "reject" || d.Percent != 100 || len(d.RUA) != 2 || d.ADKIM != "s" || d.ASPF != "r" {
+		t.Fatalf("FAIL: invalid record: %#v\n", d)
+	}
+
+	for _, v := range []string{"p=reject", "v=DMARC1; p=drop", "v=DMARC1; p=none; pct=101", "v=DMARC1"} {
+		if _, err := ParseDMARC(v); !errors.Is(err, ErrInvalidDMARC) {
+			t.Errorf("FAIL: %q: expected ErrInvalidDMARC, got %v\n", v, err)
+		}
+	}
+}
+
+func TestLookupDMARC(t *testing.T) {
+
+	srvs := newTestServers(t,
+		`_dmarc.example.com. 300 IN TXT "v=DMARC1; p=quarantine"`,
+		`_dmarc.multi.com. 300 IN TXT "v=DMARC1; p=none"`,
+		`_dmarc.multi.com. 300 IN TXT "v=DMARC1; p=reject"`,
+	)
+
+	name, _, err := LookupDMARC(context.Background(), srvs, "sub.example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if name != "_dmarc.example.com" {
+		t.Fatalf("FAIL: expected fallback to the organizational domain, got %s\n", name)
+	}
+
+	if _, _, err = LookupDMARC(context.Background(), srvs, "multi.com"); !errors.Is(err, ErrMultipleDMARC) {
+		t.Fatalf("FAIL: expected ErrMultipleDMARC, got %v\n", err)
+	}
+
+	if _, _, err = LookupDMARC(context.Background(), srvs, "none.com"); !errors.Is(err, ErrNoDMARC) {
+		t.Fatalf("FAIL: expected ErrNoDMARC, got %v\n", err)
+	}
+}
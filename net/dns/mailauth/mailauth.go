@@ -0,0 +1,367 @@
+// Email authentication posture analyzer.
+//
+// Parses and evaluates the SPF, DMARC, DKIM, MTA-STS and TLS-RPT records of a mail domain.
+package mailauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/g0rbe/gmod/net/dns"
+)
+
+// Severity is the severity of a Finding.
+type Severity int
+
+const (
+	SeverityInfo     Severity = iota // Informational, no action needed
+	SeverityWarning                  // Weakens the protection
+	SeverityCritical                 // The protection is missing or broken
+)
+
+func (s Severity) String() string {
+
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// Finding is an issue found by Analyze.
+type Finding struct {
+	Severity Severity
+	Check    string // "spf", "dmarc", "dkim", "mta-sts" or "tls-rpt"
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s: %s", f.Severity, f.Check, f.Message)
+}
+
+// Config configures Analyze.
+type Config struct {
+	Servers    dns.Resolver // Resolver used for the DNS queries (eg.: *dns.Servers), defaults to dns.DefaultResolver
+	HTTPClient *http.Client // Client used to fetch the MTA-STS policy, defaults to http.DefaultClient
+	Selectors  []string     // DKIM selectors to check, defaults to DefaultDKIMSelectors
+	IP         net.IP       // If set, the SPF policy is evaluated for IP (see CheckHost())
+	Sender     string       // Sender address of the SPF evaluation, defaults to "postmaster@domain"
+}
+
+// SPFReport is the SPF part of a Report.
+type SPFReport struct {
+	Record  string // The raw record, empty if not found
+	Parsed  *SPF   // Nil if not found or invalid
+	Lookups int    // Number of DNS querying terms (see CountLookups())
+	Result  Result // Result of CheckHost() if Config.IP is set
+	Err     error  // Lookup, parse or evaluation error
+}
+
+// DMARCReport is the DMARC part of a Report.
+type DMARCReport struct {
+	Name   string // The name where the record is found (the organizational domain is used as a fallback)
+	Record string
+	Parsed *DMARC
+	Err    error
+}
+
+// DKIMReport is the result of a DKIM selector.
+type DKIMReport struct {
+	Selector string
+	Record   string
+	Parsed   *DKIM
+	Err      error
+}
+
+// MTASTSReport is the MTA-STS part of a Report.
+type MTASTSReport struct {
+	Record    string
+	Parsed    *MTASTS
+	Policy    *MTASTSPolicy // Nil if the policy cannot be fetched
+	Err       error         // Lookup or parse error of the TXT record
+	PolicyErr error         // Fetch or parse error of the policy
+}
+
+// TLSRPTReport is the TLS-RPT part of a Report.
+type TLSRPTReport struct {
+	Record string
+	Parsed *TLSRPT
+	Err    error
+}
+
+// Report is the email authentication posture of a domain.
+type Report struct {
+	Domain   string
+	SPF      SPFReport
+	DMARC    DMARCReport
+	DKIM     []DKIMReport // Only the found or invalid selectors
+	MTASTS   MTASTSReport
+	TLSRPT   TLSRPTReport
+	Findings []Finding
+}
+
+// add appends a Finding to the Report.
+func (r *Report) add(sev Severity, check string, format string, a ...any) {
+
+	r.Findings = append(r.Findings, Finding{Severity: sev, Check: check, Message: fmt.Sprintf(format, a...)})
+}
+
+// Max returns the highest severity of the findings.
+// Returns SeverityInfo if there is no finding.
+func (r *Report) Max() Severity {
+
+	max := SeverityInfo
+
+	for i := range r.Findings {
+		if r.Findings[i].Severity > max {
+			max = r.Findings[i].Severity
+		}
+	}
+
+	return max
+}
+
+// Analyze checks the email authentication records of domain and returns the Report.
+// The lookup errors are reported in the Report, error is returned only if ctx is done.
+func Analyze(ctx context.Context, domain string, conf Config) (*Report, error) {
+
+	if conf.Servers == nil {
+		conf.Servers = dns.DefaultResolver
+	}
+
+	if conf.Selectors == nil {
+		conf.Selectors = DefaultDKIMSelectors
+	}
+
+	r := &Report{Domain: dns.Clean(domain)}
+
+	r.analyzeSPF(ctx, conf)
+	r.analyzeDMARC(ctx, conf)
+	r.analyzeDKIM(ctx, conf)
+	r.analyzeMTASTS(ctx, conf)
+	r.analyzeTLSRPT(ctx, conf)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Report) analyzeSPF(ctx context.Context, conf Config) {
+
+	r.SPF.Record, r.SPF.Err = LookupSPF(ctx, conf.Servers, r.Domain)
+
+	switch {
+	case errors.Is(r.SPF.Err, ErrNoSPF):
+		r.add(SeverityCritical, "spf", "no SPF record, anyone can send mail as %s", r.Domain)
+		return
+	case errors.Is(r.SPF.Err, ErrMultipleSPF):
+		r.add(SeverityCritical, "spf", "multiple SPF records, the evaluation results permerror")
+		return
+	case r.SPF.Err != nil:
+		r.add(SeverityWarning, "spf", "failed to lookup SPF: %s", r.SPF.Err)
+		return
+	}
+
+	r.SPF.Parsed, r.SPF.Err = ParseSPF(r.SPF.Record)
+	if r.SPF.Err != nil {
+		r.add(SeverityCritical, "spf", "%s", r.SPF.Err)
+		return
+	}
+
+	var all *Mechanism
+
+	for i, m := range r.SPF.Parsed.Mechanisms {
+		switch m.Name {
+		case "all":
+			if all == nil {
+				all = &r.SPF.Parsed.Mechanisms[i]
+			}
+		case "ptr":
+			r.add(SeverityWarning, "spf", "the ptr mechanism is slow and should not be used (RFC 7208 5.5)")
+		}
+	}
+
+	switch {
+	case all == nil && r.SPF.Parsed.Redirect == "":
+		r.add(SeverityWarning, "spf", "no \"all\" mechanism, the default result is neutral")
+	case all == nil:
+		// Redirected
+	case all.Qualifier == QualifierPass:
+		r.add(SeverityCritical, "spf", "\"+all\" allows any host to send mail")
+	case all.Qualifier == QualifierNeutral:
+		r.add(SeverityWarning, "spf", "\"?all\" does not protect against spoofing")
+	case all.Qualifier == QualifierSoftFail:
+		r.add(SeverityInfo, "spf", "\"~all\" marks unauthorized mail as suspicious, \"-all\" rejects it")
+	}
+
+	n, err := CountLookups(ctx, conf.Servers, r.Domain)
+	r.SPF.Lookups = n
+
+	switch {
+	case err != nil:
+		r.add(SeverityWarning, "spf", "failed to expand the SPF policy: %s", err)
+	case n > MaxSPFLookups:
+		r.add(SeverityCritical, "spf", "the policy requires %d DNS lookups, more than %d results permerror", n, MaxSPFLookups)
+	case n > MaxSPFLookups-2:
+		r.add(SeverityWarning, "spf", "the policy requires %d DNS lookups, close to the limit (%d)", n, MaxSPFLookups)
+	}
+
+	if conf.IP != nil {
+
+		r.SPF.Result, err = CheckHost(ctx, conf.Servers, conf.IP, r.Domain, conf.Sender)
+		if err != nil && r.SPF.Err == nil {
+			r.SPF.Err = err
+		}
+
+		if r.SPF.Result != ResultPass {
+			r.add(SeverityInfo, "spf", "%s is not authorized: %s", conf.IP, r.SPF.Result)
+		}
+	}
+}
+
+func (r *Report) analyzeDMARC(ctx context.Context, conf Config) {
+
+	r.DMARC.Name, r.DMARC.Record, r.DMARC.Err = LookupDMARC(ctx, conf.Servers, r.Domain)
+
+	switch {
+	case errors.Is(r.DMARC.Err, ErrNoDMARC):
+		r.add(SeverityCritical, "dmarc", "no DMARC record")
+		return
+	case errors.Is(r.DMARC.Err, ErrMultipleDMARC):
+		r.add(SeverityCritical, "dmarc", "multiple DMARC records, DMARC is not applied")
+		return
+	case r.DMARC.Err != nil:
+		r.add(SeverityWarning, "dmarc", "failed to lookup DMARC: %s", r.DMARC.Err)
+		return
+	}
+
+	r.DMARC.Parsed, r.DMARC.Err = ParseDMARC(r.DMARC.Record)
+	if r.DMARC.Err != nil {
+		r.add(SeverityCritical, "dmarc", "%s", r.DMARC.Err)
+		return
+	}
+
+	d := r.DMARC.Parsed
+
+	if d.Policy == "none" {
+		r.add(SeverityWarning, "dmarc", "policy is \"none\", spoofed mail is delivered")
+	} else if d.SubdomainPolicy == "none" {
+		r.add(SeverityWarning, "dmarc", "subdomain policy is \"none\", spoofed mail from subdomains is delivered")
+	}
+
+	if d.Percent < 100 {
+		r.add(SeverityWarning, "dmarc", "the policy is applied only to %d%% of the mails", d.Percent)
+	}
+
+	if len(d.RUA) == 0 {
+		r.add(SeverityInfo, "dmarc", "no aggregate report address (rua)")
+	}
+}
+
+func (r *Report) analyzeDKIM(ctx context.Context, conf Config) {
+
+	for _, sel := range conf.Selectors {
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		d := DKIMReport{Selector: sel}
+
+		d.Record, d.Err = LookupDKIM(ctx, conf.Servers, sel, r.Domain)
+		if errors.Is(d.Err, ErrNoDKIM) {
+			continue
+		}
+
+		if d.Err == nil {
+			d.Parsed, d.Err = ParseDKIM(d.Record)
+		}
+
+		r.DKIM = append(r.DKIM, d)
+
+		switch {
+		case d.Err != nil:
+			r.add(SeverityWarning, "dkim", "selector %s: %s", sel, d.Err)
+		case d.Parsed.Revoked():
+			r.add(SeverityInfo, "dkim", "selector %s: key is revoked", sel)
+		case d.Parsed.KeyType == "rsa" && d.Parsed.KeyBits < 1024:
+			r.add(SeverityCritical, "dkim", "selector %s: RSA key is too short (%d bits)", sel, d.Parsed.KeyBits)
+		case d.Parsed.KeyType == "rsa" && d.Parsed.KeyBits < 2048:
+			r.add(SeverityWarning, "dkim", "selector %s: RSA key is shorter than 2048 bits (%d bits)", sel, d.Parsed.KeyBits)
+		}
+
+		if d.Parsed != nil && d.Parsed.Testing() {
+			r.add(SeverityWarning, "dkim", "selector %s: testing mode (t=y)", sel)
+		}
+	}
+
+	if len(r.DKIM) == 0 {
+		r.add(SeverityInfo, "dkim", "no DKIM key found with the checked selectors")
+	}
+}
+
+func (r *Report) analyzeMTASTS(ctx context.Context, conf Config) {
+
+	r.MTASTS.Record, r.MTASTS.Err = LookupMTASTS(ctx, conf.Servers, r.Domain)
+
+	switch {
+	case errors.Is(r.MTASTS.Err, ErrNoMTASTS):
+		r.add(SeverityInfo, "mta-sts", "no MTA-STS record, the SMTP connections can be downgraded")
+		return
+	case r.MTASTS.Err != nil:
+		r.add(SeverityWarning, "mta-sts", "failed to lookup MTA-STS: %s", r.MTASTS.Err)
+		return
+	}
+
+	r.MTASTS.Parsed, r.MTASTS.Err = ParseMTASTS(r.MTASTS.Record)
+	if r.MTASTS.Err != nil {
+		r.add(SeverityCritical, "mta-sts", "%s", r.MTASTS.Err)
+		return
+	}
+
+	r.MTASTS.Policy, r.MTASTS.PolicyErr = FetchMTASTSPolicy(ctx, conf.HTTPClient, r.Domain)
+	if r.MTASTS.PolicyErr != nil {
+		r.add(SeverityCritical, "mta-sts", "failed to fetch the policy: %s", r.MTASTS.PolicyErr)
+		return
+	}
+
+	switch r.MTASTS.Policy.Mode {
+	case "testing":
+		r.add(SeverityWarning, "mta-sts", "policy is in testing mode, failures are only reported")
+	case "none":
+		r.add(SeverityWarning, "mta-sts", "policy mode is \"none\"")
+	}
+
+	if r.MTASTS.Policy.MaxAge < 86400 {
+		r.add(SeverityInfo, "mta-sts", "max_age is less than a day (%d)", r.MTASTS.Policy.MaxAge)
+	}
+}
+
+func (r *Report) analyzeTLSRPT(ctx context.Context, conf Config) {
+
+	r.TLSRPT.Record, r.TLSRPT.Err = LookupTLSRPT(ctx, conf.Servers, r.Domain)
+
+	switch {
+	case errors.Is(r.TLSRPT.Err, ErrNoTLSRPT):
+		r.add(SeverityInfo, "tls-rpt", "no TLS-RPT record")
+		return
+	case r.TLSRPT.Err != nil:
+		r.add(SeverityWarning, "tls-rpt", "failed to lookup TLS-RPT: %s", r.TLSRPT.Err)
+		return
+	}
+
+	r.TLSRPT.Parsed, r.TLSRPT.Err = ParseTLSRPT(r.TLSRPT.Record)
+	if r.TLSRPT.Err != nil {
+		r.add(SeverityWarning, "tls-rpt", "%s", r.TLSRPT.Err)
+	}
+}
@@ -0,0 +1,178 @@
+package mailauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g0rbe/gmod/net/dns"
+	mdns "github.com/miekg/dns"
+)
+
+// newTestServers starts a local DNS server that answers from records (zone file format).
+// Unknown names are answered with NXDOMAIN.
+func newTestServers(t *testing.T, records ...string) *dns.Servers {
+
+	rrs := make([]mdns.RR, 0, len(records))
+
+	for i := range records {
+		rr, err := mdns.NewRR(records[i])
+		if err != nil {
+			t.Fatalf("FAIL: Failed to parse %s: %s\n", records[i], err)
+		}
+		rrs = append(rrs, rr)
+	}
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("FAIL: Failed to listen: %s\n", err)
+	}
+
+	h := func(w mdns.ResponseWriter, r *mdns.Msg) {
+
+		m := new(mdns.Msg)
+		m.SetReply(r)
+
+		exists := false
+
+		for _, rr := range rrs {
+
+			if !strings.EqualFold(rr.Header().Name, r.Question[0].Name) {
+				continue
+			}
+
+			exists = true
+
+			if rr.Header().Rrtype == r.Question[0].Qtype {
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+
+		if !exists {
+			m.Rcode = mdns.RcodeNameError
+		}
+
+		w.WriteMsg(m)
+	}
+
+	started := make(chan struct{})
+
+	srv := &mdns.Server{PacketConn: pc, Handler: mdns.HandlerFunc(h), NotifyStartedFunc: func() { close(started) }}
+
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+
+	<-started
+
+	srvs, err := dns.NewServersStr(1, time.Second, "udp://"+pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create servers: %s\n", err)
+	}
+
+	return &srvs
+}
+
+// testDKIMKey returns a base64 encoded RSA public key with size bits.
+func testDKIMKey(t *testing.T, bits int) string {
+
+	k, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to generate key: %s\n", err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&k.PublicKey)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to marshal key: %s\n", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+func hasFinding(r *Report, sev Severity, check string, substr string) bool {
+
+	for _, f := range r.Findings {
+		if f.Severity == sev && f.Check == check && strings.Contains(f.Message, substr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestAnalyze(t *testing.T) {
+
+	key := testDKIMKey(t, 1024)
+
+	srvs := newTestServers(t,
+		`example.com. 300 IN TXT "v=spf1 ip4:192.0.2.0/24 ?all"`,
+		`example.com. 300 IN A 192.0.2.1`,
+		`_dmarc.example.com. 300 IN TXT "v=DMARC1; p=none; pct=50"`,
+		`default._domainkey.example.com. 300 IN TXT "v=DKIM1; k=rsa; t=y; p=`+key+`"`,
+	)
+
+	r, err := Analyze(context.Background(), "example.com", Config{Servers: srvs, Selectors: []string{"default", "missing"}, IP: net.ParseIP("192.0.2.10")})
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if r.SPF.Parsed == nil || r.SPF.Result != ResultPass {
+		t.Fatalf("FAIL: invalid SPF report: %#v\n", r.SPF)
+	}
+
+	if r.DMARC.Name != "_dmarc.example.com" || r.DMARC.Parsed == nil {
+		t.Fatalf("FAIL: invalid DMARC report: %#v\n", r.DMARC)
+	}
+
+	if len(r.DKIM) != 1 || r.DKIM[0].Parsed == nil || r.DKIM[0].Parsed.KeyBits != 1024 {
+		t.Fatalf("FAIL: invalid DKIM report: %#v\n", r.DKIM)
+	}
+
+	tests := []struct {
+		Severity Severity
+		Check    string
+		Message  string
+	}{
+		{SeverityWarning, "spf", "?all"},
+		{SeverityWarning, "dmarc", "\"none\""},
+		{SeverityWarning, "dmarc", "50%"},
+		{SeverityInfo, "dmarc", "rua"},
+		{SeverityWarning, "dkim", "2048"},
+		{SeverityWarning, "dkim", "testing"},
+		{SeverityInfo, "mta-sts", "no MTA-STS"},
+		{SeverityInfo, "tls-rpt", "no TLS-RPT"},
+	}
+
+	for i := range tests {
+		if !hasFinding(r, tests[i].Severity, tests[i].Check, tests[i].Message) {
+			t.Errorf("FAIL: missing %s finding of %s: %q\n", tests[i].Severity, tests[i].Check, tests[i].Message)
+		}
+	}
+
+	if r.Max() != SeverityWarning {
+		t.Fatalf("FAIL: unexpected max severity: %s\n", r.Max())
+	}
+}
+
+func TestAnalyzeMissing(t *testing.T) {
+
+	srvs := newTestServers(t, `example.com. 300 IN A 192.0.2.1`)
+
+	r, err := Analyze(context.Background(), "example.com", Config{Servers: srvs, Selectors: []string{"default"}})
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if !hasFinding(r, SeverityCritical, "spf", "no SPF") || !hasFinding(r, SeverityCritical, "dmarc", "no DMARC") {
+		t.Fatalf("FAIL: missing critical findings: %v\n", r.Findings)
+	}
+
+	if r.Max() != SeverityCritical {
+		t.Fatalf("FAIL: unexpected max severity: %s\n", r.Max())
+	}
+}
@@ -0,0 +1,241 @@
+package mailauth
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/g0rbe/gmod/net/dns"
+)
+
+var (
+	ErrNoMTASTS       = errors.New("no MTA-STS record")
+	ErrInvalidMTASTS  = errors.New("invalid MTA-STS record")
+	ErrInvalidPolicy  = errors.New("invalid MTA-STS policy")
+	ErrPolicyNotFound = errors.New("MTA-STS policy not found")
+	ErrPolicyTooLarge = errors.New("MTA-STS policy is too large")
+	ErrNoTLSRPT       = errors.New("no TLS-RPT record")
+	ErrInvalidTLSRPT  = errors.New("invalid TLS-RPT record")
+)
+
+// maxPolicySize is the maximum size of the MTA-STS policy in bytes.
+const maxPolicySize = 64 * 1024
+
+// MTASTS is a parsed MTA-STS TXT record.
+// See more: https://www.rfc-editor.org/rfc/rfc8461.html#section-3.1
+type MTASTS struct {
+	ID string // Policy ID
+}
+
+// MTASTSPolicy is a parsed MTA-STS policy.
+// See more: https://www.rfc-editor.org/rfc/rfc8461.html#section-3.2
+type MTASTSPolicy struct {
+	Version string   // "STSv1"
+	Mode    string   // "enforce", "testing" or "none"
+	MX      []string // Allowed MX patterns (eg.: "*.example.com")
+	MaxAge  int      // Maximum lifetime of the policy in seconds
+}
+
+// TLSRPT is a parsed SMTP TLS Reporting record.
+// See more: https://www.rfc-editor.org/rfc/rfc8460.html#section-3
+type TLSRPT struct {
+	RUA []string // Report URIs (mailto: or https:)
+}
+
+// ParseMTASTS parses the MTA-STS TXT record s.
+func ParseMTASTS(s string) (*MTASTS, error) {
+
+	tags, err := parseTags(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMTASTS, err)
+	}
+
+	if len(tags) == 0 || tags[0][0] != "v" || tags[0][1] != "STSv1" {
+		return nil, fmt.Errorf("%w: missing version", ErrInvalidMTASTS)
+	}
+
+	r := new(MTASTS)
+
+	for _, t := range tags[1:] {
+		if t[0] == "id" {
+			r.ID = t[1]
+		}
+	}
+
+	if r.ID == "" {
+		return nil, fmt.Errorf("%w: missing id", ErrInvalidMTASTS)
+	}
+
+	return r, nil
+}
+
+// LookupMTASTS returns the MTA-STS TXT record of domain from _mta-sts.domain.
+// Returns ErrNoMTASTS if no record found.
+func LookupMTASTS(ctx context.Context, srvs dns.Resolver, domain string) (string, error) {
+
+	return lookupTagged(ctx, srvs, "_mta-sts."+dns.Clean(domain), "v=STSv1", ErrNoMTASTS)
+}
+
+// lookupTagged returns the TXT record of name that starts with prefix.
+// Returns notFound if no such record found.
+func lookupTagged(ctx context.Context, srvs dns.Resolver, name string, prefix string, notFound error) (string, error) {
+
+	txts, err := txtRecords(ctx, srvs, name)
+	if err != nil {
+		if errors.Is(err, dns.ErrName) {
+			return "", notFound
+		}
+		return "", err
+	}
+
+	var r []string
+
+	for i := range txts {
+		if strings.HasPrefix(strings.ReplaceAll(txts[i], " ", ""), prefix) {
+			r = append(r, txts[i])
+		}
+	}
+
+	switch len(r) {
+	case 0:
+		return "", notFound
+	case 1:
+		return r[0], nil
+	default:
+		return "", fmt.Errorf("multiple records at %s", name)
+	}
+}
+
+// ParseMTASTSPolicy parses the MTA-STS policy s.
+func ParseMTASTSPolicy(s string) (*MTASTSPolicy, error) {
+
+	r := new(MTASTSPolicy)
+	hasMaxAge := false
+
+	sc := bufio.NewScanner(strings.NewReader(s))
+
+	for sc.Scan() {
+
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid line: %s", ErrInvalidPolicy, line)
+		}
+
+		v = strings.TrimSpace(v)
+
+		switch strings.TrimSpace(k) {
+		case "version":
+			r.Version = v
+		case "mode":
+			if v != "enforce" && v != "testing" && v != "none" {
+				return nil, fmt.Errorf("%w: invalid mode: %s", ErrInvalidPolicy, v)
+			}
+			r.Mode = v
+		case "mx":
+			r.MX = append(r.MX, v)
+		case "max_age":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 || n > 31557600 {
+				return nil, fmt.Errorf("%w: invalid max_age: %s", ErrInvalidPolicy, v)
+			}
+			r.MaxAge = n
+			hasMaxAge = true
+		}
+	}
+
+	switch {
+	case r.Version != "STSv1":
+		return nil, fmt.Errorf("%w: invalid version: %s", ErrInvalidPolicy, r.Version)
+	case r.Mode == "":
+		return nil, fmt.Errorf("%w: missing mode", ErrInvalidPolicy)
+	case !hasMaxAge:
+		return nil, fmt.Errorf("%w: missing max_age", ErrInvalidPolicy)
+	case r.Mode != "none" && len(r.MX) == 0:
+		return nil, fmt.Errorf("%w: missing mx", ErrInvalidPolicy)
+	}
+
+	return r, nil
+}
+
+// FetchMTASTSPolicy fetches and parses the MTA-STS policy of domain from https://mta-sts.domain/.well-known/mta-sts.txt.
+// If client is nil, http.DefaultClient is used. Redirects are not followed.
+func FetchMTASTSPolicy(ctx context.Context, client *http.Client, domain string) (*MTASTSPolicy, error) {
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	// The policy must not be fetched with redirects (RFC 8461 3.3)
+	c := *client
+	c.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://mta-sts."+dns.Clean(domain)+"/.well-known/mta-sts.txt", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", ErrPolicyNotFound, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPolicySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if len(body) > maxPolicySize {
+		return nil, ErrPolicyTooLarge
+	}
+
+	return ParseMTASTSPolicy(string(body))
+}
+
+// ParseTLSRPT parses the TLS-RPT record s.
+func ParseTLSRPT(s string) (*TLSRPT, error) {
+
+	tags, err := parseTags(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTLSRPT, err)
+	}
+
+	if len(tags) == 0 || tags[0][0] != "v" || tags[0][1] != "TLSRPTv1" {
+		return nil, fmt.Errorf("%w: missing version", ErrInvalidTLSRPT)
+	}
+
+	r := new(TLSRPT)
+
+	for _, t := range tags[1:] {
+		if t[0] == "rua" {
+			r.RUA = splitList(t[1])
+		}
+	}
+
+	if len(r.RUA) == 0 {
+		return nil, fmt.Errorf("%w: missing rua", ErrInvalidTLSRPT)
+	}
+
+	return r, nil
+}
+
+// LookupTLSRPT returns the TLS-RPT record of domain from _smtp._tls.domain.
+// Returns ErrNoTLSRPT if no record found.
+func LookupTLSRPT(ctx context.Context, srvs dns.Resolver, domain string) (string, error) {
+
+	return lookupTagged(ctx, srvs, "_smtp._tls."+dns.Clean(domain), "v=TLSRPTv1", ErrNoTLSRPT)
+}
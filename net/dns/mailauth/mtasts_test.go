@@ -0,0 +1,83 @@
+package mailauth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseMTASTSPolicy(t *testing.T) {
+
+	p, err := ParseMTASTSPolicy("version: STSv1\r\nmode: enforce\r\nmx: mail.example.com\r\nmx: *.example.net\r\nmax_age: 604800\r\n")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if p.Mode != "enforce" || len(p.MX) != 2 || p.MaxAge != 604800 {
+		t.Fatalf("FAIL: invalid policy: %#v\n", p)
+	}
+
+	for _, v := range []string{"version: STSv1\nmode: enforce\nmax_age: 1\n", "version: STSv1\nmode: strict\nmx: a\nmax_age: 1\n", "mode: none\nmax_age: 1\n"} {
+		if _, err := ParseMTASTSPolicy(v); !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("FAIL: %q: expected ErrInvalidPolicy, got %v\n", v, err)
+		}
+	}
+}
+
+func TestFetchMTASTSPolicy(t *testing.T) {
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Host != "mta-sts.example.com" || r.URL.Path != "/.well-known/mta-sts.txt" {
+			http.Redirect(w, r, "https://mta-sts.example.com/.well-known/mta-sts.txt", http.StatusFound)
+			return
+		}
+
+		w.Write([]byte("version: STSv1\nmode: testing\nmx: mail.example.com\nmax_age: 3600\n"))
+	}))
+	defer ts.Close()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, ts.Listener.Addr().String())
+			},
+		},
+	}
+
+	p, err := FetchMTASTSPolicy(context.Background(), client, "example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if p.Mode != "testing" || p.MaxAge != 3600 {
+		t.Fatalf("FAIL: invalid policy: %#v\n", p)
+	}
+
+	if _, err = FetchMTASTSPolicy(context.Background(), client, "example.org"); !errors.Is(err, ErrPolicyNotFound) {
+		t.Fatalf("FAIL: expected ErrPolicyNotFound on redirect, got %v\n", err)
+	}
+
+	srvs := newTestServers(t,
+		`_mta-sts.example.com. 300 IN TXT "v=STSv1; id=20240101"`,
+		`_smtp._tls.example.com. 300 IN TXT "v=TLSRPTv1; rua=mailto:tls@example.com"`,
+	)
+
+	r, err := Analyze(context.Background(), "example.com", Config{Servers: srvs, HTTPClient: client, Selectors: []string{}})
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if r.MTASTS.Parsed == nil || r.MTASTS.Parsed.ID != "20240101" || r.MTASTS.Policy == nil || r.TLSRPT.Parsed == nil {
+		t.Fatalf("FAIL: invalid report: %#v %#v\n", r.MTASTS, r.TLSRPT)
+	}
+
+	if !hasFinding(r, SeverityWarning, "mta-sts", "testing") || !hasFinding(r, SeverityInfo, "mta-sts", "max_age") {
+		t.Fatalf("FAIL: missing MTA-STS findings: %v\n", r.Findings)
+	}
+}
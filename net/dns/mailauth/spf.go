@@ -0,0 +1,850 @@
+package mailauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/g0rbe/gmod/net/dns"
+	mdns "github.com/miekg/dns"
+)
+
+var (
+	// MaxSPFLookups is the maximum number of DNS querying terms (include, a, mx, ptr, exists and redirect) in a check_host() evaluation.
+	// See more: https://www.rfc-editor.org/rfc/rfc7208.html#section-4.6.4
+	MaxSPFLookups = 10
+
+	// MaxSPFVoidLookups is the maximum number of DNS queries with NXDOMAIN or empty answer in a check_host() evaluation.
+	MaxSPFVoidLookups = 2
+)
+
+var (
+	ErrNoSPF              = errors.New("no SPF record")
+	ErrMultipleSPF        = errors.New("multiple SPF records")
+	ErrInvalidSPF         = errors.New("invalid SPF record")
+	ErrTooManyLookups     = errors.New("too many DNS lookups")
+	ErrTooManyVoidLookups = errors.New("too many void DNS lookups")
+)
+
+// Qualifier is the qualifier of an SPF mechanism.
+type Qualifier byte
+
+const (
+	QualifierPass     Qualifier = '+'
+	QualifierFail     Qualifier = '-'
+	QualifierSoftFail Qualifier = '~'
+	QualifierNeutral  Qualifier = '?'
+)
+
+// Result is the result of the SPF check_host() function.
+// See more: https://www.rfc-editor.org/rfc/rfc7208.html#section-2.6
+type Result string
+
+const (
+	ResultNone      Result = "none"
+	ResultNeutral   Result = "neutral"
+	ResultPass      Result = "pass"
+	ResultFail      Result = "fail"
+	ResultSoftFail  Result = "softfail"
+	ResultTempError Result = "temperror"
+	ResultPermError Result = "permerror"
+)
+
+// result returns the Result of a matching mechanism with qualifier q.
+func (q Qualifier) result() Result {
+
+	switch q {
+	case QualifierFail:
+		return ResultFail
+	case QualifierSoftFail:
+		return ResultSoftFail
+	case QualifierNeutral:
+		return ResultNeutral
+	default:
+		return ResultPass
+	}
+}
+
+// Mechanism is an SPF mechanism.
+type Mechanism struct {
+	Qualifier Qualifier
+	Name      string     // "all", "include", "a", "mx", "ptr", "ip4", "ip6" or "exists"
+	Domain    string     // domain-spec (may contain macros), empty if not set
+	Net       *net.IPNet // Network of "ip4" and "ip6"
+	Prefix4   int        // IPv4 CIDR length of "a" and "mx", defaults to 32
+	Prefix6   int        // IPv6 CIDR length of "a" and "mx", defaults to 128
+}
+
+func (m Mechanism) String() string {
+
+	var b strings.Builder
+
+	if m.Qualifier != QualifierPass {
+		b.WriteByte(byte(m.Qualifier))
+	}
+
+	b.WriteString(m.Name)
+
+	switch {
+	case m.Net != nil:
+		b.WriteString(":" + m.Net.String())
+	case m.Domain != "":
+		b.WriteString(":" + m.Domain)
+	}
+
+	if m.Name == "a" || m.Name == "mx" {
+		if m.Prefix4 != 32 {
+			b.WriteString("/" + strconv.Itoa(m.Prefix4))
+		}
+		if m.Prefix6 != 128 {
+			b.WriteString("//" + strconv.Itoa(m.Prefix6))
+		}
+	}
+
+	return b.String()
+}
+
+// SPF is a parsed SPF record.
+// See more: https://www.rfc-editor.org/rfc/rfc7208.html#section-4.6.1
+type SPF struct {
+	Mechanisms []Mechanism
+	Redirect   string            // The redirect modifier, empty if not set
+	Exp        string            // The exp modifier, empty if not set
+	Modifiers  map[string]string // The unknown modifiers, nil if none
+}
+
+// IsSPF returns whether the TXT record s is an SPF record.
+func IsSPF(s string) bool {
+
+	return len(s) >= 6 && strings.EqualFold(s[:6], "v=spf1") && (len(s) == 6 || s[6] == ' ')
+}
+
+// ParseSPF parses the SPF record s.
+// Returns ErrInvalidSPF (wrapped) on syntax error.
+func ParseSPF(s string) (*SPF, error) {
+
+	if !IsSPF(s) {
+		return nil, fmt.Errorf("%w: missing version", ErrInvalidSPF)
+	}
+
+	r := new(SPF)
+
+	for _, term := range strings.Fields(s[6:]) {
+
+		// Modifier
+		if i := strings.IndexByte(term, '='); i > 0 && !strings.ContainsAny(term[:i], ":/") {
+
+			name, value := strings.ToLower(term[:i]), term[i+1:]
+
+			switch name {
+			case "redirect":
+				if r.Redirect != "" {
+					return nil, fmt.Errorf("%w: multiple redirect", ErrInvalidSPF)
+				}
+				r.Redirect = value
+			case "exp":
+				if r.Exp != "" {
+					return nil, fmt.Errorf("%w: multiple exp", ErrInvalidSPF)
+				}
+				r.Exp = value
+			default:
+				if r.Modifiers == nil {
+					r.Modifiers = make(map[string]string)
+				}
+				r.Modifiers[name] = value
+			}
+
+			continue
+		}
+
+		m, err := parseMechanism(term)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Mechanisms = append(r.Mechanisms, m)
+	}
+
+	return r, nil
+}
+
+// parseMechanism parses a mechanism term.
+func parseMechanism(term string) (Mechanism, error) {
+
+	m := Mechanism{Qualifier: QualifierPass, Prefix4: 32, Prefix6: 128}
+
+	switch Qualifier(term[0]) {
+	case QualifierPass, QualifierFail, QualifierSoftFail, QualifierNeutral:
+		m.Qualifier = Qualifier(term[0])
+		term = term[1:]
+	}
+
+	name, value, hasValue := strings.Cut(term, ":")
+
+	// "a/24" and "mx/24//64" has no domain-spec but has CIDR
+	if !hasValue {
+		if i := strings.IndexByte(name, '/'); i >= 0 {
+			name, value = name[:i], name[i:]
+		}
+	} else if value == "" {
+		return m, fmt.Errorf("%w: empty value: %s", ErrInvalidSPF, term)
+	}
+
+	m.Name = strings.ToLower(name)
+
+	switch m.Name {
+	case "all":
+		if value != "" || hasValue {
+			return m, fmt.Errorf("%w: invalid term: %s", ErrInvalidSPF, term)
+		}
+
+	case "include", "exists":
+		if value == "" {
+			return m, fmt.Errorf("%w: missing domain: %s", ErrInvalidSPF, term)
+		}
+		m.Domain = value
+
+	case "ptr":
+		m.Domain = value
+
+	case "a", "mx":
+
+		domain, cidr := splitCIDR(value)
+		m.Domain = domain
+
+		if cidr == "" {
+			break
+		}
+
+		var v4, v6 string
+
+		if strings.HasPrefix(cidr, "//") {
+			v6 = cidr[2:]
+		} else {
+			v4, v6, _ = strings.Cut(cidr[1:], "//")
+		}
+
+		if v4 != "" || !strings.HasPrefix(cidr, "//") {
+			n, err := strconv.Atoi(v4)
+			if err != nil || n < 0 || n > 32 {
+				return m, fmt.Errorf("%w: invalid IPv4 CIDR: %s", ErrInvalidSPF, term)
+			}
+			m.Prefix4 = n
+		}
+
+		if v6 != "" || strings.Contains(cidr, "//") {
+			n, err := strconv.Atoi(v6)
+			if err != nil || n < 0 || n > 128 {
+				return m, fmt.Errorf("%w: invalid IPv6 CIDR: %s", ErrInvalidSPF, term)
+			}
+			m.Prefix6 = n
+		}
+
+	case "ip4", "ip6":
+
+		if value == "" {
+			return m, fmt.Errorf("%w: missing address: %s", ErrInvalidSPF, term)
+		}
+
+		if !strings.Contains(value, "/") {
+			if m.Name == "ip4" {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+
+		ip, n, err := net.ParseCIDR(value)
+		if err != nil || (m.Name == "ip4") != (ip.To4() != nil) {
+			return m, fmt.Errorf("%w: invalid address: %s", ErrInvalidSPF, term)
+		}
+
+		m.Net = n
+
+	default:
+		return m, fmt.Errorf("%w: unknown mechanism: %s", ErrInvalidSPF, term)
+	}
+
+	return m, nil
+}
+
+// splitCIDR splits the value of "a" and "mx" to domain-spec and the CIDR part (starts with "/").
+// The "/" in the macros are skipped.
+func splitCIDR(v string) (string, string) {
+
+	inMacro := false
+
+	for i := 0; i < len(v); i++ {
+		switch {
+		case v[i] == '{':
+			inMacro = true
+		case v[i] == '}':
+			inMacro = false
+		case v[i] == '/' && !inMacro:
+			return v[:i], v[i:]
+		}
+	}
+
+	return v, ""
+}
+
+// txtRecords returns the TXT records of name, the strings of a record are concatenated.
+func txtRecords(ctx context.Context, srvs dns.Resolver, name string) ([]string, error) {
+
+	rr, err := srvs.TryQueryContext(ctx, name, dns.TypeTXT)
+	if err != nil {
+		return nil, err
+	}
+
+	r := make([]string, 0, len(rr))
+
+	for i := range rr {
+		if v, ok := rr[i].(*mdns.TXT); ok {
+			r = append(r, strings.Join(v.Txt, ""))
+		}
+	}
+
+	return r, nil
+}
+
+// LookupSPF returns the SPF record of domain.
+// Returns ErrNoSPF if domain has no SPF record and ErrMultipleSPF if domain has more than one SPF records.
+func LookupSPF(ctx context.Context, srvs dns.Resolver, domain string) (string, error) {
+
+	txts, err := txtRecords(ctx, srvs, domain)
+	if err != nil {
+		if errors.Is(err, dns.ErrName) {
+			return "", ErrNoSPF
+		}
+		return "", err
+	}
+
+	var r []string
+
+	for i := range txts {
+		if IsSPF(txts[i]) {
+			r = append(r, txts[i])
+		}
+	}
+
+	switch len(r) {
+	case 0:
+		return "", ErrNoSPF
+	case 1:
+		return r[0], nil
+	default:
+		return "", ErrMultipleSPF
+	}
+}
+
+// checker is a check_host() evaluation.
+type checker struct {
+	srvs    dns.Resolver
+	ip      net.IP
+	sender  string
+	lookups int
+	voids   int
+}
+
+// CheckHost evaluates the SPF policy of domain for ip and the sender address (MAIL FROM, eg.: "user@example.com").
+// If sender is empty, "postmaster@domain" is used.
+//
+// The returned error describes the reason of ResultTempError and ResultPermError.
+//
+// See more: https://www.rfc-editor.org/rfc/rfc7208.html#section-4
+func CheckHost(ctx context.Context, srvs dns.Resolver, ip net.IP, domain string, sender string) (Result, error) {
+
+	domain = dns.Clean(domain)
+
+	if sender == "" {
+		sender = "postmaster@" + domain
+	} else if !strings.Contains(sender, "@") {
+		sender = "postmaster@" + sender
+	}
+
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+
+	c := &checker{srvs: srvs, ip: ip, sender: sender}
+
+	return c.check(ctx, domain)
+}
+
+func (c *checker) check(ctx context.Context, domain string) (Result, error) {
+
+	rec, err := LookupSPF(ctx, c.srvs, domain)
+	switch {
+	case errors.Is(err, ErrNoSPF):
+		return ResultNone, err
+	case errors.Is(err, ErrMultipleSPF):
+		return ResultPermError, err
+	case err != nil:
+		return ResultTempError, err
+	}
+
+	spf, err := ParseSPF(rec)
+	if err != nil {
+		return ResultPermError, err
+	}
+
+	for _, m := range spf.Mechanisms {
+
+		match, res, err := c.match(ctx, m, domain)
+		if err != nil {
+			return res, err
+		}
+
+		if match {
+			return m.Qualifier.result(), nil
+		}
+	}
+
+	if spf.Redirect == "" {
+		return ResultNeutral, nil
+	}
+
+	if err := c.lookup(); err != nil {
+		return ResultPermError, err
+	}
+
+	target, err := c.expand(spf.Redirect, domain)
+	if err != nil {
+		return ResultPermError, err
+	}
+
+	res, err := c.check(ctx, target)
+	if res == ResultNone {
+		return ResultPermError, fmt.Errorf("redirect to %s: %w", target, err)
+	}
+
+	return res, err
+}
+
+// lookup counts a DNS querying term.
+func (c *checker) lookup() error {
+
+	c.lookups++
+
+	if c.lookups > MaxSPFLookups {
+		return ErrTooManyLookups
+	}
+
+	return nil
+}
+
+// query queries name with type t and counts the void lookups.
+// Returns the Result and the error if the evaluation must be stopped.
+func (c *checker) query(ctx context.Context, name string, t uint16) ([]mdns.RR, Result, error) {
+
+	rr, err := c.srvs.TryQueryContext(ctx, name, t)
+	if err != nil && !errors.Is(err, dns.ErrName) {
+		return nil, ResultTempError, err
+	}
+
+	if len(rr) == 0 {
+		c.voids++
+		if c.voids > MaxSPFVoidLookups {
+			return nil, ResultPermError, ErrTooManyVoidLookups
+		}
+	}
+
+	return rr, "", nil
+}
+
+// addrs returns the addresses of name with the same family as the checked IP.
+func (c *checker) addrs(ctx context.Context, name string) ([]net.IP, Result, error) {
+
+	t := dns.TypeA
+	if c.ip.To4() == nil {
+		t = dns.TypeAAAA
+	}
+
+	rr, res, err := c.query(ctx, name, t)
+	if err != nil {
+		return nil, res, err
+	}
+
+	ips := make([]net.IP, 0, len(rr))
+
+	for i := range rr {
+		switch v := rr[i].(type) {
+		case *mdns.A:
+			ips = append(ips, v.A)
+		case *mdns.AAAA:
+			ips = append(ips, v.AAAA)
+		}
+	}
+
+	return ips, "", nil
+}
+
+// contains returns whether the checked IP is in the network of ip with the CIDR length of m.
+func (c *checker) contains(m Mechanism, ip net.IP) bool {
+
+	if v4 := ip.To4(); v4 != nil {
+
+		if c.ip.To4() == nil {
+			return false
+		}
+
+		return v4.Mask(net.CIDRMask(m.Prefix4, 32)).Equal(c.ip.To4().Mask(net.CIDRMask(m.Prefix4, 32)))
+	}
+
+	if c.ip.To4() != nil {
+		return false
+	}
+
+	return ip.Mask(net.CIDRMask(m.Prefix6, 128)).Equal(c.ip.Mask(net.CIDRMask(m.Prefix6, 128)))
+}
+
+// match returns whether m matches.
+// If the evaluation must be stopped, returns the Result and the error.
+func (c *checker) match(ctx context.Context, m Mechanism, domain string) (bool, Result, error) {
+
+	if err := ctx.Err(); err != nil {
+		return false, ResultTempError, err
+	}
+
+	if m.Name == "all" {
+		return true, "", nil
+	}
+
+	if m.Name == "ip4" || m.Name == "ip6" {
+		return m.Net.Contains(c.ip), "", nil
+	}
+
+	if err := c.lookup(); err != nil {
+		return false, ResultPermError, err
+	}
+
+	target := domain
+
+	if m.Domain != "" {
+
+		var err error
+
+		if target, err = c.expand(m.Domain, domain); err != nil {
+			return false, ResultPermError, err
+		}
+	}
+
+	switch m.Name {
+	case "include":
+
+		res, err := c.check(ctx, target)
+
+		switch res {
+		case ResultPass:
+			return true, "", nil
+		case ResultFail, ResultSoftFail, ResultNeutral:
+			return false, "", nil
+		case ResultNone:
+			return false, ResultPermError, fmt.Errorf("include %s: %w", target, err)
+		default:
+			return false, res, err
+		}
+
+	case "a":
+
+		ips, res, err := c.addrs(ctx, target)
+		if err != nil {
+			return false, res, err
+		}
+
+		for i := range ips {
+			if c.contains(m, ips[i]) {
+				return true, "", nil
+			}
+		}
+
+	case "mx":
+
+		rr, res, err := c.query(ctx, target, dns.TypeMX)
+		if err != nil {
+			return false, res, err
+		}
+
+		n := 0
+
+		for i := range rr {
+
+			mx, ok := rr[i].(*mdns.MX)
+			if !ok {
+				continue
+			}
+
+			if n++; n > MaxSPFLookups {
+				return false, ResultPermError, fmt.Errorf("%w: too many MX", ErrTooManyLookups)
+			}
+
+			ips, res, err := c.addrs(ctx, mx.Mx)
+			if err != nil {
+				return false, res, err
+			}
+
+			for ii := range ips {
+				if c.contains(m, ips[ii]) {
+					return true, "", nil
+				}
+			}
+		}
+
+	case "ptr":
+
+		name, err := mdns.ReverseAddr(c.ip.String())
+		if err != nil {
+			return false, "", nil
+		}
+
+		// The PTR lookup errors are not fatal
+		rr, err := c.srvs.TryQueryContext(ctx, name, dns.TypePTR)
+		if err != nil {
+			return false, "", nil
+		}
+
+		for i := 0; i < len(rr) && i < MaxSPFLookups; i++ {
+
+			ptr, ok := rr[i].(*mdns.PTR)
+			if !ok {
+				continue
+			}
+
+			name := dns.Clean(ptr.Ptr)
+
+			if name != target && !strings.HasSuffix(name, "."+target) {
+				continue
+			}
+
+			// Validate the name
+			ips, _, err := c.addrs(ctx, name)
+			if err != nil {
+				continue
+			}
+
+			for ii := range ips {
+				if ips[ii].Equal(c.ip) {
+					return true, "", nil
+				}
+			}
+		}
+
+	case "exists":
+
+		rr, res, err := c.query(ctx, target, dns.TypeA)
+		if err != nil {
+			return false, res, err
+		}
+
+		return len(rr) > 0, "", nil
+	}
+
+	return false, "", nil
+}
+
+// expand expands the macros in the domain-spec s.
+// See more: https://www.rfc-editor.org/rfc/rfc7208.html#section-7
+func (c *checker) expand(s string, domain string) (string, error) {
+
+	if !strings.Contains(s, "%") {
+		return dns.Clean(s), nil
+	}
+
+	local, senderDomain, _ := strings.Cut(c.sender, "@")
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		if i+1 >= len(s) {
+			return "", fmt.Errorf("%w: invalid macro: %s", ErrInvalidSPF, s)
+		}
+
+		i++
+
+		switch s[i] {
+		case '%':
+			b.WriteByte('%')
+			continue
+		case '_':
+			b.WriteByte(' ')
+			continue
+		case '-':
+			b.WriteString("%20")
+			continue
+		case '{':
+		default:
+			return "", fmt.Errorf("%w: invalid macro: %s", ErrInvalidSPF, s)
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 2 {
+			return "", fmt.Errorf("%w: invalid macro: %s", ErrInvalidSPF, s)
+		}
+
+		macro := s[i+1 : i+end]
+		i += end
+
+		var v string
+
+		switch macro[0] | 0x20 {
+		case 's':
+			v = c.sender
+		case 'l':
+			v = local
+		case 'o':
+			v = senderDomain
+		case 'd':
+			v = domain
+		case 'i':
+			v = macroIP(c.ip)
+		case 'p':
+			v = "unknown"
+		case 'v':
+			if c.ip.To4() != nil {
+				v = "in-addr"
+			} else {
+				v = "ip6"
+			}
+		case 'h':
+			v = senderDomain
+		default:
+			return "", fmt.Errorf("%w: invalid macro letter: %s", ErrInvalidSPF, s)
+		}
+
+		// Transformers and delimiters
+		rest := macro[1:]
+
+		digits := 0
+		for len(rest) > 0 && rest[0] >= '0' && rest[0] <= '9' {
+			digits = digits*10 + int(rest[0]-'0')
+			rest = rest[1:]
+		}
+
+		reverse := false
+		if len(rest) > 0 && (rest[0] == 'r' || rest[0] == 'R') {
+			reverse = true
+			rest = rest[1:]
+		}
+
+		delims := "."
+		if rest != "" {
+			if strings.Trim(rest, ".-+,/_=") != "" {
+				return "", fmt.Errorf("%w: invalid macro delimiter: %s", ErrInvalidSPF, s)
+			}
+			delims = rest
+		}
+
+		parts := strings.FieldsFunc(v, func(r rune) bool { return strings.ContainsRune(delims, r) })
+
+		if reverse {
+			for l, r := 0, len(parts)-1; l < r; l, r = l+1, r-1 {
+				parts[l], parts[r] = parts[r], parts[l]
+			}
+		}
+
+		if digits > 0 && digits < len(parts) {
+			parts = parts[len(parts)-digits:]
+		}
+
+		b.WriteString(strings.Join(parts, "."))
+	}
+
+	return dns.Clean(b.String()), nil
+}
+
+// macroIP returns the IP in the format of the "i" macro.
+func macroIP(ip net.IP) string {
+
+	if v4 := ip.To4(); v4 != nil {
+		return v4.String()
+	}
+
+	nibbles := make([]string, 0, 32)
+
+	for _, b := range ip.To16() {
+		nibbles = append(nibbles, strconv.FormatUint(uint64(b>>4), 16), strconv.FormatUint(uint64(b&0x0f), 16))
+	}
+
+	return strings.Join(nibbles, ".")
+}
+
+// CountLookups returns the number of DNS querying terms in the SPF policy of domain, including the nested include and redirect policies.
+// Macros are not expanded, a domain-spec with macro is counted, but not followed.
+//
+// The evaluation of the policy fails with ResultPermError if the count is greater than MaxSPFLookups.
+func CountLookups(ctx context.Context, srvs dns.Resolver, domain string) (int, error) {
+
+	return countLookups(ctx, srvs, dns.Clean(domain), make(map[string]bool))
+}
+
+func countLookups(ctx context.Context, srvs dns.Resolver, domain string, seen map[string]bool) (int, error) {
+
+	if seen[domain] {
+		return 0, fmt.Errorf("%w: loop at %s", ErrInvalidSPF, domain)
+	}
+
+	seen[domain] = true
+	defer delete(seen, domain)
+
+	rec, err := LookupSPF(ctx, srvs, domain)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", domain, err)
+	}
+
+	spf, err := ParseSPF(rec)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", domain, err)
+	}
+
+	n := 0
+
+	for _, m := range spf.Mechanisms {
+
+		switch m.Name {
+		case "a", "mx", "ptr", "exists":
+			n++
+		case "include":
+
+			n++
+
+			if strings.Contains(m.Domain, "%") {
+				continue
+			}
+
+			v, err := countLookups(ctx, srvs, dns.Clean(m.Domain), seen)
+			n += v
+			if err != nil {
+				return n, err
+			}
+		}
+
+		// Stop on the first match-all mechanism, the rest is not evaluated
+		if m.Name == "all" {
+			return n, nil
+		}
+	}
+
+	if spf.Redirect != "" {
+
+		n++
+
+		if !strings.Contains(spf.Redirect, "%") {
+			v, err := countLookups(ctx, srvs, dns.Clean(spf.Redirect), seen)
+			n += v
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,81 @@
+package mailauth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestParseSPF(t *testing.T) {
+
+	s, err := ParseSPF("v=spf1 ip4:192.0.2.0/24 ip6:2001:db8::/32 a:mail.example.com/28 mx//64 -include:_spf.example.com ~all redirect=_spf.example.org")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(s.Mechanisms) != 6 {
+		t.Fatalf("FAIL: unexpected number of mechanisms: %d\n", len(s.Mechanisms))
+	}
+
+	if s.Mechanisms[4].Qualifier != QualifierFail || s.Mechanisms[4].Domain != "_spf.example.com" {
+		t.Fatalf("FAIL: invalid include: %#v\n", s.Mechanisms[4])
+	}
+
+	if s.Mechanisms[2].Prefix4 != 28 || s.Mechanisms[3].Prefix6 != 64 {
+		t.Fatalf("FAIL: invalid CIDR: %#v %#v\n", s.Mechanisms[2], s.Mechanisms[3])
+	}
+
+	if s.Redirect != "_spf.example.org" {
+		t.Fatalf("FAIL: invalid redirect: %s\n", s.Redirect)
+	}
+
+	for _, v := range []string{"v=spf2 -all", "v=spf1 foo:bar", "v=spf1 ip4:192.0.2.300", "v=spf1 redirect=a redirect=b"} {
+		if _, err := ParseSPF(v); !errors.Is(err, ErrInvalidSPF) {
+			t.Errorf("FAIL: %q: expected ErrInvalidSPF, got %v\n", v, err)
+		}
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+
+	srvs := newTestServers(t,
+		`example.com. 300 IN TXT "v=spf1 mx include:_spf.example.com -all"`,
+		`example.com. 300 IN MX 10 mail.example.com.`,
+		`mail.example.com. 300 IN A 192.0.2.1`,
+		`_spf.example.com. 300 IN TXT "v=spf1 ip4:198.51.100.0/24 exists:%{i}._ip.example.com ~all"`,
+		`203.0.113.5._ip.example.com. 300 IN A 127.0.0.2`,
+		`loop.example.com. 300 IN TXT "v=spf1 include:loop.example.com -all"`,
+	)
+
+	tests := []struct {
+		IP     string
+		Domain string
+		Result Result
+	}{
+		{"192.0.2.1", "example.com", ResultPass},
+		{"198.51.100.20", "example.com", ResultPass},
+		{"203.0.113.5", "example.com", ResultPass},
+		{"203.0.113.6", "example.com", ResultFail},
+		{"192.0.2.1", "missing.example.com", ResultNone},
+		{"192.0.2.1", "loop.example.com", ResultPermError},
+	}
+
+	for i := range tests {
+
+		r, _ := CheckHost(context.Background(), srvs, net.ParseIP(tests[i].IP), tests[i].Domain, "")
+		if r != tests[i].Result {
+			t.Errorf("FAIL: %s/%s: expected %s, got %s\n", tests[i].Domain, tests[i].IP, tests[i].Result, r)
+		}
+	}
+
+	n, err := CountLookups(context.Background(), srvs, "example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	// mx, include, exists
+	if n != 3 {
+		t.Fatalf("FAIL: expected 3 lookups, got %d\n", n)
+	}
+}
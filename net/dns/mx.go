@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"fmt"
 
 	mdns "github.com/miekg/dns"
@@ -17,16 +18,8 @@ func (m MX) String() string {
 	return fmt.Sprintf("%d %s", m.Preference, m.Exchange)
 }
 
-// QueryMX ask the server and returns a slice of MX.
-// The answer slice will be nil in case of error.
-//
-// The other record types are ignored.
-func (s *Server) QueryMX(name string) ([]MX, error) {
-
-	rr, err := s.query(name, TypeMX)
-	if err != nil {
-		return nil, err
-	}
+// parseMX converts the answer rr to MX.
+func parseMX(rr []mdns.RR) ([]MX, error) {
 
 	r := make([]MX, 0, len(rr))
 
@@ -41,6 +34,9 @@ func (s *Server) QueryMX(name string) ([]MX, error) {
 		case *mdns.DNAME:
 			// Ignore DNAME
 			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
 		default:
 			return nil, fmt.Errorf("unknown type: %T", v)
 		}
@@ -49,22 +45,56 @@ func (s *Server) QueryMX(name string) ([]MX, error) {
 	return r, nil
 }
 
+// QueryMX ask the server and returns a slice of MX.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QueryMX(name string) ([]MX, error) {
+
+	return s.QueryMXContext(context.Background(), name)
+}
+
+// QueryMXContext is like QueryMX, but the query is bound to ctx.
+func (s *Server) QueryMXContext(ctx context.Context, name string) ([]MX, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeMX)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMX(rr)
+}
+
 // QueryMX ask a random server from servers and returns a slice of MX.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func (s *Servers) QueryMX(name string) ([]MX, error) {
 
-	return s.Get(-1).QueryMX(name)
+	return s.QueryMXContext(context.Background(), name)
 }
 
-// QueryMX ask a random server from DefaultServers and returns a slice of MX.
+// QueryMXContext is like QueryMX, but the query is bound to ctx.
+func (s *Servers) QueryMXContext(ctx context.Context, name string) ([]MX, error) {
+
+	return s.Get(-1).QueryMXContext(ctx, name)
+}
+
+// QueryMX asks the DefaultResolver and returns a slice of MX.
+// Unlike Servers.QueryMX, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QueryMX is the same as TryQueryMX.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func QueryMX(name string) ([]MX, error) {
 
-	return DefaultServers.QueryMX(name)
+	return QueryMXContext(context.Background(), name)
+}
+
+// QueryMXContext is like QueryMX, but the query is bound to ctx.
+func QueryMXContext(ctx context.Context, name string) ([]MX, error) {
+
+	return QueryMXWith(ctx, DefaultResolver, name)
 }
 
 // TryQueryMX asks the servers for type MX. If any error occurred, retries with next server (except if error is NXDOMAIN).
@@ -74,50 +104,61 @@ func QueryMX(name string) ([]MX, error) {
 // The first used server is random. The other record types are ignored.
 func (s *Servers) TryQueryMX(name string) ([]MX, error) {
 
-	rr, err := s.TryQuery(name, TypeMX)
-	if err != nil {
-		return nil, err
-	}
+	return s.TryQueryMXContext(context.Background(), name)
+}
 
-	r := make([]MX, 0, len(rr))
+// TryQueryMXContext is like TryQueryMX, but the query is bound to ctx.
+func (s *Servers) TryQueryMXContext(ctx context.Context, name string) ([]MX, error) {
 
-	for i := range rr {
-
-		switch v := rr[i].(type) {
-		case *mdns.MX:
-			r = append(r, MX{Preference: int(v.Preference), Exchange: v.Mx})
-		case *mdns.CNAME:
-			// Ignore CNAME
-			continue
-		case *mdns.DNAME:
-			// Ignore DNAME
-			continue
-		default:
-			return nil, fmt.Errorf("unknown type: %T", v)
-		}
-	}
-
-	return r, nil
+	return QueryMXWith(ctx, s, name)
 }
 
-// TryQueryMX asks the DefaultServers for type MX. If any error occurred, retries with next server (except if error is NXDOMAIN).
+// TryQueryMX asks the DefaultResolver for type MX. If any error occurred, retries with next server (except if error is NXDOMAIN).
 //
 // In case of error, the answer will be nil and return ErrX or any unknown error.
 //
 // The first used server is random. The other record types are ignored.
 func TryQueryMX(name string) ([]MX, error) {
 
-	return DefaultServers.TryQueryMX(name)
+	return TryQueryMXContext(context.Background(), name)
+}
+
+// TryQueryMXContext is like TryQueryMX, but the query is bound to ctx.
+func TryQueryMXContext(ctx context.Context, name string) ([]MX, error) {
+
+	return QueryMXWith(ctx, DefaultResolver, name)
+}
+
+// QueryMXWith asks r for type MX and converts the answer like QueryMX.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QueryMXWith(ctx context.Context, r Resolver, name string) ([]MX, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeMX)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMX(rr)
 }
 
 // IsSetMX checks whether an MX type record set for name.
 // NXDOMAIN is not an error here, because it means "not found".
 func (s *Servers) IsSetMX(name string) (bool, error) {
-	return s.IsSet(name, TypeMX)
+	return s.IsSetMXContext(context.Background(), name)
 }
 
-// IsSetMX checks whether an MX type record set for name using the DefaultServers.
+// IsSetMXContext is like IsSetMX, but the query is bound to ctx.
+func (s *Servers) IsSetMXContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeMX)
+}
+
+// IsSetMX checks whether an MX type record set for name using the DefaultResolver.
 // NXDOMAIN is not an error here, because it means "not found".
 func IsSetMX(name string) (bool, error) {
-	return DefaultServers.IsSetMX(name)
+	return IsSetMXContext(context.Background(), name)
+}
+
+// IsSetMXContext is like IsSetMX, but the query is bound to ctx.
+func IsSetMXContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeMX)
 }
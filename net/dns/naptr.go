@@ -0,0 +1,169 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+
+	mdns "github.com/miekg/dns"
+)
+
+var TypeNAPTR uint16 = 35
+
+// See more: https://www.rfc-editor.org/rfc/rfc3403.html#section-4.1
+type NAPTR struct {
+	Order       uint16 // Lower Order must be processed first
+	Preference  uint16 // Lower Preference is preferred within the same Order
+	Flags       string // Eg.: "S", "A", "U" or "P"
+	Service     string // Eg.: "SIP+D2U"
+	Regexp      string // Substitution expression
+	Replacement string // Next domain name to query
+}
+
+func (n NAPTR) String() string {
+	return fmt.Sprintf("%d %d %q %q %q %s", n.Order, n.Preference, n.Flags, n.Service, n.Regexp, n.Replacement)
+}
+
+// parseNAPTR converts the answer rr to NAPTR.
+func parseNAPTR(rr []mdns.RR) ([]NAPTR, error) {
+
+	r := make([]NAPTR, 0, len(rr))
+
+	for i := range rr {
+
+		switch v := rr[i].(type) {
+		case *mdns.NAPTR:
+			r = append(r, NAPTR{Order: v.Order, Preference: v.Preference, Flags: v.Flags, Service: v.Service, Regexp: v.Regexp, Replacement: v.Replacement})
+		case *mdns.CNAME:
+			// Ignore CNAME
+			continue
+		case *mdns.DNAME:
+			// Ignore DNAME
+			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
+		default:
+			return nil, fmt.Errorf("unknown type: %T", v)
+		}
+	}
+
+	return r, nil
+}
+
+// QueryNAPTR ask the server and returns a slice of NAPTR.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QueryNAPTR(name string) ([]NAPTR, error) {
+
+	return s.QueryNAPTRContext(context.Background(), name)
+}
+
+// QueryNAPTRContext is like QueryNAPTR, but the query is bound to ctx.
+func (s *Server) QueryNAPTRContext(ctx context.Context, name string) ([]NAPTR, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeNAPTR)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNAPTR(rr)
+}
+
+// QueryNAPTR ask a random server from servers and returns a slice of NAPTR.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Servers) QueryNAPTR(name string) ([]NAPTR, error) {
+
+	return s.QueryNAPTRContext(context.Background(), name)
+}
+
+// QueryNAPTRContext is like QueryNAPTR, but the query is bound to ctx.
+func (s *Servers) QueryNAPTRContext(ctx context.Context, name string) ([]NAPTR, error) {
+
+	return s.Get(-1).QueryNAPTRContext(ctx, name)
+}
+
+// QueryNAPTR asks the DefaultResolver and returns a slice of NAPTR.
+// Unlike Servers.QueryNAPTR, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QueryNAPTR is the same as TryQueryNAPTR.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func QueryNAPTR(name string) ([]NAPTR, error) {
+
+	return QueryNAPTRContext(context.Background(), name)
+}
+
+// QueryNAPTRContext is like QueryNAPTR, but the query is bound to ctx.
+func QueryNAPTRContext(ctx context.Context, name string) ([]NAPTR, error) {
+
+	return QueryNAPTRWith(ctx, DefaultResolver, name)
+}
+
+// TryQueryNAPTR asks the servers for type NAPTR. If any error occurred, retries with next server (except if error is NXDOMAIN).
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func (s *Servers) TryQueryNAPTR(name string) ([]NAPTR, error) {
+
+	return s.TryQueryNAPTRContext(context.Background(), name)
+}
+
+// TryQueryNAPTRContext is like TryQueryNAPTR, but the query is bound to ctx.
+func (s *Servers) TryQueryNAPTRContext(ctx context.Context, name string) ([]NAPTR, error) {
+
+	return QueryNAPTRWith(ctx, s, name)
+}
+
+// TryQueryNAPTR asks the DefaultResolver for type NAPTR. If any error occurred, retries with next server (except if error is NXDOMAIN).
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func TryQueryNAPTR(name string) ([]NAPTR, error) {
+
+	return TryQueryNAPTRContext(context.Background(), name)
+}
+
+// TryQueryNAPTRContext is like TryQueryNAPTR, but the query is bound to ctx.
+func TryQueryNAPTRContext(ctx context.Context, name string) ([]NAPTR, error) {
+
+	return QueryNAPTRWith(ctx, DefaultResolver, name)
+}
+
+// QueryNAPTRWith asks r for type NAPTR and converts the answer like QueryNAPTR.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QueryNAPTRWith(ctx context.Context, r Resolver, name string) ([]NAPTR, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeNAPTR)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNAPTR(rr)
+}
+
+// IsSetNAPTR checks whether an NAPTR type record set for name.
+// NXDOMAIN is not an error here, because it means "not found".
+func (s *Servers) IsSetNAPTR(name string) (bool, error) {
+	return s.IsSetNAPTRContext(context.Background(), name)
+}
+
+// IsSetNAPTRContext is like IsSetNAPTR, but the query is bound to ctx.
+func (s *Servers) IsSetNAPTRContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeNAPTR)
+}
+
+// IsSetNAPTR checks whether an NAPTR type record set for name using the DefaultResolver.
+// NXDOMAIN is not an error here, because it means "not found".
+func IsSetNAPTR(name string) (bool, error) {
+	return IsSetNAPTRContext(context.Background(), name)
+}
+
+// IsSetNAPTRContext is like IsSetNAPTR, but the query is bound to ctx.
+func IsSetNAPTRContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeNAPTR)
+}
@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"fmt"
 
 	mdns "github.com/miekg/dns"
@@ -8,16 +9,8 @@ import (
 
 var TypeNS uint16 = 2
 
-// QueryNS ask the server and returns a slice of string.
-// The answer slice will be nil in case of error.
-//
-// The other record types are ignored.
-func (s *Server) QueryNS(name string) ([]string, error) {
-
-	rr, err := s.query(name, TypeNS)
-	if err != nil {
-		return nil, err
-	}
+// parseNS converts the answer rr to NS.
+func parseNS(rr []mdns.RR) ([]string, error) {
 
 	r := make([]string, 0, len(rr))
 
@@ -32,6 +25,9 @@ func (s *Server) QueryNS(name string) ([]string, error) {
 		case *mdns.DNAME:
 			// Ignore DNAME
 			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
 		default:
 			return nil, fmt.Errorf("unknown type: %T", v)
 		}
@@ -40,22 +36,56 @@ func (s *Server) QueryNS(name string) ([]string, error) {
 	return r, nil
 }
 
+// QueryNS ask the server and returns a slice of string.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QueryNS(name string) ([]string, error) {
+
+	return s.QueryNSContext(context.Background(), name)
+}
+
+// QueryNSContext is like QueryNS, but the query is bound to ctx.
+func (s *Server) QueryNSContext(ctx context.Context, name string) ([]string, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeNS)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNS(rr)
+}
+
 // QueryNS ask a random server from servers and returns a slice of string.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func (s *Servers) QueryNS(name string) ([]string, error) {
 
-	return s.Get(-1).QueryNS(name)
+	return s.QueryNSContext(context.Background(), name)
 }
 
-// QueryNS ask a random server from DefaultServers and returns a slice of string.
+// QueryNSContext is like QueryNS, but the query is bound to ctx.
+func (s *Servers) QueryNSContext(ctx context.Context, name string) ([]string, error) {
+
+	return s.Get(-1).QueryNSContext(ctx, name)
+}
+
+// QueryNS asks the DefaultResolver and returns a slice of string.
+// Unlike Servers.QueryNS, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QueryNS is the same as TryQueryNS.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func QueryNS(name string) ([]string, error) {
 
-	return DefaultServers.QueryNS(name)
+	return QueryNSContext(context.Background(), name)
+}
+
+// QueryNSContext is like QueryNS, but the query is bound to ctx.
+func QueryNSContext(ctx context.Context, name string) ([]string, error) {
+
+	return QueryNSWith(ctx, DefaultResolver, name)
 }
 
 // TryQueryNS asks the servers for type NS. If any error occurred, retries with next server (except if error is NXDOMAIN).
@@ -65,50 +95,61 @@ func QueryNS(name string) ([]string, error) {
 // The first used server is random. The other record types are ignored.
 func (s *Servers) TryQueryNS(name string) ([]string, error) {
 
-	rr, err := s.TryQuery(name, TypeNS)
-	if err != nil {
-		return nil, err
-	}
+	return s.TryQueryNSContext(context.Background(), name)
+}
 
-	r := make([]string, 0, len(rr))
+// TryQueryNSContext is like TryQueryNS, but the query is bound to ctx.
+func (s *Servers) TryQueryNSContext(ctx context.Context, name string) ([]string, error) {
 
-	for i := range rr {
-
-		switch v := rr[i].(type) {
-		case *mdns.NS:
-			r = append(r, v.Ns)
-		case *mdns.CNAME:
-			// Ignore CNAME
-			continue
-		case *mdns.DNAME:
-			// Ignore DNAME
-			continue
-		default:
-			return nil, fmt.Errorf("unknown type: %T", v)
-		}
-	}
-
-	return r, nil
+	return QueryNSWith(ctx, s, name)
 }
 
-// TryQueryNS asks the DefaultServers for type NS. If any error occurred, retries with next server (except if error is NXDOMAIN).
+// TryQueryNS asks the DefaultResolver for type NS. If any error occurred, retries with next server (except if error is NXDOMAIN).
 //
 // In case of error, the answer will be nil and return ErrX or any unknown error.
 //
 // The first used server is random. The other record types are ignored.
 func TryQueryNS(name string) ([]string, error) {
 
-	return DefaultServers.TryQueryNS(name)
+	return TryQueryNSContext(context.Background(), name)
+}
+
+// TryQueryNSContext is like TryQueryNS, but the query is bound to ctx.
+func TryQueryNSContext(ctx context.Context, name string) ([]string, error) {
+
+	return QueryNSWith(ctx, DefaultResolver, name)
+}
+
+// QueryNSWith asks r for type NS and converts the answer like QueryNS.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QueryNSWith(ctx context.Context, r Resolver, name string) ([]string, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeNS)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNS(rr)
 }
 
 // IsSetNS checks whether an NS type record set for name.
 // NXDOMAIN is not an error here, because it means "not found".
 func (s *Servers) IsSetNS(name string) (bool, error) {
-	return s.IsSet(name, TypeNS)
+	return s.IsSetNSContext(context.Background(), name)
 }
 
-// IsSetNS checks whether an NS type record set for name using the DefaultServers.
+// IsSetNSContext is like IsSetNS, but the query is bound to ctx.
+func (s *Servers) IsSetNSContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeNS)
+}
+
+// IsSetNS checks whether an NS type record set for name using the DefaultResolver.
 // NXDOMAIN is not an error here, because it means "not found".
 func IsSetNS(name string) (bool, error) {
-	return DefaultServers.IsSetNS(name)
+	return IsSetNSContext(context.Background(), name)
+}
+
+// IsSetNSContext is like IsSetNS, but the query is bound to ctx.
+func IsSetNSContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeNS)
 }
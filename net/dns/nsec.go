@@ -0,0 +1,363 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/g0rbe/slitu"
+	mdns "github.com/miekg/dns"
+)
+
+var (
+	TypeNSEC       uint16 = 47
+	TypeNSEC3      uint16 = 50
+	TypeNSEC3PARAM uint16 = 51
+)
+
+var (
+	ErrNoNSEC  = errors.New("zone has no NSEC chain")
+	ErrNoNSEC3 = errors.New("zone has no NSEC3 chain")
+)
+
+var (
+	// MaxNSECWalk is the maximum number of names walked in an NSEC chain.
+	MaxNSECWalk = 100000
+
+	// MaxNSEC3Candidates is the maximum number of random names hashed offline to find a name in an uncovered range of the NSEC3 chain.
+	// If no candidate found, the chain is considered complete.
+	MaxNSEC3Candidates = 10000
+)
+
+// NSEC is a link of an NSEC chain.
+type NSEC struct {
+	Name  string   // Owner name
+	Next  string   // Next owner name in the canonical order
+	Types []uint16 // Types that exist at Name
+}
+
+func (n NSEC) String() string {
+
+	types := make([]string, 0, len(n.Types))
+	for i := range n.Types {
+		types = append(types, mdns.Type(n.Types[i]).String())
+	}
+
+	return fmt.Sprintf("%s %s %s", n.Name, n.Next, strings.Join(types, " "))
+}
+
+// WalkNSEC enumerates the names of zone by following its NSEC chain from the apex until the chain returns to the apex.
+// Every name in the chain is queried for type NSEC.
+//
+// Returns the walked links along with the error if the walk is interrupted.
+// Returns ErrNoNSEC if the apex has no NSEC record (eg.: the zone is unsigned or uses NSEC3).
+// The walk stops after MaxNSECWalk names.
+func (s *Servers) WalkNSEC(zone string) ([]NSEC, error) {
+
+	return s.WalkNSECContext(context.Background(), zone)
+}
+
+// WalkNSECContext is like WalkNSEC, but the queries are bound to ctx.
+func (s *Servers) WalkNSECContext(ctx context.Context, zone string) ([]NSEC, error) {
+
+	zone = strings.ToLower(mdns.Fqdn(zone))
+
+	var (
+		r    []NSEC
+		seen = make(map[string]bool)
+		name = zone
+	)
+
+	for len(r) < MaxNSECWalk {
+
+		rr, err := s.TryQueryContext(ctx, name, TypeNSEC)
+		if err != nil {
+			if len(r) == 0 && errors.Is(err, ErrName) {
+				return nil, ErrNoNSEC
+			}
+			return r, fmt.Errorf("failed to query %s: %w", name, err)
+		}
+
+		var link *NSEC
+
+		for i := range rr {
+			if v, ok := rr[i].(*mdns.NSEC); ok && strings.EqualFold(v.Hdr.Name, name) {
+				link = &NSEC{Name: strings.ToLower(v.Hdr.Name), Next: strings.ToLower(v.NextDomain), Types: v.TypeBitMap}
+				break
+			}
+		}
+
+		if link == nil {
+			if len(r) == 0 {
+				return nil, ErrNoNSEC
+			}
+			return r, fmt.Errorf("%w at %s", ErrNoNSEC, name)
+		}
+
+		r = append(r, *link)
+		seen[link.Name] = true
+
+		if link.Next == zone || seen[link.Next] {
+			return r, nil
+		}
+
+		// The minimally covering NSEC records ("black lies") point to a nonexistent name
+		if !mdns.IsSubDomain(zone, link.Next) || strings.HasPrefix(link.Next, "\\000.") {
+			return r, fmt.Errorf("%w: synthesized next name at %s: %s", ErrNoNSEC, link.Name, link.Next)
+		}
+
+		name = link.Next
+	}
+
+	return r, nil
+}
+
+// WalkNSEC enumerates the names of zone using the DefaultServers.
+// See Servers.WalkNSEC().
+func WalkNSEC(zone string) ([]NSEC, error) {
+
+	return DefaultServers.WalkNSECContext(context.Background(), zone)
+}
+
+// WalkNSECContext is like WalkNSEC, but the queries are bound to ctx.
+func WalkNSECContext(ctx context.Context, zone string) ([]NSEC, error) {
+
+	return DefaultServers.WalkNSECContext(ctx, zone)
+}
+
+// NSEC3Params is the hash parameters of an NSEC3 chain.
+type NSEC3Params struct {
+	Hash       uint8  // Hash algorithm, 1 is SHA-1
+	Iterations uint16 // Additional iterations
+	Salt       string // Hex encoded salt, "-" or empty means no salt
+}
+
+// NSEC3Hash returns the base32hex encoded NSEC3 hash of name with params p (eg.: "2T7B4G4VSA5SMI47K61MV5BV1A22BOJR").
+func NSEC3Hash(name string, p NSEC3Params) string {
+
+	salt := p.Salt
+	if salt == "-" {
+		salt = ""
+	}
+
+	return mdns.HashName(mdns.Fqdn(name), p.Hash, p.Iterations, salt)
+}
+
+// NSEC3Chain is the collected NSEC3 chain of a zone.
+// It can be saved (eg.: as JSON) and matched against wordlists offline.
+type NSEC3Chain struct {
+	Zone    string
+	Params  NSEC3Params
+	OptOut  bool              // Any record has the Opt-Out flag, the unsigned delegations are not in the chain
+	Hashes  map[string]string // Owner hash -> next hash
+	Queries int               // Number of queries sent
+}
+
+// covered returns whether hash is an owner or covered by a collected NSEC3 record.
+func (c *NSEC3Chain) covered(hash string) bool {
+
+	if _, ok := c.Hashes[hash]; ok {
+		return true
+	}
+
+	for owner, next := range c.Hashes {
+
+		if owner < next {
+			if hash > owner && hash < next {
+				return true
+			}
+		} else if hash > owner || hash < next {
+			// The last record of the chain
+			return true
+		}
+	}
+
+	return false
+}
+
+// Complete returns whether the chain is closed: the links form one cycle through every collected hash.
+func (c *NSEC3Chain) Complete() bool {
+
+	if len(c.Hashes) == 0 {
+		return false
+	}
+
+	var start string
+	for start = range c.Hashes {
+		break
+	}
+
+	cur := start
+
+	for i := 0; i < len(c.Hashes); i++ {
+
+		next, ok := c.Hashes[cur]
+		if !ok {
+			return false
+		}
+
+		cur = next
+	}
+
+	return cur == start
+}
+
+// Sorted returns the owner hashes in order.
+func (c *NSEC3Chain) Sorted() []string {
+
+	r := make([]string, 0, len(c.Hashes))
+
+	for h := range c.Hashes {
+		r = append(r, h)
+	}
+
+	sort.Strings(r)
+
+	return r
+}
+
+// add adds the NSEC3 records of the zone from rr.
+func (c *NSEC3Chain) add(rr []mdns.RR) {
+
+	for i := range rr {
+
+		v, ok := rr[i].(*mdns.NSEC3)
+		if !ok {
+			continue
+		}
+
+		hash, parent, ok := strings.Cut(strings.ToLower(v.Hdr.Name), ".")
+		if !ok || parent != c.Zone {
+			continue
+		}
+
+		c.Hashes[strings.ToUpper(hash)] = strings.ToUpper(v.NextDomain)
+
+		if v.Flags&1 == 1 {
+			c.OptOut = true
+		}
+	}
+}
+
+// Match hashes every word as a label under the zone and returns the names with a hash in the chain (name -> hash).
+func (c *NSEC3Chain) Match(words []string) map[string]string {
+
+	r := make(map[string]string)
+
+	for i := range words {
+
+		name := strings.ToLower(strings.TrimSuffix(words[i], ".")) + "." + c.Zone
+
+		h := NSEC3Hash(name, c.Params)
+
+		if _, ok := c.Hashes[h]; ok {
+			r[name] = h
+		}
+	}
+
+	return r
+}
+
+// MatchReader is like Match, but reads the words from r (see ReadWordlist()).
+func (c *NSEC3Chain) MatchReader(r io.Reader) (map[string]string, error) {
+
+	words, err := ReadWordlist(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wordlist: %w", err)
+	}
+
+	return c.Match(words), nil
+}
+
+// CollectNSEC3 collects the NSEC3 chain of zone.
+// The parameters are queried from the NSEC3PARAM record of the apex.
+// Random names are hashed offline, and only those are queried whose hash is not covered by the collected records,
+// so every query reveals new hashes. The collection stops when the chain is complete, no uncovered candidate found
+// in MaxNSEC3Candidates hashes or after maxQueries queries (0 means no limit).
+//
+// Returns ErrNoNSEC3 if the zone has no NSEC3PARAM record.
+// Returns the collected chain along with the error if the collection is interrupted.
+func (s *Servers) CollectNSEC3(zone string, maxQueries int) (*NSEC3Chain, error) {
+
+	return s.CollectNSEC3Context(context.Background(), zone, maxQueries)
+}
+
+// CollectNSEC3Context is like CollectNSEC3, but the queries are bound to ctx.
+func (s *Servers) CollectNSEC3Context(ctx context.Context, zone string, maxQueries int) (*NSEC3Chain, error) {
+
+	zone = strings.ToLower(mdns.Fqdn(zone))
+
+	rr, err := s.TryQueryContext(ctx, zone, TypeNSEC3PARAM)
+	if err != nil && !errors.Is(err, ErrName) {
+		return nil, fmt.Errorf("failed to query NSEC3PARAM: %w", err)
+	}
+
+	c := &NSEC3Chain{Zone: zone, Hashes: make(map[string]string)}
+	found := false
+
+	for i := range rr {
+		if v, ok := rr[i].(*mdns.NSEC3PARAM); ok {
+			c.Params = NSEC3Params{Hash: v.Hash, Iterations: v.Iterations, Salt: strings.ToUpper(v.Salt)}
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return nil, ErrNoNSEC3
+	}
+
+	dnssec := &EDNSOptions{DNSSECOK: true}
+
+	for maxQueries <= 0 || c.Queries < maxQueries {
+
+		if c.Complete() {
+			return c, nil
+		}
+
+		name := ""
+
+		for i := 0; i < MaxNSEC3Candidates; i++ {
+
+			v := slitu.RandomString(charSet, 12) + "." + zone
+
+			if !c.covered(NSEC3Hash(v, c.Params)) {
+				name = v
+				break
+			}
+		}
+
+		if name == "" {
+			return c, nil
+		}
+
+		in, err := s.tryQueryMsgContext(ctx, name, TypeA, dnssec)
+		c.Queries++
+
+		if err != nil && !errors.Is(err, ErrName) {
+			return c, fmt.Errorf("failed to query %s: %w", name, err)
+		}
+
+		if in != nil {
+			c.add(in.Ns)
+		}
+	}
+
+	return c, nil
+}
+
+// CollectNSEC3 collects the NSEC3 chain of zone using the DefaultServers.
+// See Servers.CollectNSEC3().
+func CollectNSEC3(zone string, maxQueries int) (*NSEC3Chain, error) {
+
+	return DefaultServers.CollectNSEC3Context(context.Background(), zone, maxQueries)
+}
+
+// CollectNSEC3Context is like CollectNSEC3, but the queries are bound to ctx.
+func CollectNSEC3Context(ctx context.Context, zone string, maxQueries int) (*NSEC3Chain, error) {
+
+	return DefaultServers.CollectNSEC3Context(ctx, zone, maxQueries)
+}
@@ -0,0 +1,126 @@
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
+	mdns "github.com/miekg/dns"
+)
+
+var testNSEC3Params = NSEC3Params{Hash: mdns.SHA1, Iterations: 1, Salt: "AABBCCDD"}
+
+// nsecTestZones returns the NSEC signed "example.com." (apex, api and www)
+// and the NSEC3 signed "example.org." (apex, www and mail).
+func nsecTestZones() []*dnstest.Zone {
+
+	com := dnstest.MustZone("example.com",
+		"@ 300 IN NSEC api A NSEC",
+		"api 300 IN A 192.0.2.1",
+		"api 300 IN NSEC www A NSEC",
+		"www 300 IN A 192.0.2.2",
+		"www 300 IN NSEC @ A NSEC",
+	)
+
+	var hashes []string
+	for _, n := range []string{"example.org.", "www.example.org.", "mail.example.org."} {
+		hashes = append(hashes, NSEC3Hash(n, testNSEC3Params))
+	}
+	sort.Strings(hashes)
+
+	params := fmt.Sprintf("%d 0 %d %s", testNSEC3Params.Hash, testNSEC3Params.Iterations, testNSEC3Params.Salt)
+
+	org := []string{
+		"@ 0 IN NSEC3PARAM " + params,
+		"www 300 IN A 192.0.2.1",
+		"mail 300 IN A 192.0.2.2",
+	}
+
+	for i := range hashes {
+		org = append(org, strings.ToLower(hashes[i])+" 300 IN NSEC3 "+params+" "+hashes[(i+1)%len(hashes)]+" A")
+	}
+
+	return []*dnstest.Zone{com, dnstest.MustZone("example.org", org...)}
+}
+
+func TestServersWalkNSEC(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, nsecTestZones()...)
+
+	r, err := srvs.WalkNSEC("Example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	var names []string
+	for i := range r {
+		names = append(names, r[i].Name)
+	}
+
+	if strings.Join(names, ",") != "example.com.,api.example.com.,www.example.com." {
+		t.Fatalf("FAIL: Invalid names: %v\n", names)
+	}
+
+	if s := r[1].String(); s != "api.example.com. www.example.com. A NSEC" {
+		t.Fatalf("FAIL: Invalid string: %s\n", s)
+	}
+
+	_, err = srvs.WalkNSEC("example.org")
+	if !errors.Is(err, ErrNoNSEC) {
+		t.Fatalf("FAIL: Expected ErrNoNSEC, got: %v\n", err)
+	}
+}
+
+func TestServersCollectNSEC3(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, nsecTestZones()...)
+
+	c, err := srvs.CollectNSEC3("example.org", 100)
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if !c.Complete() {
+		t.Fatalf("FAIL: Chain is not complete: %v\n", c.Hashes)
+	}
+
+	if len(c.Hashes) != 3 || c.Params != testNSEC3Params {
+		t.Fatalf("FAIL: Invalid chain: %#v\n", c)
+	}
+
+	m, err := c.MatchReader(strings.NewReader("ftp\nWWW\nmail\nadmin\n"))
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(m) != 2 || m["www.example.org."] == "" || m["mail.example.org."] == "" {
+		t.Fatalf("FAIL: Invalid matches: %v\n", m)
+	}
+
+	_, err = srvs.CollectNSEC3("example.com", 100)
+	if !errors.Is(err, ErrNoNSEC3) {
+		t.Fatalf("FAIL: Expected ErrNoNSEC3, got: %v\n", err)
+	}
+}
+
+func TestNSEC3ChainComplete(t *testing.T) {
+
+	c := NSEC3Chain{Hashes: map[string]string{"A": "C", "C": "E"}}
+
+	if c.Complete() {
+		t.Fatalf("FAIL: Open chain is complete\n")
+	}
+
+	if !c.covered("B") || !c.covered("D") || c.covered("F") {
+		t.Fatalf("FAIL: Invalid coverage\n")
+	}
+
+	c.Hashes["E"] = "A"
+
+	if !c.Complete() || !c.covered("F") {
+		t.Fatalf("FAIL: Closed chain is not complete\n")
+	}
+}
@@ -1,7 +1,9 @@
 package dns
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/miekg/dns"
@@ -12,6 +14,12 @@ import (
 // network must be "tcp", "tcp-tls" or "udp".
 func Probe(network, ip, port string, timeout time.Duration) (bool, error) {
 
+	return ProbeContext(context.Background(), network, ip, port, timeout)
+}
+
+// ProbeContext is like Probe, but the query is bound to ctx.
+func ProbeContext(ctx context.Context, network, ip, port string, timeout time.Duration) (bool, error) {
+
 	if network != "tcp" && network != "tcp-tls" && network != "udp" {
 		return false, fmt.Errorf("invalid network: %s", network)
 	}
@@ -24,7 +32,24 @@ func Probe(network, ip, port string, timeout time.Duration) (bool, error) {
 	c.Net = network
 	c.Timeout = timeout
 
-	_, _, err := c.Exchange(m, ip+":"+port)
+	_, _, err := c.ExchangeContext(ctx, m, net.JoinHostPort(ip, port))
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
 
 	return err == nil, nil
 }
+
+// routable returns whether the local host has a route to ip (eg.: false for an IPv6 address on an IPv4 only host).
+// No packet is sent, only the route is looked up by connecting an UDP socket.
+func routable(ip string) bool {
+
+	c, err := net.Dial("udp", net.JoinHostPort(ip, "53"))
+	if err != nil {
+		return false
+	}
+
+	c.Close()
+
+	return true
+}
@@ -0,0 +1,464 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	mdns "github.com/miekg/dns"
+)
+
+var (
+	ErrNotPropagated = errors.New("not propagated")
+	ErrNoSOA         = errors.New("no SOA")
+	ErrNoAddress     = errors.New("no address")
+	ErrNoRoute       = errors.New("no route")
+)
+
+// PropagationConfig configures the propagation checks.
+type PropagationConfig struct {
+	Port     string        // Port of the nameservers, defaults to "53"
+	Timeout  time.Duration // Timeout of a query, defaults to 2 seconds
+	Interval time.Duration // Wait between the checks in WaitPropagation(), defaults to 5 seconds
+	Serial   int           // WaitPropagation() waits for at least this SOA serial (eg.: the serial after the change), 0 means any
+	Records  []Record      // WaitPropagation() waits for this answer (in any order), nil means any consistent answer
+	Family   int           // Address family of the nameserver addresses to check: 4 or 6, 0 means both
+}
+
+// NSResult is the result of the queries sent to an address of an authoritative nameserver.
+type NSResult struct {
+	NS            string   // Name of the nameserver
+	IP            string   // Empty if the address of NS cant be resolved
+	Serial        int      // Serial of the SOA of the zone, 0 if failed
+	Rcode         string   // Rcode of the answer, empty if failed
+	Authoritative bool     // The answer has the AA flag
+	Records       []Record // The sorted answer
+	Err           error    // The error of the queries
+	Unreachable   bool     // The local host has no route to IP (eg.: IPv6 on an IPv4 only host), Err is ErrNoRoute
+}
+
+// Propagation is the state of a name with type in the authoritative nameservers of a zone.
+type Propagation struct {
+	Zone    string
+	Name    string
+	Type    uint16
+	Results []NSResult // A result for every address of every nameserver, sorted by NS and IP
+}
+
+// Serial returns the highest SOA serial of the nameservers.
+func (p *Propagation) Serial() int {
+
+	var s int
+
+	for i := range p.Results {
+		if p.Results[i].Err == nil && p.Results[i].Serial > s {
+			s = p.Results[i].Serial
+		}
+	}
+
+	return s
+}
+
+// Serials returns the distinct SOA serials in ascending order.
+func (p *Propagation) Serials() []int {
+
+	var r []int
+
+	for i := range p.Results {
+
+		if p.Results[i].Err != nil {
+			continue
+		}
+
+		found := false
+		for ii := range r {
+			if r[ii] == p.Results[i].Serial {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			r = append(r, p.Results[i].Serial)
+		}
+	}
+
+	sort.Ints(r)
+
+	return r
+}
+
+// first returns the first result with a reachable address.
+// Returns nil if every address is unreachable.
+func (p *Propagation) first() *NSResult {
+
+	for i := range p.Results {
+		if !p.Results[i].Unreachable {
+			return &p.Results[i]
+		}
+	}
+
+	return nil
+}
+
+// InSync returns whether every nameserver answered authoritatively with the same serial, rcode and answer.
+// The unreachable addresses are excluded (see NSResult.Unreachable).
+// Returns false if p has no reachable result.
+func (p *Propagation) InSync() bool {
+
+	first := p.first()
+	if first == nil {
+		return false
+	}
+
+	for _, r := range p.Results {
+
+		if r.Unreachable {
+			continue
+		}
+
+		if r.Err != nil || !r.Authoritative {
+			return false
+		}
+
+		if r.Serial != first.Serial || r.Rcode != first.Rcode || !sameRecords(r.Records, first.Records) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Lagging returns the results that failed or differ from the nameservers with the highest serial.
+// The unreachable addresses are not included.
+func (p *Propagation) Lagging() []NSResult {
+
+	var (
+		serial = p.Serial()
+		ref    *NSResult
+		r      []NSResult
+	)
+
+	for i := range p.Results {
+		if p.Results[i].Err == nil && p.Results[i].Serial == serial {
+			ref = &p.Results[i]
+			break
+		}
+	}
+
+	for i := range p.Results {
+
+		v := p.Results[i]
+
+		if v.Unreachable {
+			continue
+		}
+
+		if v.Err != nil || ref == nil || v.Serial != serial || v.Rcode != ref.Rcode || !sameRecords(v.Records, ref.Records) {
+			r = append(r, v)
+		}
+	}
+
+	return r
+}
+
+// converged returns whether p is in sync and matches the serial and the records of conf.
+func (p *Propagation) converged(conf PropagationConfig) bool {
+
+	if !p.InSync() || p.Serial() < conf.Serial {
+		return false
+	}
+
+	if conf.Records != nil {
+
+		want := append([]Record(nil), conf.Records...)
+		sortRecords(want)
+
+		return sameRecords(p.first().Records, want)
+	}
+
+	return true
+}
+
+// String returns a summary of p, one line for every nameserver address.
+func (p *Propagation) String() string {
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s %s in %s: in sync: %v\n", p.Name, TypeToString(p.Type), p.Zone, p.InSync())
+
+	for _, r := range p.Results {
+
+		if r.Err != nil {
+			fmt.Fprintf(&b, "%s (%s): %s\n", r.NS, r.IP, r.Err)
+			continue
+		}
+
+		values := make([]string, 0, len(r.Records))
+		for i := range r.Records {
+			values = append(values, r.Records[i].Value)
+		}
+
+		fmt.Fprintf(&b, "%s (%s): serial=%d aa=%v %s [%s]\n", r.NS, r.IP, r.Serial, r.Authoritative, r.Rcode, strings.Join(values, " "))
+	}
+
+	return b.String()
+}
+
+// sortRecords sorts rs by type and value.
+func sortRecords(rs []Record) {
+
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].Type != rs[j].Type {
+			return rs[i].Type < rs[j].Type
+		}
+		return rs[i].Value < rs[j].Value
+	})
+}
+
+// sameRecords returns whether the sorted a and b are equal.
+func sameRecords(a, b []Record) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CheckPropagation resolves the NS set of zone with s, and asks every IPv4 and IPv6 address (see conf.Family) of every nameserver
+// directly for the SOA of zone and for name with type t.
+// The nameserver addresses are queried concurrently, the failures are reported in the NSResults.
+// The addresses without local route are not queried, they are reported as Unreachable and excluded from InSync().
+//
+// Returns ErrNoNameservers if zone has no NS record.
+func (s *Servers) CheckPropagation(zone string, name string, t uint16, conf PropagationConfig) (*Propagation, error) {
+
+	return s.CheckPropagationContext(context.Background(), zone, name, t, conf)
+}
+
+// CheckPropagationContext is like CheckPropagation, but the queries are bound to ctx.
+func (s *Servers) CheckPropagationContext(ctx context.Context, zone string, name string, t uint16, conf PropagationConfig) (*Propagation, error) {
+
+	if conf.Port == "" {
+		conf.Port = "53"
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = 2 * time.Second
+	}
+
+	zone = strings.ToLower(mdns.Fqdn(zone))
+	name = strings.ToLower(mdns.Fqdn(name))
+
+	nss, err := s.TryQueryNSContext(ctx, zone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query NS: %w", err)
+	}
+
+	if len(nss) == 0 {
+		return nil, fmt.Errorf("%w for %s", ErrNoNameservers, zone)
+	}
+
+	p := &Propagation{Zone: zone, Name: name, Type: t}
+
+	for _, ns := range nss {
+
+		ns = strings.ToLower(ns)
+
+		var (
+			ips        []net.IP
+			err4, err6 error
+		)
+
+		if conf.Family != 6 {
+			var v4 []net.IP
+			v4, err4 = s.TryQueryAContext(ctx, ns)
+			ips = append(ips, v4...)
+		}
+
+		if conf.Family != 4 {
+			var v6 []net.IP
+			v6, err6 = s.TryQueryAAAAContext(ctx, ns)
+			ips = append(ips, v6...)
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if len(ips) == 0 {
+
+			err := errors.Join(err4, err6)
+			if err == nil {
+				err = ErrNoAddress
+			}
+
+			p.Results = append(p.Results, NSResult{NS: ns, Err: fmt.Errorf("failed to resolve nameserver: %w", err)})
+			continue
+		}
+
+		for i := range ips {
+			p.Results = append(p.Results, NSResult{NS: ns, IP: ips[i].String()})
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	for i := range p.Results {
+
+		if p.Results[i].IP == "" {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(r *NSResult) {
+			defer wg.Done()
+			r.check(ctx, zone, name, t, conf)
+		}(&p.Results[i])
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(p.Results, func(i, j int) bool {
+		if p.Results[i].NS != p.Results[j].NS {
+			return p.Results[i].NS < p.Results[j].NS
+		}
+		return p.Results[i].IP < p.Results[j].IP
+	})
+
+	return p, nil
+}
+
+// check asks r.IP for the SOA of zone and for name with type t and fills r.
+func (r *NSResult) check(ctx context.Context, zone string, name string, t uint16, conf PropagationConfig) {
+
+	if !routable(r.IP) {
+		r.Unreachable = true
+		r.Err = ErrNoRoute
+		return
+	}
+
+	srv, err := NewServer("udp", r.IP, conf.Port, conf.Timeout)
+	if err != nil {
+		r.Err = err
+		return
+	}
+
+	soa, err := srv.QuerySOAContext(ctx, zone)
+	if err != nil {
+		r.Err = fmt.Errorf("failed to query SOA: %w", err)
+		return
+	}
+
+	if soa == nil {
+		r.Err = ErrNoSOA
+		return
+	}
+
+	r.Serial = soa.Serial
+
+	in, err := srv.queryMsgContext(ctx, name, t)
+	if err != nil && (in == nil || !errors.Is(err, ErrName)) {
+		r.Err = fmt.Errorf("failed to query %s: %w", TypeToString(t), err)
+		return
+	}
+
+	r.Rcode = mdns.RcodeToString[in.Rcode]
+	r.Authoritative = in.Authoritative
+
+	r.Records, err = toRecords(in.Answer)
+	if err != nil {
+		r.Err = err
+		return
+	}
+
+	sortRecords(r.Records)
+}
+
+// propagationAfter waits for the next check of WaitPropagation, the tests replace it to drive the checks.
+var propagationAfter = time.After
+
+// WaitPropagation checks the propagation (see CheckPropagation()) every conf.Interval until every nameserver is in sync
+// with at least conf.Serial and with the answer conf.Records (if set), or ctx is done (eg.: use context.WithTimeout()).
+// The failed checks (eg.: the NS set can not be resolved) are retried in the next interval.
+//
+// Returns the last Propagation. If ctx is done before the convergence, returns the last Propagation along with
+// ErrNotPropagated wrapping ctx.Err() and the error of the last check (if failed).
+func (s *Servers) WaitPropagation(ctx context.Context, zone string, name string, t uint16, conf PropagationConfig) (*Propagation, error) {
+
+	if conf.Interval <= 0 {
+		conf.Interval = 5 * time.Second
+	}
+
+	var (
+		last    *Propagation
+		lastErr error
+	)
+
+	notPropagated := func() error {
+		if lastErr != nil {
+			return fmt.Errorf("%w: %w (last error: %w)", ErrNotPropagated, ctx.Err(), lastErr)
+		}
+		return fmt.Errorf("%w: %w", ErrNotPropagated, ctx.Err())
+	}
+
+	for {
+
+		p, err := s.CheckPropagationContext(ctx, zone, name, t, conf)
+
+		switch {
+		case ctx.Err() != nil:
+			return last, notPropagated()
+		case err != nil:
+			lastErr = err
+		default:
+			last, lastErr = p, nil
+			if p.converged(conf) {
+				return p, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return last, notPropagated()
+		case <-propagationAfter(conf.Interval):
+		}
+	}
+}
+
+// CheckPropagation checks the propagation of name with type t in zone using the DefaultServers to resolve the nameservers.
+// See Servers.CheckPropagation().
+func CheckPropagation(zone string, name string, t uint16, conf PropagationConfig) (*Propagation, error) {
+
+	return DefaultServers.CheckPropagationContext(context.Background(), zone, name, t, conf)
+}
+
+// CheckPropagationContext is like CheckPropagation, but the queries are bound to ctx.
+func CheckPropagationContext(ctx context.Context, zone string, name string, t uint16, conf PropagationConfig) (*Propagation, error) {
+
+	return DefaultServers.CheckPropagationContext(ctx, zone, name, t, conf)
+}
+
+// WaitPropagation waits for the propagation of name with type t in zone using the DefaultServers to resolve the nameservers.
+// See Servers.WaitPropagation().
+func WaitPropagation(ctx context.Context, zone string, name string, t uint16, conf PropagationConfig) (*Propagation, error) {
+
+	return DefaultServers.WaitPropagation(ctx, zone, name, t, conf)
+}
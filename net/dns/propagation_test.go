@@ -0,0 +1,235 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
+	mdns "github.com/miekg/dns"
+)
+
+// propagationZone returns "example.com." with the SOA serial, the nameservers ns1 (127.0.0.1) and ns2 (127.0.0.2), the A record of www
+// and the additional records.
+func propagationZone(serial string, www string, records ...string) *dnstest.Zone {
+
+	return dnstest.MustZone("example.com", append([]string{
+		"@ 300 IN SOA ns1 hostmaster " + serial + " 7200 3600 1209600 300",
+		"@ 300 IN NS ns1",
+		"@ 300 IN NS ns2",
+		"ns1 300 IN A 127.0.0.1",
+		"ns2 300 IN A 127.0.0.2",
+		"www 300 IN A " + www,
+	}, records...)...)
+}
+
+func TestCheckPropagation(t *testing.T) {
+
+	srvs, _, port := newZoneTestServersOnIPs(t, []*dnstest.Zone{propagationZone("2", "192.0.2.2")}, []*dnstest.Zone{propagationZone("1", "192.0.2.1")})
+
+	p, err := srvs.CheckPropagation("Example.com", "www.example.com", TypeA, PropagationConfig{Port: port, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(p.Results) != 2 || p.Results[0].NS != "ns1.example.com." || p.Results[1].IP != "127.0.0.2" {
+		t.Fatalf("FAIL: Invalid results: %s\n", p)
+	}
+
+	if p.InSync() {
+		t.Fatalf("FAIL: Nameservers are in sync:\n%s\n", p)
+	}
+
+	if s := p.Serials(); len(s) != 2 || s[0] != 1 || s[1] != 2 || p.Serial() != 2 {
+		t.Fatalf("FAIL: Invalid serials: %v\n", s)
+	}
+
+	l := p.Lagging()
+	if len(l) != 1 || l[0].NS != "ns2.example.com." || l[0].Records[0].Value != "192.0.2.1" {
+		t.Fatalf("FAIL: Invalid lagging: %v\n", l)
+	}
+}
+
+func TestCheckPropagationNXDOMAIN(t *testing.T) {
+
+	srvs, _, port := newZoneTestServersOnIPs(t, []*dnstest.Zone{propagationZone("1", "192.0.2.1")}, []*dnstest.Zone{propagationZone("1", "192.0.2.1")})
+
+	p, err := srvs.CheckPropagation("example.com", "invalid.example.com", TypeA, PropagationConfig{Port: port, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if !p.InSync() || p.Results[0].Rcode != "NXDOMAIN" || len(p.Results[0].Records) != 0 {
+		t.Fatalf("FAIL: Invalid propagation:\n%s\n", p)
+	}
+}
+
+// drivePropagation replaces the wait of WaitPropagation: the nth wait calls fn(n) and returns the channel returned by fn.
+func drivePropagation(t *testing.T, fn func(n int) <-chan time.Time) {
+
+	n := 0
+
+	propagationAfter = func(time.Duration) <-chan time.Time {
+		n++
+		return fn(n)
+	}
+
+	t.Cleanup(func() { propagationAfter = time.After })
+}
+
+// fire returns a channel that is ready to receive.
+func fire() <-chan time.Time {
+
+	c := make(chan time.Time, 1)
+	c <- time.Now()
+
+	return c
+}
+
+func TestWaitPropagation(t *testing.T) {
+
+	srvs, ts, port := newZoneTestServersOnIPs(t, []*dnstest.Zone{propagationZone("2", "192.0.2.2")}, []*dnstest.Zone{propagationZone("1", "192.0.2.1")})
+	ns2 := ts[1]
+
+	conf := PropagationConfig{
+		Port:     port,
+		Timeout:  time.Second,
+		Interval: time.Hour,
+		Serial:   2,
+		Records:  []Record{{Type: TypeA, Value: "192.0.2.2"}},
+	}
+
+	// Never converges, cancelled at the second wait
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	waits := 0
+
+	drivePropagation(t, func(n int) <-chan time.Time {
+		waits = n
+		if n == 2 {
+			cancel()
+			return nil
+		}
+		return fire()
+	})
+
+	p, err := srvs.WaitPropagation(ctx, "example.com", "www.example.com", TypeA, conf)
+	if !errors.Is(err, ErrNotPropagated) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("FAIL: Expected ErrNotPropagated, got: %v\n", err)
+	}
+
+	if p == nil || p.InSync() || waits != 2 {
+		t.Fatalf("FAIL: Invalid last propagation after %d waits: %v\n", waits, p)
+	}
+
+	// ns2 receives the change at the second wait
+	drivePropagation(t, func(n int) <-chan time.Time {
+		waits = n
+		if n == 2 {
+			ns2.AddZone(propagationZone("2", "192.0.2.2"))
+		}
+		return fire()
+	})
+
+	p, err = srvs.WaitPropagation(context.Background(), "example.com", "www.example.com", TypeA, conf)
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if !p.InSync() || p.Serial() != 2 || waits != 2 {
+		t.Fatalf("FAIL: Not propagated after %d waits:\n%s\n", waits, p)
+	}
+}
+
+func TestCheckPropagationFamily(t *testing.T) {
+
+	// The IPv6 address of ns2 is not served
+	z := propagationZone("1", "192.0.2.1", "ns2 300 IN AAAA 2001:db8::53")
+
+	srvs, _, port := newZoneTestServersOnIPs(t, []*dnstest.Zone{z}, []*dnstest.Zone{z})
+
+	p, err := srvs.CheckPropagation("example.com", "www.example.com", TypeA, PropagationConfig{Port: port, Timeout: time.Second, Family: 4})
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(p.Results) != 2 || !p.InSync() {
+		t.Fatalf("FAIL: Invalid IPv4 propagation:\n%s\n", p)
+	}
+
+	p, err = srvs.CheckPropagation("example.com", "www.example.com", TypeA, PropagationConfig{Port: port, Timeout: 100 * time.Millisecond, Family: 6})
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(p.Results) != 2 || p.Results[1].IP != "2001:db8::53" || p.InSync() {
+		t.Fatalf("FAIL: Invalid IPv6 propagation:\n%s\n", p)
+	}
+}
+
+func TestPropagationUnreachable(t *testing.T) {
+
+	p := &Propagation{Results: []NSResult{
+		{NS: "ns1.example.com.", IP: "2001:db8::1", Err: ErrNoRoute, Unreachable: true},
+		{NS: "ns1.example.com.", IP: "192.0.2.1", Serial: 2, Rcode: "NOERROR", Authoritative: true},
+		{NS: "ns2.example.com.", IP: "192.0.2.2", Serial: 2, Rcode: "NOERROR", Authoritative: true},
+	}}
+
+	if !p.InSync() || len(p.Lagging()) != 0 || !p.converged(PropagationConfig{Serial: 2, Records: []Record{}}) {
+		t.Fatalf("FAIL: Unreachable address is not excluded:\n%s\n", p)
+	}
+
+	p.Results = p.Results[:1]
+
+	if p.InSync() {
+		t.Fatalf("FAIL: Only unreachable addresses are in sync\n")
+	}
+}
+
+func TestWaitPropagationRetry(t *testing.T) {
+
+	z := propagationZone("1", "192.0.2.1")
+
+	srvs, ts, port := newZoneTestServersOnIPs(t, []*dnstest.Zone{z}, []*dnstest.Zone{z})
+	ns1 := ts[0]
+
+	// The NS set can not be resolved
+	ns1.SetRule("example.com", TypeNS, dnstest.Rule{Rcode: mdns.RcodeServerFailure})
+
+	conf := PropagationConfig{Port: port, Timeout: time.Second, Interval: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	waits := 0
+
+	drivePropagation(t, func(n int) <-chan time.Time {
+		waits = n
+		if n == 2 {
+			cancel()
+			return nil
+		}
+		return fire()
+	})
+
+	_, err := srvs.WaitPropagation(ctx, "example.com", "www.example.com", TypeA, conf)
+	if !errors.Is(err, ErrNotPropagated) || !errors.Is(err, ErrServerFailure) || waits != 2 {
+		t.Fatalf("FAIL: Expected ErrNotPropagated with the last error after 2 waits, got: %v after %d waits\n", err, waits)
+	}
+
+	// The NS set is fixed at the second wait
+	drivePropagation(t, func(n int) <-chan time.Time {
+		waits = n
+		if n == 2 {
+			ns1.ResetRules()
+		}
+		return fire()
+	})
+
+	p, err := srvs.WaitPropagation(context.Background(), "example.com", "www.example.com", TypeA, conf)
+	if err != nil || !p.InSync() || waits != 2 {
+		t.Fatalf("FAIL: Not propagated after %d waits: %v\n%v\n", waits, err, p)
+	}
+}
@@ -0,0 +1,222 @@
+package dns
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// PublicSuffixListURL is the URL of the current Public Suffix List.
+const PublicSuffixListURL = "https://publicsuffix.org/list/public_suffix_list.dat"
+
+var (
+	// suffixList is the list used by GetTLD() and the functions built on it, nil means the compiled-in table.
+	suffixList atomic.Pointer[SuffixList]
+
+	// privateSuffixes makes the private suffixes registrable boundaries.
+	privateSuffixes atomic.Bool
+)
+
+// SuffixList is a parsed Public Suffix List.
+// The normal, wildcard ("*.ck") and exception ("!www.ck") rules are supported.
+// The rules are stored in ASCII (punycode) form.
+//
+// See more: https://github.com/publicsuffix/list/wiki/Format
+type SuffixList struct {
+	rules      map[string]bool // Rule -> private
+	wildcards  map[string]bool // Parent of the wildcard ("ck" for "*.ck") -> private
+	exceptions map[string]bool // Exception without "!" -> private
+}
+
+// ParseSuffixList parses the Public Suffix List from r.
+// The rules between the "===BEGIN PRIVATE DOMAINS===" and "===END PRIVATE DOMAINS===" markers are private.
+func ParseSuffixList(r io.Reader) (*SuffixList, error) {
+
+	l := &SuffixList{
+		rules:      make(map[string]bool),
+		wildcards:  make(map[string]bool),
+		exceptions: make(map[string]bool),
+	}
+
+	var (
+		private bool
+		n       int
+	)
+
+	s := bufio.NewScanner(r)
+
+	for s.Scan() {
+
+		n++
+
+		line := strings.TrimSpace(s.Text())
+
+		switch {
+		case strings.HasPrefix(line, "//"):
+			if strings.Contains(line, "===BEGIN PRIVATE DOMAINS===") {
+				private = true
+			} else if strings.Contains(line, "===END PRIVATE DOMAINS===") {
+				private = false
+			}
+			continue
+		case line == "":
+			continue
+		}
+
+		// The rule is the first field of the line
+		rule := strings.Fields(line)[0]
+
+		exception := strings.HasPrefix(rule, "!")
+		rule = strings.TrimPrefix(rule, "!")
+
+		wildcard := strings.HasPrefix(rule, "*.")
+		rule = strings.TrimPrefix(rule, "*.")
+
+		rule, err := idna.Lookup.ToASCII(rule)
+		if err != nil || strings.Contains(rule, "*") || (exception && wildcard) {
+			return nil, fmt.Errorf("invalid rule in line %d: %s", n, line)
+		}
+
+		switch {
+		case exception:
+			l.exceptions[rule] = private
+		case wildcard:
+			l.wildcards[rule] = private
+		default:
+			l.rules[rule] = private
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read list: %w", err)
+	}
+
+	if l.Len() == 0 {
+		return nil, fmt.Errorf("empty list")
+	}
+
+	return l, nil
+}
+
+// LoadSuffixList parses the Public Suffix List file at path.
+func LoadSuffixList(path string) (*SuffixList, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open list: %w", err)
+	}
+	defer f.Close()
+
+	return ParseSuffixList(f)
+}
+
+// FetchSuffixList downloads and parses the Public Suffix List from url (eg.: PublicSuffixListURL).
+// If client is nil, http.DefaultClient is used.
+func FetchSuffixList(ctx context.Context, client *http.Client, url string) (*SuffixList, error) {
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download list: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download list: %s", resp.Status)
+	}
+
+	return ParseSuffixList(resp.Body)
+}
+
+// Len returns the number of rules in l.
+func (l *SuffixList) Len() int {
+
+	return len(l.rules) + len(l.wildcards) + len(l.exceptions)
+}
+
+// PublicSuffix returns the public suffix of domain d and whether the matching rule is an ICANN rule.
+// If no rule matches, the last label is returned with false (the "*" default rule).
+// It is like golang.org/x/net/publicsuffix.PublicSuffix(), but uses the rules of l.
+func (l *SuffixList) PublicSuffix(d string) (string, bool) {
+
+	d = strings.ToLower(d)
+
+	labels := strings.Split(d, ".")
+
+	var (
+		suffix  = labels[len(labels)-1]
+		private = true
+	)
+
+	// Walk the suffixes from the shortest to the longest, the longest matching rule wins
+	for i := len(labels) - 1; i >= 0; i-- {
+
+		s := strings.Join(labels[i:], ".")
+
+		// The exception rules take priority, the public suffix is the exception without its first label
+		if p, ok := l.exceptions[s]; ok {
+			return strings.Join(labels[i+1:], "."), !p
+		}
+
+		if p, ok := l.rules[s]; ok {
+			suffix, private = s, p
+		}
+
+		if i < len(labels)-1 {
+			if p, ok := l.wildcards[strings.Join(labels[i+1:], ".")]; ok {
+				suffix, private = s, p
+			}
+		}
+	}
+
+	return suffix, !private
+}
+
+// SetSuffixList sets the Public Suffix List used by GetTLD() and the functions built on it.
+// Set l to nil to use the compiled-in table of golang.org/x/net/publicsuffix (default).
+// It is safe to call concurrently with the domain functions.
+func SetSuffixList(l *SuffixList) {
+
+	suffixList.Store(l)
+}
+
+// GetSuffixList returns the Public Suffix List set by SetSuffixList().
+// Returns nil if the compiled-in table is used.
+func GetSuffixList() *SuffixList {
+
+	return suffixList.Load()
+}
+
+// SetPrivateSuffixes sets whether the private suffixes (eg.: "github.io") are registrable boundaries.
+// If enabled, GetTLD("foo.github.io") returns "github.io" and GetDomain() returns "foo.github.io".
+// By default, the private suffixes are skipped and only the ICANN suffixes are used.
+func SetPrivateSuffixes(enabled bool) {
+
+	privateSuffixes.Store(enabled)
+}
+
+// publicSuffix returns the public suffix of d with the list set by SetSuffixList() or the compiled-in table.
+func publicSuffix(d string) (string, bool) {
+
+	if l := suffixList.Load(); l != nil {
+		return l.PublicSuffix(d)
+	}
+
+	return publicsuffix.PublicSuffix(d)
+}
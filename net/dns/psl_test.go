@@ -0,0 +1,114 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+)
+
+var testSuffixList = `// ===BEGIN ICANN DOMAINS===
+com
+uk
+co.uk
+ck
+*.ck
+!www.ck
+jp
+*.kobe.jp
+!city.kobe.jp
+hu
+// Internationalized rule
+公司.hu
+// ===END ICANN DOMAINS===
+
+// ===BEGIN PRIVATE DOMAINS===
+github.io
+*.compute.example.com
+// ===END PRIVATE DOMAINS===
+`
+
+func TestSuffixListPublicSuffix(t *testing.T) {
+
+	l, err := ParseSuffixList(strings.NewReader(testSuffixList))
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	cases := []struct {
+		D      string
+		Suffix string
+		ICANN  bool
+	}{
+		{D: "example.com", Suffix: "com", ICANN: true},
+		{D: "www.example.co.uk", Suffix: "co.uk", ICANN: true},
+		{D: "a.b.ck", Suffix: "b.ck", ICANN: true},
+		{D: "www.ck", Suffix: "ck", ICANN: true},
+		{D: "a.www.ck", Suffix: "ck", ICANN: true},
+		{D: "a.b.kobe.jp", Suffix: "b.kobe.jp", ICANN: true},
+		{D: "a.city.kobe.jp", Suffix: "kobe.jp", ICANN: true},
+		{D: "example.xn--55qx5d.hu", Suffix: "xn--55qx5d.hu", ICANN: true},
+		{D: "foo.github.io", Suffix: "github.io", ICANN: false},
+		{D: "a.b.compute.example.com", Suffix: "b.compute.example.com", ICANN: false},
+		{D: "example.unknown", Suffix: "unknown", ICANN: false},
+		{D: "Example.COM", Suffix: "com", ICANN: true},
+	}
+
+	for i := range cases {
+
+		s, icann := l.PublicSuffix(cases[i].D)
+
+		if s != cases[i].Suffix || icann != cases[i].ICANN {
+			t.Fatalf("FAIL: %s: got %s %v, want: %s %v\n", cases[i].D, s, icann, cases[i].Suffix, cases[i].ICANN)
+		}
+	}
+
+	if _, err := ParseSuffixList(strings.NewReader("// comment only\n")); err == nil {
+		t.Fatalf("FAIL: Empty list parsed\n")
+	}
+
+	if _, err := ParseSuffixList(strings.NewReader("!*.ck\n")); err == nil {
+		t.Fatalf("FAIL: Invalid rule parsed\n")
+	}
+}
+
+func TestSetSuffixList(t *testing.T) {
+
+	l, err := ParseSuffixList(strings.NewReader(testSuffixList))
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	SetSuffixList(l)
+	t.Cleanup(func() { SetSuffixList(nil) })
+
+	if GetSuffixList() != l {
+		t.Fatalf("FAIL: List is not set\n")
+	}
+
+	if d := GetDomain("www.a.b.ck"); d != "a.b.ck" {
+		t.Fatalf("FAIL: Invalid domain of www.a.b.ck: %s\n", d)
+	}
+
+	// The private suffixes are skipped by default
+	if d := GetDomain("foo.github.io"); d != "github.io" || !HasSub("foo.github.io") {
+		t.Fatalf("FAIL: Invalid domain of foo.github.io: %s\n", d)
+	}
+
+	SetPrivateSuffixes(true)
+	t.Cleanup(func() { SetPrivateSuffixes(false) })
+
+	if d := GetDomain("foo.github.io"); d != "foo.github.io" || HasSub("foo.github.io") {
+		t.Fatalf("FAIL: Invalid domain of foo.github.io in private mode: %s\n", d)
+	}
+
+	p := GetParts("www.foo.github.io")
+	if p == nil || p.TLD != "github.io" || p.Domain != "foo" || p.Sub != "www" {
+		t.Fatalf("FAIL: Invalid parts of www.foo.github.io: %#v\n", p)
+	}
+
+	// The compiled-in table in private mode
+	SetSuffixList(nil)
+
+	if tld := GetTLD("foo.github.io"); tld != "github.io" {
+		t.Fatalf("FAIL: Invalid TLD of foo.github.io with the compiled-in table: %s\n", tld)
+	}
+}
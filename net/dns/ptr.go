@@ -0,0 +1,355 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/g0rbe/gmod/net/ip"
+	mdns "github.com/miekg/dns"
+)
+
+var TypePTR uint16 = 12
+
+// PTRResult is the result of a reverse lookup in ReverseSweep.
+type PTRResult struct {
+	IP    net.IP
+	Names []string // PTR targets
+	Err   error    // ErrX, context error or any unknown error
+}
+
+// reverseName returns the reverse name (in-addr.arpa or ip6.arpa) of name if name is an IP address, else returns name.
+func reverseName(name string) string {
+
+	if net.ParseIP(name) == nil {
+		return name
+	}
+
+	r, err := mdns.ReverseAddr(name)
+	if err != nil {
+		return name
+	}
+
+	return r
+}
+
+// reverseToIP converts the reverse name (in-addr.arpa or ip6.arpa) to IP address.
+// Returns nil if name is not a valid reverse name.
+func reverseToIP(name string) net.IP {
+
+	name = strings.ToLower(mdns.Fqdn(name))
+
+	switch {
+	case strings.HasSuffix(name, ".in-addr.arpa."):
+
+		labels := strings.Split(strings.TrimSuffix(name, ".in-addr.arpa."), ".")
+		if len(labels) != 4 {
+			return nil
+		}
+
+		return net.ParseIP(labels[3] + "." + labels[2] + "." + labels[1] + "." + labels[0]).To4()
+
+	case strings.HasSuffix(name, ".ip6.arpa."):
+
+		labels := strings.Split(strings.TrimSuffix(name, ".ip6.arpa."), ".")
+		if len(labels) != 32 {
+			return nil
+		}
+
+		var b strings.Builder
+
+		for i := 31; i >= 0; i-- {
+			b.WriteString(labels[i])
+			if i%4 == 0 && i != 0 {
+				b.WriteByte(':')
+			}
+		}
+
+		return net.ParseIP(b.String())
+
+	default:
+		return nil
+	}
+}
+
+// parsePTR converts the answer rr to PTR.
+func parsePTR(rr []mdns.RR) ([]string, error) {
+
+	r := make([]string, 0, len(rr))
+
+	for i := range rr {
+
+		switch v := rr[i].(type) {
+		case *mdns.PTR:
+			r = append(r, v.Ptr)
+		case *mdns.CNAME:
+			// Ignore CNAME (RFC 2317 classless delegation)
+			continue
+		case *mdns.DNAME:
+			// Ignore DNAME
+			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
+		default:
+			return nil, fmt.Errorf("unknown type: %T", v)
+		}
+	}
+
+	return r, nil
+}
+
+// QueryPTR ask the server and returns a slice of string.
+// Name can be an IP address (converted to the in-addr.arpa or ip6.arpa name) or a reverse name.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QueryPTR(name string) ([]string, error) {
+
+	return s.QueryPTRContext(context.Background(), name)
+}
+
+// QueryPTRContext is like QueryPTR, but the query is bound to ctx.
+func (s *Server) QueryPTRContext(ctx context.Context, name string) ([]string, error) {
+
+	rr, err := s.queryContext(ctx, reverseName(name), TypePTR)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePTR(rr)
+}
+
+// QueryPTR ask a random server from servers and returns a slice of string.
+// Name can be an IP address (converted to the in-addr.arpa or ip6.arpa name) or a reverse name.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Servers) QueryPTR(name string) ([]string, error) {
+
+	return s.QueryPTRContext(context.Background(), name)
+}
+
+// QueryPTRContext is like QueryPTR, but the query is bound to ctx.
+func (s *Servers) QueryPTRContext(ctx context.Context, name string) ([]string, error) {
+
+	return s.Get(-1).QueryPTRContext(ctx, name)
+}
+
+// QueryPTR asks the DefaultResolver and returns a slice of string.
+// Name can be an IP address (converted to the in-addr.arpa or ip6.arpa name) or a reverse name.
+// Unlike Servers.QueryPTR, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QueryPTR is the same as TryQueryPTR.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func QueryPTR(name string) ([]string, error) {
+
+	return QueryPTRContext(context.Background(), name)
+}
+
+// QueryPTRContext is like QueryPTR, but the query is bound to ctx.
+func QueryPTRContext(ctx context.Context, name string) ([]string, error) {
+
+	return QueryPTRWith(ctx, DefaultResolver, name)
+}
+
+// TryQueryPTR asks the servers for type PTR. If any error occurred, retries with next server (except if error is NXDOMAIN).
+// Name can be an IP address (converted to the in-addr.arpa or ip6.arpa name) or a reverse name.
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func (s *Servers) TryQueryPTR(name string) ([]string, error) {
+
+	return s.TryQueryPTRContext(context.Background(), name)
+}
+
+// TryQueryPTRContext is like TryQueryPTR, but the query is bound to ctx.
+func (s *Servers) TryQueryPTRContext(ctx context.Context, name string) ([]string, error) {
+
+	return QueryPTRWith(ctx, s, name)
+}
+
+// TryQueryPTR asks the DefaultResolver for type PTR. If any error occurred, retries with next server (except if error is NXDOMAIN).
+// Name can be an IP address (converted to the in-addr.arpa or ip6.arpa name) or a reverse name.
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func TryQueryPTR(name string) ([]string, error) {
+
+	return TryQueryPTRContext(context.Background(), name)
+}
+
+// TryQueryPTRContext is like TryQueryPTR, but the query is bound to ctx.
+func TryQueryPTRContext(ctx context.Context, name string) ([]string, error) {
+
+	return QueryPTRWith(ctx, DefaultResolver, name)
+}
+
+// QueryPTRWith asks r for type PTR and converts the answer like QueryPTR.
+// Name can be an IP address (converted to the in-addr.arpa or ip6.arpa name) or a reverse name.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QueryPTRWith(ctx context.Context, r Resolver, name string) ([]string, error) {
+
+	rr, err := r.TryQueryContext(ctx, reverseName(name), TypePTR)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePTR(rr)
+}
+
+// IsSetPTR checks whether an PTR type record set for name.
+// Name can be an IP address (converted to the in-addr.arpa or ip6.arpa name) or a reverse name.
+// NXDOMAIN is not an error here, because it means "not found".
+func (s *Servers) IsSetPTR(name string) (bool, error) {
+	return s.IsSetPTRContext(context.Background(), name)
+}
+
+// IsSetPTRContext is like IsSetPTR, but the query is bound to ctx.
+func (s *Servers) IsSetPTRContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, reverseName(name), TypePTR)
+}
+
+// IsSetPTR checks whether an PTR type record set for name using the DefaultResolver.
+// Name can be an IP address (converted to the in-addr.arpa or ip6.arpa name) or a reverse name.
+// NXDOMAIN is not an error here, because it means "not found".
+func IsSetPTR(name string) (bool, error) {
+	return IsSetPTRContext(context.Background(), name)
+}
+
+// IsSetPTRContext is like IsSetPTR, but the query is bound to ctx.
+func IsSetPTRContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, reverseName(name), TypePTR)
+}
+
+// ReverseSweep resolves the PTR records of every address in n (including the first and the last address) concurrently
+// and streams the results through the returned channel. The addresses are listed with ip.GetListContext().
+//
+// Only the addresses with PTR records and the failed lookups are sent, NXDOMAIN and empty answers are skipped.
+//
+// The returned channel is closed when every address is resolved or ctx is done.
+// Returns error if n is invalid.
+func (s *Servers) ReverseSweep(ctx context.Context, n net.IPNet, conf BulkConfig) (<-chan PTRResult, error) {
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	first, last, usable, err := ip.GetListContext(ctx, n)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	results := make(chan PTRResult)
+
+	go func() {
+		defer cancel()
+		s.reverseSweep(ctx, first, last, usable, conf, func(r PTRResult) {
+			select {
+			case results <- r:
+			case <-ctx.Done():
+			}
+		})
+		close(results)
+	}()
+
+	return results, nil
+}
+
+// ReverseSweepFunc is like ReverseSweep, but calls fn with every result instead of streaming them through a channel.
+// fn is called concurrently from the workers.
+//
+// Returns when every address is resolved (nil), when ctx is done (ctx.Err()) or if n is invalid.
+func (s *Servers) ReverseSweepFunc(ctx context.Context, n net.IPNet, conf BulkConfig, fn func(PTRResult)) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	first, last, usable, err := ip.GetListContext(ctx, n)
+	if err != nil {
+		return err
+	}
+
+	return s.reverseSweep(ctx, first, last, usable, conf, fn)
+}
+
+func (s *Servers) reverseSweep(ctx context.Context, first, last net.IP, usable <-chan net.IP, conf BulkConfig, fn func(PTRResult)) error {
+
+	jobs := make(chan BulkJob)
+
+	go func() {
+
+		defer close(jobs)
+
+		send := func(v net.IP) bool {
+
+			name, err := mdns.ReverseAddr(v.String())
+			if err != nil {
+				return true
+			}
+
+			select {
+			case jobs <- BulkJob{Name: name, Type: TypePTR}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if !send(first) {
+			return
+		}
+
+		// usable is nil for /32, /31, /128 and /127
+		if usable != nil {
+			for v := range usable {
+				if !send(v) {
+					return
+				}
+			}
+		}
+
+		if last != nil {
+			send(last)
+		}
+	}()
+
+	return s.BulkFunc(ctx, jobs, conf, func(r BulkResult) {
+
+		res := PTRResult{IP: reverseToIP(r.Job.Name), Err: r.Err}
+
+		if errors.Is(r.Err, ErrName) {
+			return
+		}
+
+		for _, rr := range r.Answer {
+			if v, ok := rr.(*mdns.PTR); ok {
+				res.Names = append(res.Names, v.Ptr)
+			}
+		}
+
+		if res.Err == nil && len(res.Names) == 0 {
+			return
+		}
+
+		fn(res)
+	})
+}
+
+// ReverseSweep resolves the PTR records of every address in n using the DefaultServers.
+// See Servers.ReverseSweep().
+func ReverseSweep(ctx context.Context, n net.IPNet, conf BulkConfig) (<-chan PTRResult, error) {
+
+	return DefaultServers.ReverseSweep(ctx, n, conf)
+}
+
+// ReverseSweepFunc is like ReverseSweep, but calls fn with every result instead of streaming them through a channel.
+// See Servers.ReverseSweepFunc().
+func ReverseSweepFunc(ctx context.Context, n net.IPNet, conf BulkConfig, fn func(PTRResult)) error {
+
+	return DefaultServers.ReverseSweepFunc(ctx, n, conf, fn)
+}
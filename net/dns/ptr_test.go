@@ -0,0 +1,218 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
+	mdns "github.com/miekg/dns"
+)
+
+func TestReverseToIP(t *testing.T) {
+
+	for _, v := range []string{"192.0.2.1", "2001:db8::1", "2001:db8:1:2:3:4:5:6"} {
+
+		name, err := mdns.ReverseAddr(v)
+		if err != nil {
+			t.Fatalf("FAIL: %s\n", err)
+		}
+
+		if ip := reverseToIP(name); !ip.Equal(net.ParseIP(v)) {
+			t.Fatalf("FAIL: %s: IP wanted: %s, IP got: %s\n", name, v, ip)
+		}
+	}
+
+	for _, v := range []string{"example.com", "1.2.in-addr.arpa.", "1.ip6.arpa."} {
+		if ip := reverseToIP(v); ip != nil {
+			t.Fatalf("FAIL: %s: nil wanted, got: %s\n", v, ip)
+		}
+	}
+}
+
+// newPTRTestServers returns Servers with a dnstest.Server that answers the PTR queries of
+// 192.0.2.1, 192.0.2.6 and 2001:db8::2 with "host-<ip>.example.com.", SERVFAIL for 192.0.2.3 and NXDOMAIN for the others.
+func newPTRTestServers(t *testing.T) Servers {
+
+	var records []string
+
+	for _, ip := range []string{"192.0.2.1", "192.0.2.6", "2001:db8::2"} {
+		name, _ := mdns.ReverseAddr(ip)
+		records = append(records, name+" 60 IN PTR host-"+strings.ReplaceAll(ip, ":", "-")+".example.com.")
+	}
+
+	srvs, ts := newZoneTestServers(t,
+		dnstest.MustZone("2.0.192.in-addr.arpa", records[:2]...),
+		dnstest.MustZone("8.b.d.0.1.0.0.2.ip6.arpa", records[2:]...),
+	)
+
+	name, _ := mdns.ReverseAddr("192.0.2.3")
+	ts.SetRule(name, 0, dnstest.Rule{Rcode: mdns.RcodeServerFailure})
+
+	return srvs
+}
+
+func TestQueryPTR(t *testing.T) {
+
+	srvs := newPTRTestServers(t)
+
+	r, err := srvs.Get(0).QueryPTR("192.0.2.1")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(r) != 1 || r[0] != "host-192.0.2.1.example.com." {
+		t.Fatalf("FAIL: Invalid answer: %v\n", r)
+	}
+
+	r, err = srvs.TryQueryPTR("2.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(r) != 1 || r[0] != "host-2001-db8--2.example.com." {
+		t.Fatalf("FAIL: Invalid answer: %v\n", r)
+	}
+
+	if _, err = srvs.TryQueryPTR("192.0.2.2"); !errors.Is(err, ErrName) {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", ErrName, err)
+	}
+
+	set, err := srvs.IsSetPTR("192.0.2.6")
+	if err != nil || !set {
+		t.Fatalf("FAIL: PTR is not set for 192.0.2.6: %v\n", err)
+	}
+}
+
+func TestServersReverseSweep(t *testing.T) {
+
+	srvs := newPTRTestServers(t)
+
+	cases := []struct {
+		Net   string
+		Found string
+		Fail  string
+	}{
+		{Net: "192.0.2.0/29", Found: "192.0.2.1,192.0.2.6", Fail: "192.0.2.3"},
+		{Net: "2001:db8::/126", Found: "2001:db8::2", Fail: ""},
+	}
+
+	for i := range cases {
+
+		_, n, err := net.ParseCIDR(cases[i].Net)
+		if err != nil {
+			t.Fatalf("FAIL: %s\n", err)
+		}
+
+		ch, err := srvs.ReverseSweep(context.Background(), *n, BulkConfig{Workers: 4})
+		if err != nil {
+			t.Fatalf("FAIL: %s: %s\n", cases[i].Net, err)
+		}
+
+		var found, fail []string
+
+		for r := range ch {
+
+			if r.Err != nil {
+				fail = append(fail, r.IP.String())
+				continue
+			}
+
+			if len(r.Names) != 1 || !strings.HasPrefix(r.Names[0], "host-") {
+				t.Fatalf("FAIL: %s: Invalid names: %v\n", r.IP, r.Names)
+			}
+
+			found = append(found, r.IP.String())
+		}
+
+		sort.Strings(found)
+
+		if strings.Join(found, ",") != cases[i].Found || strings.Join(fail, ",") != cases[i].Fail {
+			t.Fatalf("FAIL: %s: found wanted: %s, got: %v, failed wanted: %s, got: %v\n", cases[i].Net, cases[i].Found, found, cases[i].Fail, fail)
+		}
+	}
+
+	if _, err := srvs.ReverseSweep(context.Background(), net.IPNet{}, BulkConfig{}); err == nil {
+		t.Fatalf("FAIL: error wanted for invalid network, got nil\n")
+	}
+}
+
+func TestServersReverseSweepFunc(t *testing.T) {
+
+	srvs := newPTRTestServers(t)
+
+	cases := []struct {
+		Net   string
+		Found string
+	}{
+		{Net: "192.0.2.1/32", Found: "192.0.2.1"},
+		{Net: "192.0.2.6/31", Found: "192.0.2.6"},
+		{Net: "2001:db8::2/128", Found: "2001:db8::2"},
+		{Net: "2001:db8::2/127", Found: "2001:db8::2"},
+		{Net: "192.0.2.0/29", Found: "192.0.2.1,192.0.2.6"},
+	}
+
+	for i := range cases {
+
+		_, n, err := net.ParseCIDR(cases[i].Net)
+		if err != nil {
+			t.Fatalf("FAIL: %s\n", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+		var (
+			m     sync.Mutex
+			found []string
+		)
+
+		err = srvs.ReverseSweepFunc(ctx, *n, BulkConfig{Workers: 4}, func(r PTRResult) {
+			if r.Err == nil {
+				m.Lock()
+				found = append(found, r.IP.String())
+				m.Unlock()
+			}
+		})
+
+		cancel()
+
+		if err != nil {
+			t.Fatalf("FAIL: %s: %s\n", cases[i].Net, err)
+		}
+
+		sort.Strings(found)
+
+		if strings.Join(found, ",") != cases[i].Found {
+			t.Fatalf("FAIL: %s: found wanted: %s, got: %v\n", cases[i].Net, cases[i].Found, found)
+		}
+	}
+}
+
+func TestServersReverseSweepFuncDeadline(t *testing.T) {
+
+	srvs := newPTRTestServers(t)
+
+	_, n, err := net.ParseCIDR("2001:db8::/80")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	err = srvs.ReverseSweepFunc(ctx, *n, BulkConfig{Workers: 4}, func(r PTRResult) {})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("FAIL: Expected context.DeadlineExceeded, got: %v\n", err)
+	}
+
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("FAIL: ReverseSweepFunc returned after %s\n", d)
+	}
+}
@@ -0,0 +1,261 @@
+package dns
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/elmasy-com/elnet/validator"
+	mdns "github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// doqALPN is the ALPN token of DNS-over-QUIC.
+// See: https://www.rfc-editor.org/rfc/rfc9250.html#section-4.1.1
+const doqALPN = "doq"
+
+// DoQ error codes.
+// See: https://www.rfc-editor.org/rfc/rfc9250.html#section-8.4
+const (
+	doqNoError       quic.ApplicationErrorCode = 0x0
+	doqInternalError quic.StreamErrorCode      = 0x1
+)
+
+// doqConn is a shared, lazily dialed QUIC connection.
+// The connection is reused across queries, every query is sent on a new stream.
+type doqConn struct {
+	m       sync.Mutex
+	conn    quic.Connection
+	tlsConf *tls.Config
+	timeout time.Duration
+}
+
+// get returns the cached connection or dials a new one to addr if there is no usable connection.
+func (d *doqConn) get(ctx context.Context, addr string) (quic.Connection, error) {
+
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	if d.conn != nil && d.conn.Context().Err() == nil {
+		return d.conn, nil
+	}
+
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	conn, err := quic.DialAddr(ctx, addr, d.tlsConf, &quic.Config{HandshakeIdleTimeout: d.timeout})
+	if err != nil {
+		return nil, err
+	}
+
+	d.conn = conn
+
+	return conn, nil
+}
+
+// drop closes and forgets conn if it is the cached connection.
+func (d *doqConn) drop(conn quic.Connection) {
+
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	if d.conn != conn {
+		return
+	}
+
+	d.conn.CloseWithError(doqNoError, "")
+	d.conn = nil
+}
+
+// close closes the cached connection.
+func (d *doqConn) close() error {
+
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	if d.conn == nil {
+		return nil
+	}
+
+	err := d.conn.CloseWithError(doqNoError, "")
+	d.conn = nil
+
+	return err
+}
+
+// NewServerQUIC creates a new DNS-over-QUIC (RFC 9250) Server.
+//
+// If port is empty, defaults to "853".
+//
+// config is used to configure the TLS client. If config is nil, the default configuration is used.
+// The ALPN is always set to "doq".
+//
+// The connection is dialed on the first query and reused by the consecutive queries (and by the copies of the Server).
+// Use Close() to close the connection.
+func NewServerQUIC(ip string, port string, config *tls.Config, timeout time.Duration) (Server, error) {
+
+	srv := Server{Protocol: "quic", edns: new(ednsState)}
+
+	if ip == "" {
+		return srv, fmt.Errorf("ip is empty")
+	}
+
+	if validator.IPv4(ip) {
+		srv.IP = ip
+		srv.family = 4
+	} else if validator.IPv6(ip) {
+		srv.IP = ip
+		srv.family = 6
+	} else {
+		return srv, fmt.Errorf("invalid ip: %s", ip)
+	}
+
+	if port == "" {
+		port = "853"
+	}
+
+	if !validator.Port(port) {
+		return srv, fmt.Errorf("invalid port: %s", port)
+	}
+
+	srv.Port = port
+
+	if config == nil {
+		config = new(tls.Config)
+	} else {
+		config = config.Clone()
+	}
+
+	config.NextProtos = []string{doqALPN}
+	config.MinVersion = tls.VersionTLS13
+
+	srv.doq = &doqConn{tlsConf: config, timeout: timeout}
+
+	return srv, nil
+}
+
+// exchangeQUIC sends msg to the DoQ server s on a new stream and returns the response.
+// If the cached connection is unusable, redial once.
+func (s *Server) exchangeQUIC(ctx context.Context, msg *mdns.Msg) (*mdns.Msg, error) {
+
+	if s.doq.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.doq.timeout)
+		defer cancel()
+	}
+
+	var (
+		conn   quic.Connection
+		stream quic.Stream
+		err    error
+	)
+
+	for i := 0; i < 2; i++ {
+
+		conn, err = s.doq.get(ctx, s.Server())
+		if err != nil {
+			break
+		}
+
+		stream, err = conn.OpenStreamSync(ctx)
+		if err == nil {
+			break
+		}
+
+		// The connection is probably closed by the server (eg.: idle timeout)
+		s.doq.drop(conn)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	// Unblock the pending read/write on cancellation.
+	stop := context.AfterFunc(ctx, func() { stream.CancelRead(doqInternalError); stream.CancelWrite(doqInternalError) })
+	defer stop()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetDeadline(deadline)
+	}
+
+	// The Message ID must be 0.
+	// See: https://www.rfc-editor.org/rfc/rfc9250.html#section-4.2.1
+	id := msg.Id
+	msg.Id = 0
+	defer func() { msg.Id = id }()
+
+	buf, err := msg.Pack()
+	if err != nil {
+		stream.CancelWrite(doqInternalError)
+		return nil, fmt.Errorf("failed to pack message: %w", err)
+	}
+
+	// Every message is prefixed with a 2-byte length field.
+	out := make([]byte, 2, 2+len(buf))
+	binary.BigEndian.PutUint16(out, uint16(len(buf)))
+	out = append(out, buf...)
+
+	if _, err = stream.Write(out); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to write stream: %w", err)
+	}
+
+	// The client must send the STREAM FIN after the query.
+	if err = stream.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close stream: %w", err)
+	}
+
+	var l [2]byte
+
+	if _, err = io.ReadFull(stream, l[:]); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to read length: %w", err)
+	}
+
+	body := make([]byte, binary.BigEndian.Uint16(l[:]))
+
+	if _, err = io.ReadFull(stream, body); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	in := new(mdns.Msg)
+
+	if err = in.Unpack(body); err != nil {
+		return nil, fmt.Errorf("failed to unpack message: %w", err)
+	}
+
+	if in.Id != 0 {
+		return nil, mdns.ErrId
+	}
+
+	in.Id = id
+
+	return in, nil
+}
+
+// Close closes the underlying connection of s.
+// Only the "quic" protocol keeps a connection open between queries, Close is a no-op for the other protocols.
+func (s *Server) Close() error {
+
+	if s.doq == nil {
+		return nil
+	}
+
+	return s.doq.close()
+}
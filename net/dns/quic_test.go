@@ -0,0 +1,231 @@
+package dns
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mdns "github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// newDoQServer starts a local in-process DoQ stand-in with a self-signed certificate for 127.0.0.1.
+// Returns an A record 192.0.2.1 for "example.com." and NXDOMAIN for everything else.
+//
+// The returned counter counts the accepted connections and the pool trusts the certificate.
+func newDoQServer(t *testing.T) (string, *atomic.Int32, *x509.CertPool) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to generate key: %s\n", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create certificate: %s\n", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to parse certificate: %s\n", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	l, err := quic.ListenAddr("127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		NextProtos:   []string{doqALPN},
+	}, nil)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to listen: %s\n", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	conns := new(atomic.Int32)
+
+	go func() {
+		for {
+			conn, err := l.Accept(context.Background())
+			if err != nil {
+				return
+			}
+
+			conns.Add(1)
+
+			go func() {
+				for {
+					stream, err := conn.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+
+					go serveDoQStream(stream)
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String(), conns, pool
+}
+
+func serveDoQStream(stream quic.Stream) {
+
+	defer stream.Close()
+
+	// Read until FIN
+	buf, err := io.ReadAll(stream)
+	if err != nil || len(buf) < 2 {
+		return
+	}
+
+	req := new(mdns.Msg)
+	if err := req.Unpack(buf[2:]); err != nil {
+		return
+	}
+
+	// RFC 9250: the Message ID must be 0
+	if req.Id != 0 {
+		stream.CancelWrite(doqInternalError)
+		return
+	}
+
+	resp := new(mdns.Msg)
+	resp.SetReply(req)
+
+	if req.Question[0].Name == "example.com." && req.Question[0].Qtype == mdns.TypeA {
+		resp.Answer = append(resp.Answer, &mdns.A{
+			Hdr: mdns.RR_Header{Name: "example.com.", Rrtype: mdns.TypeA, Class: mdns.ClassINET, Ttl: 60},
+			A:   net.ParseIP("192.0.2.1"),
+		})
+	} else if req.Question[0].Name != "example.com." {
+		resp.Rcode = mdns.RcodeNameError
+	}
+
+	out, err := resp.Pack()
+	if err != nil {
+		return
+	}
+
+	l := make([]byte, 2)
+	binary.BigEndian.PutUint16(l, uint16(len(out)))
+
+	stream.Write(append(l, out...))
+}
+
+func TestNewServerStrQUIC(t *testing.T) {
+
+	s, err := NewServerStr("quic://127.0.0.1", 2*time.Second)
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if s.String() != "quic://127.0.0.1:853" {
+		t.Fatalf("FAIL: string wanted: quic://127.0.0.1:853, string got: %s\n", s.String())
+	}
+
+	if _, err = NewServer("quic", "::1", "8853", 2*time.Second); err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+}
+
+func TestServerQueryAQUIC(t *testing.T) {
+
+	addr, conns, pool := newDoQServer(t)
+
+	ip, port, _ := net.SplitHostPort(addr)
+
+	s, err := NewServerQUIC(ip, port, &tls.Config{RootCAs: pool}, 2*time.Second)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create server: %s\n", err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+
+		r, err := s.QueryA("example.com")
+		if err != nil {
+			t.Fatalf("FAIL: Failed to query example.com: %s\n", err)
+		}
+
+		if len(r) != 1 || !r[0].Equal(net.ParseIP("192.0.2.1")) {
+			t.Fatalf("FAIL: Invalid answer: %v\n", r)
+		}
+	}
+
+	_, err = s.QueryA("invalid.example.com")
+	if !errors.Is(err, ErrName) {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", ErrName, err)
+	}
+
+	if n := conns.Load(); n != 1 {
+		t.Fatalf("FAIL: The connection is not reused: %d connections\n", n)
+	}
+
+	// Redial after close
+	if err = s.Close(); err != nil {
+		t.Fatalf("FAIL: Failed to close: %s\n", err)
+	}
+
+	if _, err = s.QueryA("example.com"); err != nil {
+		t.Fatalf("FAIL: Failed to query example.com after close: %s\n", err)
+	}
+
+	if n := conns.Load(); n != 2 {
+		t.Fatalf("FAIL: Invalid number of connections: %d, want: 2\n", n)
+	}
+}
+
+func TestServersTryQueryQUICMixed(t *testing.T) {
+
+	addr, _, pool := newDoQServer(t)
+
+	ip, port, _ := net.SplitHostPort(addr)
+
+	doq, err := NewServerQUIC(ip, port, &tls.Config{RootCAs: pool}, 2*time.Second)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create server: %s\n", err)
+	}
+
+	// The silent UDP server never answers, TryQuery must fall over to the DoQ server.
+	_, udpPort, _ := net.SplitHostPort(silentUDP(t))
+
+	udp, err := NewServer("udp", "127.0.0.1", udpPort, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create server: %s\n", err)
+	}
+
+	srvs := NewServersSlice(10, udp, doq)
+	defer srvs.Close()
+
+	r, err := srvs.TryQueryA("example.com")
+	if err != nil {
+		t.Fatalf("FAIL: Failed to query example.com: %s\n", err)
+	}
+
+	if len(r) != 1 {
+		t.Fatalf("FAIL: Invalid answer: %v\n", r)
+	}
+}
@@ -0,0 +1,146 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	mdns "github.com/miekg/dns"
+)
+
+// Resolver is the interface of the query backends: Server, Servers and ResolverFunc implement it.
+// TryQueryContext must return the Answer section for name with type t, and the error like Servers.TryQuery.
+//
+// The typed queries are functions over a Resolver (eg.: QueryAWith(), QueryMXWith()),
+// the other package functions that accept a Resolver (eg.: QueryAllWith(), IsSetWith()) can be used with any of them,
+// and the tests can inject a fake with ResolverFunc.
+type Resolver interface {
+	TryQueryContext(ctx context.Context, name string, t uint16) ([]mdns.RR, error)
+}
+
+var (
+	_ Resolver = (*Server)(nil)
+	_ Resolver = (*Servers)(nil)
+	_ Resolver = ResolverFunc(nil)
+)
+
+// DefaultResolver is the Resolver used by the package level query functions (eg.: QueryA(), TryQueryA(), IsSetA()),
+// QueryAll(), ProbeWildcard(), IsWildcard() and IsExists().
+// Defaults to DefaultServers.
+//
+// The functions that need the messages, the nameservers or the concurrency of Servers (eg.: ReverseSweep(), Enumerate(),
+// WalkNSEC(), CollectNSEC3(), CheckOpenTransfer(), CheckPropagation(), Audit()) use the DefaultServers.
+var DefaultResolver Resolver = &DefaultServers
+
+// TryQuery asks the DefaultResolver for type t and returns the Answer section.
+// See Servers.TryQuery().
+func TryQuery(name string, t uint16) ([]mdns.RR, error) {
+
+	return DefaultResolver.TryQueryContext(context.Background(), name, t)
+}
+
+// TryQueryContext is like TryQuery, but the queries are bound to ctx.
+func TryQueryContext(ctx context.Context, name string, t uint16) ([]mdns.RR, error) {
+
+	return DefaultResolver.TryQueryContext(ctx, name, t)
+}
+
+// TryQuery asks the server for type t and returns the Answer section.
+// Unlike Servers.TryQuery, the query is not retried.
+func (s *Server) TryQuery(name string, t uint16) ([]mdns.RR, error) {
+
+	return s.TryQueryContext(context.Background(), name, t)
+}
+
+// TryQueryContext is like TryQuery, but the query is bound to ctx.
+func (s *Server) TryQueryContext(ctx context.Context, name string, t uint16) ([]mdns.RR, error) {
+
+	return s.queryContext(ctx, name, t)
+}
+
+// ResolverFunc is an adapter to use a function as a Resolver (eg.: a fake in tests or a new backend).
+// The function must return the Answer section for name with type t, and the error like Servers.TryQuery.
+type ResolverFunc func(ctx context.Context, name string, t uint16) ([]mdns.RR, error)
+
+// TryQueryContext calls f with ctx, name and t.
+func (f ResolverFunc) TryQueryContext(ctx context.Context, name string, t uint16) ([]mdns.RR, error) {
+
+	return f(ctx, name, t)
+}
+
+// IsSet checks whether a record with type t is set for name using the DefaultResolver.
+// See Servers.IsSet().
+func IsSet(name string, t uint16) (bool, error) {
+
+	return IsSetWith(context.Background(), DefaultResolver, name, t)
+}
+
+// IsSetContext is like IsSet, but the queries are bound to ctx.
+func IsSetContext(ctx context.Context, name string, t uint16) (bool, error) {
+
+	return IsSetWith(ctx, DefaultResolver, name, t)
+}
+
+// IsSetWith is like IsSet, but uses r for the queries.
+func IsSetWith(ctx context.Context, r Resolver, name string, t uint16) (bool, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, t)
+
+	return len(rr) != 0, err
+}
+
+// IsExistsWith is like IsExists, but uses r for the queries.
+func IsExistsWith(ctx context.Context, r Resolver, name string) (bool, error) {
+
+	for _, t := range []uint16{TypeA, TypeAAAA, TypeTXT, TypeCNAME, TypeMX, TypeNS, TypeCAA, TypeSRV} {
+
+		rr, err := r.TryQueryContext(ctx, name, t)
+		if err != nil {
+			return false, fmt.Errorf("check %s failed: %w", TypeToString(t), err)
+		}
+
+		if len(rr) != 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// wildcardProber is implemented by the Resolvers with their own wildcard probe (eg.: Servers with WildcardCache).
+type wildcardProber interface {
+	ProbeWildcardContext(ctx context.Context, name string, t uint16) (*Wildcard, error)
+}
+
+// ProbeWildcardWith is like ProbeWildcard, but uses r for the probe queries.
+// If r is a Servers, its WildcardCache is used.
+//
+// NOTE: Use IsValid() and Clean() before this function!
+func ProbeWildcardWith(ctx context.Context, r Resolver, name string, t uint16) (*Wildcard, error) {
+
+	if p, ok := r.(wildcardProber); ok {
+		return p.ProbeWildcardContext(ctx, name, t)
+	}
+
+	parent := name[strings.IndexByte(name, '.')+1:]
+
+	if !HasSub(name) {
+		// Domain without subdomain cant be a wildcard
+		return &Wildcard{Parent: parent, Type: t}, nil
+	}
+
+	return probeWildcard(ctx, r, parent, t)
+}
+
+// IsWildcardWith is like IsWildcard, but uses r for the probe queries.
+//
+// NOTE: Use IsValid() and Clean() before this function!
+func IsWildcardWith(ctx context.Context, r Resolver, name string, t uint16) (bool, error) {
+
+	w, err := ProbeWildcardWith(ctx, r, name, t)
+	if err != nil {
+		return false, err
+	}
+
+	return w.IsWildcard(), nil
+}
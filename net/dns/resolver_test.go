@@ -0,0 +1,218 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
+	mdns "github.com/miekg/dns"
+)
+
+// newFakeResolver returns a ResolverFunc that answers from records (zone file format).
+// The names under "wild.example.com." without own records have a wildcard A record.
+func newFakeResolver(t *testing.T, records ...string) ResolverFunc {
+
+	var rrs []mdns.RR
+
+	for i := range records {
+		rr, err := mdns.NewRR(records[i])
+		if err != nil {
+			t.Fatalf("FAIL: Invalid record %q: %s\n", records[i], err)
+		}
+		rrs = append(rrs, rr)
+	}
+
+	return func(ctx context.Context, name string, qtype uint16) ([]mdns.RR, error) {
+
+		name = strings.ToLower(mdns.Fqdn(name))
+
+		var (
+			r      []mdns.RR
+			exists bool
+		)
+
+		for i := range rrs {
+			if rrs[i].Header().Name == name {
+				exists = true
+				if rrs[i].Header().Rrtype == qtype {
+					r = append(r, rrs[i])
+				}
+			}
+		}
+
+		switch {
+		case exists:
+			return r, nil
+		case strings.HasSuffix(name, ".wild.example.com."):
+			if qtype == TypeA {
+				return []mdns.RR{&mdns.A{Hdr: mdns.RR_Header{Name: name, Rrtype: mdns.TypeA, Class: mdns.ClassINET, Ttl: 300}, A: net.ParseIP("192.0.2.2")}}, nil
+			}
+			return nil, nil
+		default:
+			return nil, ErrName
+		}
+	}
+}
+
+func TestResolverFunc(t *testing.T) {
+
+	r := newFakeResolver(t,
+		"example.com. 300 IN MX 10 mail.example.com.",
+		"example.com. 300 IN A 192.0.2.1",
+		"1.2.0.192.in-addr.arpa. 300 IN PTR example.com.",
+		"old.example.com. 300 IN DNAME example.net.",
+	)
+
+	mx, err := QueryMXWith(context.Background(), r, "example.com")
+	if err != nil || len(mx) != 1 || mx[0].Exchange != "mail.example.com." {
+		t.Fatalf("FAIL: Invalid MX: %v, %v\n", mx, err)
+	}
+
+	ptr, err := QueryPTRWith(context.Background(), r, "192.0.2.1")
+	if err != nil || len(ptr) != 1 || ptr[0] != "example.com." {
+		t.Fatalf("FAIL: Invalid PTR: %v, %v\n", ptr, err)
+	}
+
+	dname, err := QueryDNAMEWith(context.Background(), r, "old.example.com")
+	if err != nil || dname != "example.net." {
+		t.Fatalf("FAIL: Invalid DNAME: %v, %v\n", dname, err)
+	}
+
+	if _, err := QueryAWith(context.Background(), r, "invalid.example.com"); !errors.Is(err, ErrName) {
+		t.Fatalf("FAIL: Expected ErrName, got: %v\n", err)
+	}
+}
+
+func TestQueryAllWith(t *testing.T) {
+
+	r := newFakeResolver(t,
+		"example.com. 300 IN A 192.0.2.1",
+		"real.wild.example.com. 300 IN A 192.0.2.3",
+		"real.wild.example.com. 300 IN TXT \"genuine\"",
+		"same.wild.example.com. 300 IN A 192.0.2.2",
+	)
+
+	rs, err := QueryAllWith(context.Background(), r, "real.wild.example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(rs) != 2 || rs[0] != (Record{Type: TypeA, Value: "192.0.2.3"}) || rs[1] != (Record{Type: TypeTXT, Value: "genuine"}) {
+		t.Fatalf("FAIL: Invalid records: %v\n", rs)
+	}
+
+	rs, err = QueryAllWith(context.Background(), r, "same.wild.example.com")
+	if err != nil || len(rs) != 0 {
+		t.Fatalf("FAIL: Wildcard answer is not filtered: %v, %v\n", rs, err)
+	}
+
+	wc, err := IsWildcardWith(context.Background(), r, "test.wild.example.com", TypeA)
+	if err != nil || !wc {
+		t.Fatalf("FAIL: test.wild.example.com is not a wildcard: %v\n", err)
+	}
+
+	wc, err = IsWildcardWith(context.Background(), r, "www.example.com", TypeA)
+	if err != nil || wc {
+		t.Fatalf("FAIL: www.example.com is a wildcard: %v\n", err)
+	}
+}
+
+func TestIsExistsWith(t *testing.T) {
+
+	r := newFakeResolver(t, "example.com. 300 IN TXT \"v=spf1 -all\"")
+
+	ok, err := IsExistsWith(context.Background(), r, "example.com")
+	if err != nil || !ok {
+		t.Fatalf("FAIL: example.com not exists: %v\n", err)
+	}
+
+	_, err = IsExistsWith(context.Background(), r, "invalid.example.com")
+	if !errors.Is(err, ErrName) {
+		t.Fatalf("FAIL: Expected ErrName, got: %v\n", err)
+	}
+}
+
+func TestDefaultResolver(t *testing.T) {
+
+	orig := DefaultResolver
+	t.Cleanup(func() { DefaultResolver = orig })
+
+	DefaultResolver = newFakeResolver(t, "example.com. 300 IN A 192.0.2.1")
+
+	ips, err := TryQueryA("example.com")
+	if err != nil || len(ips) != 1 || !ips[0].Equal(net.ParseIP("192.0.2.1")) {
+		t.Fatalf("FAIL: Invalid A: %v, %v\n", ips, err)
+	}
+
+	ips, err = QueryA("example.com")
+	if err != nil || len(ips) != 1 || !ips[0].Equal(net.ParseIP("192.0.2.1")) {
+		t.Fatalf("FAIL: Invalid A: %v, %v\n", ips, err)
+	}
+
+	ok, err := IsExists("example.com")
+	if err != nil || !ok {
+		t.Fatalf("FAIL: example.com not exists: %v\n", err)
+	}
+
+	if ok, err = IsSetA("example.com"); err != nil || !ok {
+		t.Fatalf("FAIL: A is not set for example.com: %v\n", err)
+	}
+
+	if ok, err = IsSetMX("example.com"); err != nil || ok {
+		t.Fatalf("FAIL: MX is set for example.com: %v\n", err)
+	}
+}
+
+func TestDefaultResolverPTR(t *testing.T) {
+
+	orig := DefaultResolver
+	t.Cleanup(func() { DefaultResolver = orig })
+
+	var names []string
+
+	DefaultResolver = ResolverFunc(func(ctx context.Context, name string, qtype uint16) ([]mdns.RR, error) {
+		names = append(names, name)
+		return newFakeResolver(t, "1.2.0.192.in-addr.arpa. 300 IN PTR example.com.")(ctx, name, qtype)
+	})
+
+	if ok, err := IsSetPTR("192.0.2.1"); err != nil || !ok {
+		t.Fatalf("FAIL: PTR is not set for 192.0.2.1: %v\n", err)
+	}
+
+	if ptr, err := QueryPTR("192.0.2.1"); err != nil || len(ptr) != 1 || ptr[0] != "example.com." {
+		t.Fatalf("FAIL: Invalid PTR: %v, %v\n", ptr, err)
+	}
+
+	for i := range names {
+		if names[i] != "1.2.0.192.in-addr.arpa." {
+			t.Fatalf("FAIL: Invalid queried name: %v\n", names)
+		}
+	}
+
+	if len(names) != 2 {
+		t.Fatalf("FAIL: Invalid queries: %v\n", names)
+	}
+}
+
+func TestServerResolver(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, dnstest.MustZone("example.com",
+		"@ 300 IN A 192.0.2.1",
+		"@ 300 IN MX 10 mail",
+	))
+
+	r := srvs.Get(0)
+
+	mx, err := QueryMXWith(context.Background(), r, "example.com")
+	if err != nil || len(mx) != 1 || mx[0].Exchange != "mail.example.com." {
+		t.Fatalf("FAIL: Invalid MX: %v, %v\n", mx, err)
+	}
+
+	rs, err := QueryAllWith(context.Background(), r, "example.com")
+	if err != nil || len(rs) < 2 {
+		t.Fatalf("FAIL: Invalid records: %v, %v\n", rs, err)
+	}
+}
@@ -1,7 +1,10 @@
 package dns
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"net/http"
 	"net/url"
 	"time"
 
@@ -10,25 +13,33 @@ import (
 )
 
 type Server struct {
-	Protocol string // Protocol name, must be "udp", "tcp" or "tcp-tls"
-	IP       string // Ip address
-	Port     string // Destination port
-	family   int    // IP address family, must be "4" for IPv4 or "6" for IPv6
-	client   *mdns.Client
+	Protocol   string // Protocol name, must be "udp", "tcp", "tcp-tls", "https", "quic" or "iterative"
+	IP         string // Ip address (or hostname for "https")
+	Port       string // Destination port
+	family     int    // IP address family, must be "4" for IPv4 or "6" for IPv6
+	client     *mdns.Client
+	url        string       // DoH endpoint URL, used with "https"
+	method     string       // DoH HTTP method, used with "https"
+	httpClient *http.Client // DoH HTTP client, used with "https"
+	doq        *doqConn     // DoQ connection, used with "quic"
+	iter       *iterative   // Iterative resolver, used with "iterative"
+	edns       *ednsState   // EDNS options and DNS Cookies of the queries, shared by the copies of the Server
 }
 
 // NewServer creates a new Server.
 //
-// The protocol must be "udp", "tcp" or "tcp-tls".
+// The protocol must be "udp", "tcp", "tcp-tls", "https" or "quic".
+// For "https", the DoH request is sent with POST to https://ip:port/dns-query (see NewServerHTTPS).
+// For "quic", the DoQ connection is created with the default TLS config (see NewServerQUIC).
 func NewServer(protocol string, ip string, port string, timeout time.Duration) (Server, error) {
 
-	srv := Server{}
+	srv := Server{edns: new(ednsState)}
 
 	if protocol == "" {
 		return srv, fmt.Errorf("protocol is empty")
 	}
 
-	if protocol != "udp" && protocol != "tcp" && protocol != "tcp-tls" {
+	if protocol != "udp" && protocol != "tcp" && protocol != "tcp-tls" && protocol != "https" && protocol != "quic" {
 		return srv, fmt.Errorf("invalid protocol: %s", protocol)
 	}
 
@@ -58,6 +69,14 @@ func NewServer(protocol string, ip string, port string, timeout time.Duration) (
 
 	srv.Port = port
 
+	if srv.Protocol == "quic" {
+		return NewServerQUIC(srv.IP, srv.Port, nil, timeout)
+	}
+
+	if srv.Protocol == "https" {
+		return newServerHTTPS(&url.URL{Scheme: "https", Host: net.JoinHostPort(srv.IP, srv.Port)}, http.MethodPost, &http.Client{Timeout: timeout})
+	}
+
 	srv.client = new(mdns.Client)
 	srv.client.Net = srv.Protocol
 	srv.client.Timeout = timeout
@@ -71,26 +90,34 @@ func NewServer(protocol string, ip string, port string, timeout time.Duration) (
 //
 //	[protocol://]ip[:port]
 //
-// If protocol is missing, defaults to "udp". Valid protocols are: "udp", "tcp" and "tcp-tls".
+// If protocol is missing, defaults to "udp". Valid protocols are: "udp", "tcp", "tcp-tls", "https" and "quic".
 //
 // If port is missing, defaults to "53".
 //
+// The "https" protocol creates a DNS-over-HTTPS server with POST method (see NewServerHTTPS),
+// the host can be a hostname, the port defaults to "443" and the path defaults to "/dns-query".
+//
+// The "quic" protocol creates a DNS-over-QUIC server with the default TLS config (see NewServerQUIC),
+// the port defaults to "853".
+//
 // Valid strings:
 //   - udp://127.0.0.1:53 -> UDP query to 127.0.0.1 on port 53 (IPv4)
 //   - udp://[::1]:53 -> UDP query to ::1 on port 53 (IPv6)
 //   - udp://127.0.0.1 -> UDP query to 127.0.0.1 on port 53
 //   - 127.0.0.1:53 -> UDP query to 127.0.0.1 on port 53
 //   - 127.0.0.1 -> UDP query to 127.0.0.1 on port 53
+//   - https://dns.google/dns-query -> DoH query to dns.google on port 443
+//   - quic://94.140.14.140 -> DoQ query to 94.140.14.140 on port 853
 func NewServerStr(s string, timeout time.Duration) (Server, error) {
 
 	// The given server string is only an IPv4 address.
 	if validator.IPv4(s) {
-		return Server{Protocol: "udp", IP: s, Port: "53", family: 4, client: &mdns.Client{Net: "udp", Timeout: timeout}}, nil
+		return Server{Protocol: "udp", IP: s, Port: "53", family: 4, client: &mdns.Client{Net: "udp", Timeout: timeout}, edns: new(ednsState)}, nil
 	}
 
 	// The given server string is only an IPv6 address.
 	if validator.IPv6(s) {
-		return Server{Protocol: "udp", IP: s, Port: "53", family: 6, client: &mdns.Client{Net: "udp", Timeout: timeout}}, nil
+		return Server{Protocol: "udp", IP: s, Port: "53", family: 6, client: &mdns.Client{Net: "udp", Timeout: timeout}, edns: new(ednsState)}, nil
 	}
 
 	r, err := url.Parse(s)
@@ -98,16 +125,24 @@ func NewServerStr(s string, timeout time.Duration) (Server, error) {
 		return Server{}, err
 	}
 
-	sr := Server{Protocol: r.Scheme, IP: r.Hostname(), Port: r.Port()}
+	if r.Scheme == "https" {
+		return newServerHTTPS(r, http.MethodPost, &http.Client{Timeout: timeout})
+	}
+
+	sr := Server{Protocol: r.Scheme, IP: r.Hostname(), Port: r.Port(), edns: new(ednsState)}
 
 	if sr.Protocol == "" {
 		sr.Protocol = "udp"
 	}
 
-	if sr.Protocol != "udp" && sr.Protocol != "tcp" && sr.Protocol != "tcp-tls" {
+	if sr.Protocol != "udp" && sr.Protocol != "tcp" && sr.Protocol != "tcp-tls" && sr.Protocol != "quic" {
 		return Server{}, fmt.Errorf("invalid protocol: %s", sr.Protocol)
 	}
 
+	if sr.Protocol == "quic" {
+		return NewServerQUIC(sr.IP, sr.Port, nil, timeout)
+	}
+
 	if validator.IPv4(sr.IP) {
 		sr.family = 4
 	} else if validator.IPv6(sr.IP) {
@@ -134,6 +169,10 @@ func NewServerStr(s string, timeout time.Duration) (Server, error) {
 // String format and return the Server struct.
 func (s Server) String() string {
 
+	if s.Protocol == "https" {
+		return s.url
+	}
+
 	switch s.family {
 	case 4:
 		return fmt.Sprintf("%s://%s:%s", s.Protocol, s.IP, s.Port)
@@ -173,37 +212,121 @@ func (s *Server) ToTCP() *Server {
 	srv.client = new(mdns.Client)
 	srv.client.Net = "tcp"
 	srv.client.Timeout = s.client.Timeout
+	srv.edns = s.edns
 
 	return srv
 }
 
+// exchangeContext sends msg to s and returns the response.
+// The exchange is aborted when ctx is done, the returned error is ctx.Err() in this case.
+func (s *Server) exchangeContext(ctx context.Context, msg *mdns.Msg) (*mdns.Msg, error) {
+
+	switch s.Protocol {
+	case "https":
+		return s.exchangeHTTPS(ctx, msg)
+	case "quic":
+		return s.exchangeQUIC(ctx, msg)
+	case "iterative":
+		return s.exchangeIterative(ctx, msg)
+	}
+
+	conn, err := s.client.DialContext(ctx, s.Server())
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Unblock the pending read/write on cancellation.
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	in, _, err := s.client.ExchangeWithConnContext(ctx, msg, conn)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	return in, nil
+}
+
 // Generic query for type t to server s.
 // Returns the Answer section.
 // In case of error, the answer will be nil and return ErrX or any unknown error.
 // If the returned messsage is truncated, create a TCP server from s and retry the query.
 func (s *Server) query(name string, t uint16) ([]mdns.RR, error) {
 
+	return s.queryContext(context.Background(), name, t)
+}
+
+// queryContext is like query, but the query and the TCP fallback are bound to ctx.
+func (s *Server) queryContext(ctx context.Context, name string, t uint16) ([]mdns.RR, error) {
+
+	in, err := s.queryMsgContext(ctx, name, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return in.Answer, nil
+}
+
+// queryMsgContext is like queryContext, but returns the whole response message.
+// If the server responded with a non-zero rcode, returns the response along with the rcode error.
+func (s *Server) queryMsgContext(ctx context.Context, name string, t uint16) (*mdns.Msg, error) {
+
+	return s.queryMsgEDNSContext(ctx, name, t, nil)
+}
+
+// queryMsgEDNSContext is like queryMsgContext, but the query is sent with the EDNS options o.
+// If o is nil, the options of s are used.
+func (s *Server) queryMsgEDNSContext(ctx context.Context, name string, t uint16, o *EDNSOptions) (*mdns.Msg, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	edns, jar := s.ednsOptions()
+
+	if o == nil {
+		o = edns
+	}
+
 	msg := new(mdns.Msg)
 	msg.SetQuestion(mdns.Fqdn(name), t)
 
-	in, _, err := s.client.Exchange(msg, s.Server())
+	if o != nil {
+		o.apply(msg, jar)
+	}
+
+	in, err := s.exchangeContext(ctx, msg)
 	if err != nil {
-		return nil, err
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, newQueryError(s, name, t, nil, err)
 	}
 
 	if in.Truncated {
 
 		tcpS := s.ToTCP()
 		if tcpS == nil {
-			return nil, ErrTruncated
+			return nil, newQueryError(s, name, t, nil, ErrTruncated)
 		}
 
-		return tcpS.query(name, t)
+		return tcpS.queryMsgEDNSContext(ctx, name, t, o)
+	}
+
+	if o != nil && o.Cookie {
+		jar.update(newEDNS(in))
 	}
 
-	if in.Rcode == 0 {
-		return in.Answer, nil
+	if in.Rcode != 0 {
+		return in, newQueryError(s, name, t, in, nil)
 	}
 
-	return nil, RcodeToError(in.Rcode)
+	return in, nil
 }
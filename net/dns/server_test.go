@@ -1,10 +1,15 @@
 package dns
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/g0rbe/gmod/net/dns/dnstest"
 	mdns "github.com/miekg/dns"
 )
 
@@ -318,3 +323,176 @@ func TestServerQueryATCPTLS(t *testing.T) {
 		}
 	}
 }
+
+// silentUDP starts a UDP listener that reads the queries but never answers.
+func silentUDP(t *testing.T) string {
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("FAIL: Failed to listen: %s\n", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			if _, _, err := conn.ReadFrom(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestServerQueryContextCanceled(t *testing.T) {
+
+	_, port, _ := net.SplitHostPort(silentUDP(t))
+
+	s, err := NewServer("udp", "127.0.0.1", port, 10*time.Second)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create server: %s\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+
+	_, err = s.QueryAContext(ctx, "example.com")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", context.Canceled, err)
+	}
+
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("FAIL: Query returned after %s, cancellation is not honored\n", d)
+	}
+}
+
+// localDNS starts a local UDP DNS server with handler h.
+// Returns the address of the server (eg.: "127.0.0.1:12345").
+func localDNS(t *testing.T, h mdns.HandlerFunc) string {
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("FAIL: Failed to listen: %s\n", err)
+	}
+
+	started := make(chan struct{})
+
+	srv := &mdns.Server{PacketConn: pc, Handler: h, NotifyStartedFunc: func() { close(started) }}
+
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+
+	<-started
+
+	return pc.LocalAddr().String()
+}
+
+// newZoneTestServers starts a dnstest.Server serving zones and returns the Servers using it over UDP.
+func newZoneTestServers(t *testing.T, zones ...*dnstest.Zone) (Servers, *dnstest.Server) {
+
+	srvs, ts := newZoneTestServersN(t, 1, zones...)
+
+	return srvs, ts[0]
+}
+
+// newZoneTestServersN starts n dnstest.Server serving the same zones and returns the Servers using them over UDP.
+func newZoneTestServersN(t *testing.T, n int, zones ...*dnstest.Zone) (Servers, []*dnstest.Server) {
+
+	var (
+		ts    = make([]*dnstest.Server, 0, n)
+		addrs = make([]string, 0, n)
+	)
+
+	for i := 0; i < n; i++ {
+
+		s, err := dnstest.NewServer(zones...)
+		if err != nil {
+			t.Fatalf("FAIL: Failed to start test server: %s\n", err)
+		}
+
+		t.Cleanup(func() { s.Close() })
+
+		ts = append(ts, s)
+		addrs = append(addrs, "udp://"+s.Addr)
+	}
+
+	srvs, err := NewServersStr(1, time.Second, addrs...)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create servers: %s\n", err)
+	}
+
+	return srvs, ts
+}
+
+// newZoneTestServersOnIPs starts a dnstest.Server for each element of zones on the same port of 127.0.0.1, 127.0.0.2, ...
+// (zones[i] is served on 127.0.0.<i+1>). Returns the Servers using the first server over UDP, the servers and the port.
+// The test is skipped if the addresses are not usable.
+func newZoneTestServersOnIPs(t *testing.T, zones ...[]*dnstest.Zone) (Servers, []*dnstest.Server, string) {
+
+	for try := 0; try < 10; try++ {
+
+		var (
+			ts   []*dnstest.Server
+			port = "0"
+		)
+
+		for i := range zones {
+
+			s, err := dnstest.NewServerAddr(net.JoinHostPort(fmt.Sprintf("127.0.0.%d", i+1), port), zones[i]...)
+			if err != nil {
+				break
+			}
+
+			ts = append(ts, s)
+			_, port, _ = net.SplitHostPort(s.Addr)
+		}
+
+		if len(ts) != len(zones) {
+			for i := range ts {
+				ts[i].Close()
+			}
+			continue
+		}
+
+		t.Cleanup(func() {
+			for i := range ts {
+				ts[i].Close()
+			}
+		})
+
+		srvs, err := NewServersStr(1, time.Second, "udp://"+ts[0].Addr)
+		if err != nil {
+			t.Fatalf("FAIL: Failed to create servers: %s\n", err)
+		}
+
+		return srvs, ts, port
+	}
+
+	t.Skipf("Failed to listen on 127.0.0.1-127.0.0.%d\n", len(zones))
+
+	return Servers{}, nil, ""
+}
+
+func TestServerQueryTruncatedFallback(t *testing.T) {
+
+	srvs, ts := newZoneTestServers(t, dnstest.MustZone("example.com", "@ 300 IN A 192.0.2.1"))
+
+	ts.SetRule("example.com", mdns.TypeA, dnstest.Rule{Truncate: true})
+
+	r, err := srvs.Get(0).QueryA("example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(r) != 1 || r[0].String() != "192.0.2.1" {
+		t.Fatalf("FAIL: unexpected answer: %v\n", r)
+	}
+
+	q := ts.Queries()
+	if len(q) != 2 {
+		t.Fatalf("FAIL: expected a UDP and a TCP query, got %d\n", len(q))
+	}
+}
@@ -1,12 +1,14 @@
 package dns
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
 	"sync"
 	"time"
 
+	"github.com/elmasy-com/slices"
 	mdns "github.com/miekg/dns"
 )
 
@@ -14,6 +16,9 @@ type Servers struct {
 	srvs       []Server
 	maxRetries int
 	m          *sync.Mutex
+	cache      *Cache
+	wildcards  *WildcardCache
+	health     *health
 }
 
 // NewServersSlice creates a new Servers from srvs.
@@ -27,12 +32,13 @@ func NewServersSlice(retries int, srvs ...Server) Servers {
 
 	sr.m = new(sync.Mutex)
 	sr.maxRetries = retries
+	sr.health = newHealth()
 
 	return sr
 }
 
 // NewServersStr creates a new Servers from srvs.
-// The protocol must be "udp", "tcp" or "tcp-tls". The ddefault protocol is "udp" and the default port is "53".
+// The protocol must be "udp", "tcp", "tcp-tls", "https" or "quic". The ddefault protocol is "udp" and the default port is "53".
 // Retries is the maximum number retries allowed in TryQuery function.
 // Timeout is a cumulative timeout for dial.
 func NewServersStr(retries int, timeout time.Duration, s ...string) (Servers, error) {
@@ -45,6 +51,7 @@ func NewServersStr(retries int, timeout time.Duration, s ...string) (Servers, er
 	srvs.srvs = make([]Server, 0, len(s))
 	srvs.m = new(sync.Mutex)
 	srvs.maxRetries = retries
+	srvs.health = newHealth()
 
 	for i := range s {
 
@@ -70,30 +77,113 @@ func (s *Servers) Append(srv Server) {
 
 // Get returns a DNS server to use.
 // If index is between the servers range, returns the selected server.
-// If index is not between the servers range, returns a random one.
+// If index is not between the servers range, returns one selected by the strategy (see SetStrategy(), random by default).
+//
+// Quarantined servers are skipped (see SetQuarantine()), even if selected by index.
+// Get does not record the result of the query, so it never returns a server for the probe after the quarantine:
+// the quarantined servers are recovered by TryQuery, Bulk (and the functions built on them) or ProbeQuarantined().
 //
 // Set index to -1 to get a random one.
 func (s *Servers) Get(index int) *Server {
 
+	return &s.srvs[s.index(index, false)]
+}
+
+// index returns the index of the server to use (see Get()).
+// If probe is true, the caller records the result (see health.choose()).
+func (s *Servers) index(index int, probe bool) int {
+
 	switch l := len(s.srvs); l {
 	case 0:
 		// No server is configured
 		panic("no servers configured")
 	case 1:
 		// Only ne server
-		return &s.srvs[0]
+		return 0
 	default:
 
+		if s.health != nil {
+			return s.health.choose(index, l, probe)
+		}
+
 		if index >= 0 && index < l {
 			// If index is between the servers range, return the selected server
-			return &s.srvs[index]
+			return index
 		} else {
 			// If not in range, return a random server
-			return &s.srvs[rand.Intn(l)]
+			return rand.Intn(l)
 		}
 	}
 }
 
+// next returns the index of the server for the next attempt of a query from the first n servers.
+// The server is selected with the strategy, the quarantined servers and the servers in tried are skipped if possible (see health.choose()).
+// The caller must record the result of the query.
+func (s *Servers) next(n int, tried []int) int {
+
+	switch {
+	case n == 0:
+		panic("no servers configured")
+	case n == 1:
+		return 0
+	case s.health != nil:
+		return s.health.choose(-1, n, true, tried...)
+	}
+
+	// Random server from the not tried ones
+	avail := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		if !slices.Contains(tried, i) {
+			avail = append(avail, i)
+		}
+	}
+
+	if len(avail) == 0 {
+		return rand.Intn(n)
+	}
+
+	return avail[rand.Intn(len(avail))]
+}
+
+// Close closes the underlying connections of the servers (see Server.Close()).
+// Returns the first error, but tries to close every server.
+func (s *Servers) Close() error {
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	var err error
+
+	for i := range s.srvs {
+		if cerr := s.srvs[i].Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close %s: %w", s.srvs[i].String(), cerr)
+		}
+	}
+
+	return err
+}
+
+// SetCache sets the response cache used by TryQuery (and the functions built on it).
+// Set c to nil to disable the cache.
+//
+// Use WithoutCache() to disable the cache for a single query.
+func (s *Servers) SetCache(c *Cache) {
+
+	s.m.Lock()
+	s.cache = c
+	s.m.Unlock()
+}
+
+// Cache returns the response cache of the Servers.
+// Returns nil if the cache is disabled.
+func (s *Servers) Cache() *Cache {
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.cache
+}
+
 // GetMaxRetries returns the maximum number retries configured in the Servers.
 func (s *Servers) GetMaxRetries() int {
 
@@ -115,21 +205,90 @@ func (s *Servers) SetMaxRetries(n int) {
 // NOTE: The first used server is random.
 func (s *Servers) TryQuery(name string, t uint16) ([]mdns.RR, error) {
 
+	return s.TryQueryContext(context.Background(), name, t)
+}
+
+// TryQueryContext is like TryQuery, but the queries are bound to ctx.
+// The retries stop as soon as ctx is done and ctx.Err() is returned.
+//
+// If a cache is set (see SetCache()), the answer is returned from the cache when possible.
+func (s *Servers) TryQueryContext(ctx context.Context, name string, t uint16) ([]mdns.RR, error) {
+
+	cache := s.Cache()
+
+	if cache != nil && isCacheDisabled(ctx) {
+		cache = nil
+	}
+
+	if cache != nil {
+		if rr, ok, err := cache.get(name, t); ok {
+			return rr, err
+		}
+	}
+
+	in, err := s.tryQueryMsgContext(ctx, name, t, nil)
+
+	if cache != nil {
+		cache.put(name, t, in, err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return in.Answer, nil
+}
+
+// tryQueryMsgContext does the retries of TryQueryContext without the cache and returns the last response.
+// The queries are sent with the EDNS options o, or with the options of the server if o is nil.
+// If every attempt failed, the returned error is QueryErrors.
+func (s *Servers) tryQueryMsgContext(ctx context.Context, name string, t uint16, o *EDNSOptions) (*mdns.Msg, error) {
+
 	var (
-		err        = ErrInvalidMaxRetries
-		rr         []mdns.RR
+		err        error
+		errs       QueryErrors
+		in         *mdns.Msg
+		tried      []int
 		maxRetries = s.maxRetries - 1
 	)
 
 	for i := -1; i < maxRetries; i++ {
 
-		rr, err = s.Get(i).query(name, t)
-		if err == nil || errors.Is(err, ErrName) {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		idx := s.next(len(s.srvs), tried)
+		tried = append(tried, idx)
+		start := time.Now()
+
+		in, err = s.srvs[idx].queryMsgEDNSContext(ctx, name, t, o)
+
+		if s.health != nil {
+			s.health.record(idx, time.Since(start), err)
+		}
+
+		if err == nil {
+			return in, nil
+		}
+
+		var qe *QueryError
+		if errors.As(err, &qe) {
+			qe.Attempt = len(errs) + 1
+		}
+
+		errs = append(errs, err)
+
+		if errors.Is(err, ErrName) {
 			break
 		}
 	}
 
-	return rr, err
+	if len(errs) == 0 {
+		return nil, ErrInvalidMaxRetries
+	}
+
+	return in, errs
 }
 
 // IsSet checks whether a record with type t is set for name.
@@ -137,7 +296,13 @@ func (s *Servers) TryQuery(name string, t uint16) ([]mdns.RR, error) {
 // NXDOMAIN is not an error here, because it means "not found".
 func (s *Servers) IsSet(name string, t uint16) (bool, error) {
 
-	rr, err := s.TryQuery(name, t)
+	return s.IsSetContext(context.Background(), name, t)
+}
+
+// IsSetContext is like IsSet, but the queries are bound to ctx.
+func (s *Servers) IsSetContext(ctx context.Context, name string, t uint16) (bool, error) {
+
+	rr, err := s.TryQueryContext(ctx, name, t)
 
 	return len(rr) != 0, err
 }
@@ -1,6 +1,8 @@
 package dns
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -155,3 +157,41 @@ func TestNewServersIsSet(t *testing.T) {
 		t.Fatalf("FAIL: A record for example.com is not set!\n")
 	}
 }
+
+func TestServersTryQueryContextDeadline(t *testing.T) {
+
+	srvs, err := NewServersStr(5, 10*time.Second, "udp://"+silentUDP(t), "udp://"+silentUDP(t))
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create servers: %s\n", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err = srvs.TryQueryContext(ctx, "example.com", TypeA)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", context.DeadlineExceeded, err)
+	}
+
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("FAIL: TryQuery returned after %s, deadline is not honored\n", d)
+	}
+}
+
+func TestServersIsWildcardContextCanceled(t *testing.T) {
+
+	srvs, err := NewServersStr(3, 10*time.Second, "udp://"+silentUDP(t))
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create servers: %s\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = srvs.IsWildcardContext(ctx, "www.example.com", TypeA)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", context.Canceled, err)
+	}
+}
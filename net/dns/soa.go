@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"fmt"
 
 	mdns "github.com/miekg/dns"
@@ -23,16 +24,8 @@ func (s SOA) String() string {
 	return fmt.Sprintf("%s %s %d %d %d %d %d", s.Mname, s.Rname, s.Serial, s.Refresh, s.Retry, s.Expire, s.MinTTL)
 }
 
-// QuerySOA ask the server and returns a SOA struct pointer.
-// The answer slice will be nil in case of error.
-//
-// The other record types are ignored.
-func (s *Server) QuerySOA(name string) (*SOA, error) {
-
-	rr, err := s.query(name, TypeSOA)
-	if err != nil {
-		return nil, err
-	}
+// parseSOA converts the answer rr to SOA.
+func parseSOA(rr []mdns.RR) (*SOA, error) {
 
 	for i := range rr {
 
@@ -45,6 +38,9 @@ func (s *Server) QuerySOA(name string) (*SOA, error) {
 		case *mdns.DNAME:
 			// Ignore DNAME
 			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
 		default:
 			return nil, fmt.Errorf("unknown type: %T", v)
 		}
@@ -53,22 +49,56 @@ func (s *Server) QuerySOA(name string) (*SOA, error) {
 	return nil, nil
 }
 
+// QuerySOA ask the server and returns a SOA struct pointer.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QuerySOA(name string) (*SOA, error) {
+
+	return s.QuerySOAContext(context.Background(), name)
+}
+
+// QuerySOAContext is like QuerySOA, but the query is bound to ctx.
+func (s *Server) QuerySOAContext(ctx context.Context, name string) (*SOA, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeSOA)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSOA(rr)
+}
+
 // QuerySOA ask a random server from servers and returns a SOA struct pointer.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func (s *Servers) QuerySOA(name string) (*SOA, error) {
 
-	return s.Get(-1).QuerySOA(name)
+	return s.QuerySOAContext(context.Background(), name)
 }
 
-// QuerySOA ask a random server from DefaultServers and returns a SOA struct pointer.
+// QuerySOAContext is like QuerySOA, but the query is bound to ctx.
+func (s *Servers) QuerySOAContext(ctx context.Context, name string) (*SOA, error) {
+
+	return s.Get(-1).QuerySOAContext(ctx, name)
+}
+
+// QuerySOA asks the DefaultResolver and returns a SOA struct pointer.
+// Unlike Servers.QuerySOA, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QuerySOA is the same as TryQuerySOA.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func QuerySOA(name string) (*SOA, error) {
 
-	return DefaultServers.QuerySOA(name)
+	return QuerySOAContext(context.Background(), name)
+}
+
+// QuerySOAContext is like QuerySOA, but the query is bound to ctx.
+func QuerySOAContext(ctx context.Context, name string) (*SOA, error) {
+
+	return QuerySOAWith(ctx, DefaultResolver, name)
 }
 
 // TryQuerySOA asks the servers for type SOA. If any error occurred, retries with next server (except if error is NXDOMAIN).
@@ -78,50 +108,63 @@ func QuerySOA(name string) (*SOA, error) {
 // The first used server is random. The other record types are ignored.
 func (s *Servers) TryQuerySOA(name string) (*SOA, error) {
 
-	rr, err := s.TryQuery(name, TypeSOA)
-	if err != nil {
-		return nil, err
-	}
-
-	for i := range rr {
+	return s.TryQuerySOAContext(context.Background(), name)
+}
 
-		switch v := rr[i].(type) {
-		case *mdns.SOA:
-			return &SOA{Mname: v.Ns, Rname: v.Mbox, Serial: int(v.Serial), Refresh: int(v.Refresh), Retry: int(v.Retry), Expire: int(v.Expire), MinTTL: int(v.Minttl)}, nil
-		case *mdns.CNAME:
-			// Ignore CNAME
-			continue
-		case *mdns.DNAME:
-			// Ignore DNAME
-			continue
-		default:
-			return nil, fmt.Errorf("unknown type: %T", v)
-		}
-	}
+// TryQuerySOAContext is like TryQuerySOA, but the query is bound to ctx.
+func (s *Servers) TryQuerySOAContext(ctx context.Context, name string) (*SOA, error) {
 
-	return nil, nil
+	return QuerySOAWith(ctx, s, name)
 }
 
-// TryQuerySOA asks the DefaultServers for type SOA. If any error occurred, retries with next server (except if error is NXDOMAIN).
+// TryQuerySOA asks the DefaultResolver for type SOA. If any error occurred, retries with next server (except if error is NXDOMAIN).
 //
 // In case of error, the answer will be nil and return ErrX or any unknown error.
 //
 // The first used server is random. The other record types are ignored.
 func TryQuerySOA(name string) (*SOA, error) {
 
-	return DefaultServers.TryQuerySOA(name)
+	return TryQuerySOAContext(context.Background(), name)
+}
+
+// TryQuerySOAContext is like TryQuerySOA, but the query is bound to ctx.
+func TryQuerySOAContext(ctx context.Context, name string) (*SOA, error) {
+
+	return QuerySOAWith(ctx, DefaultResolver, name)
+}
+
+// QuerySOAWith asks r for type SOA and converts the answer like QuerySOA.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QuerySOAWith(ctx context.Context, r Resolver, name string) (*SOA, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeSOA)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSOA(rr)
 }
 
 // IsSetSOA checks whether an SOA type record set for name.
 // NXDOMAIN is not an error here, because it means "not found".
 func (s *Servers) IsSetSOA(name string) (bool, error) {
-	return s.IsSet(name, TypeSOA)
+	return s.IsSetSOAContext(context.Background(), name)
 }
 
-// IsSetSOA checks whether an SOA type record set for name using the DefaultServers.
+// IsSetSOAContext is like IsSetSOA, but the query is bound to ctx.
+func (s *Servers) IsSetSOAContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeSOA)
+}
+
+// IsSetSOA checks whether an SOA type record set for name using the DefaultResolver.
 // NXDOMAIN is not an error here, because it means "not found".
 func IsSetSOA(name string) (bool, error) {
-	return DefaultServers.IsSetSOA(name)
+	return IsSetSOAContext(context.Background(), name)
+}
+
+// IsSetSOAContext is like IsSetSOA, but the query is bound to ctx.
+func IsSetSOAContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeSOA)
 }
 
 // TODO: Decide if the domain is registered based on the SOA record/root server
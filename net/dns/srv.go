@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"fmt"
 
 	mdns "github.com/miekg/dns"
@@ -19,16 +20,8 @@ func (s SRV) String() string {
 
 var TypeSRV uint16 = 33
 
-// QuerySRV ask the server and returns a slice of SRV.
-// The answer slice will be nil in case of error.
-//
-// The other record types are ignored.
-func (s *Server) QuerySRV(name string) ([]SRV, error) {
-
-	rr, err := s.query(name, TypeSRV)
-	if err != nil {
-		return nil, err
-	}
+// parseSRV converts the answer rr to SRV.
+func parseSRV(rr []mdns.RR) ([]SRV, error) {
 
 	r := make([]SRV, 0, len(rr))
 
@@ -43,6 +36,9 @@ func (s *Server) QuerySRV(name string) ([]SRV, error) {
 		case *mdns.DNAME:
 			// Ignore DNAME
 			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
 		default:
 			return nil, fmt.Errorf("unknown type: %T", v)
 		}
@@ -51,22 +47,56 @@ func (s *Server) QuerySRV(name string) ([]SRV, error) {
 	return r, nil
 }
 
+// QuerySRV ask the server and returns a slice of SRV.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QuerySRV(name string) ([]SRV, error) {
+
+	return s.QuerySRVContext(context.Background(), name)
+}
+
+// QuerySRVContext is like QuerySRV, but the query is bound to ctx.
+func (s *Server) QuerySRVContext(ctx context.Context, name string) ([]SRV, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeSRV)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSRV(rr)
+}
+
 // QuerySRV ask a random server from servers and returns a slice of SRV.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func (s *Servers) QuerySRV(name string) ([]SRV, error) {
 
-	return s.Get(-1).QuerySRV(name)
+	return s.QuerySRVContext(context.Background(), name)
 }
 
-// QuerySRV ask a random server from DefaultServers and returns a slice of SRV.
+// QuerySRVContext is like QuerySRV, but the query is bound to ctx.
+func (s *Servers) QuerySRVContext(ctx context.Context, name string) ([]SRV, error) {
+
+	return s.Get(-1).QuerySRVContext(ctx, name)
+}
+
+// QuerySRV asks the DefaultResolver and returns a slice of SRV.
+// Unlike Servers.QuerySRV, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QuerySRV is the same as TryQuerySRV.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func QuerySRV(name string) ([]SRV, error) {
 
-	return DefaultServers.QuerySRV(name)
+	return QuerySRVContext(context.Background(), name)
+}
+
+// QuerySRVContext is like QuerySRV, but the query is bound to ctx.
+func QuerySRVContext(ctx context.Context, name string) ([]SRV, error) {
+
+	return QuerySRVWith(ctx, DefaultResolver, name)
 }
 
 // TryQuerySRV asks the servers for type SRV. If any error occurred, retries with next server (except if error is NXDOMAIN).
@@ -76,50 +106,61 @@ func QuerySRV(name string) ([]SRV, error) {
 // The first used server is random. The other record types are ignored.
 func (s *Servers) TryQuerySRV(name string) ([]SRV, error) {
 
-	rr, err := s.TryQuery(name, TypeSRV)
-	if err != nil {
-		return nil, err
-	}
+	return s.TryQuerySRVContext(context.Background(), name)
+}
 
-	r := make([]SRV, 0, len(rr))
+// TryQuerySRVContext is like TryQuerySRV, but the query is bound to ctx.
+func (s *Servers) TryQuerySRVContext(ctx context.Context, name string) ([]SRV, error) {
 
-	for i := range rr {
-
-		switch v := rr[i].(type) {
-		case *mdns.SRV:
-			r = append(r, SRV{Priority: int(v.Priority), Weight: int(v.Weight), Port: int(v.Port), Target: v.Target})
-		case *mdns.CNAME:
-			// Ignore CNAME
-			continue
-		case *mdns.DNAME:
-			// Ignore DNAME
-			continue
-		default:
-			return nil, fmt.Errorf("unknown type: %T", v)
-		}
-	}
-
-	return r, nil
+	return QuerySRVWith(ctx, s, name)
 }
 
-// TryQuerySRV asks the DefaultServers for type SRV. If any error occurred, retries with next server (except if error is NXDOMAIN).
+// TryQuerySRV asks the DefaultResolver for type SRV. If any error occurred, retries with next server (except if error is NXDOMAIN).
 //
 // In case of error, the answer will be nil and return ErrX or any unknown error.
 //
 // The first used server is random. The other record types are ignored.
 func TryQuerySRV(name string) ([]SRV, error) {
 
-	return DefaultServers.TryQuerySRV(name)
+	return TryQuerySRVContext(context.Background(), name)
+}
+
+// TryQuerySRVContext is like TryQuerySRV, but the query is bound to ctx.
+func TryQuerySRVContext(ctx context.Context, name string) ([]SRV, error) {
+
+	return QuerySRVWith(ctx, DefaultResolver, name)
+}
+
+// QuerySRVWith asks r for type SRV and converts the answer like QuerySRV.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QuerySRVWith(ctx context.Context, r Resolver, name string) ([]SRV, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeSRV)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSRV(rr)
 }
 
 // IsSetSRV checks whether an SRV type record set for name.
 // NXDOMAIN is not an error here, because it means "not found".
 func (s *Servers) IsSetSRV(name string) (bool, error) {
-	return s.IsSet(name, TypeSRV)
+	return s.IsSetSRVContext(context.Background(), name)
 }
 
-// IsSetSRV checks whether an SRV type record set for name using the DefaultServers.
+// IsSetSRVContext is like IsSetSRV, but the query is bound to ctx.
+func (s *Servers) IsSetSRVContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeSRV)
+}
+
+// IsSetSRV checks whether an SRV type record set for name using the DefaultResolver.
 // NXDOMAIN is not an error here, because it means "not found".
 func IsSetSRV(name string) (bool, error) {
-	return DefaultServers.IsSetSRV(name)
+	return IsSetSRVContext(context.Background(), name)
+}
+
+// IsSetSRVContext is like IsSetSRV, but the query is bound to ctx.
+func IsSetSRVContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeSRV)
 }
@@ -0,0 +1,166 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+
+	mdns "github.com/miekg/dns"
+)
+
+var TypeSSHFP uint16 = 44
+
+// See more: https://www.rfc-editor.org/rfc/rfc4255.html#section-3.1
+type SSHFP struct {
+	Algorithm   uint8  // Public key algorithm (eg.: 4 is Ed25519)
+	Type        uint8  // Fingerprint type, 1 is SHA-1, 2 is SHA-256
+	FingerPrint string // Fingerprint in hex
+}
+
+func (s SSHFP) String() string {
+	return fmt.Sprintf("%d %d %s", s.Algorithm, s.Type, s.FingerPrint)
+}
+
+// parseSSHFP converts the answer rr to SSHFP.
+func parseSSHFP(rr []mdns.RR) ([]SSHFP, error) {
+
+	r := make([]SSHFP, 0, len(rr))
+
+	for i := range rr {
+
+		switch v := rr[i].(type) {
+		case *mdns.SSHFP:
+			r = append(r, SSHFP{Algorithm: v.Algorithm, Type: v.Type, FingerPrint: v.FingerPrint})
+		case *mdns.CNAME:
+			// Ignore CNAME
+			continue
+		case *mdns.DNAME:
+			// Ignore DNAME
+			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
+		default:
+			return nil, fmt.Errorf("unknown type: %T", v)
+		}
+	}
+
+	return r, nil
+}
+
+// QuerySSHFP ask the server and returns a slice of SSHFP.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QuerySSHFP(name string) ([]SSHFP, error) {
+
+	return s.QuerySSHFPContext(context.Background(), name)
+}
+
+// QuerySSHFPContext is like QuerySSHFP, but the query is bound to ctx.
+func (s *Server) QuerySSHFPContext(ctx context.Context, name string) ([]SSHFP, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeSSHFP)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSSHFP(rr)
+}
+
+// QuerySSHFP ask a random server from servers and returns a slice of SSHFP.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Servers) QuerySSHFP(name string) ([]SSHFP, error) {
+
+	return s.QuerySSHFPContext(context.Background(), name)
+}
+
+// QuerySSHFPContext is like QuerySSHFP, but the query is bound to ctx.
+func (s *Servers) QuerySSHFPContext(ctx context.Context, name string) ([]SSHFP, error) {
+
+	return s.Get(-1).QuerySSHFPContext(ctx, name)
+}
+
+// QuerySSHFP asks the DefaultResolver and returns a slice of SSHFP.
+// Unlike Servers.QuerySSHFP, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QuerySSHFP is the same as TryQuerySSHFP.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func QuerySSHFP(name string) ([]SSHFP, error) {
+
+	return QuerySSHFPContext(context.Background(), name)
+}
+
+// QuerySSHFPContext is like QuerySSHFP, but the query is bound to ctx.
+func QuerySSHFPContext(ctx context.Context, name string) ([]SSHFP, error) {
+
+	return QuerySSHFPWith(ctx, DefaultResolver, name)
+}
+
+// TryQuerySSHFP asks the servers for type SSHFP. If any error occurred, retries with next server (except if error is NXDOMAIN).
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func (s *Servers) TryQuerySSHFP(name string) ([]SSHFP, error) {
+
+	return s.TryQuerySSHFPContext(context.Background(), name)
+}
+
+// TryQuerySSHFPContext is like TryQuerySSHFP, but the query is bound to ctx.
+func (s *Servers) TryQuerySSHFPContext(ctx context.Context, name string) ([]SSHFP, error) {
+
+	return QuerySSHFPWith(ctx, s, name)
+}
+
+// TryQuerySSHFP asks the DefaultResolver for type SSHFP. If any error occurred, retries with next server (except if error is NXDOMAIN).
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func TryQuerySSHFP(name string) ([]SSHFP, error) {
+
+	return TryQuerySSHFPContext(context.Background(), name)
+}
+
+// TryQuerySSHFPContext is like TryQuerySSHFP, but the query is bound to ctx.
+func TryQuerySSHFPContext(ctx context.Context, name string) ([]SSHFP, error) {
+
+	return QuerySSHFPWith(ctx, DefaultResolver, name)
+}
+
+// QuerySSHFPWith asks r for type SSHFP and converts the answer like QuerySSHFP.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QuerySSHFPWith(ctx context.Context, r Resolver, name string) ([]SSHFP, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeSSHFP)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSSHFP(rr)
+}
+
+// IsSetSSHFP checks whether an SSHFP type record set for name.
+// NXDOMAIN is not an error here, because it means "not found".
+func (s *Servers) IsSetSSHFP(name string) (bool, error) {
+	return s.IsSetSSHFPContext(context.Background(), name)
+}
+
+// IsSetSSHFPContext is like IsSetSSHFP, but the query is bound to ctx.
+func (s *Servers) IsSetSSHFPContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeSSHFP)
+}
+
+// IsSetSSHFP checks whether an SSHFP type record set for name using the DefaultResolver.
+// NXDOMAIN is not an error here, because it means "not found".
+func IsSetSSHFP(name string) (bool, error) {
+	return IsSetSSHFPContext(context.Background(), name)
+}
+
+// IsSetSSHFPContext is like IsSetSSHFP, but the query is bound to ctx.
+func IsSetSSHFPContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeSSHFP)
+}
@@ -0,0 +1,293 @@
+package dns
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	mdns "github.com/miekg/dns"
+)
+
+var TypeSVCB uint16 = 64
+
+// SvcParams is the parsed SvcParams of an SVCB or HTTPS record.
+// See more: https://www.rfc-editor.org/rfc/rfc9460.html#section-7
+type SvcParams struct {
+	Mandatory     []string          // Keys that must be supported by the client
+	ALPN          []string          // Supported ALPN protocol IDs (eg.: "h2", "h3")
+	NoDefaultALPN bool              // The default ALPN of the scheme is not supported
+	Port          uint16            // Alternative port, 0 if not set
+	IPv4Hint      []net.IP          // IPv4 address hints
+	ECH           []byte            // ECHConfigList
+	IPv6Hint      []net.IP          // IPv6 address hints
+	Other         map[string]string // The other keys in presentation format (eg.: "dohpath"), nil if none
+}
+
+func (p SvcParams) String() string {
+
+	var v []string
+
+	if len(p.Mandatory) > 0 {
+		v = append(v, "mandatory="+strings.Join(p.Mandatory, ","))
+	}
+
+	if len(p.ALPN) > 0 {
+		v = append(v, "alpn="+strings.Join(p.ALPN, ","))
+	}
+
+	if p.NoDefaultALPN {
+		v = append(v, "no-default-alpn")
+	}
+
+	if p.Port != 0 {
+		v = append(v, "port="+strconv.Itoa(int(p.Port)))
+	}
+
+	if len(p.IPv4Hint) > 0 {
+		v = append(v, "ipv4hint="+joinIPs(p.IPv4Hint))
+	}
+
+	if len(p.ECH) > 0 {
+		v = append(v, "ech="+base64.StdEncoding.EncodeToString(p.ECH))
+	}
+
+	if len(p.IPv6Hint) > 0 {
+		v = append(v, "ipv6hint="+joinIPs(p.IPv6Hint))
+	}
+
+	keys := make([]string, 0, len(p.Other))
+	for k := range p.Other {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if p.Other[k] == "" {
+			v = append(v, k)
+		} else {
+			v = append(v, k+"="+p.Other[k])
+		}
+	}
+
+	return strings.Join(v, " ")
+}
+
+func joinIPs(ips []net.IP) string {
+
+	v := make([]string, 0, len(ips))
+
+	for i := range ips {
+		v = append(v, ips[i].String())
+	}
+
+	return strings.Join(v, ",")
+}
+
+// See more: https://www.rfc-editor.org/rfc/rfc9460.html#section-2.2
+type SVCB struct {
+	Priority uint16 // 0 is AliasMode, else ServiceMode
+	Target   string // TargetName, "." means the owner name in ServiceMode
+	Params   SvcParams
+}
+
+// HTTPS record has the same format as SVCB.
+// See more: https://www.rfc-editor.org/rfc/rfc9460.html#section-9
+type HTTPS = SVCB
+
+func (s SVCB) String() string {
+
+	if p := s.Params.String(); p != "" {
+		return fmt.Sprintf("%d %s %s", s.Priority, s.Target, p)
+	}
+
+	return fmt.Sprintf("%d %s", s.Priority, s.Target)
+}
+
+// IsAlias returns whether the record is in AliasMode.
+func (s SVCB) IsAlias() bool {
+	return s.Priority == 0
+}
+
+// newSVCB converts v to SVCB.
+func newSVCB(v *mdns.SVCB) SVCB {
+
+	r := SVCB{Priority: v.Priority, Target: v.Target}
+
+	for _, kv := range v.Value {
+
+		switch p := kv.(type) {
+		case *mdns.SVCBMandatory:
+			for i := range p.Code {
+				r.Params.Mandatory = append(r.Params.Mandatory, p.Code[i].String())
+			}
+		case *mdns.SVCBAlpn:
+			r.Params.ALPN = append(r.Params.ALPN, p.Alpn...)
+		case *mdns.SVCBNoDefaultAlpn:
+			r.Params.NoDefaultALPN = true
+		case *mdns.SVCBPort:
+			r.Params.Port = p.Port
+		case *mdns.SVCBIPv4Hint:
+			r.Params.IPv4Hint = append(r.Params.IPv4Hint, p.Hint...)
+		case *mdns.SVCBECHConfig:
+			r.Params.ECH = append([]byte(nil), p.ECH...)
+		case *mdns.SVCBIPv6Hint:
+			r.Params.IPv6Hint = append(r.Params.IPv6Hint, p.Hint...)
+		default:
+			if r.Params.Other == nil {
+				r.Params.Other = make(map[string]string)
+			}
+			r.Params.Other[kv.Key().String()] = kv.String()
+		}
+	}
+
+	return r
+}
+
+// parseSVCB converts the answer rr to SVCB.
+func parseSVCB(rr []mdns.RR) ([]SVCB, error) {
+
+	r := make([]SVCB, 0, len(rr))
+
+	for i := range rr {
+
+		switch v := rr[i].(type) {
+		case *mdns.SVCB:
+			r = append(r, newSVCB(v))
+		case *mdns.CNAME:
+			// Ignore CNAME
+			continue
+		case *mdns.DNAME:
+			// Ignore DNAME
+			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
+		default:
+			return nil, fmt.Errorf("unknown type: %T", v)
+		}
+	}
+
+	return r, nil
+}
+
+// QuerySVCB ask the server and returns a slice of SVCB.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QuerySVCB(name string) ([]SVCB, error) {
+
+	return s.QuerySVCBContext(context.Background(), name)
+}
+
+// QuerySVCBContext is like QuerySVCB, but the query is bound to ctx.
+func (s *Server) QuerySVCBContext(ctx context.Context, name string) ([]SVCB, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeSVCB)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSVCB(rr)
+}
+
+// QuerySVCB ask a random server from servers and returns a slice of SVCB.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Servers) QuerySVCB(name string) ([]SVCB, error) {
+
+	return s.QuerySVCBContext(context.Background(), name)
+}
+
+// QuerySVCBContext is like QuerySVCB, but the query is bound to ctx.
+func (s *Servers) QuerySVCBContext(ctx context.Context, name string) ([]SVCB, error) {
+
+	return s.Get(-1).QuerySVCBContext(ctx, name)
+}
+
+// QuerySVCB asks the DefaultResolver and returns a slice of SVCB.
+// Unlike Servers.QuerySVCB, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QuerySVCB is the same as TryQuerySVCB.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func QuerySVCB(name string) ([]SVCB, error) {
+
+	return QuerySVCBContext(context.Background(), name)
+}
+
+// QuerySVCBContext is like QuerySVCB, but the query is bound to ctx.
+func QuerySVCBContext(ctx context.Context, name string) ([]SVCB, error) {
+
+	return QuerySVCBWith(ctx, DefaultResolver, name)
+}
+
+// TryQuerySVCB asks the servers for type SVCB. If any error occurred, retries with next server (except if error is NXDOMAIN).
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func (s *Servers) TryQuerySVCB(name string) ([]SVCB, error) {
+
+	return s.TryQuerySVCBContext(context.Background(), name)
+}
+
+// TryQuerySVCBContext is like TryQuerySVCB, but the query is bound to ctx.
+func (s *Servers) TryQuerySVCBContext(ctx context.Context, name string) ([]SVCB, error) {
+
+	return QuerySVCBWith(ctx, s, name)
+}
+
+// TryQuerySVCB asks the DefaultResolver for type SVCB. If any error occurred, retries with next server (except if error is NXDOMAIN).
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func TryQuerySVCB(name string) ([]SVCB, error) {
+
+	return TryQuerySVCBContext(context.Background(), name)
+}
+
+// TryQuerySVCBContext is like TryQuerySVCB, but the query is bound to ctx.
+func TryQuerySVCBContext(ctx context.Context, name string) ([]SVCB, error) {
+
+	return QuerySVCBWith(ctx, DefaultResolver, name)
+}
+
+// QuerySVCBWith asks r for type SVCB and converts the answer like QuerySVCB.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QuerySVCBWith(ctx context.Context, r Resolver, name string) ([]SVCB, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeSVCB)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSVCB(rr)
+}
+
+// IsSetSVCB checks whether an SVCB type record set for name.
+// NXDOMAIN is not an error here, because it means "not found".
+func (s *Servers) IsSetSVCB(name string) (bool, error) {
+	return s.IsSetSVCBContext(context.Background(), name)
+}
+
+// IsSetSVCBContext is like IsSetSVCB, but the query is bound to ctx.
+func (s *Servers) IsSetSVCBContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeSVCB)
+}
+
+// IsSetSVCB checks whether an SVCB type record set for name using the DefaultResolver.
+// NXDOMAIN is not an error here, because it means "not found".
+func IsSetSVCB(name string) (bool, error) {
+	return IsSetSVCBContext(context.Background(), name)
+}
+
+// IsSetSVCBContext is like IsSetSVCB, but the query is bound to ctx.
+func IsSetSVCBContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeSVCB)
+}
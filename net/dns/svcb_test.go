@@ -0,0 +1,54 @@
+package dns
+
+import (
+	"testing"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
+)
+
+func TestQuerySVCB(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, dnstest.MustZone(".",
+		"_dns.resolver.example. 300 IN SVCB 1 dns.example.net. alpn=h2,h3 port=8443 ipv6hint=2001:db8::1 dohpath=/dns-query{?dns} mandatory=alpn,port",
+		"_dns.resolver.example. 300 IN SVCB 0 alias.example.net.",
+		"example.com. 300 IN HTTPS 1 . alpn=h3 no-default-alpn ech=AEX+DQBBpQAgACCW2/dfOBZAtQU55/znuUc1MpnQ2HkIqGVfCDwuLu9jWQAEAAEAAQASY2xvdWRmbGFyZS1lY2guY29tAAA= ipv4hint=192.0.2.1,192.0.2.2",
+	))
+
+	r, err := srvs.TryQuerySVCB("_dns.resolver.example")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(r) != 2 {
+		t.Fatalf("FAIL: Invalid number of records: %d\n", len(r))
+	}
+
+	p := r[0].Params
+
+	if r[0].IsAlias() || r[0].Target != "dns.example.net." || len(p.ALPN) != 2 || p.ALPN[1] != "h3" || p.Port != 8443 ||
+		len(p.IPv6Hint) != 1 || p.IPv6Hint[0].String() != "2001:db8::1" || p.Other["dohpath"] != "/dns-query{?dns}" || len(p.Mandatory) != 2 {
+		t.Fatalf("FAIL: Invalid ServiceMode record: %+v\n", r[0])
+	}
+
+	if r[0].String() != "1 dns.example.net. mandatory=alpn,port alpn=h2,h3 port=8443 ipv6hint=2001:db8::1 dohpath=/dns-query{?dns}" {
+		t.Fatalf("FAIL: Invalid string: %s\n", r[0])
+	}
+
+	if !r[1].IsAlias() || r[1].String() != "0 alias.example.net." {
+		t.Fatalf("FAIL: Invalid AliasMode record: %+v\n", r[1])
+	}
+
+	h, err := srvs.Get(0).QueryHTTPS("example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(h) != 1 || !h[0].Params.NoDefaultALPN || len(h[0].Params.ECH) == 0 || len(h[0].Params.IPv4Hint) != 2 || h[0].Params.IPv4Hint[1].String() != "192.0.2.2" {
+		t.Fatalf("FAIL: Invalid HTTPS record: %+v\n", h)
+	}
+
+	set, err := srvs.IsSetHTTPS("example.com")
+	if err != nil || !set {
+		t.Fatalf("FAIL: HTTPS is not set: %v\n", err)
+	}
+}
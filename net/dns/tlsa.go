@@ -0,0 +1,167 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+
+	mdns "github.com/miekg/dns"
+)
+
+var TypeTLSA uint16 = 52
+
+// See more: https://www.rfc-editor.org/rfc/rfc6698.html#section-2.1
+type TLSA struct {
+	Usage        uint8  // Certificate usage (eg.: 3 is DANE-EE)
+	Selector     uint8  // 0 is the full certificate, 1 is the SubjectPublicKeyInfo
+	MatchingType uint8  // 0 is the exact match, 1 is SHA-256, 2 is SHA-512
+	Certificate  string // Certificate association data in hex
+}
+
+func (t TLSA) String() string {
+	return fmt.Sprintf("%d %d %d %s", t.Usage, t.Selector, t.MatchingType, t.Certificate)
+}
+
+// parseTLSA converts the answer rr to TLSA.
+func parseTLSA(rr []mdns.RR) ([]TLSA, error) {
+
+	r := make([]TLSA, 0, len(rr))
+
+	for i := range rr {
+
+		switch v := rr[i].(type) {
+		case *mdns.TLSA:
+			r = append(r, TLSA{Usage: v.Usage, Selector: v.Selector, MatchingType: v.MatchingType, Certificate: v.Certificate})
+		case *mdns.CNAME:
+			// Ignore CNAME
+			continue
+		case *mdns.DNAME:
+			// Ignore DNAME
+			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
+		default:
+			return nil, fmt.Errorf("unknown type: %T", v)
+		}
+	}
+
+	return r, nil
+}
+
+// QueryTLSA ask the server and returns a slice of TLSA.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QueryTLSA(name string) ([]TLSA, error) {
+
+	return s.QueryTLSAContext(context.Background(), name)
+}
+
+// QueryTLSAContext is like QueryTLSA, but the query is bound to ctx.
+func (s *Server) QueryTLSAContext(ctx context.Context, name string) ([]TLSA, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeTLSA)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTLSA(rr)
+}
+
+// QueryTLSA ask a random server from servers and returns a slice of TLSA.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Servers) QueryTLSA(name string) ([]TLSA, error) {
+
+	return s.QueryTLSAContext(context.Background(), name)
+}
+
+// QueryTLSAContext is like QueryTLSA, but the query is bound to ctx.
+func (s *Servers) QueryTLSAContext(ctx context.Context, name string) ([]TLSA, error) {
+
+	return s.Get(-1).QueryTLSAContext(ctx, name)
+}
+
+// QueryTLSA asks the DefaultResolver and returns a slice of TLSA.
+// Unlike Servers.QueryTLSA, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QueryTLSA is the same as TryQueryTLSA.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func QueryTLSA(name string) ([]TLSA, error) {
+
+	return QueryTLSAContext(context.Background(), name)
+}
+
+// QueryTLSAContext is like QueryTLSA, but the query is bound to ctx.
+func QueryTLSAContext(ctx context.Context, name string) ([]TLSA, error) {
+
+	return QueryTLSAWith(ctx, DefaultResolver, name)
+}
+
+// TryQueryTLSA asks the servers for type TLSA. If any error occurred, retries with next server (except if error is NXDOMAIN).
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func (s *Servers) TryQueryTLSA(name string) ([]TLSA, error) {
+
+	return s.TryQueryTLSAContext(context.Background(), name)
+}
+
+// TryQueryTLSAContext is like TryQueryTLSA, but the query is bound to ctx.
+func (s *Servers) TryQueryTLSAContext(ctx context.Context, name string) ([]TLSA, error) {
+
+	return QueryTLSAWith(ctx, s, name)
+}
+
+// TryQueryTLSA asks the DefaultResolver for type TLSA. If any error occurred, retries with next server (except if error is NXDOMAIN).
+//
+// In case of error, the answer will be nil and return ErrX or any unknown error.
+//
+// The first used server is random. The other record types are ignored.
+func TryQueryTLSA(name string) ([]TLSA, error) {
+
+	return TryQueryTLSAContext(context.Background(), name)
+}
+
+// TryQueryTLSAContext is like TryQueryTLSA, but the query is bound to ctx.
+func TryQueryTLSAContext(ctx context.Context, name string) ([]TLSA, error) {
+
+	return QueryTLSAWith(ctx, DefaultResolver, name)
+}
+
+// QueryTLSAWith asks r for type TLSA and converts the answer like QueryTLSA.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QueryTLSAWith(ctx context.Context, r Resolver, name string) ([]TLSA, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeTLSA)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTLSA(rr)
+}
+
+// IsSetTLSA checks whether an TLSA type record set for name.
+// NXDOMAIN is not an error here, because it means "not found".
+func (s *Servers) IsSetTLSA(name string) (bool, error) {
+	return s.IsSetTLSAContext(context.Background(), name)
+}
+
+// IsSetTLSAContext is like IsSetTLSA, but the query is bound to ctx.
+func (s *Servers) IsSetTLSAContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeTLSA)
+}
+
+// IsSetTLSA checks whether an TLSA type record set for name using the DefaultResolver.
+// NXDOMAIN is not an error here, because it means "not found".
+func IsSetTLSA(name string) (bool, error) {
+	return IsSetTLSAContext(context.Background(), name)
+}
+
+// IsSetTLSAContext is like IsSetTLSA, but the query is bound to ctx.
+func IsSetTLSAContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeTLSA)
+}
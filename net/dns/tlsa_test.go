@@ -0,0 +1,34 @@
+package dns
+
+import (
+	"testing"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
+)
+
+func TestQueryTLSA(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, dnstest.MustZone(".",
+		"_25._tcp.mail.example.com. 300 IN TLSA 3 1 1 0C72AC70B745AC19998811B131D662C9AC69DBDBE7CB23E5B514B56664C5D3D6",
+		"_25._tcp.mail.example.com. 300 IN A 192.0.2.1",
+	))
+
+	r, err := srvs.TryQueryTLSA("_25._tcp.mail.example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(r) != 1 || r[0].String() != "3 1 1 0c72ac70b745ac19998811b131d662c9ac69dbdbe7cb23e5b514b56664c5d3d6" {
+		t.Fatalf("FAIL: Invalid TLSA: %v\n", r)
+	}
+
+	set, err := srvs.IsSetSSHFP("_25._tcp.mail.example.com")
+	if err != nil || set {
+		t.Fatalf("FAIL: SSHFP wanted to be unset: %v, error: %v\n", set, err)
+	}
+
+	n, err := srvs.TryQueryNAPTR("_25._tcp.mail.example.com")
+	if err != nil || len(n) != 0 {
+		t.Fatalf("FAIL: NODATA wanted: %v, error: %v\n", n, err)
+	}
+}
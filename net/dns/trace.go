@@ -0,0 +1,376 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	mdns "github.com/miekg/dns"
+)
+
+var ErrNotIterative = errors.New("server is not iterative")
+
+// TraceQuery is a query sent to an authoritative server during a trace.
+type TraceQuery struct {
+	Server        string        `json:"server,omitempty"` // Name of the nameserver, empty for the hints
+	IP            string        `json:"ip"`
+	Rcode         string        `json:"rcode,omitempty"` // Empty if no response
+	Authoritative bool          `json:"authoritative"`
+	Referral      string        `json:"referral,omitempty"` // The delegated zone if the response is a referral
+	NS            []string      `json:"ns,omitempty"`       // The nameservers of the delegated zone in the referral
+	Answer        []ZoneRecord  `json:"answer,omitempty"`
+	Duration      time.Duration `json:"duration"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// signature returns the comparable part of the response.
+func (q TraceQuery) signature() string {
+
+	answer := make([]string, 0, len(q.Answer))
+	for i := range q.Answer {
+		answer = append(answer, q.Answer[i].String())
+	}
+
+	sort.Strings(answer)
+
+	ns := append([]string(nil), q.NS...)
+	sort.Strings(ns)
+
+	return fmt.Sprintf("%s aa=%v referral=%s ns=%v answer=%v", q.Rcode, q.Authoritative, q.Referral, ns, answer)
+}
+
+// TraceStep is a level of the delegation path.
+type TraceStep struct {
+	Zone       string       `json:"zone"`                 // The zone served by the queried servers ("." for the hints)
+	Queries    []TraceQuery `json:"queries"`              // A query for every address of the nameservers
+	Referral   string       `json:"referral,omitempty"`   // The followed delegation
+	NS         []string     `json:"ns,omitempty"`         // The nameservers of the followed delegation
+	TTL        uint32       `json:"ttl,omitempty"`        // The TTL of the NS records of the followed delegation
+	Glue       []ZoneRecord `json:"glue,omitempty"`       // The glue records of the followed delegation
+	Resolved   []string     `json:"resolved,omitempty"`   // The resolved addresses of the glueless nameservers
+	Mismatches []string     `json:"mismatches,omitempty"` // The responses differ from the first usable response
+}
+
+// DelegationTrace is the delegation path of a name from the hints to the authoritative servers.
+type DelegationTrace struct {
+	Name   string       `json:"name"`
+	Type   string       `json:"type"`
+	Steps  []TraceStep  `json:"steps"`
+	Rcode  string       `json:"rcode,omitempty"` // The final rcode, empty if the trace failed
+	Answer []ZoneRecord `json:"answer,omitempty"`
+	Error  string       `json:"error,omitempty"` // The reason of the failure
+}
+
+// String renders the trace as text, like dig +trace.
+func (d *DelegationTrace) String() string {
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, ";; Trace of %s %s\n", d.Name, d.Type)
+
+	for _, s := range d.Steps {
+
+		fmt.Fprintf(&b, "\n;; %s (%d queries)\n", s.Zone, len(s.Queries))
+
+		for _, q := range s.Queries {
+
+			server := q.IP
+			if q.Server != "" {
+				server = q.Server + " (" + q.IP + ")"
+			}
+
+			switch {
+			case q.Error != "":
+				fmt.Fprintf(&b, "%s: %s in %s\n", server, q.Error, q.Duration.Round(time.Millisecond))
+			case q.Referral != "":
+				fmt.Fprintf(&b, "%s: %s referral to %s [%s] in %s\n", server, q.Rcode, q.Referral, strings.Join(q.NS, " "), q.Duration.Round(time.Millisecond))
+			default:
+				fmt.Fprintf(&b, "%s: %s aa=%v %d answers in %s\n", server, q.Rcode, q.Authoritative, len(q.Answer), q.Duration.Round(time.Millisecond))
+			}
+		}
+
+		if s.Referral != "" {
+
+			fmt.Fprintf(&b, ";; Delegation to %s (TTL %d)\n", s.Referral, s.TTL)
+
+			for _, ns := range s.NS {
+				fmt.Fprintf(&b, "%s\t%d\tIN\tNS\t%s\n", s.Referral, s.TTL, ns)
+			}
+
+			for _, g := range s.Glue {
+				fmt.Fprintf(&b, "%s\n", g)
+			}
+
+			if len(s.Resolved) > 0 {
+				fmt.Fprintf(&b, ";; Glueless, resolved: %s\n", strings.Join(s.Resolved, " "))
+			}
+		}
+
+		for _, m := range s.Mismatches {
+			fmt.Fprintf(&b, ";; MISMATCH: %s\n", m)
+		}
+	}
+
+	if d.Rcode != "" {
+
+		fmt.Fprintf(&b, "\n;; Result: %s\n", d.Rcode)
+
+		for _, a := range d.Answer {
+			fmt.Fprintf(&b, "%s\n", a)
+		}
+	}
+
+	if d.Error != "" {
+		fmt.Fprintf(&b, "\n;; Error: %s\n", d.Error)
+	}
+
+	return b.String()
+}
+
+// traceTarget is an address of a nameserver to query.
+type traceTarget struct {
+	name string
+	ip   string
+}
+
+// Trace walks the delegations of name with type t from the hints of s to the authoritative servers, like dig +trace.
+// Every IPv4 address of every nameserver is queried at each level, the IPv6 glue records are listed but not queried.
+// The first usable response is followed, the differences of the other responses are reported in TraceStep.Mismatches.
+// The CNAMEs in the answer are not followed.
+//
+// s must be an iterative Server (see NewServerIterative()), returns ErrNotIterative otherwise.
+// The returned trace is not nil if s is iterative: if the trace failed, the error is returned with the trace up to the failure.
+func (s *Server) Trace(name string, t uint16) (*DelegationTrace, error) {
+
+	return s.TraceContext(context.Background(), name, t)
+}
+
+// TraceContext is like Trace, but the queries are bound to ctx.
+func (s *Server) TraceContext(ctx context.Context, name string, t uint16) (*DelegationTrace, error) {
+
+	if s.iter == nil {
+		return nil, ErrNotIterative
+	}
+
+	d := &DelegationTrace{Name: strings.ToLower(mdns.Fqdn(name)), Type: TypeToString(t)}
+
+	err := s.iter.trace(ctx, d, t)
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	return d, err
+}
+
+// Trace traces the delegations of name with type t from the RootHints.
+// Timeout is the timeout of a single query.
+// See Server.Trace().
+func Trace(name string, t uint16, timeout time.Duration) (*DelegationTrace, error) {
+
+	return TraceContext(context.Background(), name, t, timeout)
+}
+
+// TraceContext is like Trace, but the queries are bound to ctx.
+func TraceContext(ctx context.Context, name string, t uint16, timeout time.Duration) (*DelegationTrace, error) {
+
+	s, err := NewServerIterative(nil, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.TraceContext(ctx, name, t)
+}
+
+func (it *iterative) trace(ctx context.Context, d *DelegationTrace, t uint16) error {
+
+	zone := "."
+
+	targets := make([]traceTarget, 0, len(it.hints))
+	for i := range it.hints {
+		targets = append(targets, traceTarget{ip: it.hints[i]})
+	}
+
+	for i := 0; i < MaxIterativeReferrals; i++ {
+
+		step := TraceStep{Zone: zone}
+		responses := it.traceQueries(ctx, &step, targets, d.Name, t)
+
+		d.Steps = append(d.Steps, step)
+		last := &d.Steps[len(d.Steps)-1]
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// The first usable response
+		var (
+			in  *mdns.Msg
+			sig string
+		)
+
+		for ii := range responses {
+
+			r := responses[ii]
+
+			if r == nil || (r.Rcode != mdns.RcodeSuccess && r.Rcode != mdns.RcodeNameError) {
+				continue
+			}
+
+			if child, _ := referral(r, zone, d.Name); r.Rcode == mdns.RcodeNameError || len(r.Answer) > 0 || r.Authoritative || child != "" {
+				in = r
+				sig = last.Queries[ii].signature()
+				break
+			}
+		}
+
+		if in == nil {
+			return fmt.Errorf("%w for %s", ErrNoNameservers, zone)
+		}
+
+		for ii := range responses {
+			if responses[ii] != nil && last.Queries[ii].signature() != sig {
+				last.Mismatches = append(last.Mismatches, fmt.Sprintf("%s: %s", last.Queries[ii].IP, last.Queries[ii].signature()))
+			}
+		}
+
+		child, nss := referral(in, zone, d.Name)
+
+		if in.Rcode == mdns.RcodeNameError || len(in.Answer) > 0 || child == "" {
+			d.Rcode = mdns.RcodeToString[in.Rcode]
+			d.Answer = toZoneRecords(in.Answer)
+			return nil
+		}
+
+		last.Referral = child
+		last.NS = nss
+
+		for _, rr := range in.Ns {
+			if ns, ok := rr.(*mdns.NS); ok && strings.EqualFold(ns.Hdr.Name, child) {
+				last.TTL = ns.Hdr.Ttl
+				break
+			}
+		}
+
+		targets = targets[:0]
+
+		for _, rr := range in.Extra {
+
+			for _, ns := range nss {
+
+				if !strings.EqualFold(rr.Header().Name, ns) {
+					continue
+				}
+
+				switch v := rr.(type) {
+				case *mdns.A:
+					targets = append(targets, traceTarget{name: ns, ip: v.A.String()})
+					last.Glue = append(last.Glue, toZoneRecords([]mdns.RR{rr})...)
+				case *mdns.AAAA:
+					last.Glue = append(last.Glue, toZoneRecords([]mdns.RR{rr})...)
+				}
+			}
+		}
+
+		// Glueless nameservers
+		for _, ns := range nss {
+
+			hasGlue := false
+			for ii := range targets {
+				if targets[ii].name == ns {
+					hasGlue = true
+					break
+				}
+			}
+
+			if hasGlue {
+				continue
+			}
+
+			r, err := it.resolve(ctx, ns, TypeA, 1)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				continue
+			}
+
+			for _, rr := range r.Answer {
+				if a, ok := rr.(*mdns.A); ok {
+					targets = append(targets, traceTarget{name: ns, ip: a.A.String()})
+					last.Resolved = append(last.Resolved, ns+" "+a.A.String())
+				}
+			}
+		}
+
+		if len(targets) == 0 {
+			return fmt.Errorf("%w for %s", ErrNoNameservers, child)
+		}
+
+		zone = child
+	}
+
+	return ErrMaxReferrals
+}
+
+// traceQueries sends the non-recursive query to every target concurrently and records the queries in step.
+// Returns the responses in the order of targets, nil if failed.
+func (it *iterative) traceQueries(ctx context.Context, step *TraceStep, targets []traceTarget, name string, t uint16) []*mdns.Msg {
+
+	step.Queries = make([]TraceQuery, len(targets))
+	responses := make([]*mdns.Msg, len(targets))
+
+	var wg sync.WaitGroup
+
+	for i := range targets {
+
+		wg.Add(1)
+
+		go func(i int) {
+
+			defer wg.Done()
+
+			q := &step.Queries[i]
+			q.Server = targets[i].name
+			q.IP = targets[i].ip
+
+			srv, err := NewServer("udp", targets[i].ip, it.port, it.timeout)
+			if err != nil {
+				q.Error = err.Error()
+				return
+			}
+
+			msg := new(mdns.Msg)
+			msg.SetQuestion(name, t)
+			msg.RecursionDesired = false
+
+			start := time.Now()
+
+			in, err := srv.exchangeContext(ctx, msg)
+			if err == nil && in.Truncated {
+				in, err = srv.ToTCP().exchangeContext(ctx, msg)
+			}
+
+			q.Duration = time.Since(start)
+
+			if err != nil {
+				q.Error = err.Error()
+				return
+			}
+
+			q.Rcode = mdns.RcodeToString[in.Rcode]
+			q.Authoritative = in.Authoritative
+			q.Answer = toZoneRecords(in.Answer)
+			q.Referral, q.NS = referral(in, step.Zone, name)
+
+			responses[i] = in
+		}(i)
+	}
+
+	wg.Wait()
+
+	return responses
+}
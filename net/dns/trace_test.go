@@ -0,0 +1,68 @@
+package dns
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerTrace(t *testing.T) {
+
+	s := newIterativeTestServer(t)
+
+	d, err := s.Trace("www.example.com", TypeA)
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(d.Steps) != 3 || d.Steps[0].Zone != "." || d.Steps[1].Zone != "com." || d.Steps[2].Zone != "example.com." {
+		t.Fatalf("FAIL: unexpected path:\n%s\n", d)
+	}
+
+	if d.Steps[0].Referral != "com." || d.Steps[0].TTL != 300 || len(d.Steps[0].Glue) != 1 {
+		t.Fatalf("FAIL: unexpected root referral: %#v\n", d.Steps[0])
+	}
+
+	// The lame server of example.com is queried too
+	if len(d.Steps[2].Queries) != 2 || len(d.Steps[2].Mismatches) != 1 || !strings.Contains(d.Steps[2].Mismatches[0], "127.0.0.5") {
+		t.Fatalf("FAIL: the lame server is not reported:\n%s\n", d)
+	}
+
+	if d.Rcode != "NOERROR" || len(d.Answer) != 1 || d.Answer[0].Value != "192.0.2.10" {
+		t.Fatalf("FAIL: unexpected answer:\n%s\n", d)
+	}
+
+	if !strings.Contains(d.String(), "MISMATCH: 127.0.0.5") {
+		t.Fatalf("FAIL: the mismatch is not rendered:\n%s\n", d)
+	}
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	var v DelegationTrace
+
+	if err = json.Unmarshal(b, &v); err != nil || len(v.Steps) != 3 {
+		t.Fatalf("FAIL: invalid JSON: %s\n%s\n", err, b)
+	}
+
+	d, err = s.Trace("invalid.example.com", TypeA)
+	if err != nil || d.Rcode != "NXDOMAIN" {
+		t.Fatalf("FAIL: NXDOMAIN wanted, got %v:\n%s\n", err, d)
+	}
+}
+
+func TestServerTraceNotIterative(t *testing.T) {
+
+	s, err := NewServer("udp", "127.0.0.1", "53", time.Second)
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if _, err = s.Trace("example.com", TypeA); !errors.Is(err, ErrNotIterative) {
+		t.Fatalf("FAIL: ErrNotIterative wanted, got %v\n", err)
+	}
+}
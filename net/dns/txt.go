@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"fmt"
 
 	mdns "github.com/miekg/dns"
@@ -8,16 +9,8 @@ import (
 
 var TypeTXT uint16 = 16
 
-// QueryTXT ask the server and returns a slice of string.
-// The answer slice will be nil in case of error.
-//
-// The other record types are ignored.
-func (s *Server) QueryTXT(name string) ([]string, error) {
-
-	rr, err := s.query(name, TypeTXT)
-	if err != nil {
-		return nil, err
-	}
+// parseTXT converts the answer rr to TXT.
+func parseTXT(rr []mdns.RR) ([]string, error) {
 
 	r := make([]string, 0, len(rr))
 
@@ -32,6 +25,9 @@ func (s *Server) QueryTXT(name string) ([]string, error) {
 		case *mdns.DNAME:
 			// Ignore DNAME
 			continue
+		case *mdns.RRSIG, *mdns.NSEC, *mdns.NSEC3:
+			// Ignore the DNSSEC records (the answer to a query with DO bit)
+			continue
 		default:
 			return nil, fmt.Errorf("unknown type: %T", v)
 		}
@@ -40,22 +36,56 @@ func (s *Server) QueryTXT(name string) ([]string, error) {
 	return r, nil
 }
 
+// QueryTXT ask the server and returns a slice of string.
+// The answer slice will be nil in case of error.
+//
+// The other record types are ignored.
+func (s *Server) QueryTXT(name string) ([]string, error) {
+
+	return s.QueryTXTContext(context.Background(), name)
+}
+
+// QueryTXTContext is like QueryTXT, but the query is bound to ctx.
+func (s *Server) QueryTXTContext(ctx context.Context, name string) ([]string, error) {
+
+	rr, err := s.queryContext(ctx, name, TypeTXT)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTXT(rr)
+}
+
 // QueryTXT ask a random server from servers and returns a slice of string.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func (s *Servers) QueryTXT(name string) ([]string, error) {
 
-	return s.Get(-1).QueryTXT(name)
+	return s.QueryTXTContext(context.Background(), name)
 }
 
-// QueryTXT ask a random server from DefaultServers and returns a slice of string.
+// QueryTXTContext is like QueryTXT, but the query is bound to ctx.
+func (s *Servers) QueryTXTContext(ctx context.Context, name string) ([]string, error) {
+
+	return s.Get(-1).QueryTXTContext(ctx, name)
+}
+
+// QueryTXT asks the DefaultResolver and returns a slice of string.
+// Unlike Servers.QueryTXT, the query goes through DefaultResolver.TryQueryContext() and is retried if DefaultResolver retries
+// (eg.: DefaultServers), so QueryTXT is the same as TryQueryTXT.
 // The answer slice will be nil in case of error.
 //
 // The other record types are ignored.
 func QueryTXT(name string) ([]string, error) {
 
-	return DefaultServers.QueryTXT(name)
+	return QueryTXTContext(context.Background(), name)
+}
+
+// QueryTXTContext is like QueryTXT, but the query is bound to ctx.
+func QueryTXTContext(ctx context.Context, name string) ([]string, error) {
+
+	return QueryTXTWith(ctx, DefaultResolver, name)
 }
 
 // TryQueryTXT asks the servers for type TXT. If any error occurred, retries with next server (except if error is NXDOMAIN).
@@ -65,50 +95,61 @@ func QueryTXT(name string) ([]string, error) {
 // The first used server is random. The other record types are ignored.
 func (s *Servers) TryQueryTXT(name string) ([]string, error) {
 
-	rr, err := s.TryQuery(name, TypeTXT)
-	if err != nil {
-		return nil, err
-	}
+	return s.TryQueryTXTContext(context.Background(), name)
+}
 
-	r := make([]string, 0, len(rr))
+// TryQueryTXTContext is like TryQueryTXT, but the query is bound to ctx.
+func (s *Servers) TryQueryTXTContext(ctx context.Context, name string) ([]string, error) {
 
-	for i := range rr {
-
-		switch v := rr[i].(type) {
-		case *mdns.TXT:
-			r = append(r, v.Txt...)
-		case *mdns.CNAME:
-			// Ignore CNAME
-			continue
-		case *mdns.DNAME:
-			// Ignore DNAME
-			continue
-		default:
-			return nil, fmt.Errorf("unknown type: %T", v)
-		}
-	}
-
-	return r, nil
+	return QueryTXTWith(ctx, s, name)
 }
 
-// TryQueryTXT asks the DefaultServers for type TXT. If any error occurred, retries with next server (except if error is NXDOMAIN).
+// TryQueryTXT asks the DefaultResolver for type TXT. If any error occurred, retries with next server (except if error is NXDOMAIN).
 //
 // In case of error, the answer will be nil and return ErrX or any unknown error.
 //
 // The first used server is random. The other record types are ignored.
 func TryQueryTXT(name string) ([]string, error) {
 
-	return DefaultServers.TryQueryTXT(name)
+	return TryQueryTXTContext(context.Background(), name)
+}
+
+// TryQueryTXTContext is like TryQueryTXT, but the query is bound to ctx.
+func TryQueryTXTContext(ctx context.Context, name string) ([]string, error) {
+
+	return QueryTXTWith(ctx, DefaultResolver, name)
+}
+
+// QueryTXTWith asks r for type TXT and converts the answer like QueryTXT.
+// Whether the query is retried depends on r (eg.: Servers retries with the next server, Server does not).
+func QueryTXTWith(ctx context.Context, r Resolver, name string) ([]string, error) {
+
+	rr, err := r.TryQueryContext(ctx, name, TypeTXT)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTXT(rr)
 }
 
 // IsSetTXT checks whether an TXT type record set for name.
 // NXDOMAIN is not an error here, because it means "not found".
 func (s *Servers) IsSetTXT(name string) (bool, error) {
-	return s.IsSet(name, TypeTXT)
+	return s.IsSetTXTContext(context.Background(), name)
 }
 
-// IsSetTXT checks whether an TXT type record set for name using the DefaultServers.
+// IsSetTXTContext is like IsSetTXT, but the query is bound to ctx.
+func (s *Servers) IsSetTXTContext(ctx context.Context, name string) (bool, error) {
+	return s.IsSetContext(ctx, name, TypeTXT)
+}
+
+// IsSetTXT checks whether an TXT type record set for name using the DefaultResolver.
 // NXDOMAIN is not an error here, because it means "not found".
 func IsSetTXT(name string) (bool, error) {
-	return DefaultServers.IsSetTXT(name)
+	return IsSetTXTContext(context.Background(), name)
+}
+
+// IsSetTXTContext is like IsSetTXT, but the query is bound to ctx.
+func IsSetTXTContext(ctx context.Context, name string) (bool, error) {
+	return IsSetWith(ctx, DefaultResolver, name, TypeTXT)
 }
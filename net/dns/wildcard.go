@@ -1,133 +1,319 @@
 package dns
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/elmasy-com/slices"
 	"github.com/g0rbe/slitu"
 )
 
 var charSet = []byte("abcdefghijklmnopqrstuvwxyz0123456789")
 
-// This is a special case, where the total length of the domain is 253 and the size of first part is only one char (eg.: "a.a...").
-// There is no room to fuzz the first part, have to check every possible characters to make sure its a wildcard domain.
-func (s *Servers) wildcardBruteforceOneChar(parts []string, t uint16) (bool, error) {
+// wildcardProbes is the number of random names queried to detect a wildcard.
+const wildcardProbes = 5
 
-	for i := range charSet {
+// Wildcard is the fingerprint of a wildcard: the answers of random names under Parent with Type.
+type Wildcard struct {
+	Parent  string   // The parent of the probed names (eg.: "example.com" for "*.example.com")
+	Type    uint16   // The probed type
+	Records []Record // The union of the answers of the probed names, empty if Parent has no wildcard with Type
+}
 
-		parts[0] = string(charSet[i])
-		v := strings.Join(parts, ".")
+// IsWildcard returns whether Parent has a wildcard with Type.
+func (w *Wildcard) IsWildcard() bool {
 
-		r, err := s.IsSet(v, t)
+	return w != nil && len(w.Records) > 0
+}
 
-		if err != nil {
-			if errors.Is(err, ErrName) {
-				err = nil
-			}
-			return false, err
-		}
+// Matches returns whether the answer records could be synthesized by the wildcard: every record is in the fingerprint.
+// The answers of the probes are merged into the fingerprint, so a wildcard with rotating answers is matched too.
+//
+// Returns false if w is not a wildcard or records is empty.
+func (w *Wildcard) Matches(records []Record) bool {
+
+	if !w.IsWildcard() || len(records) == 0 {
+		return false
+	}
 
-		// Return on the first false
-		if !r {
-			return false, nil
+	for i := range records {
+		if !slices.Contains(w.Records, records[i]) {
+			return false
 		}
 	}
 
-	return true, nil
+	return true
 }
 
-// IsWildcard check if name is a wildcard domain.
-//
-// NOTE: Use IsValid() and Clean() before this function!
-func (s *Servers) IsWildcard(name string, t uint16) (bool, error) {
+// Filter returns nil if records matches the wildcard (see Matches()), records otherwise.
+func (w *Wildcard) Filter(records []Record) []Record {
 
-	if !HasSub(name) {
-		// Domain without subdomain cant be a wildcard
-		return false, nil
+	if w.Matches(records) {
+		return nil
 	}
 
-	parts := strings.Split(name, ".")
+	return records
+}
 
-	// partSize is the possible max size of first part to fuzz
-	partSize := 253 - len(name) + len(parts[0])
+// wildcardEntry is a probed or in progress Wildcard in a WildcardCache.
+type wildcardEntry struct {
+	done    chan struct{} // Closed when the probe is done
+	w       *Wildcard
+	err     error
+	expires time.Time
+}
+
+// WildcardCache is a concurrency-safe cache of the wildcard fingerprints for Servers.
+// The names with the same parent share the probes: if a parent is being probed, the concurrent lookups wait for the result.
+// Failed probes are not cached.
+type WildcardCache struct {
+	m       sync.Mutex
+	ttl     time.Duration
+	entries map[cacheKey]*wildcardEntry
+	now     func() time.Time
+}
 
-	if partSize == 1 {
-		return s.wildcardBruteforceOneChar(parts, t)
+// NewWildcardCache creates a new WildcardCache.
+// The fingerprints are kept for ttl, 0 means forever.
+func NewWildcardCache(ttl time.Duration) *WildcardCache {
+
+	return &WildcardCache{
+		ttl:     ttl,
+		entries: make(map[cacheKey]*wildcardEntry),
+		now:     time.Now,
 	}
+}
+
+// Len returns the number of cached fingerprints.
+func (c *WildcardCache) Len() int {
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return len(c.entries)
+}
 
-	// Limit the part size to 63
-	if partSize > 63 {
-		partSize = 63
+// Clear removes every fingerprint from the cache.
+func (c *WildcardCache) Clear() {
+
+	c.m.Lock()
+	c.entries = make(map[cacheKey]*wildcardEntry)
+	c.m.Unlock()
+}
+
+// get returns the fingerprint of parent with type t from the cache, or calls probe and stores the result.
+func (c *WildcardCache) get(ctx context.Context, parent string, t uint16, probe func() (*Wildcard, error)) (*Wildcard, error) {
+
+	key := newCacheKey(parent, t)
+
+	c.m.Lock()
+
+	e, ok := c.entries[key]
+	if ok && e.w != nil && c.ttl > 0 && c.now().After(e.expires) {
+		delete(c.entries, key)
+		ok = false
 	}
 
-	maxCheck := 5
+	if ok {
 
-	for i := 0; i < maxCheck; i++ {
+		c.m.Unlock()
 
-		parts[0] = slitu.RandomString(charSet, partSize)
-		v := strings.Join(parts, ".")
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 
-		r, err := s.IsSet(v, t)
-		if err != nil {
-			if errors.Is(err, ErrName) {
-				err = nil
-			}
-			return false, err
+		if e.err != nil {
+			// The probe of an other lookup failed, probe again
+			return c.get(ctx, parent, t, probe)
 		}
 
-		// Return on the first false
-		if !r {
-			return false, nil
+		return e.w, nil
+	}
+
+	e = &wildcardEntry{done: make(chan struct{})}
+	c.entries[key] = e
+
+	c.m.Unlock()
+
+	w, err := probe()
+
+	c.m.Lock()
+
+	if err != nil {
+		if c.entries[key] == e {
+			delete(c.entries, key)
 		}
+		e.err = err
+	} else {
+		e.w = w
+		e.expires = c.now().Add(c.ttl)
 	}
 
-	return true, nil
+	c.m.Unlock()
+	close(e.done)
+
+	return w, err
+}
+
+// SetWildcardCache sets the wildcard fingerprint cache used by ProbeWildcard (and the functions built on it).
+// Set c to nil to disable the cache.
+func (s *Servers) SetWildcardCache(c *WildcardCache) {
+
+	s.m.Lock()
+	s.wildcards = c
+	s.m.Unlock()
+}
+
+// WildcardCache returns the wildcard fingerprint cache of the Servers.
+// Returns nil if the cache is disabled.
+func (s *Servers) WildcardCache() *WildcardCache {
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.wildcards
 }
 
-// IsWildcard uses the DefaultServers to check if name is a wildcard domain.
+// ProbeWildcard probes the parent of name with random names with type t and returns the fingerprint of the wildcard.
+// If the parent is not a wildcard with type t, the returned Wildcard has no Records.
+// If a WildcardCache is set (see SetWildcardCache()), the fingerprint of the parent is returned from the cache when possible.
 //
 // NOTE: Use IsValid() and Clean() before this function!
-func IsWildcard(name string, t uint16) (bool, error) {
+func (s *Servers) ProbeWildcard(name string, t uint16) (*Wildcard, error) {
+
+	return s.ProbeWildcardContext(context.Background(), name, t)
+}
+
+// ProbeWildcardContext is like ProbeWildcard, but the probe queries are bound to ctx.
+func (s *Servers) ProbeWildcardContext(ctx context.Context, name string, t uint16) (*Wildcard, error) {
+
+	parent := name[strings.IndexByte(name, '.')+1:]
 
 	if !HasSub(name) {
 		// Domain without subdomain cant be a wildcard
-		return false, nil
+		return &Wildcard{Parent: parent, Type: t}, nil
 	}
 
-	parts := strings.Split(name, ".")
+	if c := s.WildcardCache(); c != nil {
+		return c.get(ctx, parent, t, func() (*Wildcard, error) { return probeWildcard(ctx, s, parent, t) })
+	}
 
-	// partSize is the possible max size of first part to fuzz
-	partSize := 253 - len(name) + len(parts[0])
+	return probeWildcard(ctx, s, parent, t)
+}
 
-	if partSize == 1 {
-		return DefaultServers.wildcardBruteforceOneChar(parts, t)
-	}
+// probeWildcard queries random names under parent with type t using r and merges the answers.
+// Returns a Wildcard without Records on the first name without answer.
+func probeWildcard(ctx context.Context, r Resolver, parent string, t uint16) (*Wildcard, error) {
 
-	// Limit the part size to 63
-	if partSize > 63 {
-		partSize = 63
-	}
+	w := &Wildcard{Parent: parent, Type: t}
 
-	maxCheck := 5
+	// partSize is the possible max size of the label to fuzz
+	partSize := 253 - len(parent) - 1
 
-	for i := 0; i < maxCheck; i++ {
+	var labels []string
 
-		parts[0] = slitu.RandomString(charSet, partSize)
-		v := strings.Join(parts, ".")
+	switch {
+	case partSize < 1:
+		// No room for a subdomain
+		return w, nil
+	case partSize == 1:
+		// This is a special case, where the total length of the domain is 253 and the size of the label is only one char (eg.: "a.a...").
+		// There is no room to fuzz the label, have to check every possible characters to make sure its a wildcard domain.
+		for i := range charSet {
+			labels = append(labels, string(charSet[i]))
+		}
+	default:
+		// Limit the part size to 63
+		if partSize > 63 {
+			partSize = 63
+		}
+		for i := 0; i < wildcardProbes; i++ {
+			labels = append(labels, slitu.RandomString(charSet, partSize))
+		}
+	}
 
-		r, err := DefaultServers.IsSet(v, t)
+	var records []Record
+
+	for _, l := range labels {
+
+		rr, err := r.TryQueryContext(ctx, l+"."+parent, t)
 		if err != nil {
 			if errors.Is(err, ErrName) {
 				err = nil
 			}
-			return false, err
+			return w, err
+		}
+
+		// Return on the first name without answer
+		if len(rr) == 0 {
+			return w, nil
+		}
+
+		recs, err := toRecords(rr)
+		if err != nil {
+			return w, fmt.Errorf("failed to parse the answer of %s: %w", l+"."+parent, err)
 		}
 
-		// Return on the first false
-		if !r {
-			return false, nil
+		for i := range recs {
+			records = slices.AppendUnique(records, recs[i])
 		}
 	}
 
-	return true, nil
+	w.Records = records
+
+	return w, nil
+}
+
+// IsWildcard check if name is a wildcard domain.
+//
+// NOTE: Use IsValid() and Clean() before this function!
+func (s *Servers) IsWildcard(name string, t uint16) (bool, error) {
+
+	return s.IsWildcardContext(context.Background(), name, t)
+}
+
+// IsWildcardContext is like IsWildcard, but the probe queries are bound to ctx.
+func (s *Servers) IsWildcardContext(ctx context.Context, name string, t uint16) (bool, error) {
+
+	w, err := s.ProbeWildcardContext(ctx, name, t)
+	if err != nil {
+		return false, err
+	}
+
+	return w.IsWildcard(), nil
+}
+
+// IsWildcard uses the DefaultResolver to check if name is a wildcard domain.
+//
+// NOTE: Use IsValid() and Clean() before this function!
+func IsWildcard(name string, t uint16) (bool, error) {
+
+	return IsWildcardWith(context.Background(), DefaultResolver, name, t)
+}
+
+// IsWildcardContext is like IsWildcard, but the probe queries are bound to ctx.
+func IsWildcardContext(ctx context.Context, name string, t uint16) (bool, error) {
+
+	return IsWildcardWith(ctx, DefaultResolver, name, t)
+}
+
+// ProbeWildcard uses the DefaultResolver to probe the parent of name for wildcard.
+// See Servers.ProbeWildcard().
+//
+// NOTE: Use IsValid() and Clean() before this function!
+func ProbeWildcard(name string, t uint16) (*Wildcard, error) {
+
+	return ProbeWildcardWith(context.Background(), DefaultResolver, name, t)
+}
+
+// ProbeWildcardContext is like ProbeWildcard, but the probe queries are bound to ctx.
+func ProbeWildcardContext(ctx context.Context, name string, t uint16) (*Wildcard, error) {
+
+	return ProbeWildcardWith(ctx, DefaultResolver, name, t)
 }
@@ -3,6 +3,8 @@ package dns
 import (
 	"testing"
 	"time"
+
+	"github.com/g0rbe/gmod/net/dns/dnstest"
 )
 
 func TestIsWildcard(t *testing.T) {
@@ -30,6 +32,147 @@ func TestIsWildcard(t *testing.T) {
 	}
 }
 
+func TestServersIsWildcardLocal(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, dnstest.MustZone("example.com",
+		"@ 300 IN A 192.0.2.1",
+		"www 300 IN A 192.0.2.1",
+		"*.wild 300 IN A 192.0.2.2",
+		"*.txt 300 IN TXT \"wildcard\"",
+	))
+
+	cases := []struct {
+		D string
+		T uint16
+		R bool
+	}{
+		{D: "example.com", T: TypeA, R: false},
+		{D: "www.example.com", T: TypeA, R: false},
+		{D: "test.wild.example.com", T: TypeA, R: true},
+		{D: "test.wild.example.com", T: TypeAAAA, R: false},
+		{D: "test.txt.example.com", T: TypeTXT, R: true},
+		{D: "test.txt.example.com", T: TypeA, R: false},
+	}
+
+	for i := range cases {
+
+		r, err := srvs.IsWildcard(cases[i].D, cases[i].T)
+		if err != nil {
+			t.Fatalf("FAIL: failed to check if %s is a wildcard: %s\n", cases[i].D, err)
+		}
+
+		if r != cases[i].R {
+			t.Fatalf("FAIL: Result for %s %s: %v, want: %v \n", cases[i].D, TypeToString(cases[i].T), r, cases[i].R)
+		}
+	}
+}
+
+func TestServersProbeWildcardLocal(t *testing.T) {
+
+	srvs, ts := newZoneTestServers(t, dnstest.MustZone("example.com",
+		"@ 300 IN A 192.0.2.1",
+		"*.wild 300 IN A 192.0.2.2",
+		"real.wild 300 IN A 192.0.2.3",
+		"same.wild 300 IN A 192.0.2.2",
+	))
+
+	srvs.SetWildcardCache(NewWildcardCache(time.Minute))
+
+	w, err := srvs.ProbeWildcard("test.wild.example.com", TypeA)
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if !w.IsWildcard() || w.Parent != "wild.example.com" || len(w.Records) != 1 || w.Records[0].Value != "192.0.2.2" {
+		t.Fatalf("FAIL: Invalid fingerprint: %#v\n", w)
+	}
+
+	queries := len(ts.Queries())
+
+	// The sibling uses the cached fingerprint of the parent
+	if _, err := srvs.ProbeWildcard("other.wild.example.com", TypeA); err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if n := len(ts.Queries()); n != queries {
+		t.Fatalf("FAIL: Parent is probed again: %d queries, want: %d\n", n, queries)
+	}
+
+	if srvs.WildcardCache().Len() != 1 {
+		t.Fatalf("FAIL: Invalid cache size: %d\n", srvs.WildcardCache().Len())
+	}
+
+	cases := []struct {
+		D string
+		R string // Expected A record, empty if filtered
+	}{
+		{D: "real.wild.example.com", R: "192.0.2.3"},
+		{D: "same.wild.example.com", R: ""},
+		{D: "random.wild.example.com", R: ""},
+		{D: "example.com", R: "192.0.2.1"},
+	}
+
+	for i := range cases {
+
+		rr, err := srvs.QueryAll(cases[i].D)
+		if err != nil {
+			t.Fatalf("FAIL: %s: %s\n", cases[i].D, err)
+		}
+
+		a := ""
+		for ii := range rr {
+			if rr[ii].Type == TypeA {
+				a = rr[ii].Value
+			}
+		}
+
+		if a != cases[i].R {
+			t.Fatalf("FAIL: A record of %s: %q, want: %q\n", cases[i].D, a, cases[i].R)
+		}
+	}
+}
+
+func TestServersWildcardCacheConcurrentSet(t *testing.T) {
+
+	srvs, _ := newZoneTestServers(t, dnstest.MustZone("example.com", "*.wild 300 IN A 192.0.2.2"))
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			srvs.SetWildcardCache(NewWildcardCache(time.Minute))
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		srvs.WildcardCache()
+	}
+
+	<-done
+
+	if _, err := srvs.ProbeWildcard("test.wild.example.com", TypeA); err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+}
+
+func TestWildcardMatches(t *testing.T) {
+
+	w := &Wildcard{Parent: "example.com", Type: TypeA, Records: []Record{{Type: TypeA, Value: "192.0.2.1"}, {Type: TypeA, Value: "192.0.2.2"}}}
+
+	if !w.Matches([]Record{{Type: TypeA, Value: "192.0.2.2"}}) {
+		t.Fatalf("FAIL: Rotated answer not matched\n")
+	}
+
+	if w.Matches([]Record{{Type: TypeA, Value: "192.0.2.2"}, {Type: TypeA, Value: "192.0.2.3"}}) {
+		t.Fatalf("FAIL: Different answer matched\n")
+	}
+
+	if w.Matches(nil) || (&Wildcard{}).Matches([]Record{{Type: TypeA, Value: "192.0.2.1"}}) {
+		t.Fatalf("FAIL: Empty matched\n")
+	}
+}
+
 func BenchmarkIsWildcard(b *testing.B) {
 
 	// Sleep 2 sec to not overflow the DNS server
@@ -0,0 +1,601 @@
+package dns
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net"
+	"strings"
+	"time"
+
+	mdns "github.com/miekg/dns"
+)
+
+var (
+	TypeAXFR uint16 = 252
+	TypeIXFR uint16 = 251
+)
+
+var (
+	ErrTransferProtocol = errors.New("zone transfer is not supported over this protocol")
+	ErrTransferFormat   = errors.New("invalid zone transfer response")
+)
+
+// MaxUnsignedXFR is the maximum number of consecutive unsigned messages in a TSIG signed zone transfer (RFC 8945 5.3.1).
+const MaxUnsignedXFR = 99
+
+// TSIG is a transaction signature key.
+// See more: https://www.rfc-editor.org/rfc/rfc8945.html
+type TSIG struct {
+	Name      string // Name of the key (eg.: "transfer-key.")
+	Algorithm string // Algorithm name (eg.: "hmac-sha256."), defaults to "hmac-sha256." if empty
+	Secret    string // Base64 encoded secret
+}
+
+// ZoneRecord is a record of a zone transfer.
+// The Type and Value of the embedded Record has the same format as in QueryAll().
+// Types not known by QueryAll() has the presentation format of the RDATA in Value.
+type ZoneRecord struct {
+	Name string
+	TTL  uint32
+	Record
+}
+
+func (r ZoneRecord) String() string {
+	return fmt.Sprintf("%s %d %s %s", r.Name, r.TTL, mdns.TypeToString[r.Type], r.Value)
+}
+
+// ZoneDiff is a change of the zone between two serials in an IXFR.
+type ZoneDiff struct {
+	From    uint32       // Serial before the change
+	To      uint32       // Serial after the change
+	Deleted []ZoneRecord // Records deleted from the zone (SOA not included)
+	Added   []ZoneRecord // Records added to the zone (SOA not included)
+}
+
+// ZoneChanges is the result of an IXFR.
+type ZoneChanges struct {
+	Serial  uint32       // Current serial of the zone
+	Full    bool         // The server sent the full zone (AXFR style response) instead of the differences
+	Records []ZoneRecord // The zone if Full is true
+	Diffs   []ZoneDiff   // The differences in order if Full is false
+}
+
+// TransferCheck is the result of an AXFR attempt to a nameserver of a zone.
+type TransferCheck struct {
+	NS      string // Name of the nameserver
+	IP      string // IP address of the nameserver, empty if failed to resolve
+	Open    bool   // The nameserver allows the zone transfer
+	Records int    // Number of transferred records
+	Err     error  // The reason of the failure
+}
+
+// AXFR transfers the zone from the server.
+// If tsig is not nil, the request is signed and the response is verified with tsig.
+//
+// The transfer is always done over TCP ("udp" servers are switched to "tcp"), or over TLS with "tcp-tls".
+// Returns ErrTransferProtocol for "https", "quic" and "iterative" servers.
+//
+// The returned slice contains the SOA record only once, as the first element.
+func (s *Server) AXFR(zone string, tsig *TSIG) ([]ZoneRecord, error) {
+
+	return s.AXFRContext(context.Background(), zone, tsig)
+}
+
+// AXFRContext is like AXFR, but the transfer is bound to ctx.
+func (s *Server) AXFRContext(ctx context.Context, zone string, tsig *TSIG) ([]ZoneRecord, error) {
+
+	msg := new(mdns.Msg)
+	msg.SetAxfr(mdns.Fqdn(zone))
+
+	rr, err := s.transfer(ctx, msg, tsig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ommit the closing SOA
+	return toZoneRecords(rr[:len(rr)-1]), nil
+}
+
+// IXFR requests the changes of the zone since serial from the server.
+// If tsig is not nil, the request is signed and the response is verified with tsig.
+//
+// If the zone is not changed since serial, the returned ZoneChanges has no Diffs.
+// If the server responds with the full zone, Full is true and the zone is in Records.
+//
+// The transfer is always done over TCP ("udp" servers are switched to "tcp"), or over TLS with "tcp-tls".
+// Returns ErrTransferProtocol for "https", "quic" and "iterative" servers.
+func (s *Server) IXFR(zone string, serial uint32, tsig *TSIG) (*ZoneChanges, error) {
+
+	return s.IXFRContext(context.Background(), zone, serial, tsig)
+}
+
+// IXFRContext is like IXFR, but the transfer is bound to ctx.
+func (s *Server) IXFRContext(ctx context.Context, zone string, serial uint32, tsig *TSIG) (*ZoneChanges, error) {
+
+	zone = mdns.Fqdn(zone)
+
+	msg := new(mdns.Msg)
+	msg.SetIxfr(zone, serial, ".", ".")
+
+	rr, err := s.transfer(ctx, msg, tsig)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseIXFR(rr)
+}
+
+// parseIXFR parses the records of an IXFR response.
+// See more: https://www.rfc-editor.org/rfc/rfc1995.html#section-4
+func parseIXFR(rr []mdns.RR) (*ZoneChanges, error) {
+
+	r := &ZoneChanges{Serial: rr[0].(*mdns.SOA).Serial}
+
+	if len(rr) == 1 {
+		// Up to date
+		return r, nil
+	}
+
+	if _, ok := rr[1].(*mdns.SOA); !ok {
+		r.Full = true
+		r.Records = toZoneRecords(rr[:len(rr)-1])
+		return r, nil
+	}
+
+	var (
+		diff    *ZoneDiff
+		deleted bool
+	)
+
+	// Every difference sequence starts with the old SOA (deleted records follow), then the new SOA (added records follow).
+	for _, v := range rr[1 : len(rr)-1] {
+
+		soa, ok := v.(*mdns.SOA)
+
+		switch {
+		case ok && (diff == nil || !deleted):
+			r.Diffs = append(r.Diffs, ZoneDiff{From: soa.Serial})
+			diff = &r.Diffs[len(r.Diffs)-1]
+			deleted = true
+		case ok:
+			diff.To = soa.Serial
+			deleted = false
+		case diff == nil:
+			return nil, ErrTransferFormat
+		case deleted:
+			diff.Deleted = append(diff.Deleted, toZoneRecords([]mdns.RR{v})...)
+		default:
+			diff.Added = append(diff.Added, toZoneRecords([]mdns.RR{v})...)
+		}
+	}
+
+	if diff == nil || deleted {
+		return nil, ErrTransferFormat
+	}
+
+	return r, nil
+}
+
+// transfer sends the AXFR/IXFR request msg and returns every record from the response messages, including the SOAs.
+// The first and the last record is guaranteed to be an SOA.
+func (s *Server) transfer(ctx context.Context, msg *mdns.Msg, tsig *TSIG) ([]mdns.RR, error) {
+
+	if s.client == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTransferProtocol, s.Protocol)
+	}
+
+	timeout := s.client.Timeout
+	if timeout == 0 {
+		timeout = time.Duration(DefaultQueryTimeoutSec) * time.Second
+	}
+
+	var secret string
+
+	if tsig != nil {
+
+		alg := tsig.Algorithm
+		if alg == "" {
+			alg = mdns.HmacSHA256
+		}
+
+		secret = tsig.Secret
+		msg.SetTsig(strings.ToLower(mdns.Fqdn(tsig.Name)), mdns.Fqdn(alg), 300, time.Now().Unix())
+	}
+
+	conn, err := s.dialTransfer(ctx, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	t := &xfrConn{Conn: &mdns.Conn{Conn: conn}, secret: secret}
+
+	// Interrupt the blocking read/write when ctx is done.
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	conn.SetWriteDeadline(time.Now().Add(timeout))
+
+	if err := t.writeMsg(msg); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	var (
+		rr     []mdns.RR
+		serial uint32 // Serial of the first SOA, the current serial of the zone
+		n      int    // Number of SOAs with the current serial
+		ixfr   = msg.Question[0].Qtype == mdns.TypeIXFR
+		diffs  = false // The IXFR response contains differences
+	)
+
+	for {
+
+		conn.SetReadDeadline(time.Now().Add(timeout))
+
+		in, err := t.readMsg()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+
+		if in.Id != msg.Id {
+			return nil, mdns.ErrId
+		}
+
+		if in.Rcode != mdns.RcodeSuccess {
+			return nil, RcodeToError(in.Rcode)
+		}
+
+		for _, v := range in.Answer {
+
+			if len(rr) == 0 {
+
+				soa, ok := v.(*mdns.SOA)
+				if !ok {
+					return nil, fmt.Errorf("%w: first record is %s, not SOA", ErrTransferFormat, mdns.TypeToString[v.Header().Rrtype])
+				}
+
+				serial = soa.Serial
+
+				// IXFR and the zone is up to date
+				if ixfr && len(msg.Ns) > 0 && msg.Ns[0].(*mdns.SOA).Serial >= serial {
+					return []mdns.RR{v}, nil
+				}
+			}
+
+			rr = append(rr, v)
+
+			soa, ok := v.(*mdns.SOA)
+			if !ok {
+				continue
+			}
+
+			if soa.Serial != serial {
+				diffs = true
+				continue
+			}
+
+			n++
+
+			// The transfer ends with the current SOA.
+			// In IXFR with differences, the current SOA is repeated at the start of the last difference sequence.
+			if (!diffs && n == 2) || n == 3 {
+
+				if t.mac != "" && !t.signed {
+					return nil, fmt.Errorf("%w: last message is not signed", mdns.ErrSig)
+				}
+
+				return rr, nil
+			}
+		}
+
+		// The subsequent messages are signed with the timers only.
+		t.timersOnly = true
+	}
+}
+
+// xfrConn is a zone transfer connection that signs the request and verifies the responses with the TSIG secret.
+type xfrConn struct {
+	*mdns.Conn
+	secret     string // Base64 encoded TSIG secret, empty if TSIG is not used
+	mac        string // MAC of the previous signed message
+	timersOnly bool
+	signed     bool   // The last read message is signed
+	unsigned   []byte // The unsigned messages since the last signed message
+	nUnsigned  int    // Number of unsigned messages since the last signed message
+}
+
+// writeMsg signs (if the TSIG is set) and writes m.
+func (c *xfrConn) writeMsg(m *mdns.Msg) error {
+
+	var (
+		out []byte
+		err error
+	)
+
+	if m.IsTsig() != nil {
+		out, c.mac, err = mdns.TsigGenerate(m, c.secret, c.mac, false)
+	} else {
+		out, err = m.Pack()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Write(out)
+
+	return err
+}
+
+// readMsg reads a message and verifies the TSIG if the request was signed.
+//
+// Only the first and the last message must be signed, up to MaxUnsignedXFR unsigned messages are allowed between.
+// The MAC of the signed message covers the unsigned messages before it (RFC 8945 5.3.1).
+func (c *xfrConn) readMsg() (*mdns.Msg, error) {
+
+	p := make([]byte, mdns.MaxMsgSize)
+
+	n, err := c.Read(p)
+	if err != nil && n == 0 {
+		return nil, err
+	}
+
+	p = p[:n]
+
+	m := new(mdns.Msg)
+
+	if err := m.Unpack(p); err != nil {
+		return nil, err
+	}
+
+	if c.mac == "" {
+		return m, nil
+	}
+
+	ts := m.IsTsig()
+	if ts == nil {
+
+		// The error responses (eg.: REFUSED) are usually not signed.
+		if m.Rcode != mdns.RcodeSuccess {
+			return m, nil
+		}
+
+		if !c.timersOnly {
+			return nil, fmt.Errorf("%w: response is not signed", mdns.ErrSig)
+		}
+
+		c.nUnsigned++
+		if c.nUnsigned > MaxUnsignedXFR {
+			return nil, fmt.Errorf("%w: more than %d unsigned messages", mdns.ErrSig, MaxUnsignedXFR)
+		}
+
+		c.unsigned = append(c.unsigned, p...)
+		c.signed = false
+
+		return m, nil
+	}
+
+	provider := &xfrTsigProvider{secret: c.secret, prior: 2 + len(c.mac)/2, unsigned: c.unsigned}
+
+	if err := mdns.TsigVerifyWithProvider(p, provider, c.mac, c.timersOnly); err != nil {
+		return nil, fmt.Errorf("TSIG verification failed: %w", err)
+	}
+
+	c.mac = ts.MAC
+	c.signed = true
+	c.unsigned = nil
+	c.nUnsigned = 0
+
+	return m, nil
+}
+
+// xfrTsigProvider is the HMAC TSIG provider of the zone transfers.
+// The MAC covers the unsigned messages since the last signed message too:
+// they are inserted after the prior MAC into the buffer built by the dns module.
+type xfrTsigProvider struct {
+	secret   string // Base64 encoded secret
+	prior    int    // Length of the prior MAC (with the 2 bytes size) at the start of the buffer, 0 if there is no prior MAC
+	unsigned []byte // The unsigned messages
+}
+
+// Generate returns the MAC of msg and the unsigned messages.
+func (p *xfrTsigProvider) Generate(msg []byte, t *mdns.TSIG) ([]byte, error) {
+
+	secret, err := base64.StdEncoding.DecodeString(p.secret)
+	if err != nil {
+		return nil, err
+	}
+
+	var h func() hash.Hash
+
+	switch mdns.CanonicalName(t.Algorithm) {
+	case mdns.HmacSHA1:
+		h = sha1.New
+	case mdns.HmacSHA224:
+		h = sha256.New224
+	case mdns.HmacSHA256:
+		h = sha256.New
+	case mdns.HmacSHA384:
+		h = sha512.New384
+	case mdns.HmacSHA512:
+		h = sha512.New
+	default:
+		return nil, mdns.ErrKeyAlg
+	}
+
+	if p.prior > len(msg) {
+		return nil, mdns.ErrSig
+	}
+
+	mac := hmac.New(h, secret)
+
+	mac.Write(msg[:p.prior])
+	mac.Write(p.unsigned)
+	mac.Write(msg[p.prior:])
+
+	return mac.Sum(nil), nil
+}
+
+// Verify checks the MAC of t.
+func (p *xfrTsigProvider) Verify(msg []byte, t *mdns.TSIG) error {
+
+	b, err := p.Generate(msg, t)
+	if err != nil {
+		return err
+	}
+
+	mac, err := hex.DecodeString(t.MAC)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal(b, mac) {
+		return mdns.ErrSig
+	}
+
+	return nil
+}
+
+// dialTransfer opens the TCP (or TLS with "tcp-tls") connection to the server.
+func (s *Server) dialTransfer(ctx context.Context, timeout time.Duration) (net.Conn, error) {
+
+	addr := net.JoinHostPort(s.IP, s.Port)
+	d := &net.Dialer{Timeout: timeout}
+
+	if s.Protocol == "tcp-tls" {
+		td := &tls.Dialer{NetDialer: d, Config: s.client.TLSConfig}
+		return td.DialContext(ctx, "tcp", addr)
+	}
+
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+// toZoneRecords converts rr to a slice of ZoneRecord.
+// A TXT record with multiple strings converted to multiple ZoneRecord (see QueryAll()).
+func toZoneRecords(rr []mdns.RR) []ZoneRecord {
+
+	zr := make([]ZoneRecord, 0, len(rr))
+
+	for i := range rr {
+
+		hdr := rr[i].Header()
+
+		rs, err := toRecords(rr[i : i+1])
+		if err != nil {
+			// Unknown type, use the RDATA in presentation format
+			rs = []Record{{Type: hdr.Rrtype, Value: strings.TrimPrefix(rr[i].String(), hdr.String())}}
+		}
+
+		for ii := range rs {
+			zr = append(zr, ZoneRecord{Name: hdr.Name, TTL: hdr.Ttl, Record: rs[ii]})
+		}
+	}
+
+	return zr
+}
+
+// CheckOpenTransfer tries an AXFR of zone from every nameserver of zone (with every IPv4 and IPv6 address).
+// The nameservers are queried with TryQueryNS(), the addresses with TryQueryA() and TryQueryAAAA().
+// The transfers are done over TCP on port 53, timeout is the timeout of the connection and every read.
+//
+// The returned slice contains a TransferCheck for every address, or one with Err for a nameserver which name cannot be resolved.
+// Returns error only if failed to get the nameservers.
+func (s *Servers) CheckOpenTransfer(zone string, timeout time.Duration) ([]TransferCheck, error) {
+
+	return s.CheckOpenTransferContext(context.Background(), zone, timeout)
+}
+
+// CheckOpenTransferContext is like CheckOpenTransfer, but the queries and the transfers are bound to ctx.
+func (s *Servers) CheckOpenTransferContext(ctx context.Context, zone string, timeout time.Duration) ([]TransferCheck, error) {
+
+	return s.checkOpenTransfer(ctx, zone, "53", timeout)
+}
+
+func (s *Servers) checkOpenTransfer(ctx context.Context, zone string, port string, timeout time.Duration) ([]TransferCheck, error) {
+
+	nss, err := s.TryQueryNSContext(ctx, zone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query NS: %w", err)
+	}
+
+	r := make([]TransferCheck, 0, len(nss))
+
+	for _, ns := range nss {
+
+		ips := make([]net.IP, 0)
+
+		v4, err4 := s.TryQueryAContext(ctx, ns)
+		ips = append(ips, v4...)
+
+		v6, err6 := s.TryQueryAAAAContext(ctx, ns)
+		ips = append(ips, v6...)
+
+		if len(ips) == 0 {
+
+			c := TransferCheck{NS: ns, Err: fmt.Errorf("no address found for %s", ns)}
+
+			if err4 != nil {
+				c.Err = fmt.Errorf("failed to resolve %s: %w", ns, err4)
+			} else if err6 != nil {
+				c.Err = fmt.Errorf("failed to resolve %s: %w", ns, err6)
+			}
+
+			r = append(r, c)
+			continue
+		}
+
+		for _, ip := range ips {
+
+			if err := ctx.Err(); err != nil {
+				return r, err
+			}
+
+			c := TransferCheck{NS: ns, IP: ip.String()}
+
+			srv, err := NewServer("tcp", c.IP, port, timeout)
+			if err != nil {
+				c.Err = err
+				r = append(r, c)
+				continue
+			}
+
+			rs, err := srv.AXFRContext(ctx, zone, nil)
+
+			c.Open = err == nil
+			c.Records = len(rs)
+			c.Err = err
+
+			r = append(r, c)
+		}
+	}
+
+	return r, nil
+}
+
+// CheckOpenTransfer tries an AXFR of zone from every nameserver of zone using the DefaultServers.
+// See Servers.CheckOpenTransfer().
+func CheckOpenTransfer(zone string, timeout time.Duration) ([]TransferCheck, error) {
+
+	return CheckOpenTransferContext(context.Background(), zone, timeout)
+}
+
+// CheckOpenTransferContext is like CheckOpenTransfer, but the queries and the transfers are bound to ctx.
+func CheckOpenTransferContext(ctx context.Context, zone string, timeout time.Duration) ([]TransferCheck, error) {
+
+	return DefaultServers.CheckOpenTransferContext(ctx, zone, timeout)
+}
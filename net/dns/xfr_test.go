@@ -0,0 +1,436 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	mdns "github.com/miekg/dns"
+)
+
+const xfrTestSecret = "c2VjcmV0LXRyYW5zZmVyLWtleS0xMjM0NTY3ODkw"
+
+// xfrTestZone returns the records of the test zone "example.com." with the given serial.
+func xfrTestZone(t *testing.T, serial string) []mdns.RR {
+
+	var rr []mdns.RR
+
+	for _, s := range []string{
+		"example.com. 300 IN SOA ns1.example.com. admin.example.com. " + serial + " 3600 600 86400 30",
+		"example.com. 300 IN NS ns1.example.com.",
+		"ns1.example.com. 300 IN A 127.0.0.1",
+		"www.example.com. 300 IN A 192.0.2.10",
+		"example.com. 300 IN TXT \"v=spf1 -all\"",
+		"example.com. 300 IN HINFO \"CPU\" \"OS\"",
+	} {
+		v, err := mdns.NewRR(s)
+		if err != nil {
+			t.Fatalf("FAIL: Failed to parse %s: %s\n", s, err)
+		}
+		rr = append(rr, v)
+	}
+
+	return rr
+}
+
+// newXFRTestServer starts a local TCP server that serves the AXFR and IXFR of "example.com." (current serial is 3)
+// and answers the NS queries over UDP on the same port.
+// If tsig is true, the transfers require the TSIG key "transfer-key." with xfrTestSecret.
+func newXFRTestServer(t *testing.T, tsig bool) string {
+
+	newRR := func(s string) mdns.RR {
+		rr, err := mdns.NewRR(s)
+		if err != nil {
+			t.Fatalf("FAIL: Failed to parse %s: %s\n", s, err)
+		}
+		return rr
+	}
+
+	zone := xfrTestZone(t, "3")
+	soa := func(serial string) mdns.RR {
+		return newRR("example.com. 300 IN SOA ns1.example.com. admin.example.com. " + serial + " 3600 600 86400 30")
+	}
+
+	h := mdns.HandlerFunc(func(w mdns.ResponseWriter, r *mdns.Msg) {
+
+		q := r.Question[0]
+
+		if q.Qtype != mdns.TypeAXFR && q.Qtype != mdns.TypeIXFR {
+			standInZone(t, "example.com.", "example.com. 300 IN NS ns1.example.com.", "ns1.example.com. 300 IN A 127.0.0.1")(w, r)
+			return
+		}
+
+		if tsig && (r.IsTsig() == nil || w.TsigStatus() != nil) {
+			m := new(mdns.Msg)
+			m.SetRcode(r, mdns.RcodeRefused)
+			w.WriteMsg(m)
+			return
+		}
+
+		var envs []*mdns.Envelope
+
+		if q.Qtype == mdns.TypeIXFR && r.Ns[0].(*mdns.SOA).Serial == 3 {
+			envs = append(envs, &mdns.Envelope{RR: []mdns.RR{zone[0]}})
+		} else if q.Qtype == mdns.TypeIXFR && r.Ns[0].(*mdns.SOA).Serial == 1 {
+			envs = append(envs,
+				&mdns.Envelope{RR: []mdns.RR{zone[0], soa("1"), newRR("old.example.com. 300 IN A 192.0.2.1"), soa("2")}},
+				&mdns.Envelope{RR: []mdns.RR{newRR("mid.example.com. 300 IN A 192.0.2.2"), soa("2"), newRR("mid.example.com. 300 IN A 192.0.2.2"), soa("3")}},
+				&mdns.Envelope{RR: []mdns.RR{newRR("new.example.com. 300 IN A 192.0.2.3"), zone[0]}},
+			)
+		} else {
+			envs = append(envs,
+				&mdns.Envelope{RR: []mdns.RR{zone[0]}},
+				&mdns.Envelope{RR: zone[1:]},
+				&mdns.Envelope{RR: []mdns.RR{zone[0]}},
+			)
+		}
+
+		ch := make(chan *mdns.Envelope)
+		done := make(chan struct{})
+
+		go func() {
+			new(mdns.Transfer).Out(w, r, ch)
+			close(done)
+		}()
+
+		for i := range envs {
+			ch <- envs[i]
+		}
+		close(ch)
+
+		<-done
+		w.Close()
+	})
+
+	var (
+		l   net.Listener
+		pc  net.PacketConn
+		err error
+	)
+
+	for try := 0; try < 10; try++ {
+
+		if l, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+			t.Fatalf("FAIL: Failed to listen: %s\n", err)
+		}
+
+		if pc, err = net.ListenPacket("udp", l.Addr().String()); err == nil {
+			break
+		}
+
+		l.Close()
+	}
+
+	if err != nil {
+		t.Fatalf("FAIL: Failed to listen: %s\n", err)
+	}
+
+	for _, srv := range []*mdns.Server{{Listener: l, Handler: h}, {PacketConn: pc, Handler: h}} {
+
+		srv := srv
+
+		if tsig {
+			srv.TsigSecret = map[string]string{"transfer-key.": xfrTestSecret}
+		}
+
+		started := make(chan struct{})
+		srv.NotifyStartedFunc = func() { close(started) }
+
+		go srv.ActivateAndServe()
+		<-started
+
+		t.Cleanup(func() { srv.Shutdown() })
+	}
+
+	return l.Addr().String()
+}
+
+func newXFRTestClient(t *testing.T, addr string) Server {
+
+	ip, port, _ := net.SplitHostPort(addr)
+
+	s, err := NewServer("udp", ip, port, time.Second)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create server: %s\n", err)
+	}
+
+	return s
+}
+
+func TestServerAXFR(t *testing.T) {
+
+	s := newXFRTestClient(t, newXFRTestServer(t, false))
+
+	r, err := s.AXFR("example.com", nil)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to transfer: %s\n", err)
+	}
+
+	want := toZoneRecords(xfrTestZone(t, "3"))
+
+	if len(r) != len(want) {
+		t.Fatalf("FAIL: Invalid number of records: %d, want: %d\n%v\n", len(r), len(want), r)
+	}
+
+	for i := range want {
+		if r[i] != want[i] {
+			t.Fatalf("FAIL: Invalid record #%d: %s, want: %s\n", i, r[i], want[i])
+		}
+	}
+
+	if r[5].Type != mdns.TypeHINFO || r[5].Value != "\"CPU\" \"OS\"" {
+		t.Fatalf("FAIL: Invalid unknown type record: %s\n", r[5])
+	}
+
+	if r[2].String() != "ns1.example.com. 300 A 127.0.0.1" {
+		t.Fatalf("FAIL: Invalid string: %s\n", r[2])
+	}
+}
+
+func TestServerIXFR(t *testing.T) {
+
+	s := newXFRTestClient(t, newXFRTestServer(t, false))
+
+	// Up to date
+	r, err := s.IXFR("example.com", 3, nil)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to transfer: %s\n", err)
+	}
+
+	if r.Serial != 3 || r.Full || len(r.Diffs) != 0 {
+		t.Fatalf("FAIL: Invalid up to date response: %+v\n", r)
+	}
+
+	// Differences
+	r, err = s.IXFR("example.com", 1, nil)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to transfer: %s\n", err)
+	}
+
+	if r.Serial != 3 || r.Full || len(r.Diffs) != 2 {
+		t.Fatalf("FAIL: Invalid differences: %+v\n", r)
+	}
+
+	if d := r.Diffs[0]; d.From != 1 || d.To != 2 || len(d.Deleted) != 1 || d.Deleted[0].Name != "old.example.com." || len(d.Added) != 1 || d.Added[0].Name != "mid.example.com." {
+		t.Fatalf("FAIL: Invalid first difference: %+v\n", d)
+	}
+
+	if d := r.Diffs[1]; d.From != 2 || d.To != 3 || len(d.Deleted) != 1 || d.Deleted[0].Name != "mid.example.com." || len(d.Added) != 1 || d.Added[0].Value != "192.0.2.3" {
+		t.Fatalf("FAIL: Invalid second difference: %+v\n", d)
+	}
+
+	// AXFR style response
+	r, err = s.IXFR("example.com", 0, nil)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to transfer: %s\n", err)
+	}
+
+	if !r.Full || len(r.Records) != 6 {
+		t.Fatalf("FAIL: Invalid full response: %+v\n", r)
+	}
+}
+
+func TestServerAXFRTSIG(t *testing.T) {
+
+	s := newXFRTestClient(t, newXFRTestServer(t, true))
+
+	if _, err := s.AXFR("example.com", nil); !errors.Is(err, ErrRefused) {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", ErrRefused, err)
+	}
+
+	if _, err := s.AXFR("example.com", &TSIG{Name: "transfer-key", Secret: "d3Jvbmc="}); err == nil {
+		t.Fatalf("FAIL: error wanted for invalid secret, got nil\n")
+	}
+
+	r, err := s.AXFR("example.com", &TSIG{Name: "transfer-key", Secret: xfrTestSecret})
+	if err != nil {
+		t.Fatalf("FAIL: Failed to transfer: %s\n", err)
+	}
+
+	if len(r) != 6 {
+		t.Fatalf("FAIL: Invalid number of records: %d\n", len(r))
+	}
+}
+
+// newXFRSparseTSIGTestServer starts a local TCP server that answers the AXFR requests with a message for every element of msgs.
+// The messages are signed with xfrTestSecret if signed is true for the index, the MAC covers the unsigned messages before it.
+// If tamper is a valid index, the message is changed after the signing.
+func newXFRSparseTSIGTestServer(t *testing.T, msgs [][]mdns.RR, signed []bool, tamper int) string {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("FAIL: Failed to listen: %s\n", err)
+	}
+
+	t.Cleanup(func() { l.Close() })
+
+	serve := func(conn net.Conn) {
+
+		defer conn.Close()
+
+		c := &mdns.Conn{Conn: conn}
+
+		// ReadMsg() fails without the TSIG secrets
+		p := make([]byte, mdns.MaxMsgSize)
+
+		n, err := c.Read(p)
+		if err != nil {
+			return
+		}
+
+		req := new(mdns.Msg)
+		if err := req.Unpack(p[:n]); err != nil || req.IsTsig() == nil {
+			return
+		}
+
+		var (
+			mac      = req.IsTsig().MAC
+			unsigned []byte
+		)
+
+		for i := range msgs {
+
+			m := new(mdns.Msg)
+			m.SetReply(req)
+			m.Answer = msgs[i]
+
+			var out []byte
+
+			if signed[i] {
+				m.SetTsig("transfer-key.", mdns.HmacSHA256, 300, time.Now().Unix())
+				out, mac, err = mdns.TsigGenerateWithProvider(m, &xfrTsigProvider{secret: xfrTestSecret, prior: 2 + len(mac)/2, unsigned: unsigned}, mac, i > 0)
+				unsigned = nil
+			} else {
+				out, err = m.Pack()
+				unsigned = append(unsigned, out...)
+			}
+
+			if err != nil {
+				t.Errorf("FAIL: Failed to pack: %s\n", err)
+				return
+			}
+
+			if i == tamper {
+				m.Answer = append(m.Answer, m.Answer...)
+				if out, err = m.Pack(); err != nil {
+					return
+				}
+			}
+
+			if _, err := c.Write(out); err != nil {
+				return
+			}
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestServerAXFRSparseTSIG(t *testing.T) {
+
+	zone := xfrTestZone(t, "3")
+	key := &TSIG{Name: "transfer-key", Secret: xfrTestSecret}
+
+	msgs := [][]mdns.RR{{zone[0]}, zone[1:3], zone[3:], {zone[0]}}
+
+	// Only the first and the last message is signed
+	s := newXFRTestClient(t, newXFRSparseTSIGTestServer(t, msgs, []bool{true, false, false, true}, -1))
+
+	r, err := s.AXFR("example.com", key)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to transfer: %s\n", err)
+	}
+
+	if len(r) != 6 {
+		t.Fatalf("FAIL: Invalid number of records: %d\n", len(r))
+	}
+
+	// Unsigned last message
+	s = newXFRTestClient(t, newXFRSparseTSIGTestServer(t, msgs, []bool{true, true, false, false}, -1))
+
+	if _, err := s.AXFR("example.com", key); !errors.Is(err, mdns.ErrSig) {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", mdns.ErrSig, err)
+	}
+
+	// Changed unsigned message
+	s = newXFRTestClient(t, newXFRSparseTSIGTestServer(t, msgs, []bool{true, false, false, true}, 2))
+
+	if _, err := s.AXFR("example.com", key); !errors.Is(err, mdns.ErrSig) {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", mdns.ErrSig, err)
+	}
+
+	// Too many unsigned messages
+	msgs = [][]mdns.RR{{zone[0]}}
+	signed := []bool{true}
+
+	for i := 0; i <= MaxUnsignedXFR; i++ {
+		msgs = append(msgs, zone[3:4])
+		signed = append(signed, false)
+	}
+
+	msgs = append(msgs, []mdns.RR{zone[0]})
+	signed = append(signed, true)
+
+	s = newXFRTestClient(t, newXFRSparseTSIGTestServer(t, msgs, signed, -1))
+
+	if _, err := s.AXFR("example.com", key); !errors.Is(err, mdns.ErrSig) || !strings.Contains(err.Error(), "unsigned") {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", mdns.ErrSig, err)
+	}
+}
+
+func TestServerAXFRProtocol(t *testing.T) {
+
+	s, err := NewServerIterative(nil, time.Second)
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if _, err := s.AXFR("example.com", nil); !errors.Is(err, ErrTransferProtocol) {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", ErrTransferProtocol, err)
+	}
+}
+
+func TestServerAXFRContextCanceled(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s := newXFRTestClient(t, newXFRTestServer(t, false))
+
+	if _, err := s.AXFRContext(ctx, "example.com", nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("FAIL: error wanted: %s, error got: %v\n", context.Canceled, err)
+	}
+}
+
+func TestServersCheckOpenTransfer(t *testing.T) {
+
+	addr := newXFRTestServer(t, false)
+	_, port, _ := net.SplitHostPort(addr)
+
+	srvs, err := NewServersStr(1, time.Second, "udp://"+addr)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to create servers: %s\n", err)
+	}
+
+	r, err := srvs.checkOpenTransfer(context.Background(), "example.com", port, time.Second)
+	if err != nil {
+		t.Fatalf("FAIL: Failed to check: %s\n", err)
+	}
+
+	if len(r) != 1 || r[0].NS != "ns1.example.com." || r[0].IP != "127.0.0.1" || !r[0].Open || r[0].Records != 6 || r[0].Err != nil {
+		t.Fatalf("FAIL: Invalid result: %+v\n", r)
+	}
+}
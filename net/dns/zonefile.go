@@ -0,0 +1,480 @@
+package dns
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	mdns "github.com/miekg/dns"
+)
+
+// maxTXTString is the maximum length of a character-string in a TXT record.
+const maxTXTString = 255
+
+// Zone is a DNS zone read from or written to an RFC 1035 master file.
+type Zone struct {
+	Origin  string       // FQDN of the zone apex (eg.: "example.com.")
+	TTL     uint32       // Default TTL ($TTL), 0 means not set
+	Records []ZoneRecord // Names are FQDNs
+}
+
+// ParseZone parses the master file from r.
+// origin is the initial origin, relative names are relative to origin until an $ORIGIN directive.
+// The $ORIGIN and $TTL directives are supported, $INCLUDE is not (see ParseZoneFile()).
+//
+// The strings of a TXT record are concatenated into one Record.
+func ParseZone(r io.Reader, origin string) (*Zone, error) {
+
+	return parseZone(r, origin, "")
+}
+
+// ParseZoneFile parses the master file at path.
+// The $ORIGIN, $TTL and $INCLUDE directives are supported, relative $INCLUDE paths are relative to path.
+//
+// The strings of a TXT record are concatenated into one Record.
+func ParseZoneFile(path string, origin string) (*Zone, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zone file: %w", err)
+	}
+	defer f.Close()
+
+	return parseZone(f, origin, path)
+}
+
+func parseZone(r io.Reader, origin string, file string) (*Zone, error) {
+
+	z := &Zone{Origin: mdns.Fqdn(strings.ToLower(origin))}
+
+	// The $TTL directive is not exposed by the parser, so look for it in the lines read by the parser
+	tr := &ttlReader{r: r}
+
+	zp := mdns.NewZoneParser(tr, z.Origin, file)
+	zp.SetIncludeAllowed(file != "")
+
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		z.Records = append(z.Records, toZoneRecord(rr)...)
+	}
+
+	if err := zp.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse zone: %w", err)
+	}
+
+	z.TTL = tr.ttl
+
+	return z, nil
+}
+
+// ttlReader passes the master file from r to the parser and records the value of the first $TTL directive.
+type ttlReader struct {
+	r     io.Reader
+	line  []byte
+	skip  bool // The current line is not a directive
+	ttl   uint32
+	found bool
+}
+
+func (t *ttlReader) Read(p []byte) (int, error) {
+
+	n, err := t.r.Read(p)
+
+	if !t.found {
+		t.scan(p[:n])
+
+		// The last line may not end with a newline
+		if err == io.EOF {
+			t.scan([]byte{'\n'})
+		}
+	}
+
+	return n, err
+}
+
+// scan collects the lines in b and looks for the $TTL directive in every complete line.
+func (t *ttlReader) scan(b []byte) {
+
+	for _, c := range b {
+
+		switch {
+		case c == '\n':
+		case t.skip:
+			continue
+		case len(t.line) == 0 && c != '$':
+			// The directives start at the beginning of the line
+			t.skip = true
+			continue
+		default:
+			t.line = append(t.line, c)
+			continue
+		}
+
+		fields := strings.Fields(string(t.line))
+		t.line, t.skip = t.line[:0], false
+
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "$TTL") {
+			continue
+		}
+
+		if ttl, ok := parseTTL(fields[1]); ok {
+			t.ttl, t.found = ttl, true
+			t.line = nil
+			return
+		}
+	}
+}
+
+// parseTTL parses the TTL s in seconds or with BIND style units (eg.: "1h30m").
+func parseTTL(s string) (uint32, bool) {
+
+	if v, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return uint32(v), true
+	}
+
+	var ttl, n uint64
+
+	for _, c := range strings.ToLower(s) {
+
+		var unit uint64
+
+		switch c {
+		case 's':
+			unit = 1
+		case 'm':
+			unit = 60
+		case 'h':
+			unit = 3600
+		case 'd':
+			unit = 86400
+		case 'w':
+			unit = 604800
+		default:
+			if c < '0' || c > '9' {
+				return 0, false
+			}
+			n = n*10 + uint64(c-'0')
+			continue
+		}
+
+		ttl += n * unit
+		n = 0
+	}
+
+	if n != 0 || ttl > 1<<32-1 {
+		return 0, false
+	}
+
+	return uint32(ttl), true
+}
+
+// toZoneRecord converts rr to ZoneRecord.
+// Unlike toZoneRecords(), the strings of TXT are concatenated.
+func toZoneRecord(rr mdns.RR) []ZoneRecord {
+
+	if v, ok := rr.(*mdns.TXT); ok {
+		return []ZoneRecord{{Name: strings.ToLower(v.Hdr.Name), TTL: v.Hdr.Ttl, Record: Record{Type: TypeTXT, Value: strings.Join(v.Txt, "")}}}
+	}
+
+	r := toZoneRecords([]mdns.RR{rr})
+
+	for i := range r {
+		r[i].Name = strings.ToLower(r[i].Name)
+	}
+
+	return r
+}
+
+// ParseZoneRecord parses a record in master file format (eg.: "www 300 IN A 192.0.2.1").
+// Relative names are relative to origin, if the TTL is missing, ttl is used.
+func ParseZoneRecord(s string, origin string, ttl uint32) (ZoneRecord, error) {
+
+	zp := mdns.NewZoneParser(strings.NewReader(s), mdns.Fqdn(origin), "")
+	zp.SetDefaultTTL(ttl)
+
+	rr, ok := zp.Next()
+	if !ok {
+		if err := zp.Err(); err != nil {
+			return ZoneRecord{}, err
+		}
+		return ZoneRecord{}, fmt.Errorf("no record")
+	}
+
+	if _, more := zp.Next(); more {
+		return ZoneRecord{}, fmt.Errorf("more than one record")
+	}
+
+	zr := toZoneRecord(rr)
+	if len(zr) != 1 {
+		return ZoneRecord{}, fmt.Errorf("invalid record: %s", s)
+	}
+
+	return zr[0], nil
+}
+
+// RDATA returns the RDATA of r in master file format.
+// The value of TXT is quoted and split into 255 bytes long strings, the value of CAA is quoted.
+// Returns error if the result is not a valid RDATA of the type.
+func (r ZoneRecord) RDATA() (string, error) {
+
+	t, ok := mdns.TypeToString[r.Type]
+	if !ok {
+		return "", fmt.Errorf("unknown type: %d", r.Type)
+	}
+
+	v := r.Value
+
+	switch r.Type {
+	case TypeTXT:
+		v = quoteTXT(v)
+	case TypeCAA:
+		// "flag tag value"
+		fields := strings.SplitN(v, " ", 3)
+		if len(fields) == 3 && !strings.HasPrefix(fields[2], "\"") {
+			v = fields[0] + " " + fields[1] + " " + strconv.Quote(fields[2])
+		}
+	}
+
+	// Validate
+	if _, err := mdns.NewRR(". 0 IN " + t + " " + v); err != nil {
+		return "", fmt.Errorf("invalid %s value %q: %w", t, r.Value, err)
+	}
+
+	return v, nil
+}
+
+// quoteTXT splits the TXT value s into quoted character-strings.
+// The escape sequences (eg.: "\"", "\010") are kept and not splitted.
+func quoteTXT(s string) string {
+
+	if s == "" {
+		return `""`
+	}
+
+	var (
+		b     strings.Builder
+		chunk int
+	)
+
+	b.WriteByte('"')
+
+	for i := 0; i < len(s); {
+
+		n := 1
+
+		if s[i] == '\\' && i+1 < len(s) {
+			n = 2
+			if i+3 < len(s) && isDigit(s[i+1]) && isDigit(s[i+2]) && isDigit(s[i+3]) {
+				n = 4
+			}
+		}
+
+		if chunk+n > maxTXTString {
+			b.WriteString(`" "`)
+			chunk = 0
+		}
+
+		if s[i] == '"' && n == 1 {
+			// Unescaped quote
+			b.WriteByte('\\')
+		}
+
+		b.WriteString(s[i : i+n])
+		chunk += n
+		i += n
+	}
+
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// relative returns name relative to origin ("@" for origin).
+// If name is not in origin, returns the FQDN.
+func relative(name string, origin string) string {
+
+	name = mdns.Fqdn(name)
+
+	switch {
+	case origin == "" || origin == ".":
+		return name
+	case strings.EqualFold(name, origin):
+		return "@"
+	case mdns.IsSubDomain(origin, name):
+		return name[:len(name)-len(origin)-1]
+	default:
+		return name
+	}
+}
+
+// WriteTo writes z to w in master file format.
+// The names are written relative to Origin, the TTL is omitted if equals to the default TTL.
+// The SOA records are written first.
+func (z *Zone) WriteTo(w io.Writer) (int64, error) {
+
+	bw := bufio.NewWriter(w)
+	n := 0
+
+	write := func(format string, a ...any) {
+		c, _ := fmt.Fprintf(bw, format, a...)
+		n += c
+	}
+
+	origin := mdns.Fqdn(z.Origin)
+
+	write("$ORIGIN %s\n", origin)
+
+	if z.TTL != 0 {
+		write("$TTL %d\n", z.TTL)
+	}
+
+	records := make([]ZoneRecord, 0, len(z.Records))
+
+	for i := range z.Records {
+		if z.Records[i].Type == TypeSOA {
+			records = append(records, z.Records[i])
+		}
+	}
+
+	for i := range z.Records {
+		if z.Records[i].Type != TypeSOA {
+			records = append(records, z.Records[i])
+		}
+	}
+
+	for i := range records {
+
+		rdata, err := records[i].RDATA()
+		if err != nil {
+			bw.Flush()
+			return int64(n), fmt.Errorf("%s: %w", records[i].Name, err)
+		}
+
+		ttl := ""
+		if records[i].TTL != z.TTL || z.TTL == 0 {
+			ttl = strconv.FormatUint(uint64(records[i].TTL), 10)
+		}
+
+		write("%s\t%s\tIN\t%s\t%s\n", relative(records[i].Name, origin), ttl, mdns.TypeToString[records[i].Type], rdata)
+	}
+
+	return int64(n), bw.Flush()
+}
+
+// Lookup returns the records of name.
+// Name can be relative to Origin, "@" means the Origin.
+func (z *Zone) Lookup(name string) []Record {
+
+	switch {
+	case name == "@":
+		name = mdns.Fqdn(z.Origin)
+	case !mdns.IsFqdn(name):
+		name = name + "." + mdns.Fqdn(z.Origin)
+	}
+
+	var r []Record
+
+	for i := range z.Records {
+		if strings.EqualFold(z.Records[i].Name, name) {
+			r = append(r, z.Records[i].Record)
+		}
+	}
+
+	return r
+}
+
+// Add adds the records to the zone with name and ttl.
+// Name can be relative to Origin, "@" means the Origin.
+func (z *Zone) Add(name string, ttl uint32, records ...Record) {
+
+	switch {
+	case name == "@":
+		name = mdns.Fqdn(z.Origin)
+	case !mdns.IsFqdn(name):
+		name = name + "." + mdns.Fqdn(z.Origin)
+	}
+
+	for i := range records {
+		z.Records = append(z.Records, ZoneRecord{Name: strings.ToLower(name), TTL: ttl, Record: records[i]})
+	}
+}
+
+// Serial returns the serial of the SOA record of the zone.
+// Returns 0 if the zone has no SOA.
+func (z *Zone) Serial() uint32 {
+
+	for i := range z.Records {
+
+		if z.Records[i].Type != TypeSOA {
+			continue
+		}
+
+		fields := strings.Fields(z.Records[i].Value)
+		if len(fields) < 3 {
+			return 0
+		}
+
+		s, _ := strconv.ParseUint(fields[2], 10, 32)
+
+		return uint32(s)
+	}
+
+	return 0
+}
+
+// Diff returns the differences between z and other.
+// The records are compared by name (case insensitive), TTL, type and value, a TTL change is a deletion and an addition.
+// The SOA records are not included.
+func (z *Zone) Diff(other *Zone) ZoneDiff {
+
+	key := func(r ZoneRecord) string {
+		return strings.ToLower(r.Name) + " " + strconv.FormatUint(uint64(r.TTL), 10) + " " + strconv.Itoa(int(r.Type)) + " " + r.Value
+	}
+
+	count := func(rs []ZoneRecord) map[string]int {
+
+		m := make(map[string]int, len(rs))
+
+		for i := range rs {
+			m[key(rs[i])]++
+		}
+
+		return m
+	}
+
+	d := ZoneDiff{From: z.Serial(), To: other.Serial()}
+
+	before := count(z.Records)
+	after := count(other.Records)
+
+	// The counters handle the duplicated records
+	for i := range z.Records {
+
+		k := key(z.Records[i])
+
+		if z.Records[i].Type != TypeSOA && after[k] <= 0 {
+			d.Deleted = append(d.Deleted, z.Records[i])
+		}
+
+		after[k]--
+	}
+
+	for i := range other.Records {
+
+		k := key(other.Records[i])
+
+		if other.Records[i].Type != TypeSOA && before[k] <= 0 {
+			d.Added = append(d.Added, other.Records[i])
+		}
+
+		before[k]--
+	}
+
+	return d
+}
@@ -0,0 +1,158 @@
+package dns
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testZoneFile = `$ORIGIN example.com.
+$TTL 1h
+@	IN	SOA	ns1 hostmaster 2024010101 7200 3600 1209600 300
+	IN	NS	ns1
+	IN	MX	10 mail
+	IN	TXT	"v=spf1 " "-all"
+	IN	CAA	0 issue "letsencrypt.org"
+ns1	IN	A	192.0.2.53
+www	300	IN	CNAME	@
+$ORIGIN sub.example.com.
+host	IN	AAAA	2001:db8::1
+`
+
+func TestParseZone(t *testing.T) {
+
+	z, err := ParseZone(strings.NewReader(testZoneFile), "example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if z.TTL != 3600 || z.Serial() != 2024010101 || len(z.Records) != 8 {
+		t.Fatalf("FAIL: unexpected zone: TTL %d, serial %d, %d records\n", z.TTL, z.Serial(), len(z.Records))
+	}
+
+	tests := []struct {
+		Name  string
+		Type  uint16
+		Value string
+	}{
+		{"@", TypeMX, "10 mail.example.com."},
+		{"@", TypeTXT, "v=spf1 -all"},
+		{"@", TypeCAA, "0 issue letsencrypt.org"},
+		{"www", TypeCNAME, "example.com."},
+		{"host.sub.example.com.", TypeAAAA, "2001:db8::1"},
+	}
+
+	for i := range tests {
+
+		found := false
+
+		for _, r := range z.Lookup(tests[i].Name) {
+			if r.Type == tests[i].Type && r.Value == tests[i].Value {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Errorf("FAIL: missing %s %s %s\n", tests[i].Name, TypeToString(tests[i].Type), tests[i].Value)
+		}
+	}
+}
+
+func TestParseZoneLateTTL(t *testing.T) {
+
+	var b strings.Builder
+
+	b.WriteString("$ORIGIN example.com.\n")
+
+	// Records with explicit TTL before the directive
+	for i := 0; b.Len() < 8192; i++ {
+		fmt.Fprintf(&b, "host%d 300 IN TXT \"%s\"\n", i, strings.Repeat("x", 100))
+	}
+
+	b.WriteString("$TTL 2h ; default\nwww IN A 192.0.2.1")
+
+	z, err := ParseZone(strings.NewReader(b.String()), "example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if z.TTL != 7200 {
+		t.Fatalf("FAIL: TTL wanted: 7200, got: %d\n", z.TTL)
+	}
+
+	if r := z.Records[len(z.Records)-1]; r.Name != "www.example.com." || r.TTL != 7200 {
+		t.Fatalf("FAIL: unexpected record: %v\n", r)
+	}
+}
+
+func TestZoneWriteTo(t *testing.T) {
+
+	z, err := ParseZone(strings.NewReader(testZoneFile), "example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	z.Add("long", 60, Record{Type: TypeTXT, Value: strings.Repeat("a", 300) + `\"quoted\"`})
+
+	var b strings.Builder
+
+	if _, err := z.WriteTo(&b); err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if !strings.Contains(b.String(), "www\t300\tIN\tCNAME\texample.com.") || !strings.Contains(b.String(), "ns1\t\tIN\tA\t192.0.2.53") {
+		t.Fatalf("FAIL: unexpected output:\n%s\n", b.String())
+	}
+
+	z2, err := ParseZone(strings.NewReader(b.String()), "example.com")
+	if err != nil {
+		t.Fatalf("FAIL: failed to parse the written zone: %s\n%s\n", err, b.String())
+	}
+
+	if d := z.Diff(z2); len(d.Added) != 0 || len(d.Deleted) != 0 {
+		t.Fatalf("FAIL: the written zone differs: %#v\n%s\n", d, b.String())
+	}
+}
+
+func TestZoneDiff(t *testing.T) {
+
+	z, err := ParseZone(strings.NewReader(testZoneFile), "example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	z2, err := ParseZone(strings.NewReader(strings.Replace(testZoneFile, "192.0.2.53", "192.0.2.54", 1)), "example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	d := z.Diff(z2)
+
+	if len(d.Deleted) != 1 || d.Deleted[0].Value != "192.0.2.53" || len(d.Added) != 1 || d.Added[0].Value != "192.0.2.54" {
+		t.Fatalf("FAIL: unexpected diff: %#v\n", d)
+	}
+}
+
+func TestParseZoneFileInclude(t *testing.T) {
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "hosts.inc"), []byte("www IN A 192.0.2.1\n"), 0o644); err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "example.com.zone"), []byte("$TTL 600\n$INCLUDE "+filepath.Join(dir, "hosts.inc")+"\n"), 0o644); err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	z, err := ParseZoneFile(filepath.Join(dir, "example.com.zone"), "example.com")
+	if err != nil {
+		t.Fatalf("FAIL: %s\n", err)
+	}
+
+	if len(z.Records) != 1 || z.Records[0].Name != "www.example.com." || z.Records[0].TTL != 600 {
+		t.Fatalf("FAIL: unexpected records: %v\n", z.Records)
+	}
+}
@@ -1,6 +1,7 @@
 package ip
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"net"
@@ -133,11 +134,18 @@ func Increase(ip net.IP) {
 // If the mask is /31 or /127, the usable channel is nil.
 func GetList(n net.IPNet) (net.IP, net.IP, <-chan net.IP, error) {
 
+	return GetListContext(context.Background(), n)
+}
+
+// GetListContext is like GetList, but the producer of the usable channel is bound to ctx.
+// If ctx is done, the usable channel is closed early.
+func GetListContext(ctx context.Context, n net.IPNet) (net.IP, net.IP, <-chan net.IP, error) {
+
 	switch {
 	case IsValid4(n.IP):
-		return GetList4(n)
+		return GetList4Context(ctx, n)
 	case IsValid6(n.IP):
-		return GetList6(n)
+		return GetList6Context(ctx, n)
 	default:
 		return nil, nil, nil, fmt.Errorf("invalid IP address: %s", n.IP)
 	}
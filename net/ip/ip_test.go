@@ -1,8 +1,10 @@
 package ip
 
 import (
+	"context"
 	"net"
 	"testing"
+	"time"
 )
 
 func TestRandom(t *testing.T) {
@@ -109,3 +111,72 @@ func TestIsValid(t *testing.T) {
 		}
 	}
 }
+
+func TestGetListContext(t *testing.T) {
+
+	_, ipnet, err := net.ParseCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("Failed to parse cidr: %s\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	_, _, u, err := GetListContext(ctx, *ipnet)
+	if err != nil {
+		t.Fatalf("Failed to GetListContext: %s\n", err)
+	}
+
+	<-u
+
+	cancel()
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case _, ok := <-u:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("Usable channel is not closed after cancel\n")
+		}
+	}
+}
+
+func TestGetListContext64(t *testing.T) {
+
+	_, ipnet, err := net.ParseCIDR("2001:db8::/64")
+	if err != nil {
+		t.Fatalf("Failed to parse cidr: %s\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+
+		defer close(done)
+
+		first, last, u, err := GetListContext(ctx, *ipnet)
+		if err != nil {
+			t.Errorf("Failed to GetListContext: %s\n", err)
+			return
+		}
+
+		if !first.Equal(net.ParseIP("2001:db8::")) || !last.Equal(net.ParseIP("2001:db8::ffff:ffff:ffff:ffff")) {
+			t.Errorf("Invalid first/last: %s %s\n", first, last)
+		}
+
+		for range u {
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("GetListContext is blocked on /64 with cancelled context\n")
+	}
+}
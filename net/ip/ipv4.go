@@ -2,6 +2,7 @@ package ip
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math/rand"
 	"net"
@@ -107,6 +108,13 @@ func GetPublic4() net.IP {
 // If the mask is /31 the usable channel is nil.
 func GetList4(n net.IPNet) (net.IP, net.IP, <-chan net.IP, error) {
 
+	return GetList4Context(context.Background(), n)
+}
+
+// GetList4Context is like GetList4, but the producer of the usable channel is bound to ctx.
+// If ctx is done, the usable channel is closed early.
+func GetList4Context(ctx context.Context, n net.IPNet) (net.IP, net.IP, <-chan net.IP, error) {
+
 	switch {
 	case !IsValid4(n.IP):
 		return nil, nil, nil, fmt.Errorf("invalid IPv4 address: %s", n.IP)
@@ -123,15 +131,11 @@ func GetList4(n net.IPNet) (net.IP, net.IP, <-chan net.IP, error) {
 		return first, nil, nil, nil
 	}
 
+	// The last address has every host bit set
 	last := make(net.IP, net.IPv4len)
-	copy(last, first)
-
-	// Find the last address
-	ip := make(net.IP, net.IPv4len)
-	copy(ip, first)
 
-	for Increase(ip); n.Contains(ip); Increase(ip) {
-		copy(last, ip)
+	for i := range last {
+		last[i] = first[i] | ^n.Mask[i]
 	}
 
 	// IPv4/31 has two IP address: first and last
@@ -144,21 +148,31 @@ func GetList4(n net.IPNet) (net.IP, net.IP, <-chan net.IP, error) {
 		With unbuffered channel, one can iterate over it with range.
 	*/
 
-	// Start again from the first address.
+	ip := make(net.IP, net.IPv4len)
 	copy(ip, first)
 
 	// TODO: Buffered channel?
 	usable := make(chan net.IP)
 
 	go func() {
+		defer close(usable)
+
 		// Increase ip until the last address
 		for Increase(ip); !ip.Equal(last); Increase(ip) {
+
+			if ctx.Err() != nil {
+				return
+			}
+
 			v := make(net.IP, len(ip))
 			copy(v, ip)
 
-			usable <- v
+			select {
+			case usable <- v:
+			case <-ctx.Done():
+				return
+			}
 		}
-		close(usable)
 	}()
 
 	return first, last, usable, nil
@@ -2,6 +2,7 @@ package ip
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math/rand"
 	"net"
@@ -110,6 +111,13 @@ func GetPublic6() net.IP {
 // If the mask is /127 the usable channel is nil.
 func GetList6(n net.IPNet) (net.IP, net.IP, <-chan net.IP, error) {
 
+	return GetList6Context(context.Background(), n)
+}
+
+// GetList6Context is like GetList6, but the producer of the usable channel is bound to ctx.
+// If ctx is done, the usable channel is closed early.
+func GetList6Context(ctx context.Context, n net.IPNet) (net.IP, net.IP, <-chan net.IP, error) {
+
 	switch {
 	case !IsValid6(n.IP):
 		return nil, nil, nil, fmt.Errorf("invalid IPv6 address: %s", n.IP)
@@ -126,15 +134,11 @@ func GetList6(n net.IPNet) (net.IP, net.IP, <-chan net.IP, error) {
 		return first, nil, nil, nil
 	}
 
+	// The last address has every host bit set
 	last := make(net.IP, net.IPv6len)
-	copy(last, first)
-
-	// Find the last address
-	ip := make(net.IP, net.IPv6len)
-	copy(ip, first)
 
-	for Increase(ip); n.Contains(ip); Increase(ip) {
-		copy(last, ip)
+	for i := range last {
+		last[i] = first[i] | ^n.Mask[i]
 	}
 
 	// IPv6/127 has two IP address: first and last
@@ -147,21 +151,31 @@ func GetList6(n net.IPNet) (net.IP, net.IP, <-chan net.IP, error) {
 		With unbuffered channel, one can iterate over it with range.
 	*/
 
-	// Start again from the first address.
+	ip := make(net.IP, net.IPv6len)
 	copy(ip, first)
 
 	// TODO: Buffered channel?
 	usable := make(chan net.IP)
 
 	go func() {
+		defer close(usable)
+
 		// Increase ip until the last address
 		for Increase(ip); !ip.Equal(last); Increase(ip) {
+
+			if ctx.Err() != nil {
+				return
+			}
+
 			v := make(net.IP, len(ip))
 			copy(v, ip)
 
-			usable <- v
+			select {
+			case usable <- v:
+			case <-ctx.Done():
+				return
+			}
 		}
-		close(usable)
 	}()
 
 	return first, last, usable, nil
@@ -71,3 +71,22 @@ func Email(v string) bool {
 
 	return true
 }
+
+// NormalizeEmail returns v with the domain part converted to lower cased A-labels (eg.: "user@Bücher.example" -> "user@xn--bcher-kva.example")
+// and whether v is a valid email address (see Email()).
+// The local part is not changed.
+func NormalizeEmail(v string) (string, bool) {
+
+	if !Email(v) {
+		return "", false
+	}
+
+	sep := strings.IndexByte(v, '@')
+
+	d, err := dns.ToASCII(v[sep+1:])
+	if err != nil {
+		return "", false
+	}
+
+	return v[:sep+1] + d, true
+}
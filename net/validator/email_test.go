@@ -18,6 +18,8 @@ func TestEmail(t *testing.T) {
 		{URL: "x@example.com", Result: true},
 		{URL: "example-indeed@strange-example.com", Result: true},
 		{URL: "test/test@test.com", Result: true},
+		{URL: "user@bücher.example", Result: true},
+		{URL: "user@xn--bcher-kva.example", Result: true},
 
 		{URL: "test", Result: false},
 		{URL: "test.com", Result: false},
@@ -26,6 +28,7 @@ func TestEmail(t *testing.T) {
 		{URL: "a.@test.com", Result: false},
 		{URL: "a..a@test.com", Result: false},
 		{URL: "a@test", Result: false},
+		{URL: "a@xn--a.example", Result: false},
 		{URL: "A@b@c@example.com", Result: false},
 		{URL: "a\"b(c)d,e:f;g<h>i[j\\k]l@example.com", Result: false},
 		{URL: "just\"not\"right@example.com", Result: false},
@@ -39,3 +42,14 @@ func TestEmail(t *testing.T) {
 		}
 	}
 }
+
+func TestNormalizeEmail(t *testing.T) {
+
+	if v, ok := NormalizeEmail("User@Bücher.Example"); !ok || v != "User@xn--bcher-kva.example" {
+		t.Fatalf("FAIL: Invalid normalized email: %s, %v", v, ok)
+	}
+
+	if _, ok := NormalizeEmail("user@xn--a.example"); ok {
+		t.Fatalf("FAIL: Invalid email normalized")
+	}
+}
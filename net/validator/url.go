@@ -265,3 +265,37 @@ func URL(v string) bool {
 
 	return checkURLCodePoints(r.Fragment)
 }
+
+// NormalizeURL returns v with the internationalized host converted to A-labels (eg.: "https://bücher.example/" -> "https://xn--bcher-kva.example/")
+// and whether v is a valid URL (see URL()).
+// If the host is not an internationalized domain name, v is returned unchanged.
+func NormalizeURL(v string) (string, bool) {
+
+	if !URL(v) {
+		return "", false
+	}
+
+	r, err := url.Parse(v)
+	if err != nil {
+		return "", false
+	}
+
+	host, port := splitHostPort(r.Host)
+
+	if !dns.IsIDN(host) {
+		return v, true
+	}
+
+	host, err = dns.ToASCII(host)
+	if err != nil {
+		return "", false
+	}
+
+	if port != "" {
+		host += ":" + port
+	}
+
+	r.Host = host
+
+	return r.String(), true
+}